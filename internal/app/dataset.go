@@ -0,0 +1,60 @@
+// dataset.go - `kubemage dataset export`: fine-tuning data from recorded sessions
+package app
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/siryoos/kubemage/internal/engine"
+	"github.com/siryoos/kubemage/internal/llm"
+)
+
+// RunDataset runs the dataset subcommand with its arguments (without "dataset")
+func RunDataset(args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "export" {
+		return fmt.Errorf("usage: kubemage dataset export [flags]")
+	}
+
+	defaults := engine.DefaultDatasetOptions()
+	fs := flag.NewFlagSet("dataset export", flag.ContinueOnError)
+	fs.SetOutput(out)
+	format := fs.String("format", defaults.Format, "output format: chat, sharegpt or alpaca")
+	minQuality := fs.Float64("min-quality", defaults.MinQuality, "drop examples scored below this quality (0-1)")
+	split := fs.Float64("val-split", defaults.ValidationSplit, "fraction of examples held out for validation")
+	dedup := fs.Float64("dedup", defaults.DedupThreshold, "prompt similarity (0-1) at which examples count as duplicates")
+	dataDir := fs.String("data", "./kubemage_data", "flight recorder directory")
+	outDir := fs.String("out", "./kubemage_data/dataset", "directory for train.jsonl and validation.jsonl")
+	noSystem := fs.Bool("no-system", false, "omit the system prompt from chat formats")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *split < 0 || *split >= 1 {
+		return fmt.Errorf("--val-split must be in [0, 1)")
+	}
+
+	recorder := engine.NewFlightRecorder(*dataDir)
+	if err := recorder.Load(); err != nil {
+		return err
+	}
+
+	opts := engine.DatasetOptions{
+		Format:          *format,
+		MinQuality:      *minQuality,
+		ValidationSplit: *split,
+		DedupThreshold:  *dedup,
+	}
+	if !*noSystem {
+		opts.SystemPrompt = llm.CommandOnlySystemPrompt()
+	}
+	stats, err := recorder.ExportDataset(*outDir, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Exported %d %s examples to %s (train %d, validation %d)\n",
+		stats.Train+stats.Validation, opts.Format, *outDir, stats.Train, stats.Validation)
+	fmt.Fprintf(out, "Dropped %d below quality %.2f and %d near-duplicates; redacted secrets in %d examples.\n",
+		stats.LowQuality, opts.MinQuality, stats.Duplicates, stats.Redacted)
+	return nil
+}
@@ -0,0 +1,90 @@
+// eval.go - `kubemage eval`: score models on a golden prompt suite
+package app
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/config"
+	"github.com/siryoos/kubemage/internal/engine"
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+// RunEval runs the eval subcommand with its arguments (without "eval") and prints the report to out
+func RunEval(ctx context.Context, cfg *config.Config, runner execx.Runner, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	fs.SetOutput(out)
+	suitePath := fs.String("suite", "eval/golden.yaml", "YAML suite of tasks and expected outputs")
+	modelList := fs.String("models", "", "comma-separated models to compare (default: the configured generation model)")
+	reportPath := fs.String("out", engine.DefaultEvalReportPath, "where to write the JSON report")
+	noCluster := fs.Bool("no-cluster", false, "check manifests by structure only, even when a cluster is reachable")
+	timeout := fs.Duration("timeout", 2*time.Minute, "timeout per case")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	suite, err := engine.LoadEvalSuite(*suitePath)
+	if err != nil {
+		return err
+	}
+	models := splitModels(*modelList)
+	if len(models) == 0 {
+		models = []string{cfg.Models.Generation}
+		if models[0] == "" {
+			models[0] = cfg.GetModel()
+		}
+	}
+
+	// Manifest dry-runs need a reachable cluster; generated commands are never run
+	dryRunner := runner
+	if *noCluster || !clusterReachable(ctx, runner) {
+		dryRunner = nil
+	}
+
+	harness := engine.NewEvalHarness(engine.LLMEvalGenerator, dryRunner)
+	harness.Timeout = *timeout
+	harness.Progress = func(res engine.EvalResult) {
+		mark := "✓"
+		if !res.Correct || !res.Valid {
+			mark = "✗"
+		}
+		fmt.Fprintf(out, "  %s %s %s (%s)\n", mark, res.Model, res.CaseID, res.Latency.Round(time.Millisecond))
+	}
+
+	fmt.Fprintf(out, "Evaluating %d cases from %s against %s\n", len(suite.Cases), *suitePath, strings.Join(models, ", "))
+	report := harness.Run(ctx, suite, models)
+	fmt.Fprintf(out, "\n%s", report.Render())
+
+	if err := report.Save(*reportPath); err != nil {
+		return fmt.Errorf("failed to save eval report: %w", err)
+	}
+	fmt.Fprintf(out, "\nReport saved to %s; model selection will favor the best-scoring models for similar prompts.\n", *reportPath)
+	if best := report.Best(); best != "" && best != cfg.Models.Generation {
+		fmt.Fprintf(out, "To make %s the default, set models.generation in config.yaml or run /model set generation %s.\n", best, best)
+	}
+	return ctx.Err()
+}
+
+func splitModels(list string) []string {
+	var models []string
+	for _, m := range strings.Split(list, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+func clusterReachable(ctx context.Context, runner execx.Runner) bool {
+	if runner == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, _, err := runner.Run(ctx, "kubectl", "cluster-info", "--request-timeout=5s")
+	return err == nil
+}
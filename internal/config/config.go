@@ -32,6 +32,11 @@ type IntelligenceSettings struct {
 	PlaybooksEnabled    bool    `yaml:"playbooks_enabled"`
 	RiskAssessment      bool    `yaml:"risk_assessment"`
 	QuickActionsEnabled bool    `yaml:"quick_actions_enabled"`
+
+	SelfConsistencySamples int `yaml:"self_consistency_samples"` // command candidates to vote on; 0 means the default, 1 disables
+	SelfConsistencyBudget  int `yaml:"self_consistency_budget"`  // seconds for sampling and dry-runs
+
+	PlaybookDirs []string `yaml:"playbook_dirs"` // YAML playbooks and patterns, later dirs win; [] disables
 }
 
 type PerformanceSettings struct {
@@ -42,6 +47,37 @@ type PerformanceSettings struct {
 	MemoryLimit     int `yaml:"memory_limit"`     // MB
 }
 
+type RetrievalSettings struct {
+	Disabled       bool    `yaml:"disabled"`
+	EmbeddingModel string  `yaml:"embedding_model"` // Ollama model used for /api/embed
+	TopK           int     `yaml:"top_k"`           // snippets added to each prompt
+	MinScore       float64 `yaml:"min_score"`       // cosine similarity cutoff
+	IndexPath      string  `yaml:"index_path"`      // on-disk vector index
+	KnowledgeDir   string  `yaml:"knowledge_dir"`   // manifests and values files to index
+}
+
+type PromptSettings struct {
+	ResponseReserve  int    `yaml:"response_reserve"`   // tokens of num_ctx kept free for the answer
+	TemplateDir      string `yaml:"template_dir"`       // overrides for built-in prompt templates
+	AllowRelativeDir bool   `yaml:"allow_relative_dir"` // honor a template_dir relative to the working directory
+	Conventions      string `yaml:"conventions"`        // org conventions injected into prompts
+}
+
+type AgentSettings struct {
+	Mode              string `yaml:"mode"`                // "react" (step-by-step) or "plan" (plan-then-act investigation)
+	MaxSteps          int    `yaml:"max_steps"`           // commands per investigation
+	TimeBudget        int    `yaml:"time_budget"`         // seconds per investigation
+	OutputTokenBudget int    `yaml:"output_token_budget"` // tokens of cluster output an investigation may read
+	Parallelism       int    `yaml:"parallelism"`         // independent checks run at once
+	Approval          string `yaml:"approval"`            // "auto" or "step" (approve each ReAct action before it runs)
+}
+
+type ReportSettings struct {
+	Dir         string `yaml:"dir"`          // where /report writes its files
+	Format      string `yaml:"format"`       // "markdown" or "html"
+	TemplateDir string `yaml:"template_dir"` // report.md.tmpl / report.html.tmpl overrides
+}
+
 type legacyPreferences struct {
 	Theme string `yaml:"theme"`
 }
@@ -49,10 +85,15 @@ type legacyPreferences struct {
 type AppConfig struct {
 	Models        ModelSettings        `yaml:"models"`
 	NumCtx        int                  `yaml:"num_ctx"`
+	NumCtxFixed   bool                 `yaml:"num_ctx_fixed"` // only lower num_ctx to a model's limit, never raise it
 	KeepAlive     string               `yaml:"keep_alive"`
 	Truncation    TruncationSettings   `yaml:"truncation"`
 	Intelligence  IntelligenceSettings `yaml:"intelligence"`
 	Performance   PerformanceSettings  `yaml:"performance"`
+	Prompt        PromptSettings       `yaml:"prompt"`
+	Retrieval     RetrievalSettings    `yaml:"retrieval"`
+	Agent         AgentSettings        `yaml:"agent"`
+	Report        ReportSettings       `yaml:"report"`
 	Theme         string               `yaml:"theme"`
 	HistoryLength int                  `yaml:"history_length"`
 	OllamaHost    string               `yaml:"ollama_host,omitempty"`
@@ -87,6 +128,11 @@ func DefaultConfig() *AppConfig {
 			PlaybooksEnabled:    true,
 			RiskAssessment:      true,
 			QuickActionsEnabled: true,
+
+			SelfConsistencySamples: 3,  // 3 candidates per command
+			SelfConsistencyBudget:  20, // 20 seconds total
+
+			PlaybookDirs: []string{"~/.kubemage/playbooks", ".kubemage/playbooks"}, // user, then repo
 		},
 		Performance: PerformanceSettings{
 			MaxConcurrent:   3,   // 3 concurrent operations
@@ -95,6 +141,29 @@ func DefaultConfig() *AppConfig {
 			RenderThrottle:  40,  // 40ms render throttle
 			MemoryLimit:     512, // 512MB memory limit
 		},
+		Prompt: PromptSettings{
+			ResponseReserve: 1024,                  // 1k tokens for the model's answer
+			TemplateDir:     "~/.kubemage/prompts", // user-owned, never the working directory
+		},
+		Retrieval: RetrievalSettings{
+			EmbeddingModel: "nomic-embed-text",
+			TopK:           4,   // 4 snippets per prompt
+			MinScore:       0.3, // ignore weak matches
+			IndexPath:      "kubemage_data/index.json",
+			KnowledgeDir:   "~/.kubemage/knowledge",
+		},
+		Agent: AgentSettings{
+			Mode:              "react",
+			MaxSteps:          12,   // 12 commands per investigation
+			TimeBudget:        120,  // 2 minutes per investigation
+			OutputTokenBudget: 6000, // ~6k tokens of cluster output
+			Parallelism:       3,    // 3 checks at once
+			Approval:          "auto",
+		},
+		Report: ReportSettings{
+			Dir:    "kubemage_data/reports",
+			Format: "markdown",
+		},
 		Theme:         "default",
 		HistoryLength: 10,
 		OllamaHost:    "http://localhost:11434",
@@ -120,6 +189,60 @@ func (cfg *AppConfig) applyDefaults() {
 	if strings.TrimSpace(cfg.KeepAlive) == "" {
 		cfg.KeepAlive = defaults.KeepAlive
 	}
+	if cfg.Intelligence.SelfConsistencySamples == 0 {
+		cfg.Intelligence.SelfConsistencySamples = defaults.Intelligence.SelfConsistencySamples
+	}
+	if cfg.Intelligence.SelfConsistencyBudget == 0 {
+		cfg.Intelligence.SelfConsistencyBudget = defaults.Intelligence.SelfConsistencyBudget
+	}
+	if cfg.Intelligence.PlaybookDirs == nil {
+		cfg.Intelligence.PlaybookDirs = defaults.Intelligence.PlaybookDirs
+	}
+	if cfg.Prompt.ResponseReserve == 0 {
+		cfg.Prompt.ResponseReserve = defaults.Prompt.ResponseReserve
+	}
+	if strings.TrimSpace(cfg.Prompt.TemplateDir) == "" {
+		cfg.Prompt.TemplateDir = defaults.Prompt.TemplateDir
+	}
+	if strings.TrimSpace(cfg.Retrieval.EmbeddingModel) == "" {
+		cfg.Retrieval.EmbeddingModel = defaults.Retrieval.EmbeddingModel
+	}
+	if cfg.Retrieval.TopK == 0 {
+		cfg.Retrieval.TopK = defaults.Retrieval.TopK
+	}
+	if cfg.Retrieval.MinScore == 0 {
+		cfg.Retrieval.MinScore = defaults.Retrieval.MinScore
+	}
+	if strings.TrimSpace(cfg.Retrieval.IndexPath) == "" {
+		cfg.Retrieval.IndexPath = defaults.Retrieval.IndexPath
+	}
+	if strings.TrimSpace(cfg.Retrieval.KnowledgeDir) == "" {
+		cfg.Retrieval.KnowledgeDir = defaults.Retrieval.KnowledgeDir
+	}
+	if strings.TrimSpace(cfg.Agent.Mode) == "" {
+		cfg.Agent.Mode = defaults.Agent.Mode
+	}
+	if cfg.Agent.MaxSteps == 0 {
+		cfg.Agent.MaxSteps = defaults.Agent.MaxSteps
+	}
+	if cfg.Agent.TimeBudget == 0 {
+		cfg.Agent.TimeBudget = defaults.Agent.TimeBudget
+	}
+	if cfg.Agent.OutputTokenBudget == 0 {
+		cfg.Agent.OutputTokenBudget = defaults.Agent.OutputTokenBudget
+	}
+	if cfg.Agent.Parallelism == 0 {
+		cfg.Agent.Parallelism = defaults.Agent.Parallelism
+	}
+	if strings.TrimSpace(cfg.Agent.Approval) == "" {
+		cfg.Agent.Approval = defaults.Agent.Approval
+	}
+	if strings.TrimSpace(cfg.Report.Dir) == "" {
+		cfg.Report.Dir = defaults.Report.Dir
+	}
+	if strings.TrimSpace(cfg.Report.Format) == "" {
+		cfg.Report.Format = defaults.Report.Format
+	}
 	if cfg.Truncation.Message == 0 {
 		if cfg.LegacyTruncation != 0 {
 			cfg.Truncation.Message = cfg.LegacyTruncation
@@ -112,6 +112,20 @@ func TestConfigLegacySupport(t *testing.T) {
 	}
 }
 
+func TestConfigSelfConsistencyCanBeDisabled(t *testing.T) {
+	cfg := &AppConfig{Intelligence: IntelligenceSettings{SelfConsistencySamples: 1}}
+	cfg.applyDefaults()
+	if cfg.Intelligence.SelfConsistencySamples != 1 {
+		t.Errorf("applyDefaults() should keep 1 sample to disable voting, got %v", cfg.Intelligence.SelfConsistencySamples)
+	}
+
+	cfg = &AppConfig{}
+	cfg.applyDefaults()
+	if cfg.Intelligence.SelfConsistencySamples != DefaultConfig().Intelligence.SelfConsistencySamples {
+		t.Errorf("applyDefaults() should use the default samples for 0, got %v", cfg.Intelligence.SelfConsistencySamples)
+	}
+}
+
 func TestLoadConfig_NonExistentFile(t *testing.T) {
 	// Test with a non-existent config file
 	cfg, err := LoadConfig()
@@ -0,0 +1,642 @@
+// command_guard.go - Verify generated commands reference real resources, objects and flags
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+// GuardIssue is one reference in a command that does not exist
+type GuardIssue struct {
+	Kind       string `json:"kind"` // "resource", "namespace", "object", "flag"
+	Value      string `json:"value"`
+	Suggestion string `json:"suggestion,omitempty"`
+
+	token       int    // index of the command field to correct
+	replacement string // corrected field, empty without a suggestion
+}
+
+// String renders the issue with its nearest-match correction
+func (i GuardIssue) String() string {
+	msg := fmt.Sprintf("unknown %s `%s`", i.Kind, i.Value)
+	if i.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean `%s`?", i.Suggestion)
+	}
+	return msg
+}
+
+// GuardReport is the outcome of checking one command
+type GuardReport struct {
+	Command   string       `json:"command"`
+	Issues    []GuardIssue `json:"issues"`
+	Corrected string       `json:"corrected,omitempty"` // command with every suggestion applied
+}
+
+// OK reports whether every reference in the command was verified or skipped
+func (r GuardReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Summary renders the issues for the chat
+func (r GuardReport) Summary() string {
+	if r.OK() {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("🔎 Possible hallucination in the suggested command:\n")
+	for _, issue := range r.Issues {
+		sb.WriteString("• " + issue.String() + "\n")
+	}
+	if r.Corrected != "" {
+		sb.WriteString(fmt.Sprintf("Corrected: `%s`", r.Corrected))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// ClusterCatalog caches api-resources, namespaces and object names for the guard
+type ClusterCatalog struct {
+	runner execx.Runner
+
+	ResourceTTL  time.Duration
+	NamespaceTTL time.Duration
+	ObjectTTL    time.Duration
+
+	mu          sync.Mutex
+	resources   map[string]string // alias (plural, singular, short name, name.group) -> canonical name
+	resourcesAt time.Time
+	namespaces  []string
+	namespaceAt time.Time
+	objects     map[string]cachedNames
+}
+
+type cachedNames struct {
+	names []string
+	at    time.Time
+}
+
+// NewClusterCatalog creates a catalog that lists the cluster through runner
+func NewClusterCatalog(runner execx.Runner) *ClusterCatalog {
+	return &ClusterCatalog{
+		runner:       runner,
+		ResourceTTL:  10 * time.Minute,
+		NamespaceTTL: time.Minute,
+		ObjectTTL:    30 * time.Second,
+		objects:      make(map[string]cachedNames),
+	}
+}
+
+// ResourceTypes returns every accepted resource alias mapped to its canonical name
+func (c *ClusterCatalog) ResourceTypes(ctx context.Context) (map[string]string, error) {
+	c.mu.Lock()
+	if c.resources != nil && time.Since(c.resourcesAt) < c.ResourceTTL {
+		defer c.mu.Unlock()
+		return c.resources, nil
+	}
+	c.mu.Unlock()
+
+	stdout, stderr, err := c.runner.Run(ctx, "kubectl", "api-resources", "--no-headers")
+	if err != nil {
+		return nil, fmt.Errorf("kubectl api-resources: %v: %s", err, strings.TrimSpace(stderr))
+	}
+	resources := parseAPIResources(stdout)
+
+	c.mu.Lock()
+	c.resources, c.resourcesAt = resources, time.Now()
+	c.mu.Unlock()
+	return resources, nil
+}
+
+// Namespaces returns the namespace names in the cluster
+func (c *ClusterCatalog) Namespaces(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	if c.namespaces != nil && time.Since(c.namespaceAt) < c.NamespaceTTL {
+		defer c.mu.Unlock()
+		return c.namespaces, nil
+	}
+	c.mu.Unlock()
+
+	names, err := c.listNames(ctx, "get", "namespaces", "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.namespaces, c.namespaceAt = names, time.Now()
+	c.mu.Unlock()
+	return names, nil
+}
+
+// Objects returns the names of resource objects in namespace
+func (c *ClusterCatalog) Objects(ctx context.Context, resource, namespace string) ([]string, error) {
+	key := resource + "/" + namespace
+	c.mu.Lock()
+	if cached, ok := c.objects[key]; ok && time.Since(cached.at) < c.ObjectTTL {
+		c.mu.Unlock()
+		return cached.names, nil
+	}
+	c.mu.Unlock()
+
+	args := []string{"get", resource, "-o", "name"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	names, err := c.listNames(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.objects[key] = cachedNames{names: names, at: time.Now()}
+	c.mu.Unlock()
+	return names, nil
+}
+
+func (c *ClusterCatalog) listNames(ctx context.Context, args ...string) ([]string, error) {
+	stdout, stderr, err := c.runner.Run(ctx, "kubectl", args...)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr))
+	}
+	var names []string
+	for _, line := range strings.Split(stdout, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		// -o name prints kind/name
+		if idx := strings.LastIndex(line, "/"); idx >= 0 {
+			line = line[idx+1:]
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
+
+// parseAPIResources reads `kubectl api-resources --no-headers`.
+// Rows have 5 columns with short names and 4 without.
+func parseAPIResources(out string) map[string]string {
+	resources := make(map[string]string)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		name, kind, apiVersion := fields[0], fields[len(fields)-1], fields[len(fields)-3]
+		var shortNames []string
+		if len(fields) >= 5 {
+			shortNames = strings.Split(fields[1], ",")
+		}
+		group := ""
+		if idx := strings.Index(apiVersion, "/"); idx >= 0 {
+			group = apiVersion[:idx]
+		}
+
+		// The first resource listed keeps the bare name; later same-named ones need their group
+		canonical := name
+		if _, taken := resources[name]; taken && group != "" {
+			canonical = name + "." + group
+		}
+		for _, alias := range append([]string{name, kind}, shortNames...) {
+			alias = strings.ToLower(alias)
+			if _, taken := resources[alias]; !taken {
+				resources[alias] = canonical
+			}
+		}
+		if group != "" {
+			resources[name+"."+group] = canonical
+		}
+	}
+	return resources
+}
+
+// CommandGuard checks generated commands against the live cluster and the flag catalog
+type CommandGuard struct {
+	catalog          *ClusterCatalog
+	recorder         *FlightRecorder
+	Timeout          time.Duration
+	CurrentNamespace func() (string, error) // namespace used when the command has no -n
+}
+
+// NewCommandGuard creates a guard; misses are recorded in recorder when it is set
+func NewCommandGuard(runner execx.Runner, recorder *FlightRecorder) *CommandGuard {
+	return &CommandGuard{
+		catalog:          NewClusterCatalog(runner),
+		recorder:         recorder,
+		Timeout:          5 * time.Second,
+		CurrentNamespace: GetCurrentNamespace,
+	}
+}
+
+// verbs whose first positional names a resource type (after a subcommand for the offset ones)
+var resourceArgVerbs = map[string]int{
+	"get": 0, "describe": 0, "delete": 0, "edit": 0, "patch": 0, "scale": 0, "label": 0,
+	"annotate": 0, "expose": 0, "wait": 0, "autoscale": 0, "top": 0,
+	"rollout": 1, "set": 1,
+}
+
+// verbs whose first positional is a pod name (or type/name)
+var podArgVerbs = map[string]bool{"logs": true, "exec": true, "port-forward": true, "attach": true}
+
+// verbs whose first positional is a node name
+var nodeArgVerbs = map[string]bool{"cordon": true, "uncordon": true, "drain": true}
+
+type commandField struct {
+	index int
+	value string
+}
+
+// Check verifies resource kinds, namespaces, object names and flags in command.
+// References that cannot be verified (e.g. the cluster is unreachable) are skipped.
+func (g *CommandGuard) Check(ctx context.Context, command string) GuardReport {
+	report := GuardReport{Command: command}
+	fields := strings.Fields(command)
+	if len(fields) < 2 || (fields[0] != "kubectl" && fields[0] != "helm") {
+		return report
+	}
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+	tool := fields[0]
+
+	verb := ""
+	namespace := ""
+	allNamespaces := false
+	var positional []commandField
+	var flags []commandField
+	for i := 1; i < len(fields); i++ {
+		f := fields[i]
+		if f == "--" {
+			break // the rest is the container command (exec, run)
+		}
+		if !strings.HasPrefix(f, "-") || f == "-" {
+			if verb == "" {
+				verb = f
+			} else {
+				positional = append(positional, commandField{i, f})
+			}
+			continue
+		}
+		flags = append(flags, commandField{i, f})
+		name, value, hasValue := strings.Cut(f, "=")
+		if name == "-A" || name == "--all-namespaces" {
+			allNamespaces = true
+		}
+		prefix := name + "="
+		if !hasValue && guardFlagTakesValue(verb, name) && i+1 < len(fields) {
+			value, prefix = fields[i+1], ""
+			i++
+		}
+		if name == "-n" || name == "--namespace" {
+			namespace = value
+			report.Issues = append(report.Issues, g.checkNamespace(ctx, value, i, prefix)...)
+		}
+	}
+
+	report.Issues = append(report.Issues, checkFlags(tool, verb, flags)...)
+
+	if tool == "kubectl" {
+		if namespace == "" && g.CurrentNamespace != nil {
+			namespace, _ = g.CurrentNamespace()
+		}
+		report.Issues = append(report.Issues, g.checkKubectlArgs(ctx, verb, positional, namespace, allNamespaces)...)
+	}
+
+	report.Corrected = correctedCommand(fields, report.Issues)
+	return report
+}
+
+// Record stores the misses of report as failure patterns in the flight recorder
+func (g *CommandGuard) Record(report GuardReport) {
+	if g == nil || g.recorder == nil {
+		return
+	}
+	for _, issue := range report.Issues {
+		g.recorder.RecordHallucination(report.Command, issue)
+	}
+}
+
+func (g *CommandGuard) checkNamespace(ctx context.Context, namespace string, token int, prefix string) []GuardIssue {
+	names, err := g.catalog.Namespaces(ctx)
+	if err != nil || containsString(names, namespace) {
+		return nil
+	}
+	issue := GuardIssue{Kind: "namespace", Value: namespace, token: token}
+	if suggestion := NearestMatch(namespace, names); suggestion != "" {
+		issue.Suggestion = suggestion
+		issue.replacement = prefix + suggestion
+	}
+	return []GuardIssue{issue}
+}
+
+func (g *CommandGuard) checkKubectlArgs(ctx context.Context, verb string, positional []commandField, namespace string, allNamespaces bool) []GuardIssue {
+	switch {
+	case podArgVerbs[verb]:
+		if len(positional) == 0 {
+			return nil
+		}
+		arg := positional[0]
+		if strings.Contains(arg.value, "/") {
+			return g.checkTypedName(ctx, arg, namespace, allNamespaces)
+		}
+		return g.checkObject(ctx, "pods", arg.value, arg.index, "", namespace, allNamespaces)
+	case nodeArgVerbs[verb]:
+		var issues []GuardIssue
+		for _, arg := range positional {
+			issues = append(issues, g.checkObject(ctx, "nodes", arg.value, arg.index, "", "", false)...)
+		}
+		return issues
+	}
+
+	offset, ok := resourceArgVerbs[verb]
+	if !ok || len(positional) <= offset {
+		return nil
+	}
+	args := positional[offset:]
+	if strings.Contains(args[0].value, "/") {
+		var issues []GuardIssue
+		for _, arg := range args {
+			// Labels, annotations and images (key=value, key-) follow the objects
+			if !isObjectArg(arg.value) {
+				continue
+			}
+			issues = append(issues, g.checkTypedName(ctx, arg, namespace, allNamespaces)...)
+		}
+		return issues
+	}
+
+	resource, issues := g.checkResourceTypes(ctx, args[0])
+	if resource == "" || verb == "explain" {
+		return issues
+	}
+	for _, arg := range args[1:] {
+		issues = append(issues, g.checkObject(ctx, resource, arg.value, arg.index, "", namespace, allNamespaces)...)
+	}
+	return issues
+}
+
+// checkTypedName handles type/name arguments
+func (g *CommandGuard) checkTypedName(ctx context.Context, arg commandField, namespace string, allNamespaces bool) []GuardIssue {
+	kind, name, _ := strings.Cut(arg.value, "/")
+	resource, issues := g.checkResourceTypes(ctx, commandField{arg.index, kind})
+	if len(issues) > 0 {
+		// Keep the name when correcting the type
+		for i := range issues {
+			if issues[i].replacement != "" {
+				issues[i].replacement += "/" + name
+			}
+		}
+		return issues
+	}
+	if resource == "" {
+		return nil
+	}
+	return g.checkObject(ctx, resource, name, arg.index, kind+"/", namespace, allNamespaces)
+}
+
+// checkResourceTypes verifies a (comma separated) resource type argument and
+// returns the canonical type when it names exactly one known resource
+func (g *CommandGuard) checkResourceTypes(ctx context.Context, arg commandField) (string, []GuardIssue) {
+	resources, err := g.catalog.ResourceTypes(ctx)
+	if err != nil || len(resources) == 0 {
+		return "", nil
+	}
+
+	kinds := strings.Split(arg.value, ",")
+	corrected := make([]string, len(kinds))
+	var unknown []string
+	canonical := ""
+	for i, kind := range kinds {
+		corrected[i] = kind
+		if c, ok := resources[strings.ToLower(kind)]; ok {
+			canonical = c
+			continue
+		}
+		if kind == "all" {
+			continue
+		}
+		unknown = append(unknown, kind)
+		// Prefer canonical names over short names and singulars
+		suggestion := NearestMatch(strings.ToLower(kind), mapValues(resources))
+		if suggestion == "" {
+			suggestion = NearestMatch(strings.ToLower(kind), mapKeys(resources))
+		}
+		if suggestion != "" {
+			corrected[i] = suggestion
+		}
+	}
+	if len(unknown) == 0 {
+		if len(kinds) > 1 {
+			return "", nil
+		}
+		return canonical, nil
+	}
+
+	issue := GuardIssue{Kind: "resource", Value: strings.Join(unknown, ","), token: arg.index}
+	if fixed := strings.Join(corrected, ","); fixed != arg.value {
+		issue.Suggestion = fixed
+		issue.replacement = fixed
+	}
+	return "", []GuardIssue{issue}
+}
+
+func (g *CommandGuard) checkObject(ctx context.Context, resource, name string, token int, prefix, namespace string, allNamespaces bool) []GuardIssue {
+	// Skip placeholders, label/annotation arguments (key=value, key-) and patch fragments
+	if name == "" || allNamespaces || strings.ContainsAny(name, "*$<>{}:'\"") || !isObjectArg(name) {
+		return nil
+	}
+	names, err := g.catalog.Objects(ctx, resource, namespace)
+	if err != nil || containsString(names, name) {
+		return nil
+	}
+	issue := GuardIssue{Kind: "object", Value: prefix + name, token: token}
+	if suggestion := NearestMatch(name, names); suggestion != "" {
+		issue.Suggestion = prefix + suggestion
+		issue.replacement = prefix + suggestion
+	}
+	return []GuardIssue{issue}
+}
+
+// isObjectArg tells object references apart from key=value and key- arguments
+func isObjectArg(value string) bool {
+	return !strings.Contains(value, "=") && !strings.HasSuffix(value, "-")
+}
+
+// checkFlags verifies flag names against the bundled catalog
+func checkFlags(tool, verb string, flags []commandField) []GuardIssue {
+	known, ok := KnownFlags(tool, verb)
+	if !ok {
+		return nil
+	}
+	set := flagSet(known...)
+
+	var issues []GuardIssue
+	for _, f := range flags {
+		name, value, hasValue := strings.Cut(f.value, "=")
+		if set[name] {
+			continue
+		}
+		if !strings.HasPrefix(name, "--") && len(name) > 2 {
+			// Combined short flags (-it) or an attached value (-ojson)
+			head := name[:2]
+			if set[head] && guardFlagTakesValue(verb, head) {
+				continue
+			}
+			combined := true
+			for _, r := range name[1:] {
+				if !set["-"+string(r)] {
+					combined = false
+					break
+				}
+			}
+			if combined {
+				continue
+			}
+		}
+
+		issue := GuardIssue{Kind: "flag", Value: name, token: f.index}
+		var sameStyle []string
+		for _, k := range known {
+			if strings.HasPrefix(k, "--") == strings.HasPrefix(name, "--") {
+				sameStyle = append(sameStyle, k)
+			}
+		}
+		if suggestion := NearestMatch(name, sameStyle); suggestion != "" {
+			issue.Suggestion = suggestion
+			issue.replacement = suggestion
+			if hasValue {
+				issue.replacement += "=" + value
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+func guardFlagTakesValue(verb, name string) bool {
+	if verb == "logs" && name == "-f" {
+		return false // --follow
+	}
+	return flagTakesValue(name)
+}
+
+func correctedCommand(fields []string, issues []GuardIssue) string {
+	corrected := append([]string(nil), fields...)
+	changed := false
+	for _, issue := range issues {
+		if issue.replacement == "" || issue.token < 0 || issue.token >= len(corrected) {
+			continue
+		}
+		corrected[issue.token] = issue.replacement
+		changed = true
+	}
+	if !changed {
+		return ""
+	}
+	return strings.Join(corrected, " ")
+}
+
+// NearestMatch returns the candidate closest to value, preferring names that
+// extend it (e.g. a pod name with its generated suffix), or "" when none is close
+func NearestMatch(value string, candidates []string) string {
+	if value == "" || len(candidates) == 0 {
+		return ""
+	}
+
+	var prefixed []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, value+"-") {
+			prefixed = append(prefixed, c)
+		}
+	}
+	if len(prefixed) > 0 {
+		sort.Slice(prefixed, func(i, j int) bool {
+			if len(prefixed[i]) != len(prefixed[j]) {
+				return len(prefixed[i]) < len(prefixed[j])
+			}
+			return prefixed[i] < prefixed[j]
+		})
+		return prefixed[0]
+	}
+
+	limit := len(value) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	best, bestDist := "", limit+1
+	for _, c := range candidates {
+		d := Levenshtein(value, c)
+		if d < bestDist || (d == bestDist && best != "" && c < best) {
+			best, bestDist = c, d
+		}
+	}
+	if bestDist > limit || bestDist >= len(value) {
+		return ""
+	}
+	return best
+}
+
+// Levenshtein returns the edit distance between a and b
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, min(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func mapValues(m map[string]string) []string {
+	seen := make(map[string]bool)
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testAPIResources = `bindings                                       v1                                true         Binding
+configmaps                        cm           v1                                true         ConfigMap
+namespaces                        ns           v1                                false        Namespace
+nodes                             no           v1                                false        Node
+pods                              po           v1                                true         Pod
+services                          svc          v1                                true         Service
+deployments                       deploy       apps/v1                           true         Deployment
+events                            ev           v1                                true         Event
+events                            ev           events.k8s.io/v1                  true         Event
+`
+
+// clusterRunner answers the listing commands used by the guard
+type clusterRunner struct {
+	outputs map[string]string
+	offline bool
+	calls   int
+}
+
+func (r *clusterRunner) Run(ctx context.Context, name string, args ...string) (string, string, error) {
+	r.calls++
+	if r.offline {
+		return "", "Unable to connect to the server", errors.New("exit status 1")
+	}
+	key := strings.Join(args, " ")
+	if out, ok := r.outputs[key]; ok {
+		return out, "", nil
+	}
+	return "", "error: the server doesn't have a resource type", errors.New("exit status 1")
+}
+
+func (r *clusterRunner) RunCommand(ctx context.Context, command string) (string, string, error) {
+	fields := strings.Fields(command)
+	return r.Run(ctx, fields[0], fields[1:]...)
+}
+
+func newTestGuard(runner *clusterRunner, recorder *FlightRecorder) *CommandGuard {
+	guard := NewCommandGuard(runner, recorder)
+	guard.CurrentNamespace = func() (string, error) { return "default", nil }
+	return guard
+}
+
+func testCluster() *clusterRunner {
+	return &clusterRunner{outputs: map[string]string{
+		"api-resources --no-headers":                  testAPIResources,
+		"get namespaces -o name":                      "namespace/default\nnamespace/kube-system\nnamespace/payments\n",
+		"get pods -o name -n default":                 "pod/web-7d4b9c9f5-abcde\npod/worker-0\n",
+		"get deployments -o name -n default":          "deployment.apps/web\n",
+		"get deployments -o name -n payments":         "deployment.apps/api\n",
+		"get nodes -o name":                           "node/node-a\nnode/node-b\n",
+		"get events.events.k8s.io -o name -n default": "",
+	}}
+}
+
+func TestLevenshtein(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"pods", "pods", 0},
+		{"podz", "pods", 1},
+		{"defualt", "default", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, tc := range cases {
+		if got := Levenshtein(tc.a, tc.b); got != tc.want {
+			t.Errorf("Levenshtein(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestNearestMatch(t *testing.T) {
+	if got := NearestMatch("web", []string{"worker-0", "web-7d4b9c9f5-abcde"}); got != "web-7d4b9c9f5-abcde" {
+		t.Errorf("expected generated pod name, got %q", got)
+	}
+	if got := NearestMatch("paymnts", []string{"default", "payments"}); got != "payments" {
+		t.Errorf("expected payments, got %q", got)
+	}
+	if got := NearestMatch("billing", []string{"default", "payments"}); got != "" {
+		t.Errorf("expected no match, got %q", got)
+	}
+}
+
+func TestParseAPIResources(t *testing.T) {
+	resources := parseAPIResources(testAPIResources)
+	for alias, want := range map[string]string{
+		"po": "pods", "pod": "pods", "deploy": "deployments", "deployments.apps": "deployments",
+		"bindings": "bindings", "events": "events", "events.events.k8s.io": "events.events.k8s.io",
+	} {
+		if got := resources[alias]; got != want {
+			t.Errorf("alias %q = %q, want %q", alias, got, want)
+		}
+	}
+}
+
+func TestCommandGuardAcceptsRealReferences(t *testing.T) {
+	guard := newTestGuard(testCluster(), nil)
+	for _, command := range []string{
+		"kubectl get pods -n default -o wide",
+		"kubectl get po,svc",
+		"kubectl describe deploy web",
+		"kubectl logs -f web-7d4b9c9f5-abcde --tail=100",
+		"kubectl exec -it web-7d4b9c9f5-abcde -n default -- ls -la",
+		"kubectl rollout restart deployment/web",
+		"kubectl get deployments -A",
+		"kubectl label pods worker-0 tier=backend --overwrite",
+		"kubectl set image deployment/web web=nginx:1.25",
+		"kubectl label deployment/web env=prod",
+		"kubectl annotate deploy/web team=x",
+		"kubectl label deploy/web env-",
+		"kubectl cordon node-a",
+		"helm upgrade --install web ./chart -n payments --set image.tag=1.2",
+	} {
+		if report := guard.Check(context.Background(), command); !report.OK() {
+			t.Errorf("%q flagged: %s", command, report.Summary())
+		}
+	}
+}
+
+func TestCommandGuardSuggestsCorrections(t *testing.T) {
+	cases := []struct {
+		command   string
+		kinds     []string
+		corrected string
+	}{
+		{"kubectl get podz -n defualt", []string{"namespace", "resource"}, "kubectl get pods -n default"},
+		{"kubectl logs web --tial 50", []string{"flag", "object"}, "kubectl logs web-7d4b9c9f5-abcde --tail 50"},
+		{"kubectl scale deploy/wbe --replicas=3 --namespace=default", []string{"object"}, "kubectl scale deploy/web --replicas=3 --namespace=default"},
+		{"kubectl get deploy api --namespace=paymnts", []string{"namespace"}, "kubectl get deploy api --namespace=payments"},
+		{"kubectl drain node-c --ignore-daemonset", []string{"flag", "object"}, "kubectl drain node-a --ignore-daemonsets"},
+	}
+	for _, tc := range cases {
+		report := newTestGuard(testCluster(), nil).Check(context.Background(), tc.command)
+		var kinds []string
+		for _, issue := range report.Issues {
+			kinds = append(kinds, issue.Kind)
+		}
+		if strings.Join(kinds, ",") != strings.Join(tc.kinds, ",") {
+			t.Errorf("%q: issues %v, want %v", tc.command, kinds, tc.kinds)
+		}
+		if report.Corrected != tc.corrected {
+			t.Errorf("%q: corrected %q, want %q", tc.command, report.Corrected, tc.corrected)
+		}
+	}
+}
+
+func TestCommandGuardOfflineChecksFlagsOnly(t *testing.T) {
+	guard := newTestGuard(&clusterRunner{offline: true}, nil)
+	report := guard.Check(context.Background(), "kubectl get podz -n nowhere --show-lables")
+	if len(report.Issues) != 1 || report.Issues[0].Kind != "flag" || report.Issues[0].Suggestion != "--show-labels" {
+		t.Errorf("expected only the flag issue, got %+v", report.Issues)
+	}
+}
+
+func TestCommandGuardCachesListings(t *testing.T) {
+	runner := testCluster()
+	guard := newTestGuard(runner, nil)
+	guard.Check(context.Background(), "kubectl get pods web -n default")
+	calls := runner.calls
+	guard.Check(context.Background(), "kubectl describe pods worker-0 -n default")
+	if runner.calls != calls {
+		t.Errorf("expected cached listings, got %d extra calls", runner.calls-calls)
+	}
+}
+
+func TestCommandGuardRecordsMisses(t *testing.T) {
+	recorder := NewFlightRecorder(t.TempDir())
+	guard := newTestGuard(testCluster(), recorder)
+
+	guard.Record(guard.Check(context.Background(), "kubectl get podz"))
+	guard.Record(guard.Check(context.Background(), "kubectl get podz -o wide"))
+
+	patterns := recorder.GetLearningInsights().FailurePatterns
+	if len(patterns) != 1 {
+		t.Fatalf("expected one failure pattern, got %+v", patterns)
+	}
+	p := patterns[0]
+	if p.ErrorType != "hallucinated-resource" || p.Context != "podz" || p.Frequency != 2 {
+		t.Errorf("unexpected pattern: %+v", p)
+	}
+	if len(p.SuccessfulFixes) != 1 || p.SuccessfulFixes[0] != "pods" {
+		t.Errorf("expected fix pods, got %v", p.SuccessfulFixes)
+	}
+}
@@ -0,0 +1,377 @@
+// conditions.go - Structural checks of root-cause patterns against live cluster objects
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+// Condition operators; a field with a [*] wildcard matches when any of its values does,
+// except not_equals, which holds when none equals the value. Conditions of one pattern
+// that share the path up to a [*] must hold for the same element.
+const (
+	OpEquals      = "equals"
+	OpNotEquals   = "not_equals"
+	OpContains    = "contains"
+	OpGreaterThan = "greater_than"
+	OpLessThan    = "less_than"
+	OpExists      = "exists" // value false means the field must be absent
+	OpRegex       = "regex"
+)
+
+// fieldSegment is one step of a field path: a map key, a list index or a [*] wildcard
+type fieldSegment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+var reFieldKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// parseFieldPath parses the JSONPath subset used by conditions, e.g.
+// "status.containerStatuses[*].lastState.terminated.reason", "{.spec.replicas}",
+// "$.metadata.labels['app.kubernetes.io/name']" or "status.conditions[0].type"
+func parseFieldPath(path string) ([]fieldSegment, error) {
+	p := strings.TrimSpace(path)
+	if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+		p = p[1 : len(p)-1]
+	}
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+	if p == "" {
+		return nil, fmt.Errorf("empty field path")
+	}
+
+	var segments []fieldSegment
+	for p != "" {
+		switch {
+		case strings.HasPrefix(p, "[*]"):
+			segments = append(segments, fieldSegment{wildcard: true})
+			p = p[3:]
+		case strings.HasPrefix(p, "['"):
+			end := strings.Index(p, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ['key'] in %q", path)
+			}
+			segments = append(segments, fieldSegment{key: p[2:end]})
+			p = p[end+2:]
+		case strings.HasPrefix(p, "["):
+			end := strings.Index(p, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [index] in %q", path)
+			}
+			index, err := strconv.Atoi(p[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid index %q in %q", p[1:end], path)
+			}
+			segments = append(segments, fieldSegment{index: index, isIndex: true})
+			p = p[end+1:]
+		case strings.HasPrefix(p, "."):
+			p = p[1:]
+			if p == "" || p[0] == '.' || p[0] == '[' {
+				return nil, fmt.Errorf("empty key in %q", path)
+			}
+		default:
+			key := reFieldKey.FindString(p)
+			if key == "" {
+				return nil, fmt.Errorf("unexpected %q in %q", p[:1], path)
+			}
+			segments = append(segments, fieldSegment{key: key})
+			p = p[len(key):]
+		}
+	}
+	return segments, nil
+}
+
+// resolveField returns every value the path reaches in a decoded JSON object
+func resolveField(obj interface{}, segments []fieldSegment) []interface{} {
+	values := []interface{}{obj}
+	for _, seg := range segments {
+		var next []interface{}
+		for _, v := range values {
+			switch {
+			case seg.wildcard:
+				switch t := v.(type) {
+				case []interface{}:
+					next = append(next, t...)
+				case map[string]interface{}:
+					for _, item := range t {
+						next = append(next, item)
+					}
+				}
+			case seg.isIndex:
+				if list, ok := v.([]interface{}); ok && seg.index < len(list) {
+					next = append(next, list[seg.index])
+				}
+			default:
+				if m, ok := v.(map[string]interface{}); ok {
+					if item, ok := m[seg.key]; ok {
+						next = append(next, item)
+					}
+				}
+			}
+		}
+		values = next
+	}
+
+	found := values[:0]
+	for _, v := range values {
+		if v != nil {
+			found = append(found, v)
+		}
+	}
+	return found
+}
+
+// ValidateCondition reports whether the check can be evaluated at all
+func ValidateCondition(c ConditionCheck) error {
+	if _, err := parseFieldPath(c.Field); err != nil {
+		return err
+	}
+	switch c.Operator {
+	case OpEquals, OpNotEquals, OpContains:
+		if c.Value == nil {
+			return fmt.Errorf("%s needs a value", c.Operator)
+		}
+	case OpGreaterThan, OpLessThan:
+		if _, ok := conditionNumber(c.Value); !ok {
+			return fmt.Errorf("%s needs a numeric value, got %v", c.Operator, c.Value)
+		}
+	case OpExists:
+		if _, ok := c.Value.(bool); c.Value != nil && !ok {
+			return fmt.Errorf("exists takes true, false or no value, got %v", c.Value)
+		}
+	case OpRegex:
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("regex needs a string value")
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Operator)
+	}
+	return nil
+}
+
+// EvaluateCondition checks one condition against a decoded JSON object
+func EvaluateCondition(obj interface{}, c ConditionCheck) (bool, error) {
+	if err := ValidateCondition(c); err != nil {
+		return false, err
+	}
+	segments, _ := parseFieldPath(c.Field)
+	return evaluateSegments(obj, segments, c), nil
+}
+
+// evaluateSegments checks a validated condition against the values its parsed path reaches
+func evaluateSegments(obj interface{}, segments []fieldSegment, c ConditionCheck) bool {
+	values := resolveField(obj, segments)
+
+	switch c.Operator {
+	case OpExists:
+		want, ok := c.Value.(bool)
+		if !ok {
+			want = true
+		}
+		return (len(values) > 0) == want
+	case OpNotEquals:
+		for _, v := range values {
+			if conditionEquals(v, c.Value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var re *regexp.Regexp
+	if c.Operator == OpRegex {
+		re = regexp.MustCompile(c.Value.(string))
+	}
+	want, _ := conditionNumber(c.Value)
+	for _, v := range values {
+		var match bool
+		switch c.Operator {
+		case OpEquals:
+			match = conditionEquals(v, c.Value)
+		case OpContains:
+			match = strings.Contains(strings.ToLower(conditionString(v)), strings.ToLower(conditionString(c.Value)))
+		case OpGreaterThan:
+			n, ok := conditionNumber(v)
+			match = ok && n > want
+		case OpLessThan:
+			n, ok := conditionNumber(v)
+			match = ok && n < want
+		case OpRegex:
+			match = re.MatchString(conditionString(v))
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the check for evidence lists, e.g. "status.containerStatuses[*].restartCount greater_than 5"
+func (c ConditionCheck) String() string {
+	if c.Operator == OpExists && c.Value == nil {
+		return c.Field + " exists"
+	}
+	return fmt.Sprintf("%s %s %v", c.Field, c.Operator, c.Value)
+}
+
+// boundCondition is a condition with its path parsed, relative to the value it is checked on
+type boundCondition struct {
+	check    ConditionCheck
+	segments []fieldSegment
+}
+
+// conditionsMatch reports whether every condition holds for the object
+func conditionsMatch(obj interface{}, conditions []ConditionCheck) bool {
+	bound := make([]boundCondition, 0, len(conditions))
+	for _, c := range conditions {
+		if err := ValidateCondition(c); err != nil {
+			return false
+		}
+		segments, _ := parseFieldPath(c.Field)
+		bound = append(bound, boundCondition{check: c, segments: segments})
+	}
+	return boundConditionsMatch(obj, bound)
+}
+
+// boundConditionsMatch evaluates conditions that share the path up to a [*] against one
+// element at a time, so "containerStatuses[*].restartCount" and
+// "containerStatuses[*].lastState.terminated.reason" must hold for the same container
+func boundConditionsMatch(obj interface{}, conditions []boundCondition) bool {
+	groups := make(map[string][]boundCondition)
+	var order []string
+	for _, c := range conditions {
+		w := wildcardIndex(c.segments)
+		if w < 0 {
+			if !evaluateSegments(obj, c.segments, c.check) {
+				return false
+			}
+			continue
+		}
+		key := segmentsKey(c.segments[:w+1])
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], c)
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		// A lone condition keeps its any-element (or, for not_equals, no-element) meaning
+		if len(group) == 1 {
+			if !evaluateSegments(obj, group[0].segments, group[0].check) {
+				return false
+			}
+			continue
+		}
+		w := wildcardIndex(group[0].segments)
+		rest := make([]boundCondition, 0, len(group))
+		for _, c := range group {
+			rest = append(rest, boundCondition{check: c.check, segments: c.segments[w+1:]})
+		}
+		matched := false
+		for _, element := range resolveField(obj, group[0].segments[:w+1]) {
+			if boundConditionsMatch(element, rest) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// wildcardIndex returns the position of the first [*] in segments, or -1
+func wildcardIndex(segments []fieldSegment) int {
+	for i, seg := range segments {
+		if seg.wildcard {
+			return i
+		}
+	}
+	return -1
+}
+
+// segmentsKey renders segments canonically so equal paths group together
+func segmentsKey(segments []fieldSegment) string {
+	var sb strings.Builder
+	for _, seg := range segments {
+		switch {
+		case seg.wildcard:
+			sb.WriteString("[*]")
+		case seg.isIndex:
+			fmt.Fprintf(&sb, "[%d]", seg.index)
+		default:
+			fmt.Fprintf(&sb, "[%q]", seg.key)
+		}
+	}
+	return sb.String()
+}
+
+func conditionEquals(v, want interface{}) bool {
+	if a, ok := conditionNumber(v); ok {
+		if b, ok := conditionNumber(want); ok {
+			return a == b
+		}
+	}
+	return conditionString(v) == conditionString(want)
+}
+
+func conditionNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func conditionString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// FetchObject runs a read-only `kubectl get ... -o json` command and decodes its output.
+// The raw output is returned too so callers can account for what they read.
+func FetchObject(ctx context.Context, runner execx.Runner, command string) (interface{}, string, error) {
+	if !IsWhitelistedAction(command) || !strings.HasSuffix(command, "-o json") {
+		return nil, "", fmt.Errorf("%q is not a read-only JSON get", command)
+	}
+	stdout, stderr, err := runner.RunCommand(ctx, command)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return nil, "", fmt.Errorf("%s: %s", command, msg)
+		}
+		return nil, "", fmt.Errorf("%s: %w", command, err)
+	}
+	var obj interface{}
+	if err := json.Unmarshal([]byte(stdout), &obj); err != nil {
+		return nil, stdout, fmt.Errorf("%s: %w", command, err)
+	}
+	return obj, stdout, nil
+}
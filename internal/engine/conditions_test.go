@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const oomPodJSON = `{
+  "metadata": {"name": "api-7d9f8b6c5-x2k4j", "labels": {"app.kubernetes.io/name": "api"}},
+  "status": {
+    "phase": "Running",
+    "containerStatuses": [
+      {"name": "sidecar", "restartCount": 0, "state": {"running": {}}},
+      {"name": "api", "restartCount": 7,
+       "state": {"waiting": {"reason": "CrashLoopBackOff"}},
+       "lastState": {"terminated": {"reason": "OOMKilled", "exitCode": 137}}}
+    ]
+  }
+}`
+
+func decodeObject(t *testing.T, data string) interface{} {
+	t.Helper()
+	var obj interface{}
+	if err := json.Unmarshal([]byte(data), &obj); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestEvaluateCondition(t *testing.T) {
+	pod := decodeObject(t, oomPodJSON)
+	cases := []struct {
+		check ConditionCheck
+		want  bool
+	}{
+		{ConditionCheck{"status.containerStatuses[*].restartCount", OpGreaterThan, 5}, true},
+		{ConditionCheck{"{.status.containerStatuses[0].restartCount}", OpGreaterThan, 5}, false},
+		{ConditionCheck{"$.status.containerStatuses[1].lastState.terminated.reason", OpEquals, "OOMKilled"}, true},
+		{ConditionCheck{"status.containerStatuses[*].lastState.terminated.exitCode", OpEquals, "137"}, true},
+		{ConditionCheck{"status.containerStatuses[*].restartCount", OpLessThan, 1}, true},
+		{ConditionCheck{"status.phase", OpNotEquals, "Running"}, false},
+		{ConditionCheck{"status.phase", OpContains, "run"}, true},
+		{ConditionCheck{"status.containerStatuses[*].state.waiting.reason", OpRegex, "BackOff$"}, true},
+		{ConditionCheck{"metadata.labels['app.kubernetes.io/name']", OpEquals, "api"}, true},
+		{ConditionCheck{"status.containerStatuses[*].lastState.terminated", OpExists, nil}, true},
+		{ConditionCheck{"spec.nodeName", OpExists, false}, true},
+		{ConditionCheck{"spec.nodeName", OpEquals, "node-1"}, false},
+	}
+	for _, c := range cases {
+		got, err := EvaluateCondition(pod, c.check)
+		if err != nil || got != c.want {
+			t.Errorf("%s: got %v, %v; want %v", c.check, got, err, c.want)
+		}
+	}
+
+	for _, bad := range []ConditionCheck{
+		{"status..phase", OpEquals, "Running"},
+		{"status.containerStatuses[x]", OpExists, nil},
+		{"status.phase", "approximately", "Running"},
+		{"status.phase", OpGreaterThan, "many"},
+		{"status.phase", OpRegex, "("},
+	} {
+		if _, err := EvaluateCondition(pod, bad); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestConditionsSharingAWildcardMatchOneElement(t *testing.T) {
+	conditions := []ConditionCheck{
+		{"status.containerStatuses[*].lastState.terminated.reason", OpEquals, "OOMKilled"},
+		{"status.containerStatuses[*].restartCount", OpGreaterThan, 5},
+		{"status.phase", OpEquals, "Running"},
+	}
+	if !conditionsMatch(decodeObject(t, oomPodJSON), conditions) {
+		t.Error("the api container is OOMKilled with 7 restarts")
+	}
+
+	split := decodeObject(t, `{"status": {"phase": "Running", "containerStatuses": [
+		{"name": "api", "restartCount": 0, "lastState": {"terminated": {"reason": "OOMKilled"}}},
+		{"name": "sidecar", "restartCount": 9, "state": {"running": {}}}]}}`)
+	if conditionsMatch(split, conditions) {
+		t.Error("the OOM kill and the restarts belong to different containers")
+	}
+
+	// A lone wildcard condition keeps its any-element meaning
+	if !conditionsMatch(split, conditions[1:]) {
+		t.Error("some container restarted more than 5 times")
+	}
+}
+
+func TestRankRootCausesCombinesIndicatorsAndConditions(t *testing.T) {
+	pl := NewPlaybookLibrary()
+	pod := decodeObject(t, oomPodJSON)
+
+	structural := pl.RankRootCausesWithObjects(nil, []interface{}{pod})
+	if len(structural) != 1 || structural[0].PatternID != "oom-killed" {
+		t.Fatalf("conditions alone should detect the OOM kill, got %+v", structural)
+	}
+	if structural[0].Confidence != 0.9 || !strings.Contains(strings.Join(structural[0].Indicators, ";"), "OOMKilled") {
+		t.Errorf("all conditions should reach the pattern's confidence and be cited: %+v", structural[0])
+	}
+
+	combined := pl.RankRootCausesWithObjects([]string{"container api: exit code 137"}, []interface{}{pod})
+	textOnly := pl.RankRootCauses([]string{"container api: exit code 137"})
+	if combined[0].Confidence <= structural[0].Confidence || combined[0].Confidence <= textOnly[0].Confidence {
+		t.Errorf("indicators and conditions together should beat either alone: %.2f vs %.2f / %.2f",
+			combined[0].Confidence, structural[0].Confidence, textOnly[0].Confidence)
+	}
+
+	healthy := decodeObject(t, `{"status": {"containerStatuses": [{"restartCount": 0, "state": {"running": {}}}]}}`)
+	if ranked := pl.RankRootCausesWithObjects(nil, []interface{}{healthy}); len(ranked) != 0 {
+		t.Errorf("a healthy pod should match nothing, got %+v", ranked)
+	}
+}
+
+func TestPatternConditionsFromYAML(t *testing.T) {
+	dir := t.TempDir()
+	data := `patterns:
+  - id: restart-storm
+    name: Restart storm
+    conditions:
+      - field: status.containerStatuses[*].restartCount
+        operator: greater_than
+        value: 5
+    confidence: 0.7
+    severity: high
+  - id: broken
+    name: Broken
+    conditions:
+      - field: status.phase
+        operator: resembles
+        value: Running
+    confidence: 0.7
+    severity: high
+`
+	if err := os.WriteFile(filepath.Join(dir, "patterns.yaml"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pl := NewPlaybookLibrary()
+	if errs := pl.LoadPlaybookDirs([]string{dir}); len(errs) != 1 || !strings.Contains(errs[0].Error(), "unknown operator") {
+		t.Fatalf("expected the unknown operator to be rejected, got %v", errs)
+	}
+	ranked := pl.RankRootCausesWithObjects(nil, []interface{}{decodeObject(t, oomPodJSON)})
+	ids := make([]string, 0, len(ranked))
+	for _, a := range ranked {
+		ids = append(ids, a.PatternID)
+	}
+	if !containsString(ids, "restart-storm") {
+		t.Errorf("YAML conditions should be evaluated, got %v", ids)
+	}
+}
+
+func TestInvestigationUsesLiveObjectConditions(t *testing.T) {
+	runner := &investigationRunner{outputs: map[string]string{
+		"kubectl get pod/api-7d9f8b6c5-x2k4j -n payments -o json": oomPodJSON,
+	}}
+	target := InvestigationTarget{Kind: "pod", Name: "api-7d9f8b6c5-x2k4j", Namespace: "payments"}
+	inv := NewInvestigator(runner, NewPlaybookLibrary(), InvestigationBudget{MaxSteps: 2}).Plan("why is pod api-7d9f8b6c5-x2k4j crashing", target, "")
+	before := investigationHypothesis(inv, "oom").Confidence
+
+	inv.Run(context.Background(), nil)
+	oom := investigationHypothesis(inv, "oom")
+	if oom.Confidence <= before || len(oom.Evidence) == 0 || !strings.HasPrefix(oom.Evidence[0], "kubectl get pod/api-7d9f8b6c5-x2k4j -n payments -o json: ") {
+		t.Errorf("the live object should support the OOM hypothesis: %.2f -> %.2f %q", before, oom.Confidence, oom.Evidence)
+	}
+}
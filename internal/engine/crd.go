@@ -0,0 +1,510 @@
+// crd.go - CustomResourceDefinition discovery, summaries and OpenAPI schema validation
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
+	"gopkg.in/yaml.v3"
+)
+
+const maxSchemaIssues = 20
+
+// PrinterColumn is an additionalPrinterColumns entry of a CRD version
+type PrinterColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	JSONPath string `json:"jsonPath"`
+}
+
+// CRDInfo summarizes one installed CustomResourceDefinition at its preferred version
+type CRDInfo struct {
+	Name           string          // e.g. "certificates.cert-manager.io"
+	Group          string          // e.g. "cert-manager.io"
+	Version        string          // storage version when served, else the first served one
+	Kind           string          // e.g. "Certificate"
+	Plural         string          // e.g. "certificates"
+	Singular       string          // e.g. "certificate"
+	ShortNames     []string        // e.g. ["cert", "certs"]
+	Namespaced     bool            // scope is Namespaced
+	PrinterColumns []PrinterColumn // columns shown by kubectl get
+	Schema         map[string]any  // openAPIV3Schema of Version, nil when absent
+}
+
+// APIVersion returns group/version as written in manifests
+func (c CRDInfo) APIVersion() string {
+	return c.Group + "/" + c.Version
+}
+
+// Summary renders a one-line description for prompts and /ctx
+func (c CRDInfo) Summary() string {
+	scope := "cluster-scoped"
+	if c.Namespaced {
+		scope = "namespaced"
+	}
+	summary := fmt.Sprintf("%s (%s, %s, resource %s.%s)", c.Kind, c.APIVersion(), scope, c.Plural, c.Group)
+	if len(c.PrinterColumns) > 0 {
+		cols := make([]string, 0, len(c.PrinterColumns))
+		for _, col := range c.PrinterColumns {
+			cols = append(cols, fmt.Sprintf("%s=%s", col.Name, col.JSONPath))
+		}
+		summary += " columns: " + strings.Join(cols, ", ")
+	}
+	return summary
+}
+
+// names lists every lowercase name the CRD answers to
+func (c CRDInfo) names() []string {
+	names := []string{strings.ToLower(c.Kind), c.Plural, c.Singular, c.Name}
+	return append(names, c.ShortNames...)
+}
+
+// CRDCatalog caches the CRDs installed in the current cluster
+type CRDCatalog struct {
+	runner  execx.Runner
+	TTL     time.Duration
+	Timeout time.Duration
+
+	mu        sync.Mutex
+	crds      []CRDInfo
+	fetchedAt time.Time
+	err       error
+}
+
+// NewCRDCatalog creates a catalog that lists CRDs through runner
+func NewCRDCatalog(runner execx.Runner) *CRDCatalog {
+	return &CRDCatalog{runner: runner, TTL: 10 * time.Minute, Timeout: 15 * time.Second}
+}
+
+// List returns the installed CRDs sorted by group and kind, refreshing after TTL
+func (c *CRDCatalog) List(ctx context.Context) ([]CRDInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < c.TTL {
+		return c.crds, c.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	out, stderr, err := c.runner.Run(ctx, "kubectl", "get", "customresourcedefinitions", "-o", "json")
+	c.fetchedAt = time.Now()
+	if err != nil {
+		c.err = fmt.Errorf("failed to list CRDs: %s", kubectlError(stderr, err))
+		return c.crds, c.err
+	}
+	crds, err := parseCRDList(out)
+	if err != nil {
+		c.err = err
+		return c.crds, c.err
+	}
+	c.crds, c.err = crds, nil
+
+	kinds := make([]string, 0, len(crds))
+	for _, crd := range crds {
+		kinds = append(kinds, crd.Kind, crd.Plural)
+	}
+	SetCustomKinds(kinds)
+	return c.crds, nil
+}
+
+// Lookup finds a CRD by kind, plural, singular, short name or full name
+func (c *CRDCatalog) Lookup(ctx context.Context, name string) (*CRDInfo, error) {
+	crds, err := c.List(ctx)
+	if err != nil && len(crds) == 0 {
+		return nil, err
+	}
+	want := strings.ToLower(strings.TrimSpace(name))
+	var candidates []string
+	for i := range crds {
+		for _, n := range crds[i].names() {
+			if n == want {
+				return &crds[i], nil
+			}
+			candidates = append(candidates, n)
+		}
+	}
+	if suggestion := NearestMatch(want, candidates); suggestion != "" {
+		return nil, fmt.Errorf("no CRD named %q is installed (did you mean %q?)", name, suggestion)
+	}
+	return nil, fmt.Errorf("no CRD named %q is installed", name)
+}
+
+// Relevant returns up to limit CRDs mentioned in text, including spaced kinds such as "kafka topics"
+func (c *CRDCatalog) Relevant(ctx context.Context, text string, limit int) []CRDInfo {
+	crds, _ := c.List(ctx)
+	if len(crds) == 0 {
+		return nil
+	}
+
+	words := explainWords(text)
+	mentioned := make(map[string]bool, len(words)*2)
+	for i, w := range words {
+		mentioned[w] = true
+		if i+1 < len(words) {
+			mentioned[w+words[i+1]] = true
+		}
+	}
+
+	var relevant []CRDInfo
+	for _, crd := range crds {
+		for _, n := range crd.names() {
+			// Short names such as "ev" or "no" collide with plain words
+			if len(n) >= 3 && mentioned[n] {
+				relevant = append(relevant, crd)
+				break
+			}
+		}
+		if limit > 0 && len(relevant) == limit {
+			break
+		}
+	}
+	return relevant
+}
+
+// Scry lists the CRD's objects with its printer columns, in namespace or cluster-wide
+func (c *CRDCatalog) Scry(ctx context.Context, crd CRDInfo, namespace string, maxLines int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	args := []string{"get", crd.Plural + "." + crd.Group}
+	if crd.Namespaced && namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	out, stderr, err := c.runner.Run(ctx, "kubectl", args...)
+	if err != nil {
+		return "", fmt.Errorf("kubectl get %s failed: %s", crd.Plural, kubectlError(stderr, err))
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = append(lines[:maxLines], fmt.Sprintf("(%d more)", len(lines)-maxLines))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// SchemaOutline lists the schema's fields below the root (skipping apiVersion, kind, metadata, status)
+func (c CRDInfo) SchemaOutline(maxFields int) []string {
+	if c.Schema == nil {
+		return nil
+	}
+	var lines []string
+	var walk func(prefix string, schema map[string]any, depth int)
+	walk = func(prefix string, schema map[string]any, depth int) {
+		props, _ := schema["properties"].(map[string]any)
+		required := stringSet(schema["required"])
+		for _, name := range sortedKeys(props) {
+			if depth == 0 && (name == "apiVersion" || name == "kind" || name == "metadata" || name == "status") {
+				continue
+			}
+			if maxFields > 0 && len(lines) >= maxFields {
+				return
+			}
+			child, _ := props[name].(map[string]any)
+			path := strings.TrimPrefix(prefix+"."+name, ".")
+			line := fmt.Sprintf("%s <%s>", path, schemaType(child))
+			if required[name] {
+				line += " (required)"
+			}
+			if enum, ok := child["enum"].([]any); ok && len(enum) > 0 {
+				line += fmt.Sprintf(" one of %v", enum)
+			}
+			lines = append(lines, line)
+
+			if items, ok := child["items"].(map[string]any); ok && schemaType(child) == "array" {
+				child = items
+			}
+			if depth < 3 {
+				walk(path, child, depth+1)
+			}
+		}
+	}
+	walk("", c.Schema, 0)
+	return lines
+}
+
+// CustomResourcePrompt asks for one custom resource shaped by the CRD schema
+func CustomResourcePrompt(crd CRDInfo, name, namespace, description string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Generate a Kubernetes %s custom resource.\n\n", crd.Kind)
+	fmt.Fprintf(&sb, "- apiVersion: %s\n- kind: %s\n- metadata.name: %s\n", crd.APIVersion(), crd.Kind, name)
+	if crd.Namespaced && namespace != "" {
+		fmt.Fprintf(&sb, "- metadata.namespace: %s\n", namespace)
+	}
+	if description != "" {
+		fmt.Fprintf(&sb, "- Requirements: %s\n", description)
+	}
+	if outline := crd.SchemaOutline(60); len(outline) > 0 {
+		sb.WriteString("\nOnly use fields from the installed CRD schema:\n")
+		for _, line := range outline {
+			sb.WriteString("  " + line + "\n")
+		}
+	}
+	sb.WriteString("\nFill every required field and do not invent fields that are not listed.")
+	return sb.String()
+}
+
+// ValidateCustomResource checks a manifest against the CRD's OpenAPI schema
+func ValidateCustomResource(manifest string, crd CRDInfo) []string {
+	var obj map[string]any
+	if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+		return []string{fmt.Sprintf("manifest is not valid YAML: %v", err)}
+	}
+	if obj == nil {
+		return []string{"manifest is empty"}
+	}
+
+	var issues []string
+	if apiVersion, _ := obj["apiVersion"].(string); !strings.HasPrefix(apiVersion, crd.Group+"/") {
+		issues = append(issues, fmt.Sprintf("apiVersion %q should be %s", apiVersion, crd.APIVersion()))
+	}
+	if kind, _ := obj["kind"].(string); kind != crd.Kind {
+		issues = append(issues, fmt.Sprintf("kind %q should be %s", kind, crd.Kind))
+	}
+	if meta, _ := obj["metadata"].(map[string]any); meta == nil || meta["name"] == nil {
+		issues = append(issues, "metadata.name is required")
+	}
+	if crd.Schema != nil {
+		validateSchemaValue("", obj, crd.Schema, &issues)
+	}
+	if len(issues) > maxSchemaIssues {
+		issues = append(issues[:maxSchemaIssues], fmt.Sprintf("(%d more)", len(issues)-maxSchemaIssues))
+	}
+	return issues
+}
+
+func validateSchemaValue(path string, value any, schema map[string]any, issues *[]string) {
+	if len(*issues) > maxSchemaIssues {
+		return
+	}
+	where := path
+	if where == "" {
+		where = "(root)"
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); !nullable && schemaType(schema) != "" {
+			*issues = append(*issues, fmt.Sprintf("%s must not be null", where))
+		}
+		return
+	}
+	if intOrString, _ := schema["x-kubernetes-int-or-string"].(bool); intOrString {
+		switch value.(type) {
+		case int, int64, uint64, string:
+		default:
+			*issues = append(*issues, fmt.Sprintf("%s must be an integer or string", where))
+		}
+		return
+	}
+
+	typ := schemaType(schema)
+	if typ != "" && !matchesSchemaType(value, typ) {
+		*issues = append(*issues, fmt.Sprintf("%s must be %s, got %s", where, typ, valueType(value)))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+			}
+		}
+		if !found {
+			*issues = append(*issues, fmt.Sprintf("%s must be one of %v, got %v", where, enum, value))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range sortedKeys(stringSetMap(schema["required"])) {
+			if _, ok := v[name]; !ok {
+				*issues = append(*issues, fmt.Sprintf("%s is required", joinPath(path, name)))
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		preserve, _ := schema["x-kubernetes-preserve-unknown-fields"].(bool)
+		for _, key := range sortedKeys(v) {
+			child := joinPath(path, key)
+			if propSchema, ok := props[key].(map[string]any); ok {
+				validateSchemaValue(child, v[key], propSchema, issues)
+			} else if extra != nil {
+				validateSchemaValue(child, v[key], extra, issues)
+			} else if len(props) > 0 && !preserve && !(path == "" && (key == "apiVersion" || key == "kind" || key == "metadata")) {
+				issue := fmt.Sprintf("unknown field %s", child)
+				if suggestion := NearestMatch(key, sortedKeys(props)); suggestion != "" {
+					issue += fmt.Sprintf(" (did you mean %s?)", joinPath(path, suggestion))
+				}
+				*issues = append(*issues, issue)
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				validateSchemaValue(fmt.Sprintf("%s[%d]", path, i), item, items, issues)
+			}
+		}
+	}
+}
+
+func parseCRDList(out string) ([]CRDInfo, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				Group string `json:"group"`
+				Scope string `json:"scope"`
+				Names struct {
+					Kind       string   `json:"kind"`
+					Plural     string   `json:"plural"`
+					Singular   string   `json:"singular"`
+					ShortNames []string `json:"shortNames"`
+				} `json:"names"`
+				Versions []struct {
+					Name                     string          `json:"name"`
+					Served                   bool            `json:"served"`
+					Storage                  bool            `json:"storage"`
+					AdditionalPrinterColumns []PrinterColumn `json:"additionalPrinterColumns"`
+					Schema                   struct {
+						OpenAPIV3Schema map[string]any `json:"openAPIV3Schema"`
+					} `json:"schema"`
+				} `json:"versions"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse CRD list: %w", err)
+	}
+
+	crds := make([]CRDInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		spec := item.Spec
+		chosen := -1
+		for i, v := range spec.Versions {
+			if v.Served && (chosen < 0 || v.Storage) {
+				chosen = i
+			}
+		}
+		if chosen < 0 {
+			continue
+		}
+		version := spec.Versions[chosen]
+		singular := spec.Names.Singular
+		if singular == "" {
+			singular = strings.ToLower(spec.Names.Kind)
+		}
+		crds = append(crds, CRDInfo{
+			Name:           item.Metadata.Name,
+			Group:          spec.Group,
+			Version:        version.Name,
+			Kind:           spec.Names.Kind,
+			Plural:         spec.Names.Plural,
+			Singular:       singular,
+			ShortNames:     spec.Names.ShortNames,
+			Namespaced:     spec.Scope == "Namespaced",
+			PrinterColumns: version.AdditionalPrinterColumns,
+			Schema:         version.Schema.OpenAPIV3Schema,
+		})
+	}
+	sort.Slice(crds, func(i, j int) bool {
+		if crds[i].Group != crds[j].Group {
+			return crds[i].Group < crds[j].Group
+		}
+		return crds[i].Kind < crds[j].Kind
+	})
+	return crds, nil
+}
+
+func schemaType(schema map[string]any) string {
+	typ, _ := schema["type"].(string)
+	return typ
+}
+
+func matchesSchemaType(value any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch n := value.(type) {
+		case int, int64, uint64:
+			return true
+		case float64:
+			return n == float64(int64(n))
+		}
+		return false
+	case "number":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func valueType(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func stringSet(v any) map[string]bool {
+	set := make(map[string]bool)
+	items, _ := v.([]any)
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func stringSetMap(v any) map[string]any {
+	set := make(map[string]any)
+	for s := range stringSet(v) {
+		set[s] = true
+	}
+	return set
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const testCRDList = `{"items": [
+  {
+    "metadata": {"name": "certificates.cert-manager.io"},
+    "spec": {
+      "group": "cert-manager.io",
+      "scope": "Namespaced",
+      "names": {"kind": "Certificate", "plural": "certificates", "singular": "certificate", "shortNames": ["cert", "certs"]},
+      "versions": [
+        {"name": "v1alpha2", "served": false, "storage": false},
+        {
+          "name": "v1", "served": true, "storage": true,
+          "additionalPrinterColumns": [
+            {"name": "Ready", "type": "string", "jsonPath": ".status.conditions[?(@.type==\"Ready\")].status"},
+            {"name": "Secret", "type": "string", "jsonPath": ".spec.secretName"}
+          ],
+          "schema": {"openAPIV3Schema": {
+            "type": "object",
+            "properties": {
+              "apiVersion": {"type": "string"},
+              "kind": {"type": "string"},
+              "metadata": {"type": "object"},
+              "spec": {
+                "type": "object",
+                "required": ["secretName", "issuerRef"],
+                "properties": {
+                  "secretName": {"type": "string"},
+                  "dnsNames": {"type": "array", "items": {"type": "string"}},
+                  "duration": {"type": "string"},
+                  "privateKey": {"type": "object", "properties": {
+                    "algorithm": {"type": "string", "enum": ["RSA", "ECDSA", "Ed25519"]},
+                    "size": {"type": "integer"}
+                  }},
+                  "issuerRef": {"type": "object", "required": ["name"], "properties": {
+                    "name": {"type": "string"},
+                    "kind": {"type": "string"}
+                  }},
+                  "secretTemplate": {"type": "object", "properties": {
+                    "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+                  }},
+                  "extra": {"type": "object", "x-kubernetes-preserve-unknown-fields": true}
+                }
+              },
+              "status": {"type": "object"}
+            }
+          }}
+        }
+      ]
+    }
+  },
+  {
+    "metadata": {"name": "kafkatopics.kafka.strimzi.io"},
+    "spec": {
+      "group": "kafka.strimzi.io",
+      "scope": "Namespaced",
+      "names": {"kind": "KafkaTopic", "plural": "kafkatopics", "singular": "kafkatopic", "shortNames": ["kt"]},
+      "versions": [{"name": "v1beta2", "served": true, "storage": true}]
+    }
+  }
+]}`
+
+func newCRDRunner() *explainRunner {
+	return &explainRunner{outputs: map[string]string{
+		"get customresourcedefinitions -o json": testCRDList,
+		"get certificates.cert-manager.io -n payments": "NAME      READY   SECRET\n" +
+			"api-tls   False   api-tls\n",
+	}}
+}
+
+func TestCRDCatalogParsesAndCaches(t *testing.T) {
+	runner := newCRDRunner()
+	catalog := NewCRDCatalog(runner)
+
+	crds, err := catalog.List(context.Background())
+	if err != nil || len(crds) != 2 {
+		t.Fatalf("List() = %+v, %v", crds, err)
+	}
+	cert := crds[0]
+	if cert.Kind != "Certificate" || cert.Version != "v1" || !cert.Namespaced || len(cert.PrinterColumns) != 2 || cert.Schema == nil {
+		t.Errorf("unexpected certificate info: %+v", cert)
+	}
+	if summary := cert.Summary(); !strings.Contains(summary, "cert-manager.io/v1") || !strings.Contains(summary, "Secret=.spec.secretName") {
+		t.Errorf("unexpected summary %q", summary)
+	}
+
+	if _, err := catalog.List(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := runner.count("get customresourcedefinitions -o json"); n != 1 {
+		t.Errorf("expected one CRD listing within the TTL, got %d", n)
+	}
+
+	for _, name := range []string{"Certificate", "certs", "certificates.cert-manager.io"} {
+		if crd, err := catalog.Lookup(context.Background(), name); err != nil || crd.Kind != "Certificate" {
+			t.Errorf("Lookup(%q) = %+v, %v", name, crd, err)
+		}
+	}
+	if _, err := catalog.Lookup(context.Background(), "certificat"); err == nil || !strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("expected a suggestion, got %v", err)
+	}
+}
+
+func TestCRDCatalogRelevantAndScry(t *testing.T) {
+	catalog := NewCRDCatalog(newCRDRunner())
+	ctx := context.Background()
+
+	relevant := catalog.Relevant(ctx, "why are my Kafka topics not ready?", 3)
+	if len(relevant) != 1 || relevant[0].Kind != "KafkaTopic" {
+		t.Errorf("expected KafkaTopic, got %+v", relevant)
+	}
+	if relevant := catalog.Relevant(ctx, "what is the kt of this", 3); len(relevant) != 0 {
+		t.Errorf("short names should not match plain words, got %+v", relevant)
+	}
+
+	crd, _ := catalog.Lookup(ctx, "cert")
+	out, err := catalog.Scry(ctx, *crd, "payments", 10)
+	if err != nil || !strings.Contains(out, "api-tls   False") {
+		t.Errorf("Scry() = %q, %v", out, err)
+	}
+
+	if mode := RouteIntent("certificate for api.example.com with letsencrypt", nil).Mode; mode != ModeGenerate {
+		t.Errorf("expected installed CRD kinds to route to generate, got %s", mode)
+	}
+}
+
+func TestValidateCustomResource(t *testing.T) {
+	crds, err := parseCRDList(testCRDList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := crds[0]
+
+	valid := `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: api-tls
+spec:
+  secretName: api-tls
+  dnsNames: [api.example.com]
+  privateKey:
+    algorithm: ECDSA
+    size: 256
+  issuerRef:
+    name: letsencrypt
+  secretTemplate:
+    labels:
+      team: payments
+  extra:
+    anything: goes
+`
+	if issues := ValidateCustomResource(valid, cert); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+
+	invalid := `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: api-tls
+spec:
+  dnsName: api.example.com
+  privateKey:
+    algorithm: DSA
+    size: large
+  issuerRef: {}
+  secretTemplate:
+    labels:
+      replicas: 3
+`
+	issues := strings.Join(ValidateCustomResource(invalid, cert), "\n")
+	for _, want := range []string{
+		"spec.secretName is required",
+		"spec.issuerRef.name is required",
+		"unknown field spec.dnsName (did you mean spec.dnsNames?)",
+		"spec.privateKey.algorithm must be one of [RSA ECDSA Ed25519], got DSA",
+		"spec.privateKey.size must be integer, got string",
+		"spec.secretTemplate.labels.replicas must be string, got integer",
+	} {
+		if !strings.Contains(issues, want) {
+			t.Errorf("missing issue %q in:\n%s", want, issues)
+		}
+	}
+
+	if issues := ValidateCustomResource("apiVersion: v1\nkind: Secret\nmetadata: {}\n", cert); len(issues) < 3 {
+		t.Errorf("expected apiVersion, kind and name issues, got %v", issues)
+	}
+}
+
+func TestCustomResourcePromptListsSchemaFields(t *testing.T) {
+	crds, _ := parseCRDList(testCRDList)
+	prompt := CustomResourcePrompt(crds[0], "api-tls", "payments", "TLS for api.example.com")
+	for _, want := range []string{
+		"apiVersion: cert-manager.io/v1",
+		"metadata.namespace: payments",
+		"spec.secretName <string> (required)",
+		"spec.privateKey.algorithm <string> one of [RSA ECDSA Ed25519]",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "status <object>") {
+		t.Errorf("prompt should skip status:\n%s", prompt)
+	}
+}
@@ -0,0 +1,276 @@
+// dataset.go - Fine-tuning dataset export: quality filter, dedup, redaction and train/validation split
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dataset formats written by ExportDataset
+const (
+	DatasetChat     = "chat"     // {"messages":[{"role","content"}]}, as used by OpenAI fine-tuning
+	DatasetShareGPT = "sharegpt" // {"conversations":[{"from","value"}]}
+	DatasetAlpaca   = "alpaca"   // {"instruction","input","output"}
+)
+
+// DatasetOptions controls which examples are exported and how
+type DatasetOptions struct {
+	Format          string
+	MinQuality      float64 // examples scored below this are dropped
+	ValidationSplit float64 // fraction of examples held out for validation
+	DedupThreshold  float64 // prompt similarity (0-1) at which examples count as duplicates
+	SystemPrompt    string  // system turn for chat formats, omitted when empty
+}
+
+// DefaultDatasetOptions matches the quality bar of GenerateLoRATrainingSet
+func DefaultDatasetOptions() DatasetOptions {
+	return DatasetOptions{Format: DatasetChat, MinQuality: 0.7, ValidationSplit: 0.1, DedupThreshold: 0.9}
+}
+
+// DatasetStats reports what an export kept and dropped
+type DatasetStats struct {
+	Examples   int `json:"examples"`
+	LowQuality int `json:"low_quality"`
+	Duplicates int `json:"duplicates"`
+	Redacted   int `json:"redacted"`
+	Train      int `json:"train"`
+	Validation int `json:"validation"`
+}
+
+// BuildDataset filters, redacts and deduplicates examples, then splits them into train and validation sets.
+// The split is keyed on the prompt, so re-exports keep each example on the same side.
+func BuildDataset(examples []TrainingExample, opts DatasetOptions) (train, validation []TrainingExample, stats DatasetStats) {
+	stats.Examples = len(examples)
+
+	sorted := append([]TrainingExample(nil), examples...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Quality > sorted[j].Quality })
+
+	var kept []TrainingExample
+	var keptTokens []map[string]bool
+	for _, example := range sorted {
+		if example.Quality < opts.MinQuality || strings.TrimSpace(example.Input) == "" || strings.TrimSpace(example.Output) == "" {
+			stats.LowQuality++
+			continue
+		}
+		example, redacted := redactExample(example)
+		if redacted {
+			stats.Redacted++
+		}
+
+		// Examples are sorted by quality, so the first of a group of near-identical prompts is the best one
+		tokens := promptTokens(example.Input)
+		duplicate := false
+		for _, other := range keptTokens {
+			if jaccard(tokens, other) >= opts.DedupThreshold {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			stats.Duplicates++
+			continue
+		}
+		kept = append(kept, example)
+		keptTokens = append(keptTokens, tokens)
+	}
+
+	for _, example := range kept {
+		if inValidationSplit(example.Input, opts.ValidationSplit) {
+			validation = append(validation, example)
+		} else {
+			train = append(train, example)
+		}
+	}
+	// Tiny datasets would otherwise end up with an empty validation set
+	if len(validation) == 0 && opts.ValidationSplit > 0 && len(train) >= 10 {
+		validation, train = train[len(train)-1:], train[:len(train)-1]
+	}
+	stats.Train, stats.Validation = len(train), len(validation)
+	return train, validation, stats
+}
+
+// redactExample re-runs RedactSensitive over every text field
+func redactExample(example TrainingExample) (TrainingExample, bool) {
+	redacted := false
+	redact := func(s string) string {
+		result := RedactSensitive(s)
+		if len(result.Replacements) > 0 {
+			redacted = true
+		}
+		return result.Sanitized
+	}
+
+	example.Input = redact(example.Input)
+	example.Output = redact(example.Output)
+	if len(example.Context) > 0 {
+		context := make(map[string]string, len(example.Context))
+		for k, v := range example.Context {
+			context[k] = redact(v)
+		}
+		example.Context = context
+	}
+	return example, redacted
+}
+
+var (
+	reGeneratedSuffix = regexp.MustCompile(`-[a-z0-9]{6,10}-[a-z0-9]{5}\b`)
+	reDigits          = regexp.MustCompile(`\d+`)
+	reTokenSplit      = regexp.MustCompile(`[^a-z0-9#_.-]+`)
+)
+
+// promptTokens lowercases a prompt and masks generated pod suffixes and numbers,
+// so "api-7d4b9c9f5-abcde" and "api-5c8f1d2e7-xyzwv" compare equal
+func promptTokens(prompt string) map[string]bool {
+	masked := reGeneratedSuffix.ReplaceAllString(strings.ToLower(prompt), "-#")
+	masked = reDigits.ReplaceAllString(masked, "#")
+	tokens := make(map[string]bool)
+	for _, t := range reTokenSplit.Split(masked, -1) {
+		if t != "" {
+			tokens[t] = true
+		}
+	}
+	return tokens
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for t := range a {
+		if b[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func inValidationSplit(prompt string, fraction float64) bool {
+	if fraction <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(strings.TrimSpace(prompt))))
+	return float64(h.Sum32()%1000) < fraction*1000
+}
+
+// datasetPrompt renders the user turn with the recorded cluster context
+func datasetPrompt(example TrainingExample) string {
+	ctx := datasetContext(example)
+	if ctx == "" {
+		return example.Input
+	}
+	return fmt.Sprintf("[CTX] %s\n%s", ctx, example.Input)
+}
+
+func datasetContext(example TrainingExample) string {
+	keys := make([]string, 0, len(example.Context))
+	for k, v := range example.Context {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+example.Context[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// WriteDatasetJSONL writes one JSON record per example in the given format
+func WriteDatasetJSONL(w io.Writer, examples []TrainingExample, format, systemPrompt string) error {
+	type chatMessage struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	type shareGPTTurn struct {
+		From  string `json:"from"`
+		Value string `json:"value"`
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, example := range examples {
+		var record any
+		switch format {
+		case DatasetChat:
+			var messages []chatMessage
+			if systemPrompt != "" {
+				messages = append(messages, chatMessage{Role: "system", Content: systemPrompt})
+			}
+			messages = append(messages,
+				chatMessage{Role: "user", Content: datasetPrompt(example)},
+				chatMessage{Role: "assistant", Content: example.Output})
+			record = map[string]any{"messages": messages}
+		case DatasetShareGPT:
+			var turns []shareGPTTurn
+			if systemPrompt != "" {
+				turns = append(turns, shareGPTTurn{From: "system", Value: systemPrompt})
+			}
+			turns = append(turns,
+				shareGPTTurn{From: "human", Value: datasetPrompt(example)},
+				shareGPTTurn{From: "gpt", Value: example.Output})
+			record = map[string]any{"conversations": turns}
+		case DatasetAlpaca:
+			record = map[string]string{
+				"instruction": example.Input,
+				"input":       datasetContext(example),
+				"output":      example.Output,
+			}
+		default:
+			return fmt.Errorf("unknown dataset format %q (use %s, %s or %s)", format, DatasetChat, DatasetShareGPT, DatasetAlpaca)
+		}
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to encode dataset record: %w", err)
+		}
+	}
+	return nil
+}
+
+// ExportDataset writes train.jsonl and validation.jsonl for the recorder's successful sessions into dir
+func (fr *FlightRecorder) ExportDataset(dir string, opts DatasetOptions) (DatasetStats, error) {
+	// Fail on a bad format before touching the output directory
+	switch opts.Format {
+	case DatasetChat, DatasetShareGPT, DatasetAlpaca:
+	default:
+		return DatasetStats{}, fmt.Errorf("unknown dataset format %q (use %s, %s or %s)", opts.Format, DatasetChat, DatasetShareGPT, DatasetAlpaca)
+	}
+
+	train, validation, stats := BuildDataset(fr.ExportTrainingData(), opts)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return stats, fmt.Errorf("failed to create dataset directory: %w", err)
+	}
+	systemPrompt := RedactSensitive(opts.SystemPrompt).Sanitized
+	for name, examples := range map[string][]TrainingExample{"train.jsonl": train, "validation.jsonl": validation} {
+		if err := writeDatasetFile(filepath.Join(dir, name), examples, opts.Format, systemPrompt); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+func writeDatasetFile(path string, examples []TrainingExample, format, systemPrompt string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	w := bufio.NewWriter(file)
+	if err := WriteDatasetJSONL(w, examples, format, systemPrompt); err != nil {
+		file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func datasetExample(input, output string, quality float64) TrainingExample {
+	return TrainingExample{
+		Input:   input,
+		Output:  output,
+		Quality: quality,
+		Type:    "command",
+		Context: map[string]string{"namespace": "payments", "context": "prod"},
+	}
+}
+
+func TestBuildDatasetFiltersDedupsAndRedacts(t *testing.T) {
+	examples := []TrainingExample{
+		datasetExample("show logs for pod api-7d4b9c9f5-abcde", "kubectl logs api-7d4b9c9f5-abcde -n payments", 0.8),
+		datasetExample("show logs for pod api-5c8f1d2e7-xyzwv", "kubectl logs api-5c8f1d2e7-xyzwv -n payments", 0.95),
+		datasetExample("scale web to 3 replicas", "kubectl scale deployment web --replicas=3", 0.5),
+		datasetExample("create a secret with password=hunter2", "kubectl create secret generic db --from-literal=password=hunter2", 0.9),
+		datasetExample("", "kubectl get pods", 1),
+	}
+
+	train, validation, stats := BuildDataset(examples, DefaultDatasetOptions())
+	if stats.Examples != 5 || stats.LowQuality != 2 || stats.Duplicates != 1 || stats.Redacted != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	kept := append(train, validation...)
+	if len(kept) != 2 || stats.Train+stats.Validation != 2 {
+		t.Fatalf("expected 2 examples, got %+v", kept)
+	}
+
+	var logs, secret *TrainingExample
+	for i := range kept {
+		if strings.HasPrefix(kept[i].Input, "show logs") {
+			logs = &kept[i]
+		} else {
+			secret = &kept[i]
+		}
+	}
+	if logs == nil || logs.Quality != 0.95 {
+		t.Errorf("dedup should keep the best-scored duplicate, got %+v", logs)
+	}
+	if secret == nil || strings.Contains(secret.Input+secret.Output, "hunter2") {
+		t.Errorf("secret was not redacted: %+v", secret)
+	}
+}
+
+func TestBuildDatasetSplitIsStable(t *testing.T) {
+	var examples []TrainingExample
+	for i := 0; i < 200; i++ {
+		examples = append(examples, datasetExample(fmt.Sprintf("task %s number %c%c", strings.Repeat("x", i%7), 'a'+i%26, 'a'+i/26), "kubectl get pods", 0.9))
+	}
+	opts := DefaultDatasetOptions()
+	opts.DedupThreshold = 1.1 // keep all
+
+	train, validation, _ := BuildDataset(examples, opts)
+	if len(validation) < 5 || len(validation) > 40 || len(train)+len(validation) != 200 {
+		t.Fatalf("unexpected split %d/%d", len(train), len(validation))
+	}
+
+	reversed := make([]TrainingExample, len(examples))
+	for i, e := range examples {
+		reversed[len(examples)-1-i] = e
+	}
+	_, again, _ := BuildDataset(reversed, opts)
+	held := make(map[string]bool)
+	for _, e := range validation {
+		held[e.Input] = true
+	}
+	for _, e := range again {
+		if !held[e.Input] {
+			t.Fatalf("%q moved to validation on re-export", e.Input)
+		}
+	}
+}
+
+func TestWriteDatasetJSONLFormats(t *testing.T) {
+	examples := []TrainingExample{datasetExample("list failing pods", "kubectl get pods --field-selector=status.phase!=Running && kubectl get events", 0.9)}
+
+	var chat bytes.Buffer
+	if err := WriteDatasetJSONL(&chat, examples, DatasetChat, "You are KubeMage."); err != nil {
+		t.Fatal(err)
+	}
+	var record struct {
+		Messages []struct{ Role, Content string } `json:"messages"`
+	}
+	if err := json.Unmarshal(chat.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if len(record.Messages) != 3 || record.Messages[0].Role != "system" || record.Messages[2].Role != "assistant" ||
+		record.Messages[1].Content != "[CTX] context=prod namespace=payments\nlist failing pods" {
+		t.Errorf("unexpected chat record: %s", chat.String())
+	}
+	if !strings.Contains(chat.String(), "&&") {
+		t.Errorf("HTML escaping should be off: %s", chat.String())
+	}
+
+	var sharegpt bytes.Buffer
+	if err := WriteDatasetJSONL(&sharegpt, examples, DatasetShareGPT, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sharegpt.String(), `"from":"human"`) || strings.Contains(sharegpt.String(), `"system"`) {
+		t.Errorf("unexpected sharegpt record: %s", sharegpt.String())
+	}
+
+	var alpaca bytes.Buffer
+	if err := WriteDatasetJSONL(&alpaca, examples, DatasetAlpaca, ""); err != nil {
+		t.Fatal(err)
+	}
+	var row map[string]string
+	if err := json.Unmarshal(alpaca.Bytes(), &row); err != nil {
+		t.Fatal(err)
+	}
+	if row["instruction"] != "list failing pods" || row["input"] != "context=prod namespace=payments" {
+		t.Errorf("unexpected alpaca record: %v", row)
+	}
+
+	if err := WriteDatasetJSONL(&alpaca, examples, "csv", ""); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestFlightRecorderExportDataset(t *testing.T) {
+	fr := NewFlightRecorder(t.TempDir())
+	fr.autoSave = false
+	for i, input := range []string{"restart the checkout deployment", "show events in payments", "why is api crashing"} {
+		err := fr.RecordSession(SessionRecord{
+			UserInput:        input,
+			Outcome:          "success",
+			UserSatisfaction: 5,
+			Suggestions: []SuggestionRecord{{
+				Type: "command", Content: fmt.Sprintf("kubectl command %d", i), Confidence: 0.6, Accepted: true, Applied: true,
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "dataset")
+	opts := DefaultDatasetOptions()
+	opts.Format = DatasetAlpaca
+	stats, err := fr.ExportDataset(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := 0
+	for _, name := range []string{"train.jsonl", "validation.jsonl"} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines++
+		}
+		file.Close()
+	}
+	if lines != 3 || stats.Train+stats.Validation != 3 {
+		t.Errorf("expected 3 exported examples, got %d lines, %+v", lines, stats)
+	}
+
+	opts.Format = "parquet"
+	if _, err := fr.ExportDataset(filepath.Join(t.TempDir(), "bad"), opts); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
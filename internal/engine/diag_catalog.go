@@ -0,0 +1,234 @@
+// diag_catalog.go - Diagnostic plans for nodes, storage, jobs, autoscaling, DNS, ingress and StatefulSets
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// diagHeuristicRule turns a well-known failure signature in step output into a hint
+type diagHeuristicRule struct {
+	pattern *regexp.Regexp
+	note    string
+}
+
+var diagHeuristicRules = []diagHeuristicRule{
+	// Pods
+	{regexp.MustCompile(`ImagePullBackOff`), "Detected ImagePullBackOff — check image name/registry/credentials/network."},
+	{regexp.MustCompile(`CrashLoopBackOff`), "Detected CrashLoopBackOff — inspect container logs; check readiness/liveness probes and app startup."},
+	{regexp.MustCompile(`OOMKilled`), "Container OOMKilled — consider limits/requests and memory usage."},
+	{regexp.MustCompile(`Insufficient (cpu|memory)`), "Pods cannot be scheduled for lack of CPU/memory — lower requests or add node capacity."},
+
+	// Nodes
+	{regexp.MustCompile(`\bNotReady\b`), "Node NotReady — check kubelet status, node networking and the container runtime."},
+	{regexp.MustCompile(`MemoryPressure\s+True`), "Node under MemoryPressure — pods may be evicted; check top consumers and memory limits."},
+	{regexp.MustCompile(`DiskPressure\s+True`), "Node under DiskPressure — free disk space (images, logs, emptyDir) or grow the volume."},
+	{regexp.MustCompile(`PIDPressure\s+True`), "Node under PIDPressure — look for pods leaking processes; consider pod PID limits."},
+	{regexp.MustCompile(`NetworkUnavailable\s+True`), "Node network unavailable — check the CNI plugin pods on this node."},
+	{regexp.MustCompile(`SchedulingDisabled`), "Node is cordoned (SchedulingDisabled) — uncordon it once maintenance is done."},
+
+	// Persistent volumes
+	{regexp.MustCompile(`ProvisioningFailed`), "Volume provisioning failed — check the StorageClass provisioner and its logs/quotas."},
+	{regexp.MustCompile(`no persistent volumes available`), "No matching PersistentVolume — create one or set a StorageClass with dynamic provisioning."},
+	{regexp.MustCompile(`WaitForFirstConsumer`), "StorageClass binds on first consumer — the PVC stays Pending until a pod using it is scheduled."},
+	{regexp.MustCompile(`storageclass\.storage\.k8s\.io "[^"]+" not found`), "The PVC's StorageClass does not exist — fix storageClassName or create the class."},
+	{regexp.MustCompile(`FailedAttachVolume|Multi-Attach error`), "Volume attach failed — a ReadWriteOnce volume may still be attached to another node."},
+	{regexp.MustCompile(`FailedMount`), "Volume mount failed — check the volume's node attachment, secrets/configmaps and filesystem."},
+
+	// Jobs and CronJobs
+	{regexp.MustCompile(`BackoffLimitExceeded`), "Job hit its backoffLimit — inspect the failed pods' logs for the error."},
+	{regexp.MustCompile(`DeadlineExceeded`), "Job exceeded activeDeadlineSeconds — it runs too long or is stuck."},
+	{regexp.MustCompile(`[Tt]oo many missed start times`), "CronJob missed too many schedules — set startingDeadlineSeconds or check the controller."},
+	{regexp.MustCompile(`Suspend:\s+True`), "CronJob is suspended — no new jobs are created until spec.suspend is false."},
+
+	// Autoscaling
+	{regexp.MustCompile(`FailedGetResourceMetric|unable to get metrics|FailedComputeMetricsReplicas`), "HPA cannot read metrics — check that metrics-server is running and its APIService is Available."},
+	{regexp.MustCompile(`<unknown>/`), "HPA targets show <unknown> — metrics are not available for the scale target yet."},
+	{regexp.MustCompile(`missing request for`), "HPA needs resource requests — set CPU/memory requests on every container of the target."},
+	{regexp.MustCompile(`MissingEndpoints|FailedDiscoveryCheck`), "metrics.k8s.io APIService is unavailable — metrics-server pods or their service are down."},
+
+	// DNS
+	{regexp.MustCompile(`plugin/loop: Loop`), "CoreDNS detected a forwarding loop — point forward to an upstream that is not the node's local resolver."},
+	{regexp.MustCompile(`plugin/errors: .*(SERVFAIL|i/o timeout)`), "CoreDNS upstream errors — check the forward servers and egress from kube-system."},
+
+	// Ingress
+	{regexp.MustCompile(`endpoints "[^"]+" not found|<error: endpoints`), "Ingress backend service has no endpoints — check the service name/port and pod readiness."},
+	{regexp.MustCompile(`no endpoints available for service`), "Backend service has no ready endpoints — 502/503 until its pods pass readiness."},
+	{regexp.MustCompile(`Connection refused|upstream prematurely closed`), "Upstream refused the connection — 502; check the service targetPort matches the container port."},
+	{regexp.MustCompile(`upstream timed out`), "Upstream timed out — 504; the backend is slow or the proxy timeout is too low."},
+
+	// StatefulSets
+	{regexp.MustCompile(`FailedCreate`), "Controller cannot create pods — check quota, PVC templates and admission webhook errors."},
+	{regexp.MustCompile(`unbound immediate PersistentVolumeClaims`), "Pods wait for their PVCs to bind — diagnose the PVC with /diag-pvc."},
+}
+
+// diagPlanBuilders builds the plan behind each /diag-<kind> command; dns is cluster-wide
+var diagPlanBuilders = map[string]func(name, ns string) DiagPlan{
+	"pod":     PlanPodNotReady,
+	"svc":     PlanServiceNotReachable,
+	"deploy":  PlanDeploymentNotReady,
+	"node":    func(name, _ string) DiagPlan { return PlanNodeNotReady(name) },
+	"pvc":     PlanPVCPending,
+	"job":     PlanJobFailed,
+	"cronjob": PlanCronJobFailed,
+	"hpa":     PlanHPANotScaling,
+	"dns":     func(_, _ string) DiagPlan { return PlanDNSResolution() },
+	"ingress": PlanIngressErrors,
+	"sts":     PlanStatefulSetStuck,
+}
+
+// DiagPlanKinds lists the kinds BuildDiagPlan accepts
+func DiagPlanKinds() []string {
+	kinds := make([]string, 0, len(diagPlanBuilders))
+	for kind := range diagPlanBuilders {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// BuildDiagPlan returns the diagnostic plan for a kind such as "pvc" or "hpa"
+func BuildDiagPlan(kind, name, ns string) (DiagPlan, error) {
+	build, ok := diagPlanBuilders[kind]
+	if !ok {
+		return DiagPlan{}, fmt.Errorf("no diagnostic plan for %q", kind)
+	}
+	if name == "" && kind != "dns" {
+		return DiagPlan{}, fmt.Errorf("/diag-%s needs a %s name", kind, kind)
+	}
+	return build(name, ns), nil
+}
+
+// PlanNodeNotReady creates a diagnostic plan for NotReady nodes and node pressure
+func PlanNodeNotReady(node string) DiagPlan {
+	return DiagPlan{
+		Title: fmt.Sprintf("Node %s NotReady or under pressure", node),
+		Steps: []string{
+			fmt.Sprintf("kubectl describe node %s", node),
+			fmt.Sprintf("kubectl get node %s -o wide", node),
+			fmt.Sprintf("kubectl top node %s", node),
+			fmt.Sprintf("kubectl get pods -A --field-selector spec.nodeName=%s", node),
+			fmt.Sprintf("kubectl get events -A --field-selector involvedObject.kind=Node,involvedObject.name=%s", node),
+		},
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 10 * time.Second, 10 * time.Second, 5 * time.Second},
+		Summary:  "Check node conditions (Ready, MemoryPressure, DiskPressure, PIDPressure, NetworkUnavailable), kubelet health and the pods it runs.",
+	}
+}
+
+// PlanPVCPending creates a diagnostic plan for Pending PVCs and volume attach failures
+func PlanPVCPending(pvc, ns string) DiagPlan {
+	base := fmt.Sprintf("-n %s", ns)
+	return DiagPlan{
+		Title: fmt.Sprintf("PVC %s Pending or failing to attach", pvc),
+		Steps: []string{
+			fmt.Sprintf("kubectl describe pvc %s %s", pvc, base),
+			"kubectl get storageclass",
+			"kubectl get pv",
+			fmt.Sprintf("kubectl get events %s --field-selector involvedObject.kind=PersistentVolumeClaim,involvedObject.name=%s", base, pvc),
+			"kubectl get volumeattachments",
+		},
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+		Summary:  "Check the StorageClass and provisioner, matching PVs, binding mode and volume attachments.",
+	}
+}
+
+// PlanJobFailed creates a diagnostic plan for failed or stuck Jobs
+func PlanJobFailed(job, ns string) DiagPlan {
+	base := fmt.Sprintf("-n %s", ns)
+	return DiagPlan{
+		Title: fmt.Sprintf("Job %s failing", job),
+		Steps: []string{
+			fmt.Sprintf("kubectl describe job %s %s", job, base),
+			fmt.Sprintf("kubectl get pods %s -l job-name=%s", base, job),
+			fmt.Sprintf("kubectl logs job/%s %s --tail=100", job, base),
+			fmt.Sprintf("kubectl get events %s --field-selector involvedObject.kind=Job,involvedObject.name=%s", base, job),
+		},
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 15 * time.Second, 5 * time.Second},
+		Summary:  "Check job conditions (BackoffLimitExceeded, DeadlineExceeded), pod exit codes and logs.",
+	}
+}
+
+// PlanCronJobFailed creates a diagnostic plan for CronJobs that fail or stop scheduling
+func PlanCronJobFailed(cronjob, ns string) DiagPlan {
+	base := fmt.Sprintf("-n %s", ns)
+	return DiagPlan{
+		Title: fmt.Sprintf("CronJob %s failing", cronjob),
+		Steps: []string{
+			fmt.Sprintf("kubectl describe cronjob %s %s", cronjob, base),
+			fmt.Sprintf("kubectl get jobs %s", base),
+			fmt.Sprintf("kubectl get events %s --field-selector involvedObject.kind=CronJob,involvedObject.name=%s", base, cronjob),
+		},
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 5 * time.Second},
+		Summary:  "Check schedule, suspend, concurrencyPolicy, startingDeadlineSeconds and the last jobs it created.",
+	}
+}
+
+// PlanHPANotScaling creates a diagnostic plan for HPAs that do not scale, usually for lack of metrics
+func PlanHPANotScaling(hpa, ns string) DiagPlan {
+	base := fmt.Sprintf("-n %s", ns)
+	return DiagPlan{
+		Title: fmt.Sprintf("HPA %s not scaling", hpa),
+		Steps: []string{
+			fmt.Sprintf("kubectl describe hpa %s %s", hpa, base),
+			fmt.Sprintf("kubectl get hpa %s %s", hpa, base),
+			"kubectl get apiservice v1beta1.metrics.k8s.io",
+			"kubectl get deployment metrics-server -n kube-system",
+			fmt.Sprintf("kubectl top pods %s", base),
+		},
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second, 10 * time.Second},
+		Summary:  "Check metrics availability (metrics-server, metrics.k8s.io APIService), resource requests on the target and min/max replicas.",
+	}
+}
+
+// PlanDNSResolution creates a diagnostic plan for cluster DNS (CoreDNS) resolution problems
+func PlanDNSResolution() DiagPlan {
+	base := "-n kube-system"
+	return DiagPlan{
+		Title: "Cluster DNS resolution failing",
+		Steps: []string{
+			fmt.Sprintf("kubectl get pods %s -l k8s-app=kube-dns -o wide", base),
+			fmt.Sprintf("kubectl get svc kube-dns %s", base),
+			fmt.Sprintf("kubectl get endpoints kube-dns %s", base),
+			fmt.Sprintf("kubectl logs %s -l k8s-app=kube-dns --tail=100", base),
+			fmt.Sprintf("kubectl get configmap coredns %s -o yaml", base),
+		},
+		Timeouts: []time.Duration{5 * time.Second, 5 * time.Second, 5 * time.Second, 15 * time.Second, 5 * time.Second},
+		Summary:  "Check CoreDNS pods and endpoints, its logs for SERVFAIL/timeouts/loops, and the Corefile forward settings.",
+	}
+}
+
+// PlanIngressErrors creates a diagnostic plan for Ingress 404/502 responses
+func PlanIngressErrors(ingress, ns string) DiagPlan {
+	base := fmt.Sprintf("-n %s", ns)
+	return DiagPlan{
+		Title: fmt.Sprintf("Ingress %s returning 404/502", ingress),
+		Steps: []string{
+			fmt.Sprintf("kubectl describe ingress %s %s", ingress, base),
+			"kubectl get ingressclass",
+			fmt.Sprintf("kubectl get endpoints %s", base),
+			fmt.Sprintf("kubectl get events %s --field-selector involvedObject.kind=Ingress,involvedObject.name=%s", base, ingress),
+			"kubectl get pods -A -l app.kubernetes.io/component=controller",
+			"kubectl logs -n ingress-nginx -l app.kubernetes.io/component=controller --tail=200",
+		},
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second},
+		Summary:  "404: check host/path rules and the ingress class. 502/504: check backend service endpoints, ports and pod readiness, and the controller logs for refused or timed out upstreams.",
+	}
+}
+
+// PlanStatefulSetStuck creates a diagnostic plan for StatefulSet rollouts that do not progress
+func PlanStatefulSetStuck(sts, ns string) DiagPlan {
+	base := fmt.Sprintf("-n %s", ns)
+	return DiagPlan{
+		Title: fmt.Sprintf("StatefulSet %s rollout stuck", sts),
+		Steps: []string{
+			fmt.Sprintf("kubectl describe statefulset %s %s", sts, base),
+			fmt.Sprintf("kubectl get pods %s -o wide", base),
+			fmt.Sprintf("kubectl get pvc %s", base),
+			fmt.Sprintf("kubectl get events %s --field-selector involvedObject.kind=StatefulSet,involvedObject.name=%s", base, sts),
+		},
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+		Summary:  "With OrderedReady, one unready pod blocks the rollout: check pod readiness, PVC binding and updateStrategy/partition.",
+	}
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+func TestBuildDiagPlanCatalog(t *testing.T) {
+	for _, kind := range DiagPlanKinds() {
+		plan, err := BuildDiagPlan(kind, "web", "shop")
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		if plan.Title == "" || plan.Summary == "" || len(plan.Timeouts) != len(plan.Steps) {
+			t.Errorf("%s: incomplete plan %+v", kind, plan)
+		}
+		for _, step := range plan.Steps {
+			if !IsWhitelistedAction(step) {
+				t.Errorf("%s: step %q is not read-only", kind, step)
+			}
+			if strings.Contains(step, "-n shop") && kind == "node" {
+				t.Errorf("node plan should not be namespaced: %q", step)
+			}
+		}
+	}
+
+	if _, err := BuildDiagPlan("pvc", "", "shop"); err == nil {
+		t.Error("expected an error for a missing name")
+	}
+	if plan, err := BuildDiagPlan("dns", "", ""); err != nil || !strings.Contains(plan.Steps[0], "-n kube-system") {
+		t.Errorf("dns plan should need no name: %v %+v", err, plan)
+	}
+	if _, err := BuildDiagPlan("crd", "x", "shop"); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+}
+
+func TestDiagHeuristicsCatalog(t *testing.T) {
+	cases := []struct {
+		output string
+		want   string
+	}{
+		{"Conditions:\n  MemoryPressure   True    KubeletHasInsufficientMemory", "MemoryPressure"},
+		{"Ready            False   KubeletNotReady\nnode-1   NotReady   <none>", "Node NotReady"},
+		{"Warning  ProvisioningFailed  persistentvolume-controller  storageclass.storage.k8s.io \"fast\" not found", "StorageClass does not exist"},
+		{"Warning  FailedAttachVolume  Multi-Attach error for volume \"pvc-1\"", "ReadWriteOnce"},
+		{"Warning  BackoffLimitExceeded  Job has reached the specified backoff limit", "backoffLimit"},
+		{"cpu: <unknown>/80%", "<unknown>"},
+		{"Warning  FailedGetResourceMetric  unable to get metrics for resource cpu", "metrics-server"},
+		{"[ERROR] plugin/errors: 2 api.example.com. A: read udp 10.0.0.5:43->8.8.8.8:53: i/o timeout", "upstream errors"},
+		{"Default backend:  <default>\n  /   web:80 (<error: endpoints \"web\" not found>)", "no endpoints"},
+		{"Warning  FailedCreate  create Pod db-1 in StatefulSet db failed: exceeded quota", "quota"},
+	}
+	for _, tc := range cases {
+		notes := strings.Join(diagHeuristics(tc.output), "\n")
+		if !strings.Contains(notes, tc.want) {
+			t.Errorf("output %q: expected a note about %q, got %q", tc.output, tc.want, notes)
+		}
+	}
+	if notes := diagHeuristics("The connection to the server was refused: dial tcp: i/o timeout"); len(notes) != 0 {
+		t.Errorf("API server errors should not be read as DNS failures: %q", notes)
+	}
+}
+
+func TestHPAPlanFindsMissingMetrics(t *testing.T) {
+	runner := execx.NewMockRunner()
+	runner.Expect("kubectl", strings.Fields("describe hpa web -n shop"), "Warning  FailedGetResourceMetric  failed to get cpu utilization: unable to get metrics for resource cpu", "", nil)
+	runner.Expect("kubectl", strings.Fields("get hpa web -n shop"), "NAME  REFERENCE       TARGETS         MINPODS\nweb   Deployment/web  <unknown>/80%   2", "", nil)
+	runner.Expect("kubectl", strings.Fields("get apiservice v1beta1.metrics.k8s.io"), "NAME                     SERVICE                      AVAILABLE\nv1beta1.metrics.k8s.io   kube-system/metrics-server   False (MissingEndpoints)", "", nil)
+	runner.Expect("kubectl", strings.Fields("get deployment metrics-server -n kube-system"), "metrics-server   0/1   1   0", "", nil)
+	runner.Expect("kubectl", strings.Fields("top pods -n shop"), "", "error: Metrics API not available", nil)
+
+	plan, _ := BuildDiagPlan("hpa", "web", "shop")
+	results := NewDiagRunner(runner, 3, time.Second).Run(context.Background(), plan, nil)
+	if runner.CallCount != 5 {
+		t.Fatalf("expected every step to run, got %d calls", runner.CallCount)
+	}
+	if !strings.Contains(strings.Join(results[2].Notes, " "), "APIService is unavailable") {
+		t.Errorf("expected the APIService note: %+v", results[2])
+	}
+	if !strings.Contains(results[4].Output, "Metrics API not available") {
+		t.Errorf("stderr should be kept as output: %+v", results[4])
+	}
+}
+
+func TestIngressPlanReadsControllerLogs(t *testing.T) {
+	runner := execx.NewMockRunner()
+	logs := "2025/03/04 10:00:00 [error] 31#31: *1 upstream timed out (110: Operation timed out) while reading response header from upstream, upstream: \"http://10.0.0.7:8080/\""
+	runner.Expect("kubectl", strings.Fields("logs -n ingress-nginx -l app.kubernetes.io/component=controller --tail=200"), logs, "", nil)
+	for i := 0; i < 6; i++ {
+		runner.Expect("kubectl", nil, "", "", nil)
+	}
+
+	plan, _ := BuildDiagPlan("ingress", "web", "shop")
+	results := NewDiagRunner(runner, 3, time.Second).Run(context.Background(), plan, nil)
+	last := results[len(results)-1]
+	if !strings.HasPrefix(last.Command, "kubectl logs -n ingress-nginx") {
+		t.Fatalf("the controller logs should be the last step: %+v", last)
+	}
+	if !strings.Contains(strings.Join(last.Notes, " "), "Upstream timed out") {
+		t.Errorf("expected the upstream timeout note from the controller logs: %+v", last.Notes)
+	}
+}
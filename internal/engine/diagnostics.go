@@ -6,20 +6,51 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/siryoos/kubemage/internal/engine/logmine"
+	"github.com/siryoos/kubemage/internal/execx"
 )
 
 type DiagResult struct {
-	Step    string
-	Command string
-	Output  string // truncated for UI
-	Notes   []string
+	Step     string
+	Command  string
+	Output   string // truncated for UI
+	Notes    []string
+	Started  time.Time // zero when the step never ran
+	Duration time.Duration
+	Logs     *logmine.Analysis // templates of a kubectl logs step, nil for other steps
 }
 
 type DiagPlan struct {
-	Title   string
-	Steps   []string // kubectl commands (read-only)
-	Summary string   // optional LLM prompt suffix / human hint
+	Title    string
+	Steps    []string        // kubectl commands (read-only)
+	Timeouts []time.Duration // per step, parallel to Steps; zero uses the runner's default
+	Summary  string          // optional LLM prompt suffix / human hint
+}
+
+// timeout returns the i-th step's timeout, or def when the plan sets none
+func (p DiagPlan) timeout(i int, def time.Duration) time.Duration {
+	if i < len(p.Timeouts) && p.Timeouts[i] > 0 {
+		return p.Timeouts[i]
+	}
+	return def
+}
+
+// DiagPlanFromPlaybook builds a plan from a playbook's steps, filling {placeholders}
+// from vars and keeping each step's timeout
+func DiagPlanFromPlaybook(pb *Playbook, vars map[string]string) DiagPlan {
+	plan := DiagPlan{Title: pb.Name, Summary: strings.Join(pb.Heuristics, "; ")}
+	for _, step := range pb.Steps {
+		command := step.Command
+		for key, value := range vars {
+			command = strings.ReplaceAll(command, "{"+key+"}", value)
+		}
+		plan.Steps = append(plan.Steps, command)
+		plan.Timeouts = append(plan.Timeouts, time.Duration(step.Timeout)*time.Second)
+	}
+	return plan
 }
 
 // Execs a command with timeout and returns combined output (truncated).
@@ -47,38 +78,156 @@ func PlanPodNotReady(pod, ns string) DiagPlan {
 		Steps: []string{
 			fmt.Sprintf("kubectl describe pod %s %s", pod, base),
 			fmt.Sprintf("kubectl get events %s --field-selector involvedObject.kind=Pod,involvedObject.name=%s --sort-by=.lastTimestamp", base, pod),
-			fmt.Sprintf("kubectl logs %s %s --tail=200 --all-containers", pod, base),
+			fmt.Sprintf("kubectl logs %s %s --tail=2000 --all-containers", pod, base),
 		},
-		Summary: "Analyze describe/events/logs to infer root cause (ImagePullBackOff, CrashLoopBackOff, OOMKilled, probe failures, etc.).",
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 15 * time.Second},
+		Summary:  "Analyze describe/events/logs to infer root cause (ImagePullBackOff, CrashLoopBackOff, OOMKilled, probe failures, etc.).",
 	}
 }
 
-// Runs a DiagPlan and returns per-step outputs.
-func RunDiagPlan(p DiagPlan) ([]DiagResult, error) {
-	results := make([]DiagResult, 0, len(p.Steps))
-	for _, c := range p.Steps {
-		out, err := runShell(8*time.Second, c, 32*1024) // cap to 32KB per step
-		step := c
-		if i := strings.Index(c, " "); i > 0 {
-			step = c[:i]
+// DiagRunner executes diagnostic plans through the engine's command runner
+type DiagRunner struct {
+	runner         execx.Runner
+	Parallelism    int           // steps run at once
+	DefaultTimeout time.Duration // for steps without a timeout of their own
+}
+
+const maxDiagOutput = 32 * 1024 // cap per step
+
+// NewDiagRunner creates a diagnostic runner; zero values fall back to 3 steps at once and 8s per step
+func NewDiagRunner(runner execx.Runner, parallelism int, timeout time.Duration) *DiagRunner {
+	if parallelism <= 0 {
+		parallelism = 3
+	}
+	if timeout <= 0 {
+		timeout = 8 * time.Second
+	}
+	return &DiagRunner{runner: runner, Parallelism: parallelism, DefaultTimeout: timeout}
+}
+
+// Run executes the plan's independent steps concurrently, at most Parallelism at a time,
+// and returns the results in plan order. onResult, when set, is called as each step
+// finishes, from the step's goroutine. Cancelling ctx stops the running steps and
+// skips the ones not yet started.
+func (d *DiagRunner) Run(ctx context.Context, p DiagPlan, onResult func(i int, r DiagResult)) []DiagResult {
+	results := make([]DiagResult, len(p.Steps))
+	sem := make(chan struct{}, d.Parallelism)
+	var wg sync.WaitGroup
+	for i, command := range p.Steps {
+		// Steps start in plan order as slots free up
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = DiagResult{Step: diagStepName(command), Command: command, Notes: []string{"error: cancelled before it ran"}}
+			if onResult != nil {
+				onResult(i, results[i])
+			}
+			continue
 		}
-		dr := DiagResult{Step: step, Command: c, Output: out}
-		if err != nil {
-			dr.Notes = append(dr.Notes, "error: "+err.Error())
+		wg.Add(1)
+		go func(i int, command string) {
+			defer wg.Done()
+			results[i] = d.runStep(ctx, command, p.timeout(i, d.DefaultTimeout))
+			<-sem
+			if onResult != nil {
+				onResult(i, results[i])
+			}
+		}(i, command)
+	}
+	wg.Wait()
+	return results
+}
+
+func (d *DiagRunner) runStep(ctx context.Context, command string, timeout time.Duration) DiagResult {
+	dr := DiagResult{Step: diagStepName(command), Command: command}
+	if !IsWhitelistedAction(command) {
+		dr.Notes = append(dr.Notes, "error: not a whitelisted read-only command")
+		return dr
+	}
+	if ctx.Err() != nil {
+		dr.Notes = append(dr.Notes, "error: cancelled before it ran")
+		return dr
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	dr.Started = time.Now()
+	stdout, stderr, err := d.runner.RunCommand(stepCtx, command)
+	dr.Duration = time.Since(dr.Started)
+	out := stdout + stderr
+	if len(out) > maxDiagOutput {
+		out = "(…truncated…)\n" + out[len(out)-maxDiagOutput:]
+	}
+	dr.Output = out
+	switch {
+	case ctx.Err() != nil:
+		dr.Notes = append(dr.Notes, "error: cancelled")
+	case stepCtx.Err() == context.DeadlineExceeded:
+		dr.Notes = append(dr.Notes, fmt.Sprintf("error: timeout after %s", timeout))
+	case err != nil:
+		dr.Notes = append(dr.Notes, "error: "+err.Error())
+	case isLogsCommand(command):
+		// Mine every line, not just what survives the output cap
+		if dr.Logs = d.mineLogs(stepCtx, command, stdout); dr.Logs != nil {
+			dr.Notes = append(dr.Notes, "logs: "+dr.Logs.Headline())
 		}
-		// quick heuristics
-		if strings.Contains(out, "ImagePullBackOff") {
-			dr.Notes = append(dr.Notes, "Detected ImagePullBackOff — check image name/registry/credentials/network.")
+	}
+	dr.Notes = append(dr.Notes, diagHeuristics(out)...)
+	return dr
+}
+
+// mineLogs clusters a logs step's output into templates. Unless the step already asked for
+// them, the previous container's logs are fetched too so that new templates stand out.
+func (d *DiagRunner) mineLogs(ctx context.Context, command, stdout string) *logmine.Analysis {
+	previous := ""
+	if !hasPreviousFlag(command) {
+		// Fails when the container never restarted; the current logs are still mined
+		if out, _, err := d.runner.RunCommand(ctx, command+" --previous"); err == nil {
+			previous = out
 		}
-		if strings.Contains(out, "CrashLoopBackOff") {
-			dr.Notes = append(dr.Notes, "Detected CrashLoopBackOff — inspect container logs; check readiness/liveness probes and app startup.")
+	}
+	analysis := logmine.Analyze(stdout, previous)
+	if analysis.Lines == 0 {
+		return nil
+	}
+	return analysis
+}
+
+func isLogsCommand(command string) bool {
+	return strings.HasPrefix(strings.TrimSpace(command), "kubectl logs ")
+}
+
+func hasPreviousFlag(command string) bool {
+	for _, f := range strings.Fields(command) {
+		if f == "-p" || f == "--previous" || f == "--previous=true" {
+			return true
 		}
-		if strings.Contains(out, "OOMKilled") {
-			dr.Notes = append(dr.Notes, "Container OOMKilled — consider limits/requests and memory usage.")
+	}
+	return false
+}
+
+// diagStepName is the command's first word, e.g. "kubectl"
+func diagStepName(command string) string {
+	if i := strings.Index(command, " "); i > 0 {
+		return command[:i]
+	}
+	return command
+}
+
+// diagHeuristics returns quick hints for well-known failure states in a step's output
+func diagHeuristics(out string) []string {
+	var notes []string
+	for _, rule := range diagHeuristicRules {
+		if rule.pattern.MatchString(out) {
+			notes = append(notes, rule.note)
 		}
-		results = append(results, dr)
 	}
-	return results, nil
+	return notes
+}
+
+// Runs a DiagPlan with the local kubectl and returns per-step outputs.
+func RunDiagPlan(p DiagPlan) ([]DiagResult, error) {
+	return NewDiagRunner(execx.NewOSRunner(), 0, 0).Run(context.Background(), p, nil), nil
 }
 
 // Convenience wrapper for a single pod.
@@ -98,7 +247,8 @@ func PlanServiceNotReachable(svc, ns string) DiagPlan {
 			fmt.Sprintf("kubectl get pods %s --show-labels", base),
 			fmt.Sprintf("kubectl get events %s --field-selector involvedObject.kind=Service,involvedObject.name=%s", base, svc),
 		},
-		Summary: "Check service endpoints, pod selectors, and networking issues.",
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 10 * time.Second, 5 * time.Second},
+		Summary:  "Check service endpoints, pod selectors, and networking issues.",
 	}
 }
 
@@ -113,7 +263,8 @@ func PlanDeploymentNotReady(deploy, ns string) DiagPlan {
 			fmt.Sprintf("kubectl get pods %s --show-labels", base),
 			fmt.Sprintf("kubectl get events %s --field-selector involvedObject.kind=Deployment,involvedObject.name=%s", base, deploy),
 		},
-		Summary: "Analyze deployment rollout, replica sets, and pod readiness.",
+		Timeouts: []time.Duration{10 * time.Second, 5 * time.Second, 10 * time.Second, 5 * time.Second},
+		Summary:  "Analyze deployment rollout, replica sets, and pod readiness.",
 	}
 }
 
@@ -147,6 +298,7 @@ type ReActStep struct {
 	Observation string
 	Allowed     bool
 	Error       string
+	Injection   []InjectionFinding // instruction-like content found in the observation
 }
 
 // ReActSession manages a ReAct-lite diagnostic session
@@ -156,6 +308,7 @@ type ReActSession struct {
 	CurrentStep int
 	Completed   bool
 	FinalAnswer string
+	Proposals   []FixProposal // fixes proposed with the final answer; never executed by the session
 }
 
 // NewReActSession creates a new ReAct-lite session
@@ -186,6 +339,10 @@ func (rs *ReActSession) ProcessModelResponse(response string) error {
 		if matches := reFinalPattern.FindStringSubmatch(line); len(matches) > 1 {
 			rs.FinalAnswer = strings.TrimSpace(matches[1])
 			rs.Completed = true
+			rs.Proposals = ParseFixProposals(response)
+			for i := range rs.Proposals {
+				rs.Proposals[i].GroundEvidence(rs.observations())
+			}
 			return nil
 		}
 
@@ -291,6 +448,7 @@ func (rs *ReActSession) ExecuteAction(action string) error {
 	} else {
 		step.Observation = output
 	}
+	step.Injection = DetectInjection(step.Observation)
 
 	rs.Steps = append(rs.Steps, step)
 	rs.CurrentStep++
@@ -309,7 +467,7 @@ func (rs *ReActSession) GetLastObservation() string {
 		return fmt.Sprintf("ERROR: %s", lastStep.Error)
 	}
 
-	observation := "Observation:\n" + lastStep.Observation
+	observation := "Observation:\n" + FenceUntrusted(lastStep.Action, lastStep.Observation).Text
 	if lastStep.Error != "" {
 		observation += fmt.Sprintf("\nError: %s", lastStep.Error)
 	}
@@ -317,6 +475,26 @@ func (rs *ReActSession) GetLastObservation() string {
 	return observation
 }
 
+// observations returns the output of every allowed step
+func (rs *ReActSession) observations() []string {
+	var observations []string
+	for _, step := range rs.Steps {
+		if step.Allowed {
+			observations = append(observations, step.Observation)
+		}
+	}
+	return observations
+}
+
+// InjectionFindings returns every suspected prompt injection seen in this session
+func (rs *ReActSession) InjectionFindings() []InjectionFinding {
+	var findings []InjectionFinding
+	for _, step := range rs.Steps {
+		findings = append(findings, step.Injection...)
+	}
+	return findings
+}
+
 // GetSessionSummary returns a summary of the entire ReAct session
 func (rs *ReActSession) GetSessionSummary() string {
 	var summary strings.Builder
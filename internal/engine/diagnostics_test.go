@@ -1,9 +1,15 @@
 package engine
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
 )
 
 func TestPlanPodNotReady(t *testing.T) {
@@ -22,7 +28,7 @@ func TestPlanPodNotReady(t *testing.T) {
 	expectedCommands := []string{
 		"kubectl describe pod test-pod -n default",
 		"kubectl get events -n default --field-selector involvedObject.kind=Pod,involvedObject.name=test-pod --sort-by=.lastTimestamp",
-		"kubectl logs test-pod -n default --tail=200 --all-containers",
+		"kubectl logs test-pod -n default --tail=2000 --all-containers",
 	}
 
 	for i, expected := range expectedCommands {
@@ -297,3 +303,115 @@ func TestDiagResultHeuristics(t *testing.T) {
 		t.Error("plan should have steps")
 	}
 }
+
+func TestDiagRunnerRunsPlanThroughRunner(t *testing.T) {
+	runner := execx.NewMockRunner()
+	runner.Expect("kubectl", strings.Fields("describe pod api-1 -n payments --show-events"), "State: Waiting\n  Reason: CrashLoopBackOff\n", "", nil)
+	runner.Expect("kubectl", strings.Fields("logs api-1 -n payments --previous"), "", "previous terminated container not found", errors.New("exit status 1"))
+	runner.Expect("kubectl", strings.Fields("get events -n payments"), "Warning OOMKilled api-1\n", "", nil)
+
+	plan := DiagPlan{Steps: []string{
+		"kubectl describe pod api-1 -n payments --show-events",
+		"kubectl logs api-1 -n payments --previous",
+		"kubectl get events -n payments",
+		"kubectl delete pod api-1 -n payments",
+	}}
+	var mu sync.Mutex
+	var streamed []int
+	results := NewDiagRunner(runner, 2, time.Second).Run(context.Background(), plan, func(i int, r DiagResult) {
+		mu.Lock()
+		streamed = append(streamed, i)
+		mu.Unlock()
+	})
+
+	if len(results) != 4 || len(streamed) != 4 {
+		t.Fatalf("expected 4 results streamed, got %d results and %v", len(results), streamed)
+	}
+	if results[0].Command != plan.Steps[0] || !strings.Contains(strings.Join(results[0].Notes, " "), "CrashLoopBackOff") {
+		t.Errorf("results should keep plan order with heuristic notes: %+v", results[0])
+	}
+	if !strings.Contains(results[1].Output, "not found") || !strings.HasPrefix(results[1].Notes[0], "error: exit status 1") {
+		t.Errorf("failed step should keep stderr and the error: %+v", results[1])
+	}
+	if !strings.Contains(strings.Join(results[2].Notes, " "), "OOMKilled") {
+		t.Errorf("expected an OOM note: %+v", results[2])
+	}
+	if results[3].Output != "" || results[3].Notes[0] != "error: not a whitelisted read-only command" || runner.CallCount != 3 {
+		t.Errorf("mutating step must not reach the runner: %+v (%d calls)", results[3], runner.CallCount)
+	}
+}
+
+func TestDiagRunnerTimeoutsAndCancel(t *testing.T) {
+	runner := &investigationRunner{delay: 200 * time.Millisecond, outputs: map[string]string{
+		"kubectl get pods -n shop":   "web-1 Running",
+		"kubectl get events -n shop": "nothing",
+	}}
+	plan := DiagPlan{
+		Steps:    []string{"kubectl get pods -n shop", "kubectl get events -n shop"},
+		Timeouts: []time.Duration{20 * time.Millisecond},
+	}
+	start := time.Now()
+	results := NewDiagRunner(runner, 2, time.Second).Run(context.Background(), plan, nil)
+	if !strings.HasPrefix(results[0].Notes[0], "error: timeout after 20ms") {
+		t.Errorf("first step should hit its own timeout: %+v", results[0])
+	}
+	if results[1].Output != "nothing" || len(results[1].Notes) != 0 {
+		t.Errorf("second step should use the default timeout: %+v", results[1])
+	}
+	if elapsed := time.Since(start); elapsed > 350*time.Millisecond {
+		t.Errorf("steps should run concurrently, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	plan.Timeouts = nil
+	results = NewDiagRunner(runner, 1, time.Second).Run(ctx, plan, nil)
+	if results[0].Notes[0] != "error: cancelled" || results[1].Notes[0] != "error: cancelled before it ran" {
+		t.Errorf("cancelling should stop the running step and skip the rest: %+v", results)
+	}
+}
+
+func TestDiagRunnerMinesLogTemplates(t *testing.T) {
+	var current strings.Builder
+	for i := 0; i < 300; i++ {
+		fmt.Fprintf(&current, "INFO GET /healthz took %dms\n", i%40)
+	}
+	current.WriteString("ERROR dial tcp 10.0.3.7:5432: connect: connection refused\n")
+	runner := execx.NewMockRunner()
+	runner.Expect("kubectl", strings.Fields("logs api-1 -n payments --tail=2000"), current.String(), "", nil)
+	runner.Expect("kubectl", strings.Fields("logs api-1 -n payments --tail=2000 --previous"), "INFO GET /healthz took 3ms\n", "", nil)
+
+	results := NewDiagRunner(runner, 1, time.Second).Run(context.Background(), DiagPlan{Steps: []string{"kubectl logs api-1 -n payments --tail=2000"}}, nil)
+	logs := results[0].Logs
+	if logs == nil || logs.Lines != 301 || len(logs.Templates) != 2 {
+		t.Fatalf("logs step should be mined into 2 templates: %+v", logs)
+	}
+	if fresh := logs.NewTemplates(); len(fresh) != 1 || !fresh[0].Error || fresh[0].FirstLine != 301 {
+		t.Errorf("the connection error should be new since the previous container: %+v", fresh)
+	}
+	if results[0].Notes[0] != "logs: 301 lines in 2 templates, 1 with errors (first at line 301), 1 new since the previous container" {
+		t.Errorf("unexpected note: %q", results[0].Notes)
+	}
+
+	// Without a previous container the current logs are still mined
+	runner = execx.NewMockRunner()
+	runner.Expect("kubectl", strings.Fields("logs api-1 -n payments"), "INFO ready\n", "", nil)
+	results = NewDiagRunner(runner, 1, time.Second).Run(context.Background(), DiagPlan{Steps: []string{"kubectl logs api-1 -n payments"}}, nil)
+	if results[0].Logs == nil || results[0].Logs.PreviousLines != 0 || len(results[0].Logs.NewTemplates()) != 0 {
+		t.Errorf("missing previous logs should not mark templates new: %+v", results[0].Logs)
+	}
+}
+
+func TestDiagPlanFromPlaybook(t *testing.T) {
+	pb := NewPlaybookLibrary().playbooks["pod-not-ready"]
+	plan := DiagPlanFromPlaybook(pb, map[string]string{"pod": "api-1", "namespace": "payments"})
+	if plan.Steps[0] != "kubectl describe pod api-1 -n payments" {
+		t.Errorf("placeholders should be filled: %q", plan.Steps[0])
+	}
+	if plan.timeout(2, time.Second) != 15*time.Second || plan.timeout(9, time.Second) != time.Second {
+		t.Errorf("unexpected timeouts: %v", plan.Timeouts)
+	}
+}
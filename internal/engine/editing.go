@@ -1,8 +1,9 @@
 package engine
 
 import (
-	"fmt"
 	"strings"
+
+	"github.com/siryoos/kubemage/internal/prompts"
 )
 
 // DiffMode represents the type of file being edited via diff
@@ -90,18 +91,10 @@ func BuildDiffEditPrompt(mode DiffMode, filePath, currentContent, instruction st
 		fileDescriptor = "Kubernetes YAML manifest"
 	}
 
-	var sb strings.Builder
-	sb.WriteString("You are an expert editor generating a unified diff patch for a single file.\n")
-	sb.WriteString("Return ONLY a valid unified diff (patch) with context lines, no explanations or commentary.\n")
-	sb.WriteString("Use the exact format produced by `git diff` including the header.\n\n")
-	sb.WriteString(fmt.Sprintf("Target file: %s (%s)\n", filePath, fileDescriptor))
-	sb.WriteString("Instruction:\n")
-	sb.WriteString(instruction)
-	sb.WriteString("\n\n")
-	sb.WriteString("Current file content:\n")
-	sb.WriteString("```yaml\n")
-	sb.WriteString(currentContent)
-	sb.WriteString("\n```\n\n")
-	sb.WriteString("Respond with the diff only. Do not restate the file content outside of the unified diff format.\n")
-	return sb.String()
+	return prompts.Text(prompts.DiffEdit, prompts.Vars{
+		"FilePath":       filePath,
+		"FileDescriptor": fileDescriptor,
+		"Instruction":    instruction,
+		"Content":        currentContent,
+	})
 }
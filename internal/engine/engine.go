@@ -28,6 +28,15 @@ type Engine struct {
 	commandGenerator       *IntelligentCommandGenerator
 	performanceMonitor     *RealTimePerformanceMonitor
 	recorder               *FlightRecorder
+	commandGuard           *CommandGuard
+	retriever              *KnowledgeRetriever
+	explainCache           *ExplainCache
+	crdCatalog             *CRDCatalog
+	memory                 *ClusterMemory
+	investigator           *Investigator
+	diagRunner             *DiagRunner
+	timeline               *TimelineBuilder
+	logStreamer            *LogStreamer
 }
 
 // Options configures the engine
@@ -58,6 +67,8 @@ func New(opts Options) (*Engine, error) {
 	// Initialize all components
 	e.facts = NewFactHelper()
 	e.knowledge = NewPlaybookLibrary()
+	// Team playbooks overlay the built-ins; bad files are reported by /playbook list
+	e.knowledge.LoadPlaybookDirs(opts.Config.Intelligence.PlaybookDirs)
 	e.optimizer = NewOptimizationAdvisor()
 	e.router = NewIntentRouter()
 	e.intelligence = NewIntelligenceEngine()
@@ -69,6 +80,10 @@ func New(opts Options) (*Engine, error) {
 	
 	// Initialize components with dependencies
 	e.modelRouter = NewModelRouter(smartCache)
+	// Without an eval report, model selection keeps its built-in rules
+	if report, err := LoadEvalReport(DefaultEvalReportPath); err == nil {
+		e.modelRouter.SeedFromEval(report)
+	}
 	e.performanceOptimizer = NewPerformanceOptimizer()
 	
 	// Create streaming manager for predictive engine
@@ -77,8 +92,39 @@ func New(opts Options) (*Engine, error) {
 	e.predictiveEngine = NewPredictiveIntelligenceEngine(smartCache, streamingManager)
 	
 	e.commandGenerator = NewIntelligentCommandGenerator(e.modelRouter, smartCache)
+	e.commandGenerator.ConfigureSelfConsistency(SelfConsistencyConfig{
+		Samples:       opts.Config.Intelligence.SelfConsistencySamples,
+		TimeBudget:    time.Duration(opts.Config.Intelligence.SelfConsistencyBudget) * time.Second,
+		DryRunTimeout: time.Duration(opts.Config.Performance.CommandTimeout) * time.Second,
+	}, LLMCommandSampler, e.runner)
 	e.performanceMonitor = NewRealTimePerformanceMonitor()
 	e.recorder = NewFlightRecorder("./kubemage_data")
+	e.commandGuard = NewCommandGuard(e.runner, e.recorder)
+	e.explainCache = NewExplainCache(e.runner, "./kubemage_data/explain")
+	e.crdCatalog = NewCRDCatalog(e.runner)
+	e.memory = NewClusterMemory("./kubemage_data/memory.json")
+	e.investigator = NewInvestigator(e.runner, e.knowledge, InvestigationBudget{
+		MaxSteps:        opts.Config.Agent.MaxSteps,
+		MaxDuration:     time.Duration(opts.Config.Agent.TimeBudget) * time.Second,
+		MaxOutputTokens: opts.Config.Agent.OutputTokenBudget,
+		Parallelism:     opts.Config.Agent.Parallelism,
+		StepTimeout:     time.Duration(opts.Config.Performance.CommandTimeout) * time.Second,
+	})
+	e.diagRunner = NewDiagRunner(e.runner, opts.Config.Agent.Parallelism, time.Duration(opts.Config.Performance.CommandTimeout)*time.Second)
+	e.timeline = NewTimelineBuilder(e.runner, time.Duration(opts.Config.Performance.CommandTimeout)*time.Second, 0)
+	// Runners that cannot follow output leave /logs unavailable
+	streamer, _ := e.runner.(execx.Streamer)
+	e.logStreamer = NewLogStreamer(e.runner, streamer, 0)
+	// Unreadable history or index data only costs retrieval quality, never startup
+	_ = e.recorder.Load()
+	_ = e.memory.Load()
+	if !opts.Config.Retrieval.Disabled {
+		index := NewVectorIndex(opts.Config.Retrieval.IndexPath, opts.Config.Retrieval.EmbeddingModel, llm.Embed)
+		index.TopK = opts.Config.Retrieval.TopK
+		index.MinScore = opts.Config.Retrieval.MinScore
+		_ = index.Load()
+		e.retriever = NewKnowledgeRetriever(index, e.knowledge, e.recorder, expandHome(opts.Config.Retrieval.KnowledgeDir))
+	}
 	
 	// Wire up dependencies
 	e.intelligence.facts = e.facts
@@ -165,4 +211,54 @@ func (e *Engine) GetValidator() *validator.ValidationPipeline {
 // GetRecorder returns the flight recorder
 func (e *Engine) GetRecorder() *FlightRecorder {
 	return e.recorder
+}
+
+// GetCommandGuard returns the hallucination guard for generated commands
+func (e *Engine) GetCommandGuard() *CommandGuard {
+	return e.commandGuard
+}
+
+// GetExplainCache returns the cached `kubectl explain` field documentation
+func (e *Engine) GetExplainCache() *ExplainCache {
+	return e.explainCache
+}
+
+// GetCRDCatalog returns the catalog of CRDs installed in the current cluster
+func (e *Engine) GetCRDCatalog() *CRDCatalog {
+	return e.crdCatalog
+}
+
+// GetClusterMemory returns the per-context facts remembered across sessions
+func (e *Engine) GetClusterMemory() *ClusterMemory {
+	return e.memory
+}
+
+// GetCommandGenerator returns the generator behind /cmd, which votes on sampled candidates
+func (e *Engine) GetCommandGenerator() *IntelligentCommandGenerator {
+	return e.commandGenerator
+}
+
+// GetInvestigator returns the plan-then-act investigator used by the agent's plan mode
+func (e *Engine) GetInvestigator() *Investigator {
+	return e.investigator
+}
+
+// GetDiagRunner returns the runner for diagnostic plans such as /diag-pod
+func (e *Engine) GetDiagRunner() *DiagRunner {
+	return e.diagRunner
+}
+
+// GetTimelineBuilder returns the builder for /timeline incident timelines
+func (e *Engine) GetTimelineBuilder() *TimelineBuilder {
+	return e.timeline
+}
+
+// GetLogStreamer returns the streamer that follows pod logs for /logs
+func (e *Engine) GetLogStreamer() *LogStreamer {
+	return e.logStreamer
+}
+
+// GetRetriever returns the knowledge retriever, or nil when retrieval is disabled
+func (e *Engine) GetRetriever() *KnowledgeRetriever {
+	return e.retriever
 }
\ No newline at end of file
@@ -0,0 +1,599 @@
+// eval.go - Golden prompt suites for comparing models on command and manifest generation
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/engine/promptbudget"
+	"github.com/siryoos/kubemage/internal/engine/validator"
+	"github.com/siryoos/kubemage/internal/execx"
+	"gopkg.in/yaml.v3"
+)
+
+// Eval case types
+const (
+	EvalCommand  = "command"
+	EvalManifest = "manifest"
+)
+
+// DefaultEvalReportPath is where `kubemage eval` saves the report that seeds model selection
+const DefaultEvalReportPath = "./kubemage_data/eval/latest.json"
+
+// EvalSuite is a YAML set of natural-language tasks with expected outputs
+type EvalSuite struct {
+	Name  string     `yaml:"name"`
+	Cases []EvalCase `yaml:"cases"`
+}
+
+// EvalCase is one task of a suite
+type EvalCase struct {
+	ID     string     `yaml:"id"`
+	Prompt string     `yaml:"prompt"`
+	Type   string     `yaml:"type"` // command (default) or manifest
+	Expect EvalExpect `yaml:"expect"`
+}
+
+// EvalExpect describes the expected command shape or manifest properties
+type EvalExpect struct {
+	Binary      string         `yaml:"binary"`       // kubectl (default) or helm
+	Verb        string         `yaml:"verb"`         // e.g. get, logs, scale
+	Kind        string         `yaml:"kind"`         // resource for commands (aliases allowed), kind for manifests
+	Flags       []string       `yaml:"flags"`        // required flags, e.g. "-n payments", "--all-namespaces", "-o"
+	AbsentFlags []string       `yaml:"absent_flags"` // flags that must not appear, e.g. "--force"
+	APIVersion  string         `yaml:"api_version"`  // manifests only
+	Fields      map[string]any `yaml:"fields"`       // manifests only: dot path to value, "*" for any value
+}
+
+// EvalGenerator produces a model's answer to one case
+type EvalGenerator func(ctx context.Context, c EvalCase, model string) (EvalAnswer, error)
+
+// EvalAnswer is a model's raw answer to one case
+type EvalAnswer struct {
+	Output string
+	Tokens int // prompt plus generated tokens reported by Ollama, 0 to estimate from the text
+}
+
+// EvalResult is the outcome of one case for one model
+type EvalResult struct {
+	CaseID     string        `json:"case_id"`
+	Model      string        `json:"model"`
+	Prompt     string        `json:"prompt"`
+	Output     string        `json:"output"`
+	Score      float64       `json:"score"`   // fraction of expectations met
+	Correct    bool          `json:"correct"` // every expectation met
+	Valid      bool          `json:"valid"`   // passed the static checks and, for manifests with a cluster, the dry-run
+	Validation string        `json:"validation"`
+	Misses     []string      `json:"misses,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	Tokens     int           `json:"tokens"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// EvalModelSummary aggregates one model's results
+type EvalModelSummary struct {
+	Model      string        `json:"model"`
+	Cases      int           `json:"cases"`
+	Accuracy   float64       `json:"accuracy"`
+	MeanScore  float64       `json:"mean_score"`
+	Validity   float64       `json:"validity"`
+	AvgLatency time.Duration `json:"avg_latency"`
+	AvgTokens  int           `json:"avg_tokens"`
+	Errors     int           `json:"errors"`
+}
+
+// EvalReport is a scored comparison of models on a suite
+type EvalReport struct {
+	Suite     string             `json:"suite"`
+	StartedAt time.Time          `json:"started_at"`
+	Cluster   bool               `json:"cluster"` // manifest dry-runs ran against a cluster
+	Models    []EvalModelSummary `json:"models"`
+	Results   []EvalResult       `json:"results"`
+}
+
+// EvalHarness runs suites against models
+type EvalHarness struct {
+	Generate EvalGenerator
+	Runner   execx.Runner     // client dry-runs of generated manifests; nil checks their structure only
+	Timeout  time.Duration    // per case, generation and validation
+	Progress func(EvalResult) // called after each case, if set
+}
+
+// NewEvalHarness creates a harness; pass a nil runner when no cluster is reachable.
+// Generated commands are never run, whatever the runner.
+func NewEvalHarness(generate EvalGenerator, runner execx.Runner) *EvalHarness {
+	return &EvalHarness{Generate: generate, Runner: runner, Timeout: 2 * time.Minute}
+}
+
+// LoadEvalSuite reads and checks a YAML suite
+func LoadEvalSuite(path string) (*EvalSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eval suite: %w", err)
+	}
+	var suite EvalSuite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse eval suite %s: %w", path, err)
+	}
+	if suite.Name == "" {
+		suite.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(suite.Cases) == 0 {
+		return nil, fmt.Errorf("eval suite %s has no cases", path)
+	}
+	seen := make(map[string]bool, len(suite.Cases))
+	for i := range suite.Cases {
+		c := &suite.Cases[i]
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("case-%d", i+1)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("eval suite %s: duplicate case id %q", path, c.ID)
+		}
+		seen[c.ID] = true
+		if strings.TrimSpace(c.Prompt) == "" {
+			return nil, fmt.Errorf("eval suite %s: case %s has no prompt", path, c.ID)
+		}
+		switch c.Type {
+		case "":
+			c.Type = EvalCommand
+		case EvalCommand, EvalManifest:
+		default:
+			return nil, fmt.Errorf("eval suite %s: case %s has unknown type %q", path, c.ID, c.Type)
+		}
+	}
+	return &suite, nil
+}
+
+// Run evaluates every case against every model, one call at a time so latencies stay comparable
+func (h *EvalHarness) Run(ctx context.Context, suite *EvalSuite, models []string) *EvalReport {
+	report := &EvalReport{Suite: suite.Name, StartedAt: time.Now(), Cluster: h.Runner != nil}
+	for _, model := range models {
+		for _, c := range suite.Cases {
+			if ctx.Err() != nil {
+				break
+			}
+			result := h.runCase(ctx, c, model)
+			report.Results = append(report.Results, result)
+			if h.Progress != nil {
+				h.Progress(result)
+			}
+		}
+	}
+	report.summarize(models)
+	return report
+}
+
+func (h *EvalHarness) runCase(ctx context.Context, c EvalCase, model string) EvalResult {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	result := EvalResult{CaseID: c.ID, Model: model, Prompt: c.Prompt}
+
+	start := time.Now()
+	answer, err := h.Generate(ctx, c, model)
+	result.Latency = time.Since(start)
+	result.Output = strings.TrimSpace(answer.Output)
+	result.Tokens = answer.Tokens
+	if result.Tokens == 0 {
+		result.Tokens = promptbudget.EstimateTokens(c.Prompt) + promptbudget.EstimateTokens(answer.Output)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		result.Misses = []string{"no output"}
+		return result
+	}
+
+	if c.Type == EvalManifest {
+		result.Score, result.Misses = scoreManifest(result.Output, c.Expect)
+		result.Valid, result.Validation = h.validateManifest(ctx, result.Output)
+	} else {
+		result.Output = cleanCandidate(result.Output)
+		result.Score, result.Misses = scoreCommand(result.Output, c.Expect)
+		result.Valid, result.Validation = validateCommandPlan(result.Output)
+	}
+	result.Correct = len(result.Misses) == 0
+	return result
+}
+
+// validateCommandPlan judges a generated command without running it: it must be a kubectl
+// or helm command with a verb, must not stream, and gets its pre-exec plan's danger level
+func validateCommandPlan(command string) (bool, string) {
+	positional, flags := normalizeCommandParts(command)
+	if len(positional) < 2 || (positional[0] != "kubectl" && positional[0] != "helm") {
+		return false, "not a kubectl or helm command"
+	}
+	for _, flag := range flags {
+		if name, _, _ := strings.Cut(flag, " "); streamingFlags[name] {
+			return false, name + " streams until interrupted"
+		}
+	}
+	plan := validator.BuildPreExecPlan(command)
+	return true, fmt.Sprintf("plan only, %s danger", plan.DangerLevel)
+}
+
+// scoreCommand checks binary, verb, resource kind and flags of a generated command
+func scoreCommand(command string, expect EvalExpect) (float64, []string) {
+	positional, flags := normalizeCommandParts(command)
+	at := func(i int) string {
+		if i < len(positional) {
+			return positional[i]
+		}
+		return ""
+	}
+
+	var checks int
+	var misses []string
+	check := func(ok bool, miss string) {
+		checks++
+		if !ok {
+			misses = append(misses, miss)
+		}
+	}
+
+	binary := expect.Binary
+	if binary == "" {
+		binary = "kubectl"
+	}
+	check(at(0) == binary, fmt.Sprintf("binary %q, want %q", at(0), binary))
+	if expect.Verb != "" {
+		check(at(1) == expect.Verb, fmt.Sprintf("verb %q, want %q", at(1), expect.Verb))
+	}
+	if expect.Kind != "" {
+		want := canonicalResource(expect.Kind)
+		got := at(2)
+		found := false
+		for _, part := range strings.Split(got, ",") {
+			if canonicalResource(strings.SplitN(part, "/", 2)[0]) == want {
+				found = true
+			}
+		}
+		check(found, fmt.Sprintf("resource %q, want %q", got, want))
+	}
+	for _, flag := range expect.Flags {
+		check(hasEvalFlag(flags, flag), fmt.Sprintf("missing flag %q", flag))
+	}
+	for _, flag := range expect.AbsentFlags {
+		check(!hasEvalFlag(flags, flag), fmt.Sprintf("unexpected flag %q", flag))
+	}
+	return float64(checks-len(misses)) / float64(checks), misses
+}
+
+// hasEvalFlag matches an expected flag, normalized like the command; a bare flag matches any value
+func hasEvalFlag(flags []string, expected string) bool {
+	_, want := normalizeCommandParts(expected)
+	if len(want) == 0 {
+		return false
+	}
+	for _, w := range want {
+		found := false
+		for _, f := range flags {
+			if f == w || (!strings.Contains(w, " ") && strings.HasPrefix(f, w+" ")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func canonicalResource(name string) string {
+	name = strings.ToLower(name)
+	if alias, ok := resourceAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// scoreManifest checks kind, apiVersion and field values of the first document
+func scoreManifest(manifest string, expect EvalExpect) (float64, []string) {
+	var obj map[string]any
+	if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil || obj == nil {
+		return 0, []string{"manifest is not a YAML object"}
+	}
+
+	var checks int
+	var misses []string
+	check := func(ok bool, miss string) {
+		checks++
+		if !ok {
+			misses = append(misses, miss)
+		}
+	}
+
+	if expect.Kind != "" {
+		check(fmt.Sprint(obj["kind"]) == expect.Kind, fmt.Sprintf("kind %v, want %s", obj["kind"], expect.Kind))
+	}
+	if expect.APIVersion != "" {
+		check(fmt.Sprint(obj["apiVersion"]) == expect.APIVersion, fmt.Sprintf("apiVersion %v, want %s", obj["apiVersion"], expect.APIVersion))
+	}
+	paths := make([]string, 0, len(expect.Fields))
+	for path := range expect.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		want := expect.Fields[path]
+		got, ok := lookupManifestPath(obj, path)
+		switch {
+		case !ok:
+			check(false, fmt.Sprintf("missing %s", path))
+		case fmt.Sprint(want) == "*":
+			check(true, "")
+		default:
+			check(fmt.Sprint(got) == fmt.Sprint(want), fmt.Sprintf("%s is %v, want %v", path, got, want))
+		}
+	}
+	if checks == 0 {
+		return 1, nil
+	}
+	return float64(checks-len(misses)) / float64(checks), misses
+}
+
+var manifestIndexPattern = regexp.MustCompile(`^(.*)\[(\d+)\]$`)
+
+// lookupManifestPath resolves paths such as spec.template.spec.containers[0].image
+func lookupManifestPath(obj map[string]any, path string) (any, bool) {
+	var current any = obj
+	for _, segment := range strings.Split(path, ".") {
+		index := -1
+		if m := manifestIndexPattern.FindStringSubmatch(segment); m != nil {
+			segment = m[1]
+			index, _ = strconv.Atoi(m[2])
+		}
+		fields, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = fields[segment]; !ok {
+			return nil, false
+		}
+		if index >= 0 {
+			items, ok := current.([]any)
+			if !ok || index >= len(items) {
+				return nil, false
+			}
+			current = items[index]
+		}
+	}
+	return current, true
+}
+
+// validateManifest requires apiVersion, kind and a name, then runs a client dry-run of the
+// manifest file when a cluster is present
+func (h *EvalHarness) validateManifest(ctx context.Context, manifest string) (bool, string) {
+	var obj struct {
+		APIVersion string `yaml:"apiVersion"`
+		Kind       string `yaml:"kind"`
+		Metadata   struct {
+			Name string `yaml:"name"`
+		} `yaml:"metadata"`
+	}
+	if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+		return false, "invalid YAML"
+	}
+	if obj.APIVersion == "" || obj.Kind == "" || obj.Metadata.Name == "" {
+		return false, "apiVersion, kind and metadata.name are required"
+	}
+	if h.Runner == nil {
+		return true, "validated by structure only"
+	}
+
+	file, err := os.CreateTemp("", "kubemage-eval-*.yaml")
+	if err != nil {
+		return false, err.Error()
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(manifest)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err.Error()
+	}
+
+	_, stderr, err := h.Runner.RunCommand(ctx, "kubectl apply --dry-run=client -f "+file.Name())
+	if err != nil {
+		return false, kubectlError(stderr, err)
+	}
+	return true, "dry-run passed"
+}
+
+func (r *EvalReport) summarize(models []string) {
+	r.Models = r.Models[:0]
+	for _, model := range models {
+		summary := EvalModelSummary{Model: model}
+		var correct, valid, tokens int
+		var score float64
+		var latency time.Duration
+		for _, res := range r.Results {
+			if res.Model != model {
+				continue
+			}
+			summary.Cases++
+			score += res.Score
+			latency += res.Latency
+			tokens += res.Tokens
+			if res.Correct {
+				correct++
+			}
+			if res.Valid {
+				valid++
+			}
+			if res.Error != "" {
+				summary.Errors++
+			}
+		}
+		if summary.Cases > 0 {
+			n := float64(summary.Cases)
+			summary.Accuracy = float64(correct) / n
+			summary.MeanScore = score / n
+			summary.Validity = float64(valid) / n
+			summary.AvgLatency = latency / time.Duration(summary.Cases)
+			summary.AvgTokens = tokens / summary.Cases
+		}
+		r.Models = append(r.Models, summary)
+	}
+}
+
+// Best returns the model with the highest accuracy, then validity, then lowest latency
+func (r *EvalReport) Best() string {
+	best := -1
+	for i, m := range r.Models {
+		if m.Cases == 0 {
+			continue
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		b := r.Models[best]
+		switch {
+		case m.Accuracy != b.Accuracy:
+			if m.Accuracy > b.Accuracy {
+				best = i
+			}
+		case m.Validity != b.Validity:
+			if m.Validity > b.Validity {
+				best = i
+			}
+		case m.AvgLatency < b.AvgLatency:
+			best = i
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return r.Models[best].Model
+}
+
+// Render formats the report as a table followed by the failed cases
+func (r *EvalReport) Render() string {
+	var sb strings.Builder
+	validation := "plan only"
+	if r.Cluster {
+		validation = "plan + manifest dry-run"
+	}
+	fmt.Fprintf(&sb, "Suite %s (%s, validation: %s)\n\n", r.Suite, r.StartedAt.Format("2006-01-02 15:04"), validation)
+
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tACCURACY\tSCORE\tVALID\tAVG LATENCY\tAVG TOKENS\tERRORS")
+	for _, m := range r.Models {
+		fmt.Fprintf(tw, "%s\t%.0f%%\t%.2f\t%.0f%%\t%s\t%d\t%d\n",
+			m.Model, m.Accuracy*100, m.MeanScore, m.Validity*100, m.AvgLatency.Round(time.Millisecond), m.AvgTokens, m.Errors)
+	}
+	tw.Flush()
+
+	var failures []string
+	for _, res := range r.Results {
+		if res.Correct && res.Valid {
+			continue
+		}
+		reasons := append([]string{}, res.Misses...)
+		if res.Error != "" {
+			reasons = append(reasons, res.Error)
+		} else if !res.Valid {
+			reasons = append(reasons, "invalid: "+res.Validation)
+		}
+		failures = append(failures, fmt.Sprintf("  ✗ %s %s: %s", res.Model, res.CaseID, strings.Join(reasons, "; ")))
+	}
+	if len(failures) > 0 {
+		sb.WriteString("\nFailures:\n" + strings.Join(failures, "\n") + "\n")
+	}
+	if best := r.Best(); best != "" {
+		fmt.Fprintf(&sb, "\nBest: %s\n", best)
+	}
+	return sb.String()
+}
+
+// Save writes the report as JSON
+func (r *EvalReport) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create eval report directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode eval report: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadEvalReport reads a report written by Save
+func LoadEvalReport(path string) (*EvalReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report EvalReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse eval report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// SeedFromEval records eval results as responses so similar queries prefer the models that scored best
+func (mpt *ModelPerformanceTracker) SeedFromEval(report *EvalReport) {
+	for _, res := range report.Results {
+		mpt.RecordResponse(ModelResponse{
+			ModelName:    res.Model,
+			Query:        res.Prompt,
+			ResponseTime: res.Latency,
+			Success:      res.Correct && res.Valid,
+			Quality:      res.Score,
+			TokensUsed:   res.Tokens,
+			Timestamp:    report.StartedAt,
+		})
+	}
+}
+
+// SeedFromEval feeds an eval report into the router's performance history
+func (mr *ModelRouter) SeedFromEval(report *EvalReport) {
+	mr.performanceTracker.SeedFromEval(report)
+}
+
+// LLMEvalGenerator answers command cases with the structured call /cmd samples through and
+// manifest cases with the one /gen-deploy makes, counting tokens as Ollama reports them
+func LLMEvalGenerator(ctx context.Context, c EvalCase, model string) (EvalAnswer, error) {
+	type result struct {
+		answer EvalAnswer
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		if c.Type == EvalManifest {
+			manifest, err := GenerateManifestStructured(c.Prompt, model)
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+			done <- result{answer: EvalAnswer{Output: manifest.Manifest, Tokens: manifest.Usage.Total()}}
+			return
+		}
+		cmd, err := GenerateCommandStructured(c.Prompt, model)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		done <- result{answer: EvalAnswer{Output: cmd.Command, Tokens: cmd.Usage.Total()}}
+	}()
+	select {
+	case r := <-done:
+		return r.answer, r.err
+	case <-ctx.Done():
+		return EvalAnswer{}, ctx.Err()
+	}
+}
@@ -0,0 +1,231 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testEvalSuite = `name: golden
+cases:
+  - id: failing-pods
+    prompt: list pods in the payments namespace with node names
+    expect:
+      verb: get
+      kind: pods
+      flags: ["-n payments", "-o"]
+      absent_flags: ["-A"]
+  - prompt: scale the web deployment to 3 replicas
+    expect:
+      verb: scale
+      kind: deploy
+      flags: ["--replicas=3"]
+  - id: nginx-deployment
+    type: manifest
+    prompt: a deployment named web running nginx:1.25 with 2 replicas
+    expect:
+      kind: Deployment
+      api_version: apps/v1
+      fields:
+        spec.replicas: 2
+        spec.template.spec.containers[0].image: nginx:1.25
+        spec.selector: "*"
+`
+
+const evalWebDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels: {app: web}
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.25
+`
+
+func writeEvalSuite(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "suite.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fakeEvalGenerator answers like a good model, a sloppy one, or a broken one
+func fakeEvalGenerator(ctx context.Context, c EvalCase, model string) (EvalAnswer, error) {
+	switch model {
+	case "good":
+		switch c.ID {
+		case "failing-pods":
+			return EvalAnswer{Output: "$ kubectl get po --namespace=payments -o wide", Tokens: 420}, nil
+		case "case-2":
+			return EvalAnswer{Output: "kubectl scale deployment/web --replicas 3", Tokens: 420}, nil
+		default:
+			return EvalAnswer{Output: evalWebDeployment, Tokens: 420}, nil
+		}
+	case "sloppy":
+		switch c.ID {
+		case "failing-pods":
+			return EvalAnswer{Output: "kubectl get pods -A", Tokens: 420}, nil
+		case "case-2":
+			return EvalAnswer{Output: "kubectl scale deployment web --replicas=3", Tokens: 420}, nil
+		default:
+			return EvalAnswer{Output: strings.Replace(evalWebDeployment, "replicas: 2", "replicas: 3", 1), Tokens: 420}, nil
+		}
+	}
+	return EvalAnswer{}, errors.New("model not found")
+}
+
+func TestLoadEvalSuite(t *testing.T) {
+	suite, err := LoadEvalSuite(writeEvalSuite(t, testEvalSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suite.Name != "golden" || len(suite.Cases) != 3 {
+		t.Fatalf("unexpected suite: %+v", suite)
+	}
+	if suite.Cases[1].ID != "case-2" || suite.Cases[1].Type != EvalCommand {
+		t.Errorf("defaults not applied: %+v", suite.Cases[1])
+	}
+
+	for _, bad := range []string{
+		"cases: []\n",
+		"cases:\n  - id: a\n    prompt: x\n    type: chart\n",
+		"cases:\n  - id: a\n    prompt: x\n  - id: a\n    prompt: y\n",
+		"cases:\n  - id: a\n",
+	} {
+		if _, err := LoadEvalSuite(writeEvalSuite(t, bad)); err == nil {
+			t.Errorf("expected an error for suite:\n%s", bad)
+		}
+	}
+}
+
+func TestScoreCommand(t *testing.T) {
+	expect := EvalExpect{Verb: "get", Kind: "pods", Flags: []string{"-n payments", "-o"}, AbsentFlags: []string{"--force"}}
+
+	if score, misses := scoreCommand("kubectl get po --namespace=payments -o wide", expect); score != 1 || len(misses) != 0 {
+		t.Errorf("expected a perfect score, got %.2f %v", score, misses)
+	}
+
+	score, misses := scoreCommand("kubectl delete pods -n default --force", expect)
+	if score < 0.33 || score > 0.34 {
+		t.Errorf("expected 2 of 6 checks, got %.2f %v", score, misses)
+	}
+	joined := strings.Join(misses, "; ")
+	for _, want := range []string{`verb "delete"`, `missing flag "-n payments"`, `unexpected flag "--force"`} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("misses %q lack %q", joined, want)
+		}
+	}
+
+	if _, misses := scoreCommand("helm upgrade --install web ./chart", EvalExpect{Binary: "helm", Verb: "upgrade", Flags: []string{"--install"}}); len(misses) != 0 {
+		t.Errorf("unexpected misses for helm: %v", misses)
+	}
+}
+
+func TestScoreManifest(t *testing.T) {
+	expect := EvalExpect{Kind: "Deployment", Fields: map[string]any{
+		"spec.replicas":                          2,
+		"spec.template.spec.containers[0].image": "nginx:1.25",
+		"spec.template.spec.containers[1].image": "*",
+	}}
+	score, misses := scoreManifest(evalWebDeployment, expect)
+	if len(misses) != 1 || misses[0] != "missing spec.template.spec.containers[1].image" || score != 0.75 {
+		t.Errorf("unexpected score %.2f %v", score, misses)
+	}
+	if _, misses := scoreManifest("- not\n- an object\n", expect); len(misses) != 1 {
+		t.Errorf("expected a single parse miss, got %v", misses)
+	}
+}
+
+func TestEvalHarnessComparesModels(t *testing.T) {
+	suite, err := LoadEvalSuite(writeEvalSuite(t, testEvalSuite))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var progress int
+	harness := NewEvalHarness(fakeEvalGenerator, nil)
+	harness.Progress = func(EvalResult) { progress++ }
+
+	report := harness.Run(context.Background(), suite, []string{"good", "sloppy", "missing"})
+	if progress != 9 || len(report.Results) != 9 {
+		t.Fatalf("expected 9 results, got %d (progress %d)", len(report.Results), progress)
+	}
+
+	byModel := make(map[string]EvalModelSummary)
+	for _, m := range report.Models {
+		byModel[m.Model] = m
+	}
+	if good := byModel["good"]; good.Accuracy != 1 || good.Validity != 1 || good.AvgTokens != 420 {
+		t.Errorf("unexpected summary for good: %+v", good)
+	}
+	if sloppy := byModel["sloppy"]; sloppy.Accuracy < 0.3 || sloppy.Accuracy > 0.4 || sloppy.MeanScore >= 1 {
+		t.Errorf("unexpected summary for sloppy: %+v", sloppy)
+	}
+	if missing := byModel["missing"]; missing.Errors != 3 || missing.Accuracy != 0 {
+		t.Errorf("unexpected summary for missing: %+v", missing)
+	}
+	if best := report.Best(); best != "good" {
+		t.Errorf("Best() = %q", best)
+	}
+
+	rendered := report.Render()
+	for _, want := range []string{"MODEL", "validation: plan only", "✗ sloppy failing-pods", "Best: good"} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("report lacks %q:\n%s", want, rendered)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "eval", "latest.json")
+	if err := report.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadEvalReport(path)
+	if err != nil || len(loaded.Results) != 9 {
+		t.Fatalf("LoadEvalReport() = %+v, %v", loaded, err)
+	}
+
+	tracker := &ModelPerformanceTracker{modelMetrics: make(map[string]*ModelMetrics)}
+	tracker.SeedFromEval(loaded)
+	if best := tracker.GetBestModelForQuery("list pods in the payments namespace"); best != "good" {
+		t.Errorf("seeded tracker picked %q", best)
+	}
+}
+
+func TestEvalHarnessNeverRunsGeneratedCommands(t *testing.T) {
+	runner := &explainRunner{outputs: map[string]string{}}
+	harness := NewEvalHarness(func(ctx context.Context, c EvalCase, model string) (EvalAnswer, error) {
+		if c.Type == EvalManifest {
+			return EvalAnswer{Output: evalWebDeployment}, nil
+		}
+		return EvalAnswer{Output: "kubectl " + model}, nil
+	}, runner)
+	suite := &EvalSuite{Name: "dry-run", Cases: []EvalCase{
+		{ID: "cmd", Prompt: "wipe", Type: EvalCommand},
+		{ID: "web", Prompt: "web", Type: EvalManifest},
+	}}
+
+	report := harness.Run(context.Background(), suite, []string{"delete ns payments", "logs -f web"})
+	for _, call := range runner.calls {
+		if !strings.HasPrefix(call, "apply --dry-run=client -f ") {
+			t.Errorf("only manifest dry-runs may run, ran %q", call)
+		}
+	}
+	if len(runner.calls) != 2 {
+		t.Errorf("expected one manifest dry-run per model, ran %q", runner.calls)
+	}
+	if !report.Cluster || !report.Results[0].Valid || report.Results[2].Valid {
+		t.Errorf("the delete should pass its plan and the streaming logs fail: %+v", report.Results)
+	}
+	if report.Results[1].Valid {
+		t.Errorf("the failed manifest dry-run should make the manifest invalid: %+v", report.Results[1])
+	}
+}
@@ -0,0 +1,532 @@
+// explain_cache.go - On-disk cache of `kubectl explain` and api-resources per context and server version
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+// ExplainField is one entry of a `kubectl explain --recursive` field tree
+type ExplainField struct {
+	Path     string `json:"path"` // relative to the resource, e.g. "spec.template.spec.containers.image"
+	Type     string `json:"type"` // e.g. "string", "[]Container", "Object"
+	Required bool   `json:"required,omitempty"`
+}
+
+// ExplainResource is the cached field tree of one resource
+type ExplainResource struct {
+	Resource    string         `json:"resource"` // canonical name, e.g. "deployments"
+	Kind        string         `json:"kind"`
+	Version     string         `json:"version"` // group/version, e.g. "apps/v1"
+	Description string         `json:"description"`
+	Fields      []ExplainField `json:"fields"`
+	FetchedAt   time.Time      `json:"fetched_at"`
+}
+
+// ExplainSnapshot is everything cached for one context at one server version
+type ExplainSnapshot struct {
+	Context       string                      `json:"context"`
+	ServerVersion string                      `json:"server_version"`
+	APIResources  string                      `json:"api_resources"` // raw `kubectl api-resources --no-headers`
+	Resources     map[string]*ExplainResource `json:"resources"`     // by canonical resource name
+	Docs          map[string]string           `json:"docs"`          // `kubectl explain <path>` output by path
+	UpdatedAt     time.Time                   `json:"updated_at"`
+}
+
+// ExplainCache serves API field documentation, fetching from the cluster only on a miss
+type ExplainCache struct {
+	runner     execx.Runner
+	dir        string
+	Timeout    time.Duration
+	RetryAfter time.Duration // wait after a failed open before asking kubectl again
+	ReopenAge  time.Duration // re-check context and server version after this long
+
+	mu       sync.Mutex
+	snapshot *ExplainSnapshot
+	aliases  map[string]string
+	online   bool
+	openedAt time.Time
+	openErr  error
+}
+
+// NewExplainCache creates a cache whose snapshots live under dir
+func NewExplainCache(runner execx.Runner, dir string) *ExplainCache {
+	return &ExplainCache{
+		runner:     runner,
+		dir:        dir,
+		Timeout:    15 * time.Second,
+		RetryAfter: time.Minute,
+		ReopenAge:  10 * time.Minute,
+	}
+}
+
+// Open loads the snapshot for the current context and server version. When the server
+// is unreachable the most recent snapshot for the context is used as-is.
+func (c *ExplainCache) Open(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.open(ctx)
+}
+
+func (c *ExplainCache) open(ctx context.Context) error {
+	c.openedAt = time.Now()
+	c.openErr = c.load(ctx)
+	return c.openErr
+}
+
+func (c *ExplainCache) load(ctx context.Context) error {
+	kubeContext, _, err := c.run(ctx, "config", "current-context")
+	if err != nil {
+		return fmt.Errorf("no current kubectl context: %w", err)
+	}
+	kubeContext = strings.TrimSpace(kubeContext)
+
+	version := ""
+	if out, _, err := c.run(ctx, "version", "-o", "json"); err == nil {
+		version = parseServerVersion(out)
+	}
+	c.online = version != ""
+
+	var path string
+	if c.online {
+		path = c.snapshotPath(kubeContext, version)
+	} else if path = c.latestSnapshot(kubeContext); path == "" {
+		return fmt.Errorf("cluster for context %s is unreachable and nothing is cached yet", kubeContext)
+	}
+
+	snapshot := &ExplainSnapshot{Context: kubeContext, ServerVersion: version}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, snapshot); err != nil {
+			return fmt.Errorf("failed to unmarshal explain cache %s: %w", path, err)
+		}
+	}
+	if snapshot.Resources == nil {
+		snapshot.Resources = make(map[string]*ExplainResource)
+	}
+	if snapshot.Docs == nil {
+		snapshot.Docs = make(map[string]string)
+	}
+
+	if snapshot.APIResources == "" && c.online {
+		out, stderr, err := c.run(ctx, "api-resources", "--no-headers")
+		if err != nil {
+			return fmt.Errorf("kubectl api-resources failed: %s", kubectlError(stderr, err))
+		}
+		snapshot.APIResources = out
+	}
+	c.snapshot = snapshot
+	c.aliases = parseAPIResources(snapshot.APIResources)
+	if c.online {
+		return c.save()
+	}
+	return nil
+}
+
+// Snapshot reports the context and server version being served
+func (c *ExplainCache) Snapshot(ctx context.Context) (kubeContext, serverVersion string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.ensure(ctx); err != nil {
+		return "", "", err
+	}
+	return c.snapshot.Context, c.snapshot.ServerVersion, nil
+}
+
+// Resource returns the field tree of a resource given any of its names, kinds or short names
+func (c *ExplainCache) Resource(ctx context.Context, name string) (*ExplainResource, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+	return c.resource(ctx, name)
+}
+
+func (c *ExplainCache) resource(ctx context.Context, name string) (*ExplainResource, error) {
+	canonical, ok := c.aliases[strings.ToLower(name)]
+	if !ok {
+		if suggestion := NearestMatch(strings.ToLower(name), mapKeys(c.aliases)); suggestion != "" {
+			return nil, fmt.Errorf("unknown resource %q (did you mean %q?)", name, suggestion)
+		}
+		return nil, fmt.Errorf("unknown resource %q", name)
+	}
+	if res, ok := c.snapshot.Resources[canonical]; ok {
+		return res, nil
+	}
+	if !c.online {
+		return nil, fmt.Errorf("%s is not cached for server %s and the cluster is unreachable", canonical, c.versionLabel())
+	}
+
+	out, stderr, err := c.run(ctx, "explain", canonical, "--recursive")
+	if err != nil {
+		return nil, fmt.Errorf("kubectl explain %s failed: %s", canonical, kubectlError(stderr, err))
+	}
+	res := parseExplainRecursive(out)
+	res.Resource = canonical
+	res.FetchedAt = time.Now()
+	c.snapshot.Resources[canonical] = res
+	return res, c.save()
+}
+
+// Explain documents a dotted path such as "deployment.spec.strategy". The live description
+// is cached on first use; offline, the cached --recursive tree below the path is shown instead.
+func (c *ExplainCache) Explain(ctx context.Context, path string) (string, error) {
+	path = strings.Trim(strings.TrimSpace(path), ".")
+	if path == "" {
+		return "", fmt.Errorf("usage: /explain <kind.field.path>")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.ensure(ctx); err != nil {
+		return "", err
+	}
+
+	parts := strings.SplitN(path, ".", 2)
+	res, err := c.resource(ctx, parts[0])
+	if err != nil {
+		return "", err
+	}
+	key := res.Resource
+	if len(parts) == 2 {
+		key += "." + parts[1]
+	}
+
+	if doc, ok := c.snapshot.Docs[key]; ok {
+		return doc, nil
+	}
+	if c.online {
+		if out, _, err := c.run(ctx, "explain", key); err == nil {
+			c.snapshot.Docs[key] = strings.TrimRight(out, "\n")
+			return c.snapshot.Docs[key], c.save()
+		}
+	}
+
+	prefix := ""
+	if len(parts) == 2 {
+		prefix = parts[1]
+	}
+	return renderFieldTree(res, prefix, c.versionLabel())
+}
+
+// FieldDocs returns field documentation for the resources mentioned in text, as retrieval
+// hits so they can be cited next to other knowledge
+func (c *ExplainCache) FieldDocs(ctx context.Context, text string, limit int) []RetrievalHit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ensure(ctx) != nil {
+		return nil
+	}
+
+	words := explainWords(text)
+	var hits []RetrievalHit
+	seen := make(map[string]bool)
+	for _, word := range words {
+		canonical, ok := c.aliases[word]
+		// Short names such as "no" or "ns" collide with plain words
+		if !ok || len(word) < 3 || seen[canonical] {
+			continue
+		}
+		seen[canonical] = true
+		res, err := c.resource(ctx, canonical)
+		if err != nil {
+			continue
+		}
+		hits = append(hits, c.fieldHit(res, words, limit))
+		if len(hits) == 2 {
+			break
+		}
+	}
+	return hits
+}
+
+// ResourceDocs returns the top-level spec fields of a resource for generation prompts
+func (c *ExplainCache) ResourceDocs(ctx context.Context, name string, limit int) (RetrievalHit, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.ensure(ctx); err != nil {
+		return RetrievalHit{}, err
+	}
+	res, err := c.resource(ctx, name)
+	if err != nil {
+		return RetrievalHit{}, err
+	}
+	return c.fieldHit(res, nil, limit), nil
+}
+
+func (c *ExplainCache) fieldHit(res *ExplainResource, words []string, limit int) RetrievalHit {
+	fields := selectFields(res.Fields, words, limit)
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		entry := fmt.Sprintf("%s <%s>", f.Path, f.Type)
+		if f.Required {
+			entry += " (required)"
+		}
+		parts = append(parts, entry)
+	}
+	text := fmt.Sprintf("%s %s fields: %s", res.Kind, res.Version, strings.Join(parts, "; "))
+	if res.Description != "" {
+		text = fmt.Sprintf("%s - %s\n%s", res.Kind, res.Description, text)
+	}
+	citation := fmt.Sprintf("kubectl explain %s @ %s", res.Resource, c.versionLabel())
+	return RetrievalHit{Doc: RetrievalDoc{ID: "explain:" + res.Resource, Kind: "explain", Citation: citation, Text: text}, Score: 1}
+}
+
+func (c *ExplainCache) ensure(ctx context.Context) error {
+	// A failed re-open keeps serving the previous snapshot
+	age := time.Since(c.openedAt)
+	if c.openErr != nil && age < c.RetryAfter {
+		if c.snapshot != nil {
+			return nil
+		}
+		return c.openErr
+	}
+	if c.snapshot != nil && age < c.ReopenAge {
+		return nil
+	}
+	if err := c.open(ctx); err != nil && c.snapshot == nil {
+		return err
+	}
+	return nil
+}
+
+func (c *ExplainCache) versionLabel() string {
+	if c.snapshot == nil || c.snapshot.ServerVersion == "" {
+		return "cached"
+	}
+	return c.snapshot.ServerVersion
+}
+
+func (c *ExplainCache) run(ctx context.Context, args ...string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	return c.runner.Run(ctx, "kubectl", args...)
+}
+
+func (c *ExplainCache) snapshotPath(kubeContext, version string) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%s@%s.json", sanitizeFileName(kubeContext), sanitizeFileName(version)))
+}
+
+// latestSnapshot finds the most recently written snapshot for a context
+func (c *ExplainCache) latestSnapshot(kubeContext string) string {
+	matches, _ := filepath.Glob(filepath.Join(c.dir, sanitizeFileName(kubeContext)+"@*.json"))
+	latest := ""
+	var latestTime time.Time
+	for _, path := range matches {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(latestTime) {
+			latest, latestTime = path, info.ModTime()
+		}
+	}
+	return latest
+}
+
+func (c *ExplainCache) save() error {
+	c.snapshot.UpdatedAt = time.Now()
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create explain cache directory: %w", err)
+	}
+	data, err := json.Marshal(c.snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal explain cache: %w", err)
+	}
+	path := c.snapshotPath(c.snapshot.Context, c.snapshot.ServerVersion)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write explain cache: %w", err)
+	}
+	return nil
+}
+
+func parseServerVersion(out string) string {
+	var v struct {
+		ServerVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		return ""
+	}
+	return v.ServerVersion.GitVersion
+}
+
+var (
+	reExplainField  = regexp.MustCompile(`^(\s*)([A-Za-z0-9_$.\-]+)\s+<([^>]*)>\s*(-required-)?\s*$`)
+	reExplainHeader = regexp.MustCompile(`^([A-Z]+):\s*(.*)$`)
+)
+
+// parseExplainRecursive reads both the pre-1.27 and current `kubectl explain --recursive` layouts
+func parseExplainRecursive(out string) *ExplainResource {
+	res := &ExplainResource{}
+	group := ""
+	section := ""
+	var description []string
+
+	type level struct {
+		indent int
+		name   string
+	}
+	var stack []level
+
+	for _, line := range strings.Split(out, "\n") {
+		if m := reExplainHeader.FindStringSubmatch(line); m != nil {
+			section = m[1]
+			switch section {
+			case "KIND":
+				res.Kind = m[2]
+			case "GROUP":
+				group = m[2]
+			case "VERSION":
+				res.Version = m[2]
+			}
+			continue
+		}
+
+		switch section {
+		case "DESCRIPTION":
+			if text := strings.TrimSpace(line); text != "" {
+				description = append(description, text)
+			}
+		case "FIELDS":
+			m := reExplainField.FindStringSubmatch(strings.ReplaceAll(line, "\t", " "))
+			if m == nil {
+				continue
+			}
+			indent := len(m[1])
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, level{indent: indent, name: m[2]})
+			names := make([]string, len(stack))
+			for i, l := range stack {
+				names[i] = l.name
+			}
+			res.Fields = append(res.Fields, ExplainField{Path: strings.Join(names, "."), Type: m[3], Required: m[4] != ""})
+		}
+	}
+
+	if group != "" && !strings.Contains(res.Version, "/") {
+		res.Version = group + "/" + res.Version
+	}
+	res.Description = strings.Join(description, " ")
+	if len(res.Description) > 300 {
+		res.Description = res.Description[:300] + "..."
+	}
+	return res
+}
+
+// renderFieldTree shows the cached fields below prefix, indented by depth
+func renderFieldTree(res *ExplainResource, prefix, version string) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "KIND:     %s\nVERSION:  %s\n", res.Kind, res.Version)
+	depth := 0
+	if prefix != "" {
+		depth = strings.Count(prefix, ".") + 1
+		found := false
+		for _, f := range res.Fields {
+			if strings.EqualFold(f.Path, prefix) {
+				fmt.Fprintf(&sb, "FIELD:    %s <%s>\n", f.Path, f.Type)
+				found = true
+			}
+		}
+		if !found {
+			paths := make([]string, 0, len(res.Fields))
+			for _, f := range res.Fields {
+				paths = append(paths, f.Path)
+			}
+			if suggestion := NearestMatch(prefix, paths); suggestion != "" {
+				return "", fmt.Errorf("%s has no field %q (did you mean %q?)", res.Resource, prefix, suggestion)
+			}
+			return "", fmt.Errorf("%s has no field %q", res.Resource, prefix)
+		}
+	} else if res.Description != "" {
+		fmt.Fprintf(&sb, "\nDESCRIPTION:\n  %s\n", res.Description)
+	}
+
+	fmt.Fprintf(&sb, "\nFIELDS (cached --recursive, server %s):\n", version)
+	for _, f := range res.Fields {
+		if prefix != "" && !strings.HasPrefix(strings.ToLower(f.Path), strings.ToLower(prefix)+".") {
+			continue
+		}
+		level := strings.Count(f.Path, ".") - depth
+		name := f.Path[strings.LastIndex(f.Path, ".")+1:]
+		required := ""
+		if f.Required {
+			required = " -required-"
+		}
+		fmt.Fprintf(&sb, "%s%s\t<%s>%s\n", strings.Repeat("  ", level+1), name, f.Type, required)
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// selectFields prefers fields named in the query, then falls back to the shallowest spec fields
+func selectFields(fields []ExplainField, words []string, limit int) []ExplainField {
+	wanted := make(map[string]bool, len(words))
+	for _, w := range words {
+		wanted[w] = true
+	}
+
+	var picked []ExplainField
+	for _, f := range fields {
+		leaf := strings.ToLower(f.Path[strings.LastIndex(f.Path, ".")+1:])
+		if wanted[leaf] || wanted[strings.ToLower(f.Path)] {
+			picked = append(picked, f)
+		}
+	}
+	if len(picked) == 0 {
+		for _, f := range fields {
+			if strings.HasPrefix(f.Path, "spec.") && strings.Count(f.Path, ".") == 1 {
+				picked = append(picked, f)
+			}
+		}
+	}
+	sort.SliceStable(picked, func(i, j int) bool {
+		return strings.Count(picked[i].Path, ".") < strings.Count(picked[j].Path, ".")
+	})
+	if limit > 0 && len(picked) > limit {
+		picked = picked[:limit]
+	}
+	return picked
+}
+
+// explainWords splits text into lowercase words and dotted paths
+func explainWords(text string) []string {
+	var words []string
+	for _, raw := range strings.Fields(strings.ToLower(text)) {
+		word := strings.Trim(raw, ".,:;!?()[]{}\"'`")
+		if word == "" {
+			continue
+		}
+		words = append(words, word)
+		if strings.Contains(word, ".") {
+			words = append(words, strings.Split(word, ".")...)
+		}
+	}
+	return words
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// kubectlError prefers the first stderr line over the bare exit status
+func kubectlError(stderr string, err error) string {
+	if line := firstLine(strings.TrimSpace(stderr)); line != "" {
+		return line
+	}
+	return err.Error()
+}
@@ -0,0 +1,242 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const explainDeploymentRecursive = `GROUP:      apps
+KIND:       Deployment
+VERSION:    v1
+
+DESCRIPTION:
+    Deployment enables declarative updates for Pods and ReplicaSets.
+
+FIELDS:
+  apiVersion	<string>
+  kind	<string>
+  metadata	<ObjectMeta>
+    annotations	<map[string]string>
+    name	<string>
+  spec	<DeploymentSpec>
+    minReadySeconds	<integer>
+    replicas	<integer>
+    selector	<LabelSelector> -required-
+      matchLabels	<map[string]string>
+    strategy	<DeploymentStrategy>
+      rollingUpdate	<RollingUpdateDeployment>
+        maxSurge	<IntOrString>
+        maxUnavailable	<IntOrString>
+      type	<string>
+    template	<PodTemplateSpec> -required-
+  status	<DeploymentStatus>
+    replicas	<integer>
+`
+
+// Layout printed by kubectl before 1.27
+const explainWidgetRecursiveLegacy = `KIND:     Widget
+VERSION:  example.com/v1alpha1
+
+DESCRIPTION:
+     Widget is a custom resource.
+
+FIELDS:
+   apiVersion	<string>
+   spec	<Object>
+      size	<integer>
+      color	<string>
+`
+
+// explainRunner serves canned kubectl output; offline only breaks server calls
+type explainRunner struct {
+	outputs map[string]string
+	offline bool
+	calls   []string
+}
+
+func (r *explainRunner) Run(ctx context.Context, name string, args ...string) (string, string, error) {
+	key := strings.Join(args, " ")
+	r.calls = append(r.calls, key)
+	if r.offline && key != "config current-context" {
+		return "", "Unable to connect to the server", errors.New("exit status 1")
+	}
+	if out, ok := r.outputs[key]; ok {
+		return out, "", nil
+	}
+	return "", "error: field does not exist", errors.New("exit status 1")
+}
+
+func (r *explainRunner) RunCommand(ctx context.Context, command string) (string, string, error) {
+	fields := strings.Fields(command)
+	return r.Run(ctx, fields[0], fields[1:]...)
+}
+
+func (r *explainRunner) count(key string) int {
+	n := 0
+	for _, call := range r.calls {
+		if call == key {
+			n++
+		}
+	}
+	return n
+}
+
+func newExplainRunner() *explainRunner {
+	return &explainRunner{outputs: map[string]string{
+		"config current-context": "kind-dev\n",
+		"version -o json":        `{"clientVersion":{"gitVersion":"v1.30.0"},"serverVersion":{"gitVersion":"v1.29.3"}}`,
+		"api-resources --no-headers": testAPIResources +
+			"widgets                           wd           example.com/v1alpha1              true         Widget\n",
+		"explain deployments --recursive":   explainDeploymentRecursive,
+		"explain widgets --recursive":       explainWidgetRecursiveLegacy,
+		"explain deployments.spec.strategy": "GROUP:      apps\nKIND:       Deployment\nVERSION:    v1\n\nFIELD: strategy <DeploymentStrategy>\n\nDESCRIPTION:\n    The deployment strategy to use to replace existing pods with new ones.\n",
+	}}
+}
+
+func TestParseExplainRecursive(t *testing.T) {
+	res := parseExplainRecursive(explainDeploymentRecursive)
+	if res.Kind != "Deployment" || res.Version != "apps/v1" {
+		t.Errorf("unexpected header: %s %s", res.Kind, res.Version)
+	}
+	want := map[string]string{
+		"spec.replicas":                        "integer",
+		"spec.strategy.rollingUpdate.maxSurge": "IntOrString",
+		"spec.selector.matchLabels":            "map[string]string",
+		"status.replicas":                      "integer",
+		"metadata.annotations":                 "map[string]string",
+	}
+	got := make(map[string]ExplainField)
+	for _, f := range res.Fields {
+		got[f.Path] = f
+	}
+	for path, typ := range want {
+		if got[path].Type != typ {
+			t.Errorf("%s: type %q, want %q", path, got[path].Type, typ)
+		}
+	}
+	if !got["spec.selector"].Required || got["spec.replicas"].Required {
+		t.Error("required markers were not parsed")
+	}
+
+	legacy := parseExplainRecursive(explainWidgetRecursiveLegacy)
+	if legacy.Version != "example.com/v1alpha1" || len(legacy.Fields) != 4 || legacy.Fields[3].Path != "spec.color" {
+		t.Errorf("unexpected legacy parse: %+v", legacy)
+	}
+}
+
+func TestExplainCacheFetchesOnceAndPersists(t *testing.T) {
+	dir := t.TempDir()
+	runner := newExplainRunner()
+	cache := NewExplainCache(runner, dir)
+
+	res, err := cache.Resource(context.Background(), "deploy")
+	if err != nil || res.Resource != "deployments" {
+		t.Fatalf("Resource(deploy) = %+v, %v", res, err)
+	}
+	if _, err := cache.Resource(context.Background(), "Deployment"); err != nil {
+		t.Fatal(err)
+	}
+	if n := runner.count("explain deployments --recursive"); n != 1 {
+		t.Errorf("expected one explain call, got %d", n)
+	}
+
+	// A new process against the same server version reads the cache from disk
+	reopened := NewExplainCache(runner, dir)
+	if _, err := reopened.Resource(context.Background(), "deployments"); err != nil {
+		t.Fatal(err)
+	}
+	if n := runner.count("explain deployments --recursive"); n != 1 {
+		t.Errorf("cache was not reused from disk, %d explain calls", n)
+	}
+	if n := runner.count("api-resources --no-headers"); n != 1 {
+		t.Errorf("api-resources should be cached per version, got %d calls", n)
+	}
+
+	// A different server version gets its own snapshot
+	runner.outputs["version -o json"] = `{"serverVersion":{"gitVersion":"v1.30.1"}}`
+	upgraded := NewExplainCache(runner, dir)
+	if _, err := upgraded.Resource(context.Background(), "deployments"); err != nil {
+		t.Fatal(err)
+	}
+	if n := runner.count("explain deployments --recursive"); n != 2 {
+		t.Errorf("expected a refetch for the new server version, got %d calls", n)
+	}
+}
+
+func TestExplainCacheServesOffline(t *testing.T) {
+	dir := t.TempDir()
+	runner := newExplainRunner()
+	if _, err := NewExplainCache(runner, dir).Explain(context.Background(), "deploy.spec.strategy"); err != nil {
+		t.Fatal(err)
+	}
+
+	runner.offline = true
+	cache := NewExplainCache(runner, dir)
+	doc, err := cache.Explain(context.Background(), "deployment.spec.strategy")
+	if err != nil || !strings.Contains(doc, "deployment strategy to use") {
+		t.Errorf("expected the cached description offline, got %q, %v", doc, err)
+	}
+
+	tree, err := cache.Explain(context.Background(), "deployments.spec.strategy.rollingUpdate")
+	if err != nil {
+		t.Fatalf("expected the cached tree offline: %v", err)
+	}
+	if !strings.Contains(tree, "maxSurge\t<IntOrString>") || strings.Contains(tree, "replicas") {
+		t.Errorf("unexpected subtree:\n%s", tree)
+	}
+
+	if _, err := cache.Explain(context.Background(), "deployments.spec.replcas"); err == nil || !strings.Contains(err.Error(), `"spec.replicas"`) {
+		t.Errorf("expected a nearest-match hint, got %v", err)
+	}
+	if _, err := cache.Resource(context.Background(), "widgets"); err == nil {
+		t.Error("uncached resources cannot be fetched offline")
+	}
+}
+
+func TestExplainCacheWithoutSnapshotFailsOffline(t *testing.T) {
+	runner := newExplainRunner()
+	runner.offline = true
+	cache := NewExplainCache(runner, t.TempDir())
+	if hits := cache.FieldDocs(context.Background(), "what does deployment spec.replicas do", 5); hits != nil {
+		t.Errorf("expected no docs, got %+v", hits)
+	}
+	calls := len(runner.calls)
+	cache.FieldDocs(context.Background(), "deployment replicas", 5)
+	if len(runner.calls) != calls {
+		t.Error("a failed open should not be retried immediately")
+	}
+}
+
+func TestExplainFieldDocsForCRDs(t *testing.T) {
+	cache := NewExplainCache(newExplainRunner(), t.TempDir())
+
+	hits := cache.FieldDocs(context.Background(), "How do I set the color of a Widget?", 5)
+	if len(hits) != 1 {
+		t.Fatalf("expected docs for the Widget CRD, got %+v", hits)
+	}
+	if hits[0].Doc.Citation != "kubectl explain widgets @ v1.29.3" {
+		t.Errorf("unexpected citation %q", hits[0].Doc.Citation)
+	}
+	if !strings.Contains(hits[0].Doc.Text, "spec.color <string>") || strings.Contains(hits[0].Doc.Text, "spec.size") {
+		t.Errorf("expected only the named field:\n%s", hits[0].Doc.Text)
+	}
+
+	hit, err := cache.ResourceDocs(context.Background(), "deployment", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"spec.replicas <integer>", "spec.selector <LabelSelector> (required)"} {
+		if !strings.Contains(hit.Doc.Text, want) {
+			t.Errorf("resource docs missing %q:\n%s", want, hit.Doc.Text)
+		}
+	}
+	if strings.Contains(hit.Doc.Text, "maxSurge") {
+		t.Errorf("resource docs should stay at the top of spec:\n%s", hit.Doc.Text)
+	}
+
+	if hits := cache.FieldDocs(context.Background(), "no idea what ns to use", 5); len(hits) != 0 {
+		t.Errorf("short names should not match plain words, got %+v", hits)
+	}
+}
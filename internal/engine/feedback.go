@@ -0,0 +1,137 @@
+// feedback.go - Explicit ratings and implicit accept/reject signals on recorded sessions
+package engine
+
+import (
+	"time"
+)
+
+// UserActionRecord actions captured by the TUI
+const (
+	UserActionAccept = "accept" // a suggestion was run as proposed
+	UserActionReject = "reject" // a suggestion was discarded or superseded by a new prompt
+	UserActionModify = "modify" // a suggestion was edited before it ran; ModifiedTo holds the edit
+	UserActionCancel = "cancel"
+	UserActionRerun  = "rerun" // a command was run again after it failed; ModifiedTo holds the retry
+	UserActionRate   = "rate"  // an explicit /rate; Target holds the optional comment
+)
+
+// AcceptedContent returns what the user actually ran for a suggestion: the last edit, if any
+func (s SessionRecord) AcceptedContent(suggestion SuggestionRecord) string {
+	content := suggestion.Content
+	for _, action := range s.UserActions {
+		if action.Action == UserActionModify && action.Target == suggestion.Content && action.ModifiedTo != "" {
+			content = action.ModifiedTo
+		}
+	}
+	return content
+}
+
+// feedbackAdjustment turns ratings and implicit signals on a suggestion into a quality delta
+func feedbackAdjustment(session SessionRecord, suggestion SuggestionRecord) float64 {
+	delta := 0.0
+	if session.UserSatisfaction > 0 && session.UserSatisfaction <= 2 {
+		delta -= 0.3
+	}
+	for _, action := range session.UserActions {
+		if action.Target != suggestion.Content && action.Target != session.AcceptedContent(suggestion) {
+			continue
+		}
+		switch action.Action {
+		case UserActionReject:
+			delta -= 0.3
+		case UserActionRerun:
+			delta -= 0.15
+		case UserActionModify:
+			// The edit is what gets exported, but the model still missed
+			delta -= 0.05
+		}
+	}
+	return delta
+}
+
+// learnFromFeedback folds accepts and rejects into the suggestion patterns' acceptance rates
+func (fr *FlightRecorder) learnFromFeedback(session SessionRecord) {
+	for _, suggestion := range session.Suggestions {
+		accepted := 0.0
+		switch {
+		case suggestion.Accepted:
+			accepted = 1.0
+		case !session.hasAction(UserActionReject, suggestion.Content):
+			continue // never acted on
+		}
+
+		inputType := fr.categorizeInput(session.UserInput)
+		found := false
+		for i, existing := range fr.learningData.SuccessfulSuggestions {
+			if existing.InputType == inputType && existing.SuggestionType == suggestion.Type {
+				fr.learningData.SuccessfulSuggestions[i].UserAcceptance = (existing.UserAcceptance + accepted) / 2.0
+				found = true
+				break
+			}
+		}
+		if !found && accepted == 0 {
+			fr.learningData.SuccessfulSuggestions = append(fr.learningData.SuccessfulSuggestions, SuggestionPattern{
+				InputType:      inputType,
+				SuggestionType: suggestion.Type,
+				Pattern:        fr.extractPattern(session.UserInput),
+				UserAcceptance: 0,
+				AvgConfidence:  suggestion.Confidence,
+			})
+		}
+	}
+
+	if session.UserSatisfaction > 0 {
+		prefs := &fr.learningData.UserPreferences
+		prefs.RatedSessions++
+		prefs.AvgSatisfaction += (float64(session.UserSatisfaction) - prefs.AvgSatisfaction) / float64(prefs.RatedSessions)
+	}
+}
+
+func (s SessionRecord) hasAction(action, target string) bool {
+	for _, a := range s.UserActions {
+		if a.Action == action && a.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// LearnFromFeedback replays a session's ratings and accept/reject signals into the learned patterns,
+// so rejected or edited suggestions lose confidence and the user's edits gain it
+func (pie *PredictiveIntelligenceEngine) LearnFromFeedback(session SessionRecord) {
+	pie.mu.Lock()
+	defer pie.mu.Unlock()
+
+	context := session.Context
+	if context == nil {
+		context = &KubeContextSummary{}
+	}
+	disliked := session.UserSatisfaction > 0 && session.UserSatisfaction <= 2
+
+	for _, suggestion := range session.Suggestions {
+		final := session.AcceptedContent(suggestion)
+		switch {
+		case final != suggestion.Content:
+			pie.patternLearner.UpdatePatterns(session.UserInput, context, suggestion.Content, false)
+			pie.patternLearner.UpdatePatterns(session.UserInput, context, final, suggestion.Applied && !disliked)
+		case suggestion.Accepted:
+			pie.patternLearner.UpdatePatterns(session.UserInput, context, final, suggestion.Applied && !disliked)
+		case session.hasAction(UserActionReject, suggestion.Content):
+			pie.patternLearner.UpdatePatterns(session.UserInput, context, suggestion.Content, false)
+		}
+	}
+
+	if session.UserSatisfaction > 0 {
+		pie.behaviorAnalyzer.RecordEvent(BehaviorEvent{
+			Timestamp: time.Now(),
+			EventType: "user_feedback",
+			Context:   context.Namespace,
+			Action:    UserActionRate,
+			Success:   !disliked,
+			Metadata: map[string]interface{}{
+				"user_input":        session.UserInput,
+				"user_satisfaction": session.UserSatisfaction,
+			},
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"testing"
+)
+
+func TestCalculateQualityUsesFeedback(t *testing.T) {
+	fr := NewFlightRecorder(t.TempDir())
+	suggestion := SuggestionRecord{Type: "command", Content: "kubectl get pods -n payments", Confidence: 0.6, Accepted: true, Applied: true}
+
+	happy := SessionRecord{UserInput: "list pods", Outcome: "success", UserSatisfaction: 5, Suggestions: []SuggestionRecord{suggestion}}
+	unhappy := happy
+	unhappy.UserSatisfaction = 1
+	rerun := happy
+	rerun.UserSatisfaction = 0
+	rerun.UserActions = []UserActionRecord{{Action: UserActionRerun, Target: suggestion.Content, ModifiedTo: suggestion.Content}}
+	rejected := happy
+	rejected.UserSatisfaction = 0
+	rejected.UserActions = []UserActionRecord{{Action: UserActionReject, Target: "kubectl delete pods --all"}}
+
+	if q := fr.calculateQuality(happy, suggestion); q < 0.89 || q > 0.91 {
+		t.Errorf("rated 5: quality %.2f, want 0.9", q)
+	}
+	if q := fr.calculateQuality(unhappy, suggestion); q > 0.41 {
+		t.Errorf("rated 1: quality %.2f, want a penalty", q)
+	}
+	if q := fr.calculateQuality(rerun, suggestion); q > 0.56 {
+		t.Errorf("rerun after failure: quality %.2f, want a penalty", q)
+	}
+	if q := fr.calculateQuality(rejected, suggestion); q < 0.69 || q > 0.71 {
+		t.Errorf("rejecting another suggestion should not affect this one: quality %.2f", q)
+	}
+
+	floor := unhappy
+	floor.UserActions = []UserActionRecord{{Action: UserActionReject, Target: suggestion.Content}, {Action: UserActionRerun, Target: suggestion.Content}}
+	if q := fr.calculateQuality(floor, SuggestionRecord{Content: suggestion.Content}); q != 0 {
+		t.Errorf("quality should not go below 0, got %.2f", q)
+	}
+}
+
+func TestExportTrainingDataPrefersUserEdits(t *testing.T) {
+	fr := NewFlightRecorder(t.TempDir())
+	fr.autoSave = false
+	err := fr.RecordSession(SessionRecord{
+		UserInput:        "tail the api logs",
+		Outcome:          "success",
+		UserSatisfaction: 4,
+		Suggestions: []SuggestionRecord{
+			{Type: "command", Content: "kubectl logs api", Confidence: 0.8, Accepted: true, Applied: true},
+			{Type: "command", Content: "kubectl delete pod api", Confidence: 0.8},
+		},
+		UserActions: []UserActionRecord{
+			{Action: UserActionModify, Target: "kubectl logs api", ModifiedTo: "kubectl logs -f deploy/api -n payments"},
+			{Action: UserActionReject, Target: "kubectl delete pod api"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	examples := fr.ExportTrainingData()
+	if len(examples) != 1 || examples[0].Output != "kubectl logs -f deploy/api -n payments" {
+		t.Fatalf("expected the edited command as output, got %+v", examples)
+	}
+
+	prefs := fr.learningData.UserPreferences
+	if prefs.RatedSessions != 1 || prefs.AvgSatisfaction != 4 {
+		t.Errorf("unexpected satisfaction stats: %d sessions, avg %.1f", prefs.RatedSessions, prefs.AvgSatisfaction)
+	}
+	if len(fr.learningData.SuccessfulSuggestions) != 1 || fr.learningData.SuccessfulSuggestions[0].UserAcceptance != 0.5 {
+		t.Errorf("one accept and one reject should average to 0.5 acceptance: %+v", fr.learningData.SuccessfulSuggestions)
+	}
+}
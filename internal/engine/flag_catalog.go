@@ -0,0 +1,192 @@
+// flag_catalog.go - Bundled kubectl/helm flag catalog used by the command guard
+package engine
+
+import "strings"
+
+// kubectlGlobalFlags are accepted by every kubectl subcommand
+var kubectlGlobalFlags = flagSet(
+	"-n", "--namespace", "--context", "--cluster", "--user", "--kubeconfig", "-s", "--server",
+	"--token", "--as", "--as-group", "--as-uid", "--insecure-skip-tls-verify", "--certificate-authority",
+	"--client-certificate", "--client-key", "--request-timeout", "--tls-server-name", "-v", "--v",
+	"--cache-dir", "--match-server-version", "--profile", "--profile-output", "--warnings-as-errors",
+	"--disable-compression", "-h", "--help",
+)
+
+// kubectlVerbFlags lists the flags each kubectl subcommand accepts beyond the globals
+var kubectlVerbFlags = map[string]map[string]bool{
+	"get": flagSet("-o", "--output", "-l", "--selector", "-A", "--all-namespaces", "-w", "--watch",
+		"--watch-only", "--field-selector", "--show-labels", "-L", "--label-columns", "--sort-by",
+		"--no-headers", "--show-kind", "--chunk-size", "--ignore-not-found", "-f", "--filename", "-R",
+		"--recursive", "-k", "--kustomize", "--raw", "--template", "--allow-missing-template-keys",
+		"--output-watch-events", "--server-print", "--subresource", "--show-managed-fields"),
+	"describe": flagSet("-l", "--selector", "-A", "--all-namespaces", "-f", "--filename", "-R", "--recursive",
+		"-k", "--kustomize", "--show-events", "--chunk-size"),
+	"logs": flagSet("-c", "--container", "-f", "--follow", "-p", "--previous", "--tail", "--since",
+		"--since-time", "--timestamps", "--all-containers", "-l", "--selector", "--prefix",
+		"--max-log-requests", "--limit-bytes", "--pod-running-timeout", "--ignore-errors",
+		"--insecure-skip-tls-verify-backend", "--all-pods"),
+	"delete": flagSet("-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "-l", "--selector",
+		"--field-selector", "--all", "-A", "--all-namespaces", "--force", "--grace-period", "--now",
+		"--wait", "--timeout", "--cascade", "--ignore-not-found", "--dry-run", "-i", "--interactive",
+		"-o", "--output", "--raw"),
+	"apply": flagSet("-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "--dry-run",
+		"--server-side", "--force-conflicts", "--field-manager", "--prune", "-l", "--selector", "--all",
+		"--force", "--grace-period", "--overwrite", "--validate", "--wait", "--timeout", "-o", "--output",
+		"--record", "--cascade", "--prune-allowlist", "--show-managed-fields", "--template"),
+	"create": flagSet("-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "--dry-run", "-o",
+		"--output", "--save-config", "--validate", "--edit", "--windows-line-endings", "--field-manager",
+		"--image", "--replicas", "--port", "--from-literal", "--from-file", "--from-env-file", "--type",
+		"--schedule", "--restart", "--tcp", "--rule", "--class", "--hard", "--scopes", "--clusterrole",
+		"--role", "--serviceaccount", "--verb", "--resource", "--docker-server", "--docker-username",
+		"--docker-password", "--docker-email", "--cert", "--key", "--namespace", "--template",
+		"--show-managed-fields", "--raw"),
+	"patch": flagSet("-p", "--patch", "--patch-file", "--type", "-f", "--filename", "-R", "--recursive",
+		"-k", "--kustomize", "--dry-run", "-o", "--output", "--local", "--field-manager", "--subresource",
+		"--record", "--template", "--show-managed-fields"),
+	"edit": flagSet("-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "-o", "--output",
+		"--validate", "--save-config", "--windows-line-endings", "--field-manager", "--subresource",
+		"--show-managed-fields", "--template"),
+	"scale": flagSet("--replicas", "--current-replicas", "--resource-version", "-f", "--filename",
+		"-R", "--recursive", "-k", "--kustomize", "-l", "--selector", "--all", "--timeout", "--dry-run",
+		"-o", "--output", "--record", "--template", "--show-managed-fields"),
+	"rollout": flagSet("-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "-l", "--selector",
+		"-w", "--watch", "--timeout", "--revision", "--to-revision", "--dry-run", "-o", "--output",
+		"--field-manager", "--template", "--show-managed-fields"),
+	"exec": flagSet("-c", "--container", "-i", "--stdin", "-t", "--tty", "-q", "--quiet", "-f",
+		"--filename", "--pod-running-timeout"),
+	"port-forward": flagSet("--address", "--pod-running-timeout"),
+	"top": flagSet("-A", "--all-namespaces", "-l", "--selector", "--containers", "--no-headers",
+		"--sort-by", "--use-protocol-buffers", "--show-capacity", "--sum", "--field-selector"),
+	"label": flagSet("--overwrite", "--all", "-A", "--all-namespaces", "-l", "--selector",
+		"--field-selector", "--list", "--local", "--resource-version", "-f", "--filename", "-R",
+		"--recursive", "-k", "--kustomize", "--dry-run", "-o", "--output", "--field-manager",
+		"--template", "--show-managed-fields"),
+	"annotate": flagSet("--overwrite", "--all", "-A", "--all-namespaces", "-l", "--selector",
+		"--field-selector", "--list", "--local", "--resource-version", "-f", "--filename", "-R",
+		"--recursive", "-k", "--kustomize", "--dry-run", "-o", "--output", "--field-manager",
+		"--template", "--show-managed-fields"),
+	"run": flagSet("--image", "--env", "--port", "--labels", "-l", "--restart", "--command", "-i",
+		"--stdin", "-t", "--tty", "--rm", "--dry-run", "-o", "--output", "--overrides", "--expose",
+		"--image-pull-policy", "--annotations", "--attach", "-q", "--quiet", "--privileged",
+		"--override-type", "--pod-running-timeout", "--field-manager", "--timeout", "--wait"),
+	"expose": flagSet("--port", "--target-port", "--protocol", "--type", "--name", "--selector",
+		"-l", "--labels", "--external-ip", "--load-balancer-ip", "--session-affinity",
+		"--cluster-ip", "-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "--dry-run",
+		"-o", "--output", "--overrides", "--field-manager", "--template", "--show-managed-fields"),
+	"set": flagSet("-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "-l", "--selector",
+		"--all", "-c", "--containers", "-e", "--env", "--from", "--keys", "--prefix", "--list",
+		"--overwrite", "--resolve", "--limits", "--requests", "--local", "--dry-run", "-o", "--output",
+		"--field-manager", "--template", "--show-managed-fields"),
+	"cordon":   flagSet("-l", "--selector", "--dry-run"),
+	"uncordon": flagSet("-l", "--selector", "--dry-run"),
+	"drain": flagSet("--ignore-daemonsets", "--delete-emptydir-data", "--force", "--grace-period",
+		"--timeout", "-l", "--selector", "--pod-selector", "--disable-eviction", "--skip-wait-for-delete-timeout",
+		"--chunk-size", "--dry-run"),
+	"taint": flagSet("--all", "-l", "--selector", "--overwrite", "--dry-run", "-o", "--output",
+		"--field-manager", "--validate", "--template", "--show-managed-fields"),
+	"explain":       flagSet("--recursive", "--api-version", "-o", "--output"),
+	"api-resources": flagSet("--api-group", "--cached", "--namespaced", "--no-headers", "-o", "--output", "--sort-by", "--verbs", "--categories"),
+	"version":       flagSet("--client", "-o", "--output"),
+	"events": flagSet("-A", "--all-namespaces", "--for", "--types", "-w", "--watch", "-o",
+		"--output", "--no-headers", "--chunk-size", "--template", "--allow-missing-template-keys",
+		"--show-managed-fields"),
+	"diff": flagSet("-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "--server-side",
+		"--force-conflicts", "--field-manager", "--prune", "-l", "--selector", "--concurrency",
+		"--show-managed-fields", "--prune-allowlist"),
+	"auth":         flagSet("-A", "--all-namespaces", "--list", "--no-headers", "--subresource", "-q", "--quiet", "-o", "--output"),
+	"cp":           flagSet("-c", "--container", "--no-preserve", "--retries"),
+	"kustomize":    flagSet("--enable-helm", "--helm-command", "--load-restrictor", "-o", "--output", "--reorder", "--env", "-e"),
+	"wait":         flagSet("--for", "--timeout", "-f", "--filename", "-R", "--recursive", "-k", "--kustomize", "-l", "--selector", "--field-selector", "--all", "-A", "--all-namespaces", "-o", "--output", "--local", "--template", "--show-managed-fields"),
+	"config":       flagSet("--current", "--minify", "--raw", "--flatten", "-o", "--output", "--namespace", "--cluster", "--user"),
+	"cluster-info": flagSet("--output-directory", "-o", "--output"),
+}
+
+// helmGlobalFlags are accepted by every helm subcommand
+var helmGlobalFlags = flagSet(
+	"-n", "--namespace", "--kube-context", "--kubeconfig", "--kube-apiserver", "--kube-as-group",
+	"--kube-as-user", "--kube-ca-file", "--kube-insecure-skip-tls-verify", "--kube-tls-server-name",
+	"--kube-token", "--burst-limit", "--qps", "--debug", "--registry-config", "--repository-cache",
+	"--repository-config", "-h", "--help",
+)
+
+var helmInstallFlags = flagSet("-f", "--values", "--set", "--set-string", "--set-file", "--set-json",
+	"--set-literal", "--version", "--devel", "--dry-run", "--wait", "--wait-for-jobs", "--timeout",
+	"--atomic", "--create-namespace", "--dependency-update", "--description", "--disable-openapi-validation",
+	"--no-hooks", "--post-renderer", "--post-renderer-args", "--render-subchart-notes", "--replace",
+	"--skip-crds", "--repo", "--username", "--password", "--ca-file", "--cert-file", "--key-file",
+	"--insecure-skip-tls-verify", "--pass-credentials", "--verify", "--keyring", "-g", "--generate-name",
+	"--name-template", "-o", "--output", "--labels", "-l", "--enable-dns", "--force", "--plain-http",
+	"--hide-notes", "--take-ownership", "--skip-schema-validation")
+
+// helmVerbFlags lists the flags each helm subcommand accepts beyond the globals
+var helmVerbFlags = map[string]map[string]bool{
+	"install": helmInstallFlags,
+	"upgrade": mergeFlagSets(helmInstallFlags, flagSet("-i", "--install", "--reuse-values",
+		"--reset-values", "--reset-then-reuse-values", "--history-max", "--cleanup-on-fail")),
+	"template": mergeFlagSets(helmInstallFlags, flagSet("-a", "--api-versions", "--include-crds",
+		"--is-upgrade", "--kube-version", "--output-dir", "-s", "--show-only", "--validate", "--release-name")),
+	"uninstall": flagSet("--dry-run", "--keep-history", "--no-hooks", "--wait", "--timeout",
+		"--description", "--cascade", "--ignore-not-found"),
+	"list": flagSet("-a", "--all", "-A", "--all-namespaces", "-d", "--date", "--deployed", "--failed",
+		"-f", "--filter", "-m", "--max", "--offset", "-o", "--output", "--pending", "-r", "--reverse",
+		"-l", "--selector", "-q", "--short", "--superseded", "--uninstalled", "--uninstalling", "--no-headers",
+		"--time-format"),
+	"ls": flagSet("-a", "--all", "-A", "--all-namespaces", "-d", "--date", "--deployed", "--failed",
+		"-f", "--filter", "-m", "--max", "--offset", "-o", "--output", "--pending", "-r", "--reverse",
+		"-l", "--selector", "-q", "--short", "--superseded", "--uninstalled", "--uninstalling", "--no-headers",
+		"--time-format"),
+	"status":     flagSet("-o", "--output", "--revision", "--show-desc", "--show-resources"),
+	"history":    flagSet("--max", "-o", "--output"),
+	"rollback":   flagSet("--cleanup-on-fail", "--dry-run", "--force", "--history-max", "--no-hooks", "--recreate-pods", "--timeout", "--wait", "--wait-for-jobs"),
+	"get":        flagSet("--revision", "-o", "--output", "-a", "--all", "--template"),
+	"show":       flagSet("--version", "--devel", "--repo", "--username", "--password", "--ca-file", "--cert-file", "--key-file", "--insecure-skip-tls-verify", "--verify", "--keyring", "--jsonpath", "--plain-http", "--pass-credentials"),
+	"lint":       flagSet("-f", "--values", "--set", "--set-string", "--set-file", "--set-json", "--set-literal", "--strict", "--with-subcharts", "--kube-version", "--quiet", "--skip-schema-validation"),
+	"repo":       flagSet("--force-update", "--username", "--password", "--ca-file", "--cert-file", "--key-file", "--insecure-skip-tls-verify", "--pass-credentials", "-o", "--output", "--fail-on-repo-update-fail"),
+	"search":     flagSet("--devel", "-o", "--output", "-r", "--regexp", "--version", "-l", "--versions", "--max-col-width", "--endpoint", "--fail-on-no-result", "--list-repo-url"),
+	"dependency": flagSet("--skip-refresh", "--verify", "--keyring"),
+	"version":    flagSet("--short", "--template", "--client", "-c"),
+	"test":       flagSet("--filter", "--logs", "--timeout"),
+	"pull":       flagSet("-d", "--destination", "--untar", "--untardir", "--version", "--devel", "--repo", "--prov", "--verify", "--keyring", "--username", "--password", "--ca-file", "--cert-file", "--key-file", "--insecure-skip-tls-verify", "--pass-credentials", "--plain-http"),
+}
+
+func flagSet(flags ...string) map[string]bool {
+	set := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		set[f] = true
+	}
+	return set
+}
+
+func mergeFlagSets(sets ...map[string]bool) map[string]bool {
+	merged := make(map[string]bool)
+	for _, set := range sets {
+		for f := range set {
+			merged[f] = true
+		}
+	}
+	return merged
+}
+
+// KnownFlags returns the catalog flags for a kubectl or helm subcommand.
+// The second result is false when the subcommand is not in the catalog.
+func KnownFlags(tool, verb string) ([]string, bool) {
+	var global map[string]bool
+	var verbs map[string]map[string]bool
+	switch tool {
+	case "kubectl":
+		global, verbs = kubectlGlobalFlags, kubectlVerbFlags
+	case "helm":
+		global, verbs = helmGlobalFlags, helmVerbFlags
+	default:
+		return nil, false
+	}
+	specific, ok := verbs[strings.ToLower(verb)]
+	if !ok {
+		return nil, false
+	}
+	flags := make([]string, 0, len(global)+len(specific))
+	for f := range mergeFlagSets(global, specific) {
+		flags = append(flags, f)
+	}
+	return flags, true
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	
 	"github.com/siryoos/kubemage/internal/engine/validator"
+	"github.com/siryoos/kubemage/internal/prompts"
 )
 
 // GenerationTemplate represents a template for generating files
@@ -37,45 +38,14 @@ type HelmChartOptions struct {
 
 // GetDeploymentGenerationPrompt creates a prompt for LLM to generate deployment YAML
 func GetDeploymentGenerationPrompt(options DeploymentOptions) string {
-	var prompt strings.Builder
-
-	prompt.WriteString("Generate a Kubernetes Deployment YAML manifest with the following specifications:\n\n")
-	prompt.WriteString(fmt.Sprintf("- Name: %s\n", options.Name))
-	prompt.WriteString(fmt.Sprintf("- Container Image: %s\n", options.Image))
-
-	if options.Replicas > 0 {
-		prompt.WriteString(fmt.Sprintf("- Replicas: %d\n", options.Replicas))
-	} else {
-		prompt.WriteString("- Replicas: 3 (default)\n")
-	}
-
-	if options.Port > 0 {
-		prompt.WriteString(fmt.Sprintf("- Container Port: %d\n", options.Port))
-	}
-
-	if len(options.Labels) > 0 {
-		prompt.WriteString("- Additional Labels:\n")
-		for key, value := range options.Labels {
-			prompt.WriteString(fmt.Sprintf("  %s: %s\n", key, value))
-		}
-	}
-
-	if len(options.Env) > 0 {
-		prompt.WriteString("- Environment Variables:\n")
-		for key, value := range options.Env {
-			prompt.WriteString(fmt.Sprintf("  %s: %s\n", key, value))
-		}
-	}
-
-	prompt.WriteString("\nRequirements:\n")
-	prompt.WriteString("- Use current Kubernetes API versions\n")
-	prompt.WriteString("- Include proper metadata labels (app.kubernetes.io/name, app.kubernetes.io/instance)\n")
-	prompt.WriteString("- Add resource requests and limits\n")
-	prompt.WriteString("- Include readiness and liveness probes if a port is specified\n")
-	prompt.WriteString("- Follow Kubernetes best practices\n")
-	prompt.WriteString("- Output only the YAML manifest, no explanations\n")
-
-	return prompt.String()
+	return prompts.Text(prompts.DeploymentGeneration, prompts.Vars{
+		"Name":     options.Name,
+		"Image":    options.Image,
+		"Replicas": options.Replicas,
+		"Port":     options.Port,
+		"Labels":   options.Labels,
+		"Env":      options.Env,
+	})
 }
 
 // GetServiceGenerationPrompt creates a prompt for LLM to generate service YAML
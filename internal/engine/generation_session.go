@@ -8,6 +8,8 @@ type GenerationType string
 const (
 	GenerationTypeDeployment GenerationType = "deployment"
 	GenerationTypeHelmChart  GenerationType = "helm-chart"
+	// GenerationTypeCustomResource generates an object of an installed CRD kind
+	GenerationTypeCustomResource GenerationType = "custom-resource"
 )
 
 // GenerationPhase captures the lifecycle state for a generation session
@@ -28,6 +30,10 @@ type GenerationSession struct {
 	TargetDir     string // directory output (for helm charts)
 	DeployOptions *DeploymentOptions
 	HelmOptions   *HelmChartOptions
+	Manifest      string            // structured manifest output, if any
+	Files         map[string]string // structured chart files, if any
+	CRD           *CRDInfo          // target kind for custom resources
+	SchemaIssues  []string          // OpenAPI schema violations left after generation
 	phase         GenerationPhase
 	response      strings.Builder
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 	
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine/validator"
 )
 
 // IntelligenceEngine coordinates all smart analysis components
@@ -33,6 +34,7 @@ type AnalysisSession struct {
 	Confidence   float64             `json:"confidence"`
 	Actions      []IntelligentAction `json:"actions"`
 	Outcome      string              `json:"outcome"`
+	Observations []string            `json:"-"` // cluster text the analysis read; taints next moves
 }
 
 // IntelligentAction represents a smart, context-aware action
@@ -160,8 +162,15 @@ func (ie *IntelligenceEngine) AnalyzeIntelligentlyHighPriority(input string, con
 	return workID
 }
 
-// AnalyzeIntelligently performs comprehensive intelligent analysis
+// AnalyzeIntelligently performs comprehensive intelligent analysis with the patterns and
+// playbooks only, so it is cheap enough for partial input while the user types
 func (ie *IntelligenceEngine) AnalyzeIntelligently(input string, context *KubeContextSummary) (*AnalysisSession, error) {
+	return ie.AnalyzeSubmitted(input, context, "")
+}
+
+// AnalyzeSubmitted analyzes a submitted request, classifying intent and root cause with
+// model; an empty model or a failed call falls back to the patterns and playbooks
+func (ie *IntelligenceEngine) AnalyzeSubmitted(input string, context *KubeContextSummary, model string) (*AnalysisSession, error) {
 	sessionID := fmt.Sprintf("analysis-%d", time.Now().Unix())
 	session := &AnalysisSession{
 		ID:        sessionID,
@@ -170,7 +179,7 @@ func (ie *IntelligenceEngine) AnalyzeIntelligently(input string, context *KubeCo
 	}
 
 	// Step 1: Route intent intelligently
-	router := RouteIntent(input, context)
+	router := ClassifyIntent(input, context, model)
 	session.Intent = &router
 
 	// Step 2: Gather relevant observations based on intent
@@ -178,10 +187,11 @@ func (ie *IntelligenceEngine) AnalyzeIntelligently(input string, context *KubeCo
 	if err != nil {
 		return nil, fmt.Errorf("failed to gather observations: %w", err)
 	}
+	session.Observations = observations
 
 	// Step 3: Perform root cause analysis if diagnostic mode
 	if router.Mode == ModeDiagnose && len(observations) > 0 {
-		rootCause := ie.knowledge.DetectRootCause(observations)
+		rootCause := ie.knowledge.AnalyzeRootCause(observations, model)
 		session.RootCause = rootCause
 		session.Confidence = rootCause.Confidence
 	}
@@ -308,6 +318,13 @@ func (ie *IntelligenceEngine) generateDiagnosticActions(session *AnalysisSession
 			}
 
 			risk := ie.assessRisk(step.Command, step.Risk)
+			automated := risk.Level == "low" && step.Category == "investigate"
+			// Next moves were picked from cluster output; injection there taints them
+			if plan := validator.BuildPreExecPlanTainted(step.Command, session.Observations...); plan.Untrusted {
+				risk.Level = plan.DangerLevel
+				risk.Factors = append(risk.Factors, plan.Notes...)
+				automated = false
+			}
 
 			actions = append(actions, IntelligentAction{
 				Type:        "fix",
@@ -316,7 +333,7 @@ func (ie *IntelligenceEngine) generateDiagnosticActions(session *AnalysisSession
 				Command:     step.Command,
 				Risk:        risk,
 				Expected:    "Resolution of identified issue",
-				Automated:   risk.Level == "low" && step.Category == "investigate",
+				Automated:   automated,
 			})
 		}
 	} else {
@@ -2,10 +2,13 @@
 package engine
 
 import (
+	contextpkg "context"
 	"fmt"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
 )
 
 // IntelligentCommandGenerator provides advanced command generation capabilities
@@ -17,6 +20,9 @@ type IntelligentCommandGenerator struct {
 	safetyValidator    *SafetyValidator
 	templateEngine     *TemplateEngine
 	performanceTracker *CommandPerformanceTracker
+	consistency        SelfConsistencyConfig
+	sampler            CommandSampler
+	runner             execx.Runner
 	mu                 sync.RWMutex
 }
 
@@ -256,7 +262,7 @@ func NewIntelligentCommandGenerator(modelRouter *ModelRouter, smartCache *SmartC
 }
 
 // GenerateOptimizedCommand generates an optimized command with caching
-func (icg *IntelligentCommandGenerator) GenerateOptimizedCommand(query string, context *KubeContextSummary) (*GeneratedCommand, error) {
+func (icg *IntelligentCommandGenerator) GenerateOptimizedCommand(ctx contextpkg.Context, query string, context *KubeContextSummary) (*GeneratedCommand, error) {
 	icg.mu.RLock()
 	defer icg.mu.RUnlock()
 
@@ -278,10 +284,19 @@ func (icg *IntelligentCommandGenerator) GenerateOptimizedCommand(query string, c
 		model = "llama3.1:8b" // Default model
 	}
 
+	// Sample several candidates and vote when self-consistency is enabled
+	var baseCommand string
+	consistency, err := icg.sampleConsistentCommand(ctx, query, model)
+	if err == nil && consistency != nil && consistency.Best.Valid {
+		baseCommand = consistency.Best.Command
+	}
+
 	// Generate base command
-	baseCommand, err := icg.generateBaseCommand(query, context, model)
-	if err != nil {
-		return nil, fmt.Errorf("base command generation failed: %w", err)
+	if baseCommand == "" {
+		baseCommand, err = icg.generateBaseCommand(query, context, model)
+		if err != nil {
+			return nil, fmt.Errorf("base command generation failed: %w", err)
+		}
 	}
 
 	// Optimize the command
@@ -293,8 +308,16 @@ func (icg *IntelligentCommandGenerator) GenerateOptimizedCommand(query string, c
 	// Calculate risk level
 	riskLevel := icg.calculateRiskLevel(optimizedCommand, safetyChecks)
 
-	// Generate alternatives
-	alternatives := icg.generateAlternatives(optimizedCommand, context)
+	// Generate alternatives, led by other valid voted candidates
+	var alternatives []string
+	if consistency != nil {
+		for _, alt := range consistency.Alternatives {
+			if alt.Valid {
+				alternatives = append(alternatives, alt.Command)
+			}
+		}
+	}
+	alternatives = append(alternatives, icg.generateAlternatives(optimizedCommand, context)...)
 
 	// Create generated command
 	generatedCmd := &GeneratedCommand{
@@ -317,6 +340,10 @@ func (icg *IntelligentCommandGenerator) GenerateOptimizedCommand(query string, c
 	generatedCmd.Metadata["model_used"] = model
 	generatedCmd.Metadata["optimization_applied"] = optimizedCommand != baseCommand
 	generatedCmd.Metadata["cache_key"] = cacheKey
+	if consistency != nil {
+		generatedCmd.Metadata["self_consistency"] = consistency
+		generatedCmd.Metadata["votes"] = fmt.Sprintf("%d/%d", consistency.Best.Votes, consistency.Sampled)
+	}
 
 	// Cache the result
 	icg.smartCache.SetL1(cacheKey, generatedCmd, 10*time.Minute)
@@ -329,8 +356,14 @@ func (icg *IntelligentCommandGenerator) GenerateOptimizedCommand(query string, c
 
 // generateBaseCommand generates the base command using LLM
 func (icg *IntelligentCommandGenerator) generateBaseCommand(query string, context *KubeContextSummary, model string) (string, error) {
-	// This would integrate with the existing Ollama generation
-	// For now, return a placeholder
+	if icg.sampler != nil {
+		out, err := icg.sampler(contextpkg.Background(), query, model, defaultConsistencyTemperatures[0])
+		if err != nil {
+			return "", err
+		}
+		return cleanCandidate(out), nil
+	}
+	// No sampler configured: fall back to a safe read-only placeholder
 	return fmt.Sprintf("kubectl get pods -n %s", context.Namespace), nil
 }
 
@@ -0,0 +1,769 @@
+// investigation.go - Plan-then-act agent: ranked hypotheses, parallel read-only checks and a structured finding.
+// Checks come from the hypothesis catalog and are scored by pattern; the model is not consulted.
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/engine/promptbudget"
+	"github.com/siryoos/kubemage/internal/engine/validator"
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+const (
+	hypothesisConfirmed    = 0.85 // the investigation stops once a hypothesis reaches this confidence
+	hypothesisRuledOut     = 0.1  // hypotheses below this are pruned with their remaining checks
+	hypothesisUndetermined = 0.3  // below this the finding does not name a root cause
+	maxEvidenceLine        = 200
+	maxStepOutput          = 16 * 1024
+)
+
+// InvestigationBudget caps how much work one investigation may do
+type InvestigationBudget struct {
+	MaxSteps        int           // commands run
+	MaxDuration     time.Duration // wall clock for the whole investigation
+	MaxOutputTokens int           // estimated tokens of cluster output read
+	Parallelism     int           // independent checks run at once
+	StepTimeout     time.Duration // per command
+}
+
+// DefaultInvestigationBudget returns the budget used when none is configured
+func DefaultInvestigationBudget() InvestigationBudget {
+	return InvestigationBudget{
+		MaxSteps:        12,
+		MaxDuration:     2 * time.Minute,
+		MaxOutputTokens: 6000,
+		Parallelism:     3,
+		StepTimeout:     8 * time.Second,
+	}
+}
+
+// InvestigationTarget is the resource a question is about; Kind is empty for namespace-wide questions
+type InvestigationTarget struct {
+	Kind      string // "pod", "deployment", "service", "pvc" or ""
+	Name      string
+	Namespace string
+}
+
+func (t InvestigationTarget) String() string {
+	if t.Kind == "" {
+		return "namespace " + t.Namespace
+	}
+	return fmt.Sprintf("%s/%s in namespace %s", t.Kind, t.Name, t.Namespace)
+}
+
+var (
+	reInvestigationTarget    = regexp.MustCompile(`(?i)\b(pods?|deployments?|deploy|services?|svc|pvcs?|persistentvolumeclaims?)(?:/|\s+)([a-z0-9][a-z0-9.-]*[a-z0-9])\b`)
+	reInvestigationNamespace = regexp.MustCompile(`(?i)(?:-n|--namespace|\bnamespace|\bns)[\s=]+([a-z0-9][a-z0-9-]*)`)
+	reReplicaSetPod          = regexp.MustCompile(`^(.+)-[a-z0-9]{8,10}-[a-z0-9]{5}$`)
+)
+
+var investigationKinds = map[string]string{
+	"pod": "pod", "pods": "pod",
+	"deployment": "deployment", "deployments": "deployment", "deploy": "deployment",
+	"service": "service", "services": "service", "svc": "service",
+	"pvc": "pvc", "pvcs": "pvc", "persistentvolumeclaim": "pvc", "persistentvolumeclaims": "pvc",
+}
+
+// ParseInvestigationTarget finds the resource and namespace a question names, e.g.
+// "why is pod api-7d9f-x2k4j not ready in namespace payments"
+func ParseInvestigationTarget(question, defaultNamespace string) InvestigationTarget {
+	target := InvestigationTarget{Namespace: defaultNamespace}
+	if m := reInvestigationNamespace.FindStringSubmatch(question); m != nil {
+		target.Namespace = m[1]
+	}
+	for _, m := range reInvestigationTarget.FindAllStringSubmatch(question, -1) {
+		name := strings.ToLower(m[2])
+		// "pods in namespace x" names no pod
+		if name == "in" || name == "are" || name == "is" || name == "not" || name == target.Namespace {
+			continue
+		}
+		target.Kind, target.Name = investigationKinds[strings.ToLower(m[1])], name
+		break
+	}
+	if target.Namespace == "" {
+		target.Namespace = "default"
+	}
+	return target
+}
+
+// hypothesisSpec is a candidate root cause with the checks that confirm or refute it
+type hypothesisSpec struct {
+	id         string
+	cause      string
+	category   string
+	kinds      []string // target kinds it applies to; "" is a namespace-wide question
+	pattern    string   // PlaybookLibrary pattern that raises its prior
+	prior      float64
+	indicators []string // lower-case text in a check's output that supports it
+	checks     []string // follow-up commands after triage, run only while it is still plausible
+	explains   []string // hypotheses that are symptoms of this one, e.g. OOM kills cause crash loops
+	advice     string
+	fix        string // mutating fix proposed as a PreExecPlan; <placeholders> need editing
+}
+
+var podKinds = []string{"pod", "deployment", ""}
+
+var hypothesisCatalog = []hypothesisSpec{
+	{
+		id: "image-pull", cause: "Image cannot be pulled", category: "image-issues",
+		kinds: podKinds, pattern: "image-pull-backoff", prior: 0.3,
+		indicators: []string{"imagepullbackoff", "errimagepull", "failed to pull image", "pull access denied", "manifest unknown"},
+		checks:     []string{"kubectl get events -n {namespace} --field-selector reason=Failed"},
+		advice:     "Fix the image name or tag, or add an imagePullSecret for the registry.",
+		fix:        "kubectl set image deployment/{deployment} -n {namespace} <container>=<image:tag>",
+	},
+	{
+		id: "crashloop", cause: "Container crashes on start", category: "application",
+		kinds: podKinds, prior: 0.3,
+		indicators: []string{"crashloopbackoff", "back-off restarting failed container", "reason: error", "panic:", "exception", "fatal"},
+		checks:     []string{"kubectl logs {ref} -n {namespace} --previous --tail=50"},
+		advice:     "Read the previous container's logs; roll back if a recent rollout introduced the crash.",
+		fix:        "kubectl rollout undo deployment/{deployment} -n {namespace}",
+	},
+	{
+		id: "oom", cause: "Container is OOMKilled", category: "resource-limits",
+		kinds: podKinds, pattern: "oom-killed", prior: 0.25,
+		indicators: []string{"oomkilled", "exit code: 137", "out of memory", "memory limit exceeded"},
+		checks:     []string{"kubectl logs {ref} -n {namespace} --previous --tail=50"},
+		explains:   []string{"crashloop"},
+		advice:     "Raise the memory limit or fix the leak that exhausts it.",
+		fix:        "kubectl set resources deployment/{deployment} -n {namespace} --limits=memory=<new-limit>",
+	},
+	{
+		id: "scheduling", cause: "Pod cannot be scheduled", category: "scheduling",
+		kinds: podKinds, pattern: "failed-scheduling", prior: 0.25,
+		indicators: []string{"failedscheduling", "insufficient cpu", "insufficient memory", "didn't match", "untolerated taint", "unschedulable"},
+		checks:     []string{"kubectl get nodes"},
+		advice:     "Lower the resource requests, add node capacity, or relax node selectors, affinity and tolerations.",
+		fix:        "kubectl set resources deployment/{deployment} -n {namespace} --requests=cpu=<cpu>,memory=<memory>",
+	},
+	{
+		id: "probe", cause: "Liveness or readiness probe fails", category: "health-checks",
+		kinds: append([]string{"service"}, podKinds...), prior: 0.2,
+		indicators: []string{"readiness probe failed", "liveness probe failed", "unhealthy", "notreadyaddresses"},
+		checks:     []string{"kubectl get events -n {namespace} --field-selector reason=Unhealthy"},
+		explains:   []string{"crashloop"},
+		advice:     "Check the probe's path, port and initialDelaySeconds against what the container serves.",
+	},
+	{
+		id: "missing-config", cause: "Referenced ConfigMap or Secret is missing", category: "configuration",
+		kinds: podKinds, prior: 0.2,
+		indicators: []string{"createcontainerconfigerror", "configmap \"", "secret \"", "failedmount"},
+		checks:     []string{"kubectl get events -n {namespace} --field-selector reason=FailedMount"},
+		advice:     "Create the missing ConfigMap or Secret, or fix the reference in the pod spec.",
+	},
+	{
+		id: "pvc-pending", cause: "PersistentVolumeClaim is not bound", category: "storage",
+		kinds: append([]string{"pvc"}, podKinds...), prior: 0.15,
+		indicators: []string{"unbound immediate persistentvolumeclaims", "waiting for first consumer", "no persistent volumes available", "provisioningfailed", "storageclass.storage.k8s.io"},
+		checks:     []string{"kubectl get pvc -n {namespace}"},
+		advice:     "Check the claim's storageClassName and that a provisioner or matching PersistentVolume exists.",
+	},
+	{
+		id: "selector-mismatch", cause: "Service selector matches no ready pods", category: "networking",
+		kinds: []string{"service"}, prior: 0.35,
+		indicators: []string{"<none>"},
+		checks:     []string{"kubectl get pods -n {namespace} --show-labels"},
+		advice:     "Make the Service selector match the pod labels, or fix the pods so they become ready.",
+		fix:        "kubectl patch service {name} -n {namespace} -p '{\"spec\":{\"selector\":{\"app\":\"<label>\"}}}'",
+	},
+}
+
+// triageCheck is a broad command run first; it tests every applicable hypothesis unless only is set
+type triageCheck struct {
+	command string
+	only    []string
+}
+
+var triageChecks = map[string][]triageCheck{
+	"pod": {
+		{command: "kubectl describe pod {name} -n {namespace}"},
+		{command: "kubectl get events -n {namespace} --field-selector involvedObject.name={name}"},
+	},
+	"deployment": {
+		{command: "kubectl get pods -n {namespace}"},
+		{command: "kubectl get events -n {namespace} --sort-by=.lastTimestamp"},
+	},
+	"service": {
+		{command: "kubectl get endpoints {name} -n {namespace}", only: []string{"selector-mismatch"}},
+		{command: "kubectl get events -n {namespace} --sort-by=.lastTimestamp", only: []string{"probe"}},
+	},
+	"pvc": {
+		{command: "kubectl describe pvc {name} -n {namespace}"},
+	},
+	"": {
+		{command: "kubectl get pods -n {namespace}"},
+		{command: "kubectl get events -n {namespace} --sort-by=.lastTimestamp"},
+	},
+}
+
+// Hypothesis is a candidate root cause ranked by confidence as evidence arrives
+type Hypothesis struct {
+	ID          string
+	Cause       string
+	Category    string
+	Confidence  float64
+	Evidence    []string // "command: matching line"
+	RuledOut    bool
+	ExplainedBy string // set when ruled out as a symptom of another hypothesis
+	Advice      string
+	Fix         string
+
+	checks     []string
+	indicators []string
+	explains   []string
+	pattern    string
+}
+
+// InvestigationStep is one planned or executed check
+type InvestigationStep struct {
+	Wave     int
+	Command  string
+	Tests    []string // hypothesis IDs this check can confirm or refute
+	Output   string
+	Error    string
+	Tokens   int
+	Duration time.Duration
+}
+
+// InvestigationFinding is the structured result of an investigation
+type InvestigationFinding struct {
+	RootCause    string
+	Category     string
+	Confidence   float64
+	Evidence     []string
+	Advice       string
+	FixCommand   string
+	Fix          *validator.PreExecPlan // nil when there is no fix command
+	Alternatives []string               // other hypotheses that were not ruled out
+	StopReason   string
+}
+
+// Investigation is a plan-then-act diagnosis of one question
+type Investigation struct {
+	Question   string
+	Target     InvestigationTarget
+	Budget     InvestigationBudget
+	Hypotheses []*Hypothesis
+	Steps      []InvestigationStep // executed steps, in order
+	Finding    *InvestigationFinding
+
+	runner       execx.Runner
+	knowledge    *PlaybookLibrary
+	observations []string // the question and context summary the plan was ranked on
+	tokens       int
+	started      time.Time
+}
+
+// Investigator plans and runs investigations with read-only commands
+type Investigator struct {
+	runner    execx.Runner
+	knowledge *PlaybookLibrary
+	Budget    InvestigationBudget
+}
+
+// NewInvestigator creates an investigator; knowledge may be nil
+func NewInvestigator(runner execx.Runner, knowledge *PlaybookLibrary, budget InvestigationBudget) *Investigator {
+	defaults := DefaultInvestigationBudget()
+	if budget.MaxSteps <= 0 {
+		budget.MaxSteps = defaults.MaxSteps
+	}
+	if budget.MaxDuration <= 0 {
+		budget.MaxDuration = defaults.MaxDuration
+	}
+	if budget.MaxOutputTokens <= 0 {
+		budget.MaxOutputTokens = defaults.MaxOutputTokens
+	}
+	if budget.Parallelism <= 0 {
+		budget.Parallelism = defaults.Parallelism
+	}
+	if budget.StepTimeout <= 0 {
+		budget.StepTimeout = defaults.StepTimeout
+	}
+	return &Investigator{runner: runner, knowledge: knowledge, Budget: budget}
+}
+
+// Plan ranks the hypotheses that apply to the target. Priors are raised for causes the
+// playbook library already detects in the question or the cluster context summary.
+func (iv *Investigator) Plan(question string, target InvestigationTarget, contextSummary string) *Investigation {
+	observations := []string{question}
+	if contextSummary != "" {
+		observations = append(observations, contextSummary)
+	}
+	patternConfidence := make(map[string]float64)
+	if iv.knowledge != nil {
+		for _, analysis := range iv.knowledge.RankRootCauses(observations) {
+			patternConfidence[analysis.PatternID] = analysis.Confidence
+		}
+	}
+	questionLower := normalizeEvidence(question)
+
+	inv := &Investigation{Question: question, Target: target, Budget: iv.Budget, runner: iv.runner, knowledge: iv.knowledge, observations: observations}
+	for _, spec := range hypothesisCatalog {
+		if !containsString(spec.kinds, target.Kind) {
+			continue
+		}
+		h := &Hypothesis{
+			ID:         spec.id,
+			Cause:      spec.cause,
+			Category:   spec.category,
+			Confidence: spec.prior + 0.4*patternConfidence[spec.pattern],
+			Advice:     spec.advice,
+			indicators: spec.indicators,
+			explains:   spec.explains,
+			pattern:    spec.pattern,
+		}
+		if matchIndicator(questionLower, spec.indicators) != "" {
+			h.Confidence += 0.15
+		}
+		// Priors alone never confirm a hypothesis
+		h.Confidence = math.Min(h.Confidence, 0.8)
+		if spec.fix != "" {
+			h.Fix = target.render(spec.fix)
+		}
+		for _, check := range spec.checks {
+			if target.Kind == "" && strings.Contains(check, "{ref}") {
+				continue
+			}
+			h.checks = append(h.checks, target.render(check))
+		}
+		inv.Hypotheses = append(inv.Hypotheses, h)
+	}
+	inv.rank()
+	return inv
+}
+
+// render fills {name}, {namespace}, {ref} and {deployment} in a command template
+func (t InvestigationTarget) render(template string) string {
+	ref := t.Kind + "/" + t.Name
+	deployment := "<deployment>"
+	switch t.Kind {
+	case "deployment":
+		deployment = t.Name
+	case "pod":
+		if m := reReplicaSetPod.FindStringSubmatch(t.Name); m != nil {
+			deployment = m[1]
+		}
+	}
+	return strings.NewReplacer(
+		"{name}", t.Name,
+		"{namespace}", t.Namespace,
+		"{ref}", ref,
+		"{deployment}", deployment,
+	).Replace(template)
+}
+
+func (inv *Investigation) rank() {
+	sort.SliceStable(inv.Hypotheses, func(i, j int) bool {
+		if inv.Hypotheses[i].RuledOut != inv.Hypotheses[j].RuledOut {
+			return !inv.Hypotheses[i].RuledOut
+		}
+		return inv.Hypotheses[i].Confidence > inv.Hypotheses[j].Confidence
+	})
+}
+
+// NextWave returns the checks to run next: triage first, then each plausible hypothesis's
+// follow-ups in rank order. Commands already run or shared by several hypotheses run once.
+func (inv *Investigation) NextWave() []InvestigationStep {
+	wave := 0
+	if len(inv.Steps) > 0 {
+		wave = inv.Steps[len(inv.Steps)-1].Wave + 1
+	}
+	ran := make(map[string]bool)
+	for _, step := range inv.Steps {
+		ran[step.Command] = true
+	}
+
+	var steps []InvestigationStep
+	index := make(map[string]int)
+	add := func(command, hypothesis string) {
+		if ran[command] {
+			return
+		}
+		if i, ok := index[command]; ok {
+			steps[i].Tests = append(steps[i].Tests, hypothesis)
+			return
+		}
+		index[command] = len(steps)
+		steps = append(steps, InvestigationStep{Wave: wave, Command: command, Tests: []string{hypothesis}})
+	}
+
+	var live []*Hypothesis
+	for _, h := range inv.Hypotheses {
+		if !h.RuledOut {
+			live = append(live, h)
+		}
+	}
+	if wave == 0 {
+		for _, check := range triageChecks[inv.Target.Kind] {
+			for _, h := range live {
+				if len(check.only) == 0 || containsString(check.only, h.ID) {
+					add(inv.Target.render(check.command), h.ID)
+				}
+			}
+		}
+		return steps
+	}
+	for _, h := range live {
+		for _, check := range h.checks {
+			if !ran[check] {
+				add(check, h.ID)
+				break
+			}
+		}
+	}
+	return steps
+}
+
+// Run executes waves of checks until a hypothesis is confirmed, every hypothesis is ruled
+// out, no checks remain or a budget runs out. onWave, if set, sees each finished wave.
+func (inv *Investigation) Run(ctx context.Context, onWave func([]InvestigationStep)) *InvestigationFinding {
+	inv.started = time.Now()
+	ctx, cancel := context.WithTimeout(ctx, inv.Budget.MaxDuration)
+	defer cancel()
+	inv.checkConditions(ctx)
+
+	reason := ""
+	for reason == "" {
+		wave := inv.NextWave()
+		switch {
+		case inv.confirmed() != nil:
+			reason = "confirmed"
+		case inv.allRuledOut():
+			reason = "all hypotheses ruled out"
+		case len(wave) == 0:
+			reason = "no checks left"
+		case len(inv.Steps) >= inv.Budget.MaxSteps:
+			reason = fmt.Sprintf("step budget (%d) used", inv.Budget.MaxSteps)
+		case inv.tokens >= inv.Budget.MaxOutputTokens:
+			reason = fmt.Sprintf("output token budget (%d) used", inv.Budget.MaxOutputTokens)
+		case ctx.Err() != nil:
+			reason = inv.contextReason(ctx)
+		}
+		if reason != "" {
+			break
+		}
+
+		if remaining := inv.Budget.MaxSteps - len(inv.Steps); len(wave) > remaining {
+			wave = wave[:remaining]
+		}
+		inv.runWave(ctx, wave)
+		inv.observe(wave)
+		inv.Steps = append(inv.Steps, wave...)
+		inv.rank()
+		if onWave != nil {
+			onWave(wave)
+		}
+		if ctx.Err() != nil {
+			reason = inv.contextReason(ctx)
+		}
+	}
+
+	inv.Finding = inv.conclude(reason)
+	return inv.Finding
+}
+
+// checkConditions fetches the target as JSON and supports the hypotheses whose playbook
+// pattern conditions all hold for it, e.g. an OOMKilled last state with restarts
+func (inv *Investigation) checkConditions(ctx context.Context) {
+	if inv.knowledge == nil || inv.Target.Kind == "" {
+		return
+	}
+	command := inv.Target.render("kubectl get {ref} -n {namespace} -o json")
+	fetchCtx, cancel := context.WithTimeout(ctx, inv.Budget.StepTimeout)
+	defer cancel()
+	obj, output, err := FetchObject(fetchCtx, inv.runner, command)
+	inv.tokens += promptbudget.EstimateTokens(output)
+	if err != nil {
+		return
+	}
+
+	for _, analysis := range inv.knowledge.RankRootCausesWithObjects(inv.observations, []interface{}{obj}) {
+		// Indicators in the observations already raised the priors; only the object adds support
+		if !analysis.Structural {
+			continue
+		}
+		for _, h := range inv.Hypotheses {
+			if h.RuledOut || h.pattern == "" || h.pattern != analysis.PatternID {
+				continue
+			}
+			h.Confidence += (1 - h.Confidence) * 0.6
+			h.Evidence = append(h.Evidence, command+": "+strings.Join(analysis.Indicators, " and "))
+		}
+	}
+	inv.rank()
+}
+
+func (inv *Investigation) contextReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Sprintf("time budget (%s) used", inv.Budget.MaxDuration)
+	}
+	return "cancelled"
+}
+
+// runWave runs independent checks in parallel, at most Budget.Parallelism at a time
+func (inv *Investigation) runWave(ctx context.Context, wave []InvestigationStep) {
+	parallelism := inv.Budget.Parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := range wave {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(step *InvestigationStep) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			inv.runStep(ctx, step)
+		}(&wave[i])
+	}
+	wg.Wait()
+}
+
+func (inv *Investigation) runStep(ctx context.Context, step *InvestigationStep) {
+	started := time.Now()
+	defer func() { step.Duration = time.Since(started) }()
+
+	if !IsWhitelistedAction(step.Command) {
+		step.Error = "not a whitelisted read-only command"
+		return
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, inv.Budget.StepTimeout)
+	defer cancel()
+	fields := strings.Fields(step.Command)
+	stdout, stderr, err := inv.runner.Run(stepCtx, fields[0], fields[1:]...)
+	output := stdout
+	if err != nil {
+		step.Error = strings.TrimSpace(stderr)
+		if step.Error == "" {
+			step.Error = err.Error()
+		}
+		output += stderr
+	}
+	if len(output) > maxStepOutput {
+		output = "(…truncated…)\n" + output[len(output)-maxStepOutput:]
+	}
+	step.Output = output
+	step.Tokens = promptbudget.EstimateTokens(output)
+}
+
+// observe updates every hypothesis the wave tests: each check showing its indicators
+// supports it, and when none does, each successful check without them weakens it.
+// Failed checks prove nothing.
+func (inv *Investigation) observe(wave []InvestigationStep) {
+	for _, step := range wave {
+		inv.tokens += step.Tokens
+	}
+	for _, h := range inv.Hypotheses {
+		if h.RuledOut {
+			continue
+		}
+		misses := 0
+		supported := false
+		for _, step := range wave {
+			if step.Error != "" || !containsString(step.Tests, h.ID) {
+				continue
+			}
+			indicator := matchIndicator(normalizeEvidence(step.Output), h.indicators)
+			if indicator == "" {
+				misses++
+				continue
+			}
+			supported = true
+			h.Confidence += (1 - h.Confidence) * 0.6
+			h.Evidence = append(h.Evidence, step.Command+": "+evidenceLine(step.Output, indicator))
+		}
+		if !supported && misses > 0 {
+			h.Confidence *= math.Pow(0.5, float64(misses))
+			h.RuledOut = h.Confidence < hypothesisRuledOut
+		}
+	}
+
+	// A confirmed cause rules out the symptoms it explains
+	for _, h := range inv.Hypotheses {
+		if h.RuledOut || len(h.Evidence) == 0 {
+			continue
+		}
+		for _, other := range inv.Hypotheses {
+			if containsString(h.explains, other.ID) && !other.RuledOut {
+				other.RuledOut, other.ExplainedBy = true, h.Cause
+			}
+		}
+	}
+}
+
+func (inv *Investigation) confirmed() *Hypothesis {
+	for _, h := range inv.Hypotheses {
+		if !h.RuledOut && h.Confidence >= hypothesisConfirmed {
+			return h
+		}
+	}
+	return nil
+}
+
+func (inv *Investigation) allRuledOut() bool {
+	for _, h := range inv.Hypotheses {
+		if !h.RuledOut {
+			return false
+		}
+	}
+	return true
+}
+
+// conclude turns the best surviving hypothesis into a finding with a fix plan
+func (inv *Investigation) conclude(reason string) *InvestigationFinding {
+	finding := &InvestigationFinding{RootCause: "Undetermined", Category: "unknown", StopReason: reason}
+	var best *Hypothesis
+	for _, h := range inv.Hypotheses {
+		if h.RuledOut {
+			continue
+		}
+		if best == nil {
+			best = h
+			continue
+		}
+		finding.Alternatives = append(finding.Alternatives, fmt.Sprintf("%s (%.0f%%)", h.Cause, h.Confidence*100))
+	}
+	if best == nil || best.Confidence < hypothesisUndetermined || len(best.Evidence) == 0 {
+		if best != nil {
+			finding.Confidence = best.Confidence
+			finding.Alternatives = append([]string{fmt.Sprintf("%s (%.0f%%)", best.Cause, best.Confidence*100)}, finding.Alternatives...)
+		}
+		finding.Advice = "No check produced evidence for a known cause. Review the step outputs, or widen the question."
+		return finding
+	}
+
+	finding.RootCause = best.Cause
+	finding.Category = best.Category
+	finding.Confidence = best.Confidence
+	finding.Evidence = append([]string(nil), best.Evidence...)
+	finding.Advice = best.Advice
+	if best.Fix != "" {
+		// The fix was chosen from the steps' output, so instruction-like text there taints it
+		outputs := make([]string, 0, len(inv.Steps))
+		for _, step := range inv.Steps {
+			outputs = append(outputs, step.Output)
+		}
+		plan := validator.BuildPreExecPlanTainted(best.Fix, outputs...)
+		finding.FixCommand = best.Fix
+		finding.Fix = &plan
+	}
+	return finding
+}
+
+// Tokens returns the estimated tokens of cluster output read so far
+func (inv *Investigation) Tokens() int {
+	return inv.tokens
+}
+
+// RenderPlan describes the ranked hypotheses and the first wave of checks
+func (inv *Investigation) RenderPlan() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧭 Investigation plan for %s\n", inv.Target)
+	sb.WriteString("Hypotheses:\n")
+	for i, h := range inv.Hypotheses {
+		fmt.Fprintf(&sb, "  %d. %s (%.0f%%)\n", i+1, h.Cause, h.Confidence*100)
+	}
+	sb.WriteString("First checks (in parallel):\n")
+	for _, step := range inv.NextWave() {
+		fmt.Fprintf(&sb, "  $ %s\n", step.Command)
+	}
+	fmt.Fprintf(&sb, "Budget: %d steps, %s, %d output tokens, %d at a time",
+		inv.Budget.MaxSteps, inv.Budget.MaxDuration, inv.Budget.MaxOutputTokens, inv.Budget.Parallelism)
+	return sb.String()
+}
+
+// RenderWave summarizes a finished wave and the hypotheses still standing
+func (inv *Investigation) RenderWave(wave []InvestigationStep) string {
+	var sb strings.Builder
+	for _, step := range wave {
+		status := "✅"
+		if step.Error != "" {
+			status = "⚠️ " + firstLine(step.Error)
+		}
+		fmt.Fprintf(&sb, "$ %s  %s\n", step.Command, status)
+	}
+	var standing, pruned []string
+	for _, h := range inv.Hypotheses {
+		switch {
+		case h.ExplainedBy != "":
+			pruned = append(pruned, fmt.Sprintf("%s (symptom of %s)", h.Cause, h.ExplainedBy))
+		case h.RuledOut:
+			pruned = append(pruned, h.Cause)
+		default:
+			standing = append(standing, fmt.Sprintf("%s %.0f%%", h.Cause, h.Confidence*100))
+		}
+	}
+	if len(standing) > 0 {
+		sb.WriteString("Standing: " + strings.Join(standing, ", ") + "\n")
+	}
+	if len(pruned) > 0 {
+		sb.WriteString("Ruled out: " + strings.Join(pruned, ", ") + "\n")
+	}
+	fmt.Fprintf(&sb, "Used %d/%d steps, %d/%d output tokens, %s", len(inv.Steps), inv.Budget.MaxSteps,
+		inv.tokens, inv.Budget.MaxOutputTokens, time.Since(inv.started).Round(time.Second))
+	return sb.String()
+}
+
+// Render formats the finding for the chat pane
+func (f *InvestigationFinding) Render() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🔎 Finding: %s (%.0f%% confidence, stopped: %s)\n", f.RootCause, f.Confidence*100, f.StopReason)
+	if len(f.Evidence) > 0 {
+		sb.WriteString("Evidence:\n")
+		for _, e := range f.Evidence {
+			sb.WriteString("  • " + e + "\n")
+		}
+	}
+	if len(f.Alternatives) > 0 {
+		sb.WriteString("Not ruled out: " + strings.Join(f.Alternatives, ", ") + "\n")
+	}
+	if f.Advice != "" {
+		sb.WriteString("Advice: " + f.Advice + "\n")
+	}
+	if f.Fix != nil {
+		fmt.Fprintf(&sb, "Proposed fix (%s risk): %s", f.Fix.DangerLevel, f.FixCommand)
+		if strings.Contains(f.FixCommand, "<") {
+			sb.WriteString("\nReplace the <placeholders> before running it.")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func matchIndicator(lowerText string, indicators []string) string {
+	for _, indicator := range indicators {
+		if strings.Contains(lowerText, indicator) {
+			return indicator
+		}
+	}
+	return ""
+}
+
+// normalizeEvidence lower-cases text and collapses whitespace so "Exit Code:    137" matches "exit code: 137"
+func normalizeEvidence(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// evidenceLine returns the output line containing the indicator, trimmed for display
+func evidenceLine(output, indicator string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(normalizeEvidence(line), indicator) {
+			line = strings.Join(strings.Fields(line), " ")
+			if len(line) > maxEvidenceLine {
+				line = line[:maxEvidenceLine] + "…"
+			}
+			return line
+		}
+	}
+	return indicator
+}
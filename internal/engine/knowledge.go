@@ -2,14 +2,23 @@
 package engine
 
 import (
+	"math"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // PlaybookLibrary manages curated diagnostic and resolution playbooks
 type PlaybookLibrary struct {
+	mu        sync.RWMutex
 	playbooks map[string]*Playbook
 	patterns  map[string]*RootCausePattern
+
+	// Playbook directories and what was loaded from them, see playbook_files.go
+	dirs        []string
+	origins     map[string]string // playbook or pattern ID -> "built-in" or file path
+	fingerprint string
+	loadErrs    []error
 }
 
 // Playbook defines systematic approaches to common Kubernetes issues
@@ -51,9 +60,10 @@ type RootCausePattern struct {
 	Severity   string           `json:"severity"`   // "low", "medium", "high", "critical"
 }
 
+// ConditionCheck is evaluated against live objects, see conditions.go
 type ConditionCheck struct {
 	Field    string      `json:"field"`    // JSON path or field name
-	Operator string      `json:"operator"` // "equals", "contains", "greater_than", etc.
+	Operator string      `json:"operator"` // "equals", "contains", "greater_than", "exists", "regex", etc.
 	Value    interface{} `json:"value"`    // Expected value
 }
 
@@ -67,11 +77,13 @@ type Solution struct {
 
 // RootCauseAnalysis represents the result of intelligent analysis
 type RootCauseAnalysis struct {
+	PatternID      string           `json:"pattern_id"`
 	RootCause      string           `json:"root_cause"`
 	Confidence     float64          `json:"confidence"`
 	Category       string           `json:"category"`
 	Severity       string           `json:"severity"`
 	Indicators     []string         `json:"indicators"`
+	Structural     bool             `json:"structural,omitempty"` // the pattern's conditions held for a live object
 	NextSteps      []ActionableStep `json:"next_steps"`
 	RelatedIssues  []string         `json:"related_issues"`
 	Timeline       string           `json:"timeline"` // Expected fix time
@@ -89,6 +101,14 @@ func NewPlaybookLibrary() *PlaybookLibrary {
 	return library
 }
 
+// snapshot returns the current playbooks and patterns. A reload replaces the maps
+// rather than changing them, so callers may read them without holding the lock.
+func (pl *PlaybookLibrary) snapshot() (map[string]*Playbook, map[string]*RootCausePattern) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return pl.playbooks, pl.patterns
+}
+
 // loadBuiltinPlaybooks loads curated playbooks for common issues
 func (pl *PlaybookLibrary) loadBuiltinPlaybooks() {
 	// Pod Not Ready Playbook
@@ -306,6 +326,9 @@ func (pl *PlaybookLibrary) loadRootCausePatterns() {
 			"imagepullbackoff", "errimagepull", "failed to pull image",
 			"image not found", "unauthorized", "pull access denied",
 		},
+		Conditions: []ConditionCheck{
+			{Field: "status.containerStatuses[*].state.waiting.reason", Operator: OpRegex, Value: "^(ErrImagePull|ImagePullBackOff|InvalidImageName)$"},
+		},
 		Solutions: []Solution{
 			{
 				Title:         "Verify Image Name and Tag",
@@ -334,6 +357,10 @@ func (pl *PlaybookLibrary) loadRootCausePatterns() {
 			"oomkilled", "out of memory", "killed", "exit code 137",
 			"memory limit exceeded",
 		},
+		Conditions: []ConditionCheck{
+			{Field: "status.containerStatuses[*].lastState.terminated.reason", Operator: OpEquals, Value: "OOMKilled"},
+			{Field: "status.containerStatuses[*].restartCount", Operator: OpGreaterThan, Value: 0},
+		},
 		Solutions: []Solution{
 			{
 				Title:         "Increase Memory Limits",
@@ -362,6 +389,9 @@ func (pl *PlaybookLibrary) loadRootCausePatterns() {
 			"failedscheduling", "insufficient", "no nodes available",
 			"unschedulable", "taints", "affinity",
 		},
+		Conditions: []ConditionCheck{
+			{Field: "status.conditions[*].reason", Operator: OpEquals, Value: "Unschedulable"},
+		},
 		Solutions: []Solution{
 			{
 				Title:         "Check Node Resources",
@@ -385,13 +415,54 @@ func (pl *PlaybookLibrary) loadRootCausePatterns() {
 
 // DetectRootCause performs intelligent analysis of observations
 func (pl *PlaybookLibrary) DetectRootCause(observations []string) *RootCauseAnalysis {
-	candidates := make(map[string]float64)
-	indicators := make(map[string][]string)
+	if ranked := pl.RankRootCauses(observations); len(ranked) > 0 {
+		return ranked[0]
+	}
+	return &RootCauseAnalysis{
+		RootCause:  "Unknown",
+		Confidence: 0.0,
+		Category:   "unknown",
+		Severity:   "unknown",
+		NextSteps:  []ActionableStep{},
+	}
+}
+
+// AnalyzeRootCause asks the model for the root cause and falls back to the playbooks
+// when no model is set, the structured call fails or the model names no cause
+func (pl *PlaybookLibrary) AnalyzeRootCause(observations []string, model string) *RootCauseAnalysis {
+	if model != "" && len(observations) > 0 {
+		analysis, err := AnalyzeRootCauseStructured(observations, model)
+		if err == nil && strings.TrimSpace(analysis.RootCause) != "" {
+			return analysis
+		}
+	}
+	return pl.DetectRootCause(observations)
+}
+
+// RankRootCauses scores every pattern against the observations, best match first
+func (pl *PlaybookLibrary) RankRootCauses(observations []string) []*RootCauseAnalysis {
+	return pl.RankRootCausesWithObjects(observations, nil)
+}
+
+// RankRootCausesWithObjects also evaluates each pattern's conditions against live objects
+// decoded from `kubectl get -o json`. A pattern whose conditions all hold for one object
+// scores as much as two indicator matches, so structural evidence alone reaches the
+// pattern's confidence and indicators on top of it raise it further.
+func (pl *PlaybookLibrary) RankRootCausesWithObjects(observations []string, objects []interface{}) []*RootCauseAnalysis {
+	type candidate struct {
+		name       string
+		score      float64
+		indicators []string
+		structural bool
+	}
+	var candidates []candidate
+	_, patterns := pl.snapshot()
 
 	// Score each pattern against observations
-	for patternName, pattern := range pl.patterns {
+	for patternName, pattern := range patterns {
 		score := 0.0
 		matched := []string{}
+		structural := false
 
 		for _, observation := range observations {
 			obsLower := strings.ToLower(observation)
@@ -403,40 +474,49 @@ func (pl *PlaybookLibrary) DetectRootCause(observations []string) *RootCauseAnal
 			}
 		}
 
-		if score > 0 {
-			candidates[patternName] = score
-			indicators[patternName] = matched
+		if len(pattern.Conditions) > 0 {
+			for _, obj := range objects {
+				if conditionsMatch(obj, pattern.Conditions) {
+					score += 2 * pattern.Confidence
+					structural = true
+					for _, c := range pattern.Conditions {
+						matched = append(matched, c.String())
+					}
+					break
+				}
+			}
 		}
-	}
 
-	// Find best match
-	var bestPattern string
-	var bestScore float64
-	for pattern, score := range candidates {
-		if score > bestScore {
-			bestScore = score
-			bestPattern = pattern
+		if score > 0 {
+			candidates = append(candidates, candidate{patternName, score, matched, structural})
 		}
 	}
 
-	if bestPattern == "" {
-		return &RootCauseAnalysis{
-			RootCause:  "Unknown",
-			Confidence: 0.0,
-			Category:   "unknown",
-			Severity:   "unknown",
-			NextSteps:  []ActionableStep{},
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
 		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	analyses := make([]*RootCauseAnalysis, 0, len(candidates))
+	for _, c := range candidates {
+		analysis := pl.analyzePattern(patterns[c.name], c.score, c.indicators)
+		analysis.PatternID = c.name
+		analysis.Structural = c.structural
+		analyses = append(analyses, analysis)
 	}
+	return analyses
+}
 
-	// Build analysis from best match
-	pattern := pl.patterns[bestPattern]
+// analyzePattern builds the analysis for a matched pattern
+func (pl *PlaybookLibrary) analyzePattern(pattern *RootCausePattern, score float64, indicators []string) *RootCauseAnalysis {
 	analysis := &RootCauseAnalysis{
 		RootCause:      pattern.Name,
-		Confidence:     min(bestScore/2.0, 1.0), // Normalize confidence
+		Confidence:     math.Min(score/2.0, 1.0), // Normalize confidence
 		Category:       pattern.Category,
 		Severity:       pattern.Severity,
-		Indicators:     indicators[bestPattern],
+		Indicators:     indicators,
 		Timeline:       pl.estimateFixTime(pattern),
 		PreventionTips: pl.getPreventionTips(pattern),
 	}
@@ -473,8 +553,9 @@ func (pl *PlaybookLibrary) GetPlaybook(issue string) *Playbook {
 	// Find best matching playbook
 	var bestMatch *Playbook
 	var bestScore float64
+	playbooks, _ := pl.snapshot()
 
-	for _, playbook := range pl.playbooks {
+	for _, playbook := range playbooks {
 		score := 0.0
 		for _, trigger := range playbook.Triggers {
 			if strings.Contains(issueLower, strings.ToLower(trigger)) {
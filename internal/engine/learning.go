@@ -5,16 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-// FlightRecorder logs interactions and outcomes for learning
+// FlightRecorder logs interactions and outcomes for learning. It is safe for concurrent
+// use: the retriever reads resolved sessions in the background while the UI records them.
 type FlightRecorder struct {
+	mu           sync.Mutex
 	sessions     []SessionRecord
 	storage      string
 	maxSessions  int
@@ -60,7 +64,7 @@ type SuggestionRecord struct {
 }
 
 type UserActionRecord struct {
-	Action     string    `json:"action"` // "accept", "reject", "modify", "cancel"
+	Action     string    `json:"action"` // "accept", "reject", "modify", "cancel", "rerun", "rate"
 	Target     string    `json:"target"` // What was acted upon
 	Timestamp  time.Time `json:"timestamp"`
 	ModifiedTo string    `json:"modified_to"` // If user modified the suggestion
@@ -126,6 +130,8 @@ type UserPreferenceLearning struct {
 	AutomationLevel     string            `json:"automation_level"` // "manual", "assisted", "automated"
 	PreferredNamespaces []string          `json:"preferred_namespaces"`
 	CommonCommands      []CommandPattern  `json:"common_commands"`
+	AvgSatisfaction     float64           `json:"avg_satisfaction"` // running mean of 1-5 ratings
+	RatedSessions       int               `json:"rated_sessions"`
 }
 
 // CommandPattern is now defined in types.go
@@ -174,8 +180,11 @@ func NewFlightRecorder(storagePath string) *FlightRecorder {
 
 // RecordSession logs a complete interaction session
 func (fr *FlightRecorder) RecordSession(session SessionRecord) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
 	session.Timestamp = time.Now()
-	session.ID = fmt.Sprintf("session-%d", session.Timestamp.Unix())
+	// Nanoseconds keep two sessions recorded in the same second apart in the index
+	session.ID = fmt.Sprintf("session-%d", session.Timestamp.UnixNano())
 
 	// Add to sessions
 	fr.sessions = append(fr.sessions, session)
@@ -190,7 +199,7 @@ func (fr *FlightRecorder) RecordSession(session SessionRecord) error {
 
 	// Auto-save if enabled
 	if fr.autoSave && time.Since(fr.lastSaved) > 5*time.Minute {
-		if err := fr.Save(); err != nil {
+		if err := fr.save(); err != nil {
 			return fmt.Errorf("failed to auto-save: %w", err)
 		}
 	}
@@ -198,6 +207,19 @@ func (fr *FlightRecorder) RecordSession(session SessionRecord) error {
 	return nil
 }
 
+// ResolvedSessions returns the recorded sessions that ended in success
+func (fr *FlightRecorder) ResolvedSessions() []SessionRecord {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	resolved := make([]SessionRecord, 0)
+	for _, session := range fr.sessions {
+		if session.Outcome == "success" {
+			resolved = append(resolved, session)
+		}
+	}
+	return resolved
+}
+
 // updateLearningData extracts learning insights from new session
 func (fr *FlightRecorder) updateLearningData(session SessionRecord) {
 	// Update intent accuracy
@@ -229,6 +251,9 @@ func (fr *FlightRecorder) updateLearningData(session SessionRecord) {
 		}
 	}
 
+	// Learn from ratings, rejections and edits
+	fr.learnFromFeedback(session)
+
 	// Learn from failures
 	if session.Outcome == "failure" && len(session.ErrorMessages) > 0 {
 		fr.learnFromFailure(session)
@@ -295,6 +320,34 @@ func (fr *FlightRecorder) learnFromFailure(session SessionRecord) {
 	fr.learningData.FailurePatterns = append(fr.learningData.FailurePatterns, pattern)
 }
 
+// RecordHallucination stores a command guard miss as a failure pattern
+func (fr *FlightRecorder) RecordHallucination(command string, issue GuardIssue) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	errorType := "hallucinated-" + issue.Kind
+	for i, existing := range fr.learningData.FailurePatterns {
+		if existing.ErrorType == errorType && existing.Context == issue.Value {
+			fr.learningData.FailurePatterns[i].Frequency++
+			if len(existing.CommonCauses) < 5 && !containsString(existing.CommonCauses, command) {
+				fr.learningData.FailurePatterns[i].CommonCauses = append(existing.CommonCauses, command)
+			}
+			return
+		}
+	}
+
+	pattern := FailurePattern{
+		ErrorType:    errorType,
+		Context:      issue.Value,
+		Frequency:    1,
+		CommonCauses: []string{command},
+	}
+	if issue.Suggestion != "" {
+		pattern.SuccessfulFixes = []string{issue.Suggestion}
+	}
+	fr.learningData.FailurePatterns = append(fr.learningData.FailurePatterns, pattern)
+	fr.learningData.LastUpdated = time.Now()
+}
+
 // updatePerformanceMetrics updates aggregate performance statistics
 func (fr *FlightRecorder) updatePerformanceMetrics(session SessionRecord) {
 	metrics := &fr.learningData.PerformanceMetrics
@@ -329,6 +382,8 @@ func (fr *FlightRecorder) updatePerformanceMetrics(session SessionRecord) {
 
 // ExportTrainingData generates training examples from recorded sessions
 func (fr *FlightRecorder) ExportTrainingData() []TrainingExample {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
 	var examples []TrainingExample
 
 	for _, session := range fr.sessions {
@@ -341,7 +396,7 @@ func (fr *FlightRecorder) ExportTrainingData() []TrainingExample {
 			if suggestion.Accepted && suggestion.Applied {
 				example := TrainingExample{
 					Input:   session.UserInput,
-					Output:  suggestion.Content,
+					Output:  session.AcceptedContent(suggestion),
 					Quality: fr.calculateQuality(session, suggestion),
 					Type:    suggestion.Type,
 					Context: fr.buildContext(session.Context),
@@ -399,6 +454,12 @@ func (fr *FlightRecorder) GenerateLoRATrainingSet() *LoRADataset {
 
 // Save persists flight recorder data to disk
 func (fr *FlightRecorder) Save() error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	return fr.save()
+}
+
+func (fr *FlightRecorder) save() error {
 	if err := os.MkdirAll(fr.storage, 0755); err != nil {
 		return fmt.Errorf("failed to create storage directory: %w", err)
 	}
@@ -425,6 +486,8 @@ func (fr *FlightRecorder) Save() error {
 
 // Load restores flight recorder data from disk
 func (fr *FlightRecorder) Load() error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
 	// Load sessions
 	sessionsFile := filepath.Join(fr.storage, "sessions.json")
 	if data, err := ioutil.ReadFile(sessionsFile); err == nil {
@@ -504,7 +567,10 @@ func (fr *FlightRecorder) calculateQuality(session SessionRecord, suggestion Sug
 		}
 	}
 
-	return min(quality, 1.0)
+	// Low ratings, rejections, edits and reruns after failure
+	quality += feedbackAdjustment(session, suggestion)
+
+	return math.Max(0, math.Min(quality, 1.0))
 }
 
 func (fr *FlightRecorder) buildContext(context *KubeContextSummary) map[string]string {
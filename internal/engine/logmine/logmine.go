@@ -0,0 +1,306 @@
+// Package logmine clusters log lines into templates, Drain-style, so that thousands of
+// lines can be shown and sent to the model as a few patterns with counts.
+package logmine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Wildcard stands for the variable parts of a template
+const Wildcard = "<*>"
+
+const (
+	defaultSimilarity = 0.5 // share of tokens a line must have in common with a template
+	maxExampleLen     = 240
+)
+
+var (
+	reErrorLine = regexp.MustCompile(`(?i)\b(error|err|fatal|panic|exception|failed|failure)\b`)
+	reWarnLine  = regexp.MustCompile(`(?i)\b(warn|warning)\b`)
+
+	// Tokens that are values rather than words: numbers, durations, sizes, IPs, hex ids,
+	// UUIDs and timestamps
+	reVariable = regexp.MustCompile(`^(` +
+		`[-+]?\d+([.,:]\d+)*[a-zA-Z%µ]{0,3}` +
+		`|0x[0-9a-fA-F]+` +
+		`|[0-9a-fA-F]{12,}` +
+		`|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}` +
+		`|\d{1,3}(\.\d{1,3}){3}(:\d+)?` +
+		`|\d{4}-\d{2}-\d{2}[T ]?[\d:.]*(Z|[+-]\d{2}:?\d{2})?` +
+		`|\d{2}:\d{2}:\d{2}([.,]\d+)?` +
+		`)$`)
+	reKeyValue = regexp.MustCompile(`^([A-Za-z_][\w.-]*[=:])(.+)$`)
+)
+
+// Template is one cluster of similar lines
+type Template struct {
+	ID        int
+	Tokens    []string
+	Count     int    // lines in the current log
+	FirstLine int    // 1-based line of the first occurrence in the current log, 0 when absent
+	Example   string // first line of the current log that matched
+	Error     bool   // some matching line looks like an error
+	Warning   bool
+	New       bool // not seen in the previous log
+
+	previous int // lines in the previous log
+}
+
+// String renders the template with wildcards, e.g. "dial tcp <*>: connect: connection refused"
+func (t *Template) String() string {
+	return strings.Join(t.Tokens, " ")
+}
+
+// Miner is a Drain-style log parser: lines are grouped by token count and first token,
+// then matched against the templates in that group by token similarity
+type Miner struct {
+	Similarity float64
+
+	groups    map[string][]*Template
+	templates []*Template
+}
+
+// NewMiner creates a miner with the default similarity threshold
+func NewMiner() *Miner {
+	return &Miner{Similarity: defaultSimilarity, groups: map[string][]*Template{}}
+}
+
+// add clusters one line; previous lines only teach the miner which templates existed before
+func (m *Miner) add(line string, lineNo int, previous bool) *Template {
+	tokens := tokenize(line)
+	if len(tokens) == 0 {
+		return nil
+	}
+	key := groupKey(tokens)
+
+	var best *Template
+	bestScore := -1.0
+	for _, t := range m.groups[key] {
+		if score := similarity(t.Tokens, tokens); score >= m.Similarity && score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+	if best == nil {
+		best = &Template{ID: len(m.templates) + 1, Tokens: tokens}
+		m.groups[key] = append(m.groups[key], best)
+		m.templates = append(m.templates, best)
+	} else {
+		for i, tok := range tokens {
+			if best.Tokens[i] != tok {
+				best.Tokens[i] = Wildcard
+			}
+		}
+	}
+
+	if previous {
+		best.previous++
+		return best
+	}
+	best.Count++
+	if best.FirstLine == 0 {
+		best.FirstLine = lineNo
+		best.Example = truncate(strings.TrimSpace(line), maxExampleLen)
+	}
+	if reErrorLine.MatchString(line) {
+		best.Error = true
+	} else if reWarnLine.MatchString(line) {
+		best.Warning = true
+	}
+	return best
+}
+
+// Analysis is the template view of a log, optionally compared with the previous container's log
+type Analysis struct {
+	Lines         int
+	PreviousLines int
+	Templates     []*Template // templates of the current log, most frequent first
+	LineTemplates []int       // template ID of each current line, 0 for blank lines
+}
+
+// Analyze mines the current log. When previous is not empty, templates that never
+// appeared in it are marked New.
+func Analyze(current, previous string) *Analysis {
+	m := NewMiner()
+	a := &Analysis{}
+	for _, line := range splitLines(previous) {
+		if m.add(line, 0, true) != nil {
+			a.PreviousLines++
+		}
+	}
+	for i, line := range splitLines(current) {
+		id := 0
+		if t := m.add(line, i+1, false); t != nil {
+			id = t.ID
+			a.Lines++
+		}
+		a.LineTemplates = append(a.LineTemplates, id)
+	}
+	for _, t := range m.templates {
+		if t.Count == 0 {
+			continue
+		}
+		t.New = a.PreviousLines > 0 && t.previous == 0
+		a.Templates = append(a.Templates, t)
+	}
+	sort.SliceStable(a.Templates, func(i, j int) bool {
+		if a.Templates[i].Count != a.Templates[j].Count {
+			return a.Templates[i].Count > a.Templates[j].Count
+		}
+		return a.Templates[i].FirstLine < a.Templates[j].FirstLine
+	})
+	return a
+}
+
+// Errors returns the error templates in order of their first occurrence
+func (a *Analysis) Errors() []*Template {
+	var errs []*Template
+	for _, t := range a.Templates {
+		if t.Error {
+			errs = append(errs, t)
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].FirstLine < errs[j].FirstLine })
+	return errs
+}
+
+// NewTemplates returns the templates absent from the previous log
+func (a *Analysis) NewTemplates() []*Template {
+	var fresh []*Template
+	for _, t := range a.Templates {
+		if t.New {
+			fresh = append(fresh, t)
+		}
+	}
+	return fresh
+}
+
+// LinesOf returns the 1-based lines of the current log that belong to the template
+func (a *Analysis) LinesOf(id int) []int {
+	var lines []int
+	for i, tid := range a.LineTemplates {
+		if tid == id {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+// Headline is a one-line summary, e.g. "1834 lines in 12 templates, 3 with errors, 2 new"
+func (a *Analysis) Headline() string {
+	s := fmt.Sprintf("%d lines in %d templates", a.Lines, len(a.Templates))
+	if errs := a.Errors(); len(errs) > 0 {
+		s += fmt.Sprintf(", %d with errors (first at line %d)", len(errs), errs[0].FirstLine)
+	}
+	if a.PreviousLines > 0 {
+		s += fmt.Sprintf(", %d new since the previous container", len(a.NewTemplates()))
+	}
+	return s
+}
+
+// Summary lists at most max templates for the model. Error and new templates come first
+// with their first occurrence, then the most frequent ones.
+func (a *Analysis) Summary(max int) string {
+	var b strings.Builder
+	b.WriteString(a.Headline() + "\n")
+
+	shown := map[int]bool{}
+	var picked []*Template
+	pick := func(t *Template) {
+		if !shown[t.ID] && len(picked) < max {
+			shown[t.ID] = true
+			picked = append(picked, t)
+		}
+	}
+	for _, t := range a.Errors() {
+		pick(t)
+	}
+	for _, t := range a.NewTemplates() {
+		pick(t)
+	}
+	for _, t := range a.Templates {
+		pick(t)
+	}
+
+	for _, t := range picked {
+		var flags []string
+		if t.Error {
+			flags = append(flags, "error")
+		} else if t.Warning {
+			flags = append(flags, "warning")
+		}
+		if t.New {
+			flags = append(flags, "new")
+		}
+		flags = append(flags, fmt.Sprintf("first at line %d", t.FirstLine))
+		fmt.Fprintf(&b, "x%d [%s] %s\n", t.Count, strings.Join(flags, ", "), t)
+		if t.Error || t.New {
+			fmt.Fprintf(&b, "    first: %s\n", t.Example)
+		}
+	}
+	if rest := len(a.Templates) - len(picked); rest > 0 {
+		fmt.Fprintf(&b, "(%d less frequent templates omitted)\n", rest)
+	}
+	return b.String()
+}
+
+// tokenize splits a line into words and masks the ones that are values
+func tokenize(line string) []string {
+	fields := strings.Fields(line)
+	for i, f := range fields {
+		fields[i] = maskToken(f)
+	}
+	return fields
+}
+
+func maskToken(tok string) string {
+	core := strings.Trim(tok, `"'()[]{},;:.`)
+	if core == "" || !strings.ContainsAny(core, "0123456789") {
+		return tok
+	}
+	if reVariable.MatchString(core) {
+		return strings.Replace(tok, core, Wildcard, 1)
+	}
+	if m := reKeyValue.FindStringSubmatch(core); m != nil && reVariable.MatchString(m[2]) {
+		return strings.Replace(tok, core, m[1]+Wildcard, 1)
+	}
+	return tok
+}
+
+// groupKey buckets lines by length and first token; a variable first token shares one bucket
+func groupKey(tokens []string) string {
+	first := tokens[0]
+	if strings.ContainsAny(first, "0123456789") {
+		first = Wildcard
+	}
+	return fmt.Sprintf("%d %s", len(tokens), first)
+}
+
+// similarity is the share of positions where the line equals the template. A wildcard
+// only matches a masked value, so a template that lost most of its words stops attracting lines.
+func similarity(template, tokens []string) float64 {
+	same := 0
+	for i, tok := range template {
+		if tok == tokens[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(template))
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
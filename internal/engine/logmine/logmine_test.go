@@ -0,0 +1,110 @@
+package logmine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMaskToken(t *testing.T) {
+	cases := map[string]string{
+		"200":                                  Wildcard,
+		"12.5ms":                               Wildcard,
+		"10.0.3.7:5432":                        Wildcard,
+		"10.0.3.7:5432:":                       Wildcard + ":",
+		"2025-03-04T10:00:01.123Z":             Wildcard,
+		"0x7f3a":                               Wildcard,
+		"3f2c9a1b-77d4-4c1e-9a0f-5b6c7d8e9f01": Wildcard,
+		"latency=12ms":                         "latency=" + Wildcard,
+		`"1500"`:                               `"` + Wildcard + `"`,
+		"(7)":                                  "(" + Wildcard + ")",
+		"refused":                              "refused",
+		"http2":                                "http2",
+	}
+	for in, want := range cases {
+		if got := maskToken(in); got != want {
+			t.Errorf("maskToken(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAnalyzeClustersLines(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("2025-03-04T10:00:%02dZ INFO GET /healthz status=200 took %dms", i%60, i+3))
+	}
+	lines = append(lines, "2025-03-04T10:01:00Z INFO user alice logged in")
+	lines = append(lines, "2025-03-04T10:01:01Z INFO user bob logged in")
+	for i := 0; i < 7; i++ {
+		lines = append(lines, fmt.Sprintf("2025-03-04T10:01:%02dZ ERROR dial tcp 10.0.3.%d:5432: connect: connection refused", 10+i, i))
+	}
+
+	a := Analyze(strings.Join(lines, "\n")+"\n\n", "")
+	if a.Lines != 59 || len(a.Templates) != 3 {
+		for _, tpl := range a.Templates {
+			t.Log(tpl.Count, tpl)
+		}
+		t.Fatalf("expected 59 lines in 3 templates, got %d in %d", a.Lines, len(a.Templates))
+	}
+	if got := a.Templates[0].String(); a.Templates[0].Count != 50 || got != "<*> INFO GET /healthz status=<*> took <*>" {
+		t.Errorf("most frequent template first: x%d %q", a.Templates[0].Count, got)
+	}
+	if got := a.Templates[2].String(); got != "<*> INFO user <*> logged in" {
+		t.Errorf("names should become a wildcard: %q", got)
+	}
+
+	if got := a.Templates[1].String(); got != "<*> ERROR dial tcp <*>: connect: connection refused" {
+		t.Errorf("the address should be masked without its colon: %q", got)
+	}
+
+	errs := a.Errors()
+	if len(errs) != 1 || errs[0].FirstLine != 53 || errs[0].Count != 7 || !strings.Contains(errs[0].Example, "10.0.3.0:5432") {
+		t.Fatalf("expected one error template first seen at line 53: %+v", errs)
+	}
+	if got := len(a.LinesOf(errs[0].ID)); got != 7 {
+		t.Errorf("LinesOf should return every line of the template, got %d", got)
+	}
+	if len(a.NewTemplates()) != 0 || strings.Contains(a.Headline(), "new") {
+		t.Error("without previous logs nothing is new")
+	}
+}
+
+func TestAnalyzeComparesWithPreviousLogs(t *testing.T) {
+	previous := "INFO starting server on :8080\nINFO connected to db at 10.0.3.7:5432\n"
+	current := "INFO starting server on :8080\n" +
+		"ERROR failed to load config: open /etc/app/config.yaml: no such file or directory\n" +
+		"INFO connected to db at 10.0.3.8:5432\n" +
+		"WARN retrying in 5s\n"
+
+	a := Analyze(current, previous)
+	if a.PreviousLines != 2 {
+		t.Errorf("expected 2 previous lines, got %d", a.PreviousLines)
+	}
+	var fresh []string
+	for _, tpl := range a.NewTemplates() {
+		fresh = append(fresh, tpl.String())
+	}
+	if len(fresh) != 2 || !strings.HasPrefix(fresh[0], "ERROR failed to load config") {
+		t.Errorf("only the config error and the retry should be new, got %q", fresh)
+	}
+
+	summary := a.Summary(2)
+	for _, s := range []string{
+		"4 lines in 4 templates, 1 with errors (first at line 2), 2 new since the previous container",
+		"x1 [error, new, first at line 2] ERROR failed to load config",
+		"    first: ERROR failed to load config: open /etc/app/config.yaml",
+		"x1 [warning, new, first at line 4] WARN retrying in <*>",
+		"(2 less frequent templates omitted)",
+	} {
+		if !strings.Contains(summary, s) {
+			t.Errorf("summary should contain %q:\n%s", s, summary)
+		}
+	}
+}
+
+func TestAnalyzeEmptyLog(t *testing.T) {
+	a := Analyze("\n  \n", "")
+	if a.Lines != 0 || len(a.Templates) != 0 || a.Headline() != "0 lines in 0 templates" {
+		t.Errorf("empty log should have no templates: %+v", a)
+	}
+}
@@ -0,0 +1,487 @@
+// logstream.go - Multi-pod log streaming: follow kubectl logs -f for every pod of a workload or selector
+package engine
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+const (
+	defaultLogStreamLines = 5000
+	defaultLogStreamTail  = 50
+	defaultLogStreamPoll  = 5 * time.Second
+	maxLogStreamPods      = 20
+	maxLogStreamLineLen   = 4096
+	logStreamQueryTimeout = 10 * time.Second
+)
+
+var (
+	reLogStreamName     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	reLogStreamSelector = regexp.MustCompile(`^[A-Za-z0-9_./!=(),-]+$`)
+	// kubectl logs --prefix writes "[pod/<pod>/<container>] <line>"
+	reLogStreamPrefix = regexp.MustCompile(`^\[pod/([^/\]]+)/([^\]]+)\] ?`)
+)
+
+// logStreamKinds maps workload kinds and their short names to kubectl resources
+var logStreamKinds = map[string]string{
+	"deploy": "deployment", "deployment": "deployment", "deployments": "deployment",
+	"sts": "statefulset", "statefulset": "statefulset", "statefulsets": "statefulset",
+	"ds": "daemonset", "daemonset": "daemonset", "daemonsets": "daemonset",
+	"rs": "replicaset", "replicaset": "replicaset", "replicasets": "replicaset",
+	"job": "job", "jobs": "job",
+	"svc": "service", "service": "service", "services": "service",
+	"po": "pod", "pod": "pod", "pods": "pod",
+}
+
+// LogStreamTarget selects the pods to follow. Exactly one of Workload, Selector or
+// PodQuery is usually set; with none, every pod of the namespace is followed.
+type LogStreamTarget struct {
+	Namespace string
+	Workload  string // "deploy/web", "sts/db", "pod/web-0"
+	Selector  string // label selector, e.g. "app=web,tier!=cache"
+	PodQuery  string // regular expression matched against pod names, like stern's pod query
+	Container string // only this container; all containers when empty
+}
+
+// String describes the target, e.g. "deploy/web in namespace shop"
+func (t LogStreamTarget) String() string {
+	var what []string
+	if t.Workload != "" {
+		what = append(what, t.Workload)
+	}
+	if t.Selector != "" {
+		what = append(what, "pods with "+t.Selector)
+	}
+	if t.PodQuery != "" {
+		what = append(what, "pods matching "+t.PodQuery)
+	}
+	if len(what) == 0 {
+		what = append(what, "all pods")
+	}
+	s := strings.Join(what, ", ")
+	if t.Container != "" {
+		s += " (container " + t.Container + ")"
+	}
+	return s + " in namespace " + t.Namespace
+}
+
+// LogStreamLine is one line of a followed pod. Event lines report pods joining or
+// leaving the stream and errors; they have no container.
+type LogStreamLine struct {
+	Seq       int
+	Time      time.Time
+	Pod       string
+	Container string
+	Text      string
+	Event     bool
+}
+
+// LogStreamer follows pod logs through kubectl; queries go through runner, log tails
+// through streamer
+type LogStreamer struct {
+	runner   execx.Runner
+	streamer execx.Streamer
+	MaxLines int           // lines kept in memory per stream; older ones are dropped
+	Tail     int           // lines of history fetched when a pod joins
+	Poll     time.Duration // how often new pods are looked for
+}
+
+// NewLogStreamer creates a streamer; a zero maxLines keeps 5000 lines
+func NewLogStreamer(runner execx.Runner, streamer execx.Streamer, maxLines int) *LogStreamer {
+	if maxLines <= 0 {
+		maxLines = defaultLogStreamLines
+	}
+	return &LogStreamer{runner: runner, streamer: streamer, MaxLines: maxLines, Tail: defaultLogStreamTail, Poll: defaultLogStreamPoll}
+}
+
+// LogStream is a running stream. Lines are read with Since; Stop ends every tail.
+type LogStream struct {
+	Target   LogStreamTarget
+	Selector string // label selector resolved from the workload
+
+	streamer *LogStreamer
+	podQuery *regexp.Regexp
+	cancel   context.CancelFunc
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	mu      sync.Mutex
+	lines   []LogStreamLine
+	nextSeq int
+	tails   map[string]bool      // pods being followed
+	lastAt  map[string]time.Time // when a pod's tail last ended, to resume without repeats
+	capped  bool
+}
+
+// Start resolves the target, looks for its pods once and keeps following them, adding
+// pods as they appear, until ctx is done or Stop is called
+func (s *LogStreamer) Start(ctx context.Context, target LogStreamTarget) (*LogStream, error) {
+	if s.streamer == nil {
+		return nil, fmt.Errorf("log streaming is unavailable")
+	}
+	if err := target.validate(); err != nil {
+		return nil, err
+	}
+	ls := &LogStream{
+		Target:   target,
+		Selector: target.Selector,
+		streamer: s,
+		done:     make(chan struct{}),
+		tails:    map[string]bool{},
+		lastAt:   map[string]time.Time{},
+	}
+	if target.PodQuery != "" {
+		re, err := regexp.Compile(target.PodQuery)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod query: %w", err)
+		}
+		ls.podQuery = re
+	}
+
+	var fieldSelector string
+	if target.Workload != "" {
+		kind, name, _ := strings.Cut(target.Workload, "/")
+		if logStreamKinds[kind] == "pod" {
+			fieldSelector = "metadata.name=" + name
+		} else {
+			selector, err := s.workloadSelector(ctx, target.Namespace, logStreamKinds[kind], name)
+			if err != nil {
+				return nil, err
+			}
+			ls.Selector = joinSelectors(selector, target.Selector)
+		}
+	}
+	pods, err := s.listPods(ctx, target.Namespace, ls.Selector, fieldSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, ls.cancel = context.WithCancel(ctx)
+	if ls.follow(ctx, pods) == 0 {
+		ls.event("", "waiting for pods of "+target.String())
+	}
+	go func() {
+		defer close(ls.done)
+		ticker := time.NewTicker(s.Poll)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				ls.wg.Wait()
+				return
+			case <-ticker.C:
+				pods, err := s.listPods(ctx, target.Namespace, ls.Selector, fieldSelector)
+				if err != nil {
+					if ctx.Err() == nil {
+						ls.event("", "cannot list pods: "+err.Error())
+					}
+					continue
+				}
+				ls.follow(ctx, pods)
+			}
+		}
+	}()
+	return ls, nil
+}
+
+func (t LogStreamTarget) validate() error {
+	if !reLogStreamName.MatchString(t.Namespace) {
+		return fmt.Errorf("invalid namespace %q", t.Namespace)
+	}
+	if t.Workload != "" {
+		kind, name, ok := strings.Cut(t.Workload, "/")
+		if !ok || logStreamKinds[kind] == "" {
+			return fmt.Errorf("unsupported workload %q: use deploy/, sts/, ds/, rs/, job/, svc/ or pod/<name>", t.Workload)
+		}
+		if !reLogStreamName.MatchString(name) {
+			return fmt.Errorf("invalid %s name %q", logStreamKinds[kind], name)
+		}
+	}
+	if t.Selector != "" && !reLogStreamSelector.MatchString(t.Selector) {
+		return fmt.Errorf("invalid label selector %q", t.Selector)
+	}
+	if t.Container != "" && !reLogStreamName.MatchString(t.Container) {
+		return fmt.Errorf("invalid container name %q", t.Container)
+	}
+	return nil
+}
+
+// streamPod is the part of a pod the streamer needs
+type streamPod struct {
+	Name  string
+	Phase string
+}
+
+// workloadSelector reads the label selector of a workload or service
+func (s *LogStreamer) workloadSelector(ctx context.Context, namespace, resource, name string) (string, error) {
+	var obj struct {
+		Spec struct {
+			Selector json.RawMessage `json:"selector"`
+		} `json:"spec"`
+	}
+	if err := s.query(ctx, &obj, "get", resource, name, "-n", namespace, "-o", "json"); err != nil {
+		return "", err
+	}
+
+	var labels map[string]string
+	if resource != "service" {
+		var selector struct {
+			MatchLabels      map[string]string `json:"matchLabels"`
+			MatchExpressions []struct {
+				Key      string   `json:"key"`
+				Operator string   `json:"operator"`
+				Values   []string `json:"values"`
+			} `json:"matchExpressions"`
+		}
+		if err := json.Unmarshal(obj.Spec.Selector, &selector); err != nil {
+			return "", fmt.Errorf("%s %s has no label selector", resource, name)
+		}
+		labels = selector.MatchLabels
+		var terms []string
+		for _, expr := range selector.MatchExpressions {
+			switch expr.Operator {
+			case "In":
+				terms = append(terms, fmt.Sprintf("%s in (%s)", expr.Key, strings.Join(expr.Values, ",")))
+			case "NotIn":
+				terms = append(terms, fmt.Sprintf("%s notin (%s)", expr.Key, strings.Join(expr.Values, ",")))
+			case "Exists":
+				terms = append(terms, expr.Key)
+			case "DoesNotExist":
+				terms = append(terms, "!"+expr.Key)
+			}
+		}
+		sort.Strings(terms)
+		if selector := joinSelectors(labelSelector(labels), strings.Join(terms, ",")); selector != "" {
+			return selector, nil
+		}
+	} else if err := json.Unmarshal(obj.Spec.Selector, &labels); err == nil && len(labels) > 0 {
+		return labelSelector(labels), nil
+	}
+	return "", fmt.Errorf("%s %s has no label selector", resource, name)
+}
+
+// listPods lists the pods of the namespace that match the selectors
+func (s *LogStreamer) listPods(ctx context.Context, namespace, selector, fieldSelector string) ([]streamPod, error) {
+	args := []string{"get", "pods", "-n", namespace}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	if fieldSelector != "" {
+		args = append(args, "--field-selector", fieldSelector)
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := s.query(ctx, &list, append(args, "-o", "json")...); err != nil {
+		return nil, err
+	}
+	pods := make([]streamPod, 0, len(list.Items))
+	for _, item := range list.Items {
+		pods = append(pods, streamPod{Name: item.Metadata.Name, Phase: item.Status.Phase})
+	}
+	return pods, nil
+}
+
+// query runs one read-only kubectl command and decodes its JSON output
+func (s *LogStreamer) query(ctx context.Context, out interface{}, args ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, logStreamQueryTimeout)
+	defer cancel()
+	stdout, stderr, err := s.runner.Run(ctx, "kubectl", args...)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return fmt.Errorf("%s", firstLine(msg))
+		}
+		return err
+	}
+	return json.Unmarshal([]byte(stdout), out)
+}
+
+// follow starts a tail for every running pod not yet followed. Pending pods have no
+// logs yet and are picked up by a later poll; completed pods are read once, as their
+// logs cannot grow. It returns the number of tails started.
+func (ls *LogStream) follow(ctx context.Context, pods []streamPod) int {
+	started := 0
+	for _, pod := range pods {
+		if pod.Phase == "Pending" || (ls.podQuery != nil && !ls.podQuery.MatchString(pod.Name)) {
+			continue
+		}
+		ls.mu.Lock()
+		_, tailed := ls.lastAt[pod.Name]
+		if ls.tails[pod.Name] || (tailed && (pod.Phase == "Succeeded" || pod.Phase == "Failed")) {
+			ls.mu.Unlock()
+			continue
+		}
+		if len(ls.tails) >= maxLogStreamPods {
+			capped := ls.capped
+			ls.capped = true
+			ls.mu.Unlock()
+			if !capped {
+				ls.event("", fmt.Sprintf("following the first %d pods only; narrow the selector to see the others", maxLogStreamPods))
+			}
+			continue
+		}
+		ls.tails[pod.Name] = true
+		since := ls.lastAt[pod.Name]
+		ls.mu.Unlock()
+
+		ls.event(pod.Name, "+ following "+pod.Name)
+		ls.wg.Add(1)
+		started++
+		go ls.tail(ctx, pod.Name, since)
+	}
+	return started
+}
+
+// tail follows one pod until its containers stop or the stream ends. A pod that is
+// followed again resumes from where its last tail ended.
+func (ls *LogStream) tail(ctx context.Context, pod string, since time.Time) {
+	defer ls.wg.Done()
+	args := []string{"logs", "-f", pod, "-n", ls.Target.Namespace, "--prefix"}
+	if ls.Target.Container != "" {
+		args = append(args, "-c", ls.Target.Container)
+	} else {
+		args = append(args, "--all-containers")
+	}
+	if since.IsZero() {
+		args = append(args, fmt.Sprintf("--tail=%d", ls.streamer.Tail))
+	} else {
+		args = append(args, "--since-time="+since.UTC().Format(time.RFC3339))
+	}
+
+	var out io.ReadCloser
+	var err error
+	if command := "kubectl " + strings.Join(args, " "); !IsWhitelistedAction(command) {
+		err = fmt.Errorf("refusing to run %q", command)
+	} else {
+		out, err = ls.streamer.streamer.Stream(ctx, "kubectl", args...)
+	}
+	if err == nil {
+		scanner := bufio.NewScanner(out)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			ls.add(pod, scanner.Text())
+		}
+		err = scanner.Err()
+		out.Close()
+	}
+
+	ls.mu.Lock()
+	delete(ls.tails, pod)
+	ls.lastAt[pod] = time.Now()
+	ls.mu.Unlock()
+	switch {
+	case ctx.Err() != nil:
+	case err != nil:
+		ls.event(pod, fmt.Sprintf("- %s: %v", pod, err))
+	default:
+		ls.event(pod, "- "+pod+" stopped logging")
+	}
+}
+
+// add stores a log line, splitting off the [pod/<pod>/<container>] prefix
+func (ls *LogStream) add(pod, text string) {
+	container := ls.Target.Container
+	if m := reLogStreamPrefix.FindStringSubmatch(text); m != nil {
+		pod, container = m[1], m[2]
+		text = text[len(m[0]):]
+	}
+	if len(text) > maxLogStreamLineLen {
+		text = text[:maxLogStreamLineLen] + "…"
+	}
+	ls.append(LogStreamLine{Pod: pod, Container: container, Text: text})
+}
+
+// event stores a line about the stream itself
+func (ls *LogStream) event(pod, text string) {
+	ls.append(LogStreamLine{Pod: pod, Text: text, Event: true})
+}
+
+func (ls *LogStream) append(line LogStreamLine) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	line.Seq = ls.nextSeq
+	line.Time = time.Now()
+	ls.nextSeq++
+	ls.lines = append(ls.lines, line)
+	// Appending reallocates once the slice is full and copies only the kept lines,
+	// so memory stays proportional to MaxLines
+	if over := len(ls.lines) - ls.streamer.MaxLines; over > 0 {
+		ls.lines = ls.lines[over:]
+	}
+}
+
+// Since returns the lines from seq on and the seq to ask for next. missed counts the
+// lines dropped from memory before they were read.
+func (ls *LogStream) Since(seq int) (lines []LogStreamLine, next int, missed int) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if len(ls.lines) == 0 {
+		return nil, ls.nextSeq, 0
+	}
+	first := ls.lines[0].Seq
+	if seq < first {
+		missed, seq = first-seq, first
+	}
+	if seq-first < len(ls.lines) {
+		lines = append(lines, ls.lines[seq-first:]...)
+	}
+	return lines, ls.nextSeq, missed
+}
+
+// Pods returns the pods being followed, sorted by name
+func (ls *LogStream) Pods() []string {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	pods := make([]string, 0, len(ls.tails))
+	for pod := range ls.tails {
+		pods = append(pods, pod)
+	}
+	sort.Strings(pods)
+	return pods
+}
+
+// Stop ends every tail and the pod polling
+func (ls *LogStream) Stop() {
+	ls.cancel()
+}
+
+// Done is closed once every tail has ended after Stop
+func (ls *LogStream) Done() <-chan struct{} {
+	return ls.done
+}
+
+func labelSelector(labels map[string]string) string {
+	terms := make([]string, 0, len(labels))
+	for k, v := range labels {
+		terms = append(terms, k+"="+v)
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, ",")
+}
+
+func joinSelectors(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "," + b
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+// waitForLines polls the stream until it holds n lines
+func waitForLines(t *testing.T, ls *LogStream, n int) []LogStreamLine {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		lines, _, _ := ls.Since(0)
+		if len(lines) >= n || time.Now().After(deadline) {
+			return lines
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLogStreamFollowsWorkloadPods(t *testing.T) {
+	runner := execx.NewMockRunner()
+	runner.Expect("kubectl", []string{"get", "deployment", "web", "-n", "shop", "-o", "json"},
+		`{"spec":{"selector":{"matchLabels":{"app":"web","tier":"front"}}}}`, "", nil)
+	runner.Expect("kubectl", []string{"get", "pods", "-n", "shop", "-l", "app=web,tier=front", "-o", "json"},
+		`{"items":[{"metadata":{"name":"web-a"},"status":{"phase":"Running"}},{"metadata":{"name":"web-b"},"status":{"phase":"Pending"}}]}`, "", nil)
+	runner.Expect("kubectl", []string{"logs", "-f", "web-a", "-n", "shop", "--prefix", "--all-containers", "--tail=50"},
+		"[pod/web-a/app] GET /healthz 200\n[pod/web-a/proxy] upstream connect error\n", "", nil)
+
+	streamer := NewLogStreamer(runner, runner, 0)
+	streamer.Poll = time.Hour
+	ls, err := streamer.Start(context.Background(), LogStreamTarget{Namespace: "shop", Workload: "deploy/web"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if ls.Selector != "app=web,tier=front" {
+		t.Errorf("selector should come from the deployment, got %q", ls.Selector)
+	}
+
+	lines := waitForLines(t, ls, 4)
+	ls.Stop()
+	select {
+	case <-ls.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop should end the stream")
+	}
+
+	if len(lines) != 4 {
+		t.Fatalf("expected a join event, two lines and an end event, got %+v", lines)
+	}
+	if !lines[0].Event || lines[0].Text != "+ following web-a" {
+		t.Errorf("the pod joining should be an event: %+v", lines[0])
+	}
+	if l := lines[2]; l.Pod != "web-a" || l.Container != "proxy" || l.Text != "upstream connect error" || l.Seq != 2 {
+		t.Errorf("the --prefix should become the pod and container: %+v", l)
+	}
+	if !lines[3].Event || lines[3].Text != "- web-a stopped logging" {
+		t.Errorf("the tail ending should be an event: %+v", lines[3])
+	}
+	if runner.CallCount != 3 {
+		t.Errorf("the Pending pod should not be followed, got %d calls", runner.CallCount)
+	}
+}
+
+func TestLogStreamCapsLinesInMemory(t *testing.T) {
+	var out strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&out, "line %d\n", i)
+	}
+	runner := execx.NewMockRunner()
+	runner.Expect("kubectl", []string{"get", "pods", "-n", "default", "-l", "app=api", "-o", "json"},
+		`{"items":[{"metadata":{"name":"api-0"},"status":{"phase":"Running"}}]}`, "", nil)
+	runner.Expect("kubectl", []string{"logs", "-f", "api-0", "-n", "default", "--prefix", "-c", "api", "--tail=50"}, out.String(), "", nil)
+
+	streamer := NewLogStreamer(runner, runner, 4)
+	streamer.Poll = time.Hour
+	ls, err := streamer.Start(context.Background(), LogStreamTarget{Namespace: "default", Selector: "app=api", Container: "api"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer ls.Stop()
+
+	// join event, 10 lines and the end event
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, next, _ := ls.Since(0); next == 12 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	lines, next, missed := ls.Since(3)
+	if next != 12 || missed != 5 || len(lines) != 4 {
+		t.Fatalf("expected the last 4 of 12 lines and 5 missed, got %d lines, next %d, missed %d", len(lines), next, missed)
+	}
+	if lines[0].Text != "line 8" || lines[0].Container != "api" {
+		t.Errorf("oldest kept line should be line 8 of container api: %+v", lines[0])
+	}
+	if lines, next, _ := ls.Since(next); len(lines) != 0 || next != 12 {
+		t.Errorf("nothing new after the last line, got %d lines", len(lines))
+	}
+}
+
+func TestLogStreamReadsCompletedPodsOnce(t *testing.T) {
+	runner := execx.NewMockRunner()
+	for i := 0; i < 50; i++ {
+		runner.Expect("kubectl", []string{"get", "pods", "-n", "batch", "--field-selector", "metadata.name=migrate-x7k2p", "-o", "json"},
+			`{"items":[{"metadata":{"name":"migrate-x7k2p"},"status":{"phase":"Succeeded"}}]}`, "", nil)
+	}
+	runner.Expect("kubectl", []string{"logs", "-f", "migrate-x7k2p", "-n", "batch", "--prefix", "--all-containers", "--tail=50"},
+		"[pod/migrate-x7k2p/migrate] applied 12 migrations\n", "", nil)
+
+	streamer := NewLogStreamer(runner, runner, 0)
+	streamer.Poll = 10 * time.Millisecond
+	ls, err := streamer.Start(context.Background(), LogStreamTarget{Namespace: "batch", Workload: "pod/migrate-x7k2p"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	ls.Stop()
+	<-ls.Done()
+
+	lines, _, _ := ls.Since(0)
+	joins := 0
+	for _, line := range lines {
+		if line.Event && strings.HasPrefix(line.Text, "+ following") {
+			joins++
+		}
+	}
+	if joins != 1 || len(lines) != 3 {
+		t.Errorf("a completed pod should be read once, got %d joins in %d lines: %+v", joins, len(lines), lines)
+	}
+}
+
+func TestLogStreamRejectsInvalidTargets(t *testing.T) {
+	runner := execx.NewMockRunner()
+	streamer := NewLogStreamer(runner, runner, 0)
+	for _, target := range []LogStreamTarget{
+		{Namespace: "default", Workload: "cronjob/backup"},
+		{Namespace: "default", Workload: "deploy/web;rm"},
+		{Namespace: "default", Selector: "app=web; rm -rf /"},
+		{Namespace: "Default"},
+		{Namespace: "default", PodQuery: "web-("},
+	} {
+		if _, err := streamer.Start(context.Background(), target); err == nil {
+			t.Errorf("expected an error for %+v", target)
+		}
+	}
+	if runner.CallCount != 0 {
+		t.Errorf("invalid targets should not run kubectl, got %d calls", runner.CallCount)
+	}
+}
@@ -0,0 +1,264 @@
+// memory.go - Durable per-context facts remembered across sessions, e.g. "ingress class is nginx-internal"
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Who saved a memory
+const (
+	MemorySourceUser  = "user"
+	MemorySourceAgent = "agent" // proposed during diagnosis and approved by the user
+)
+
+const maxMemoryFactLength = 300
+
+// MemoryFact is one remembered fact about a cluster
+type MemoryFact struct {
+	ID        int       `json:"id"`
+	Fact      string    `json:"fact"`
+	Namespace string    `json:"namespace,omitempty"` // set when the fact only concerns one namespace
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ClusterMemory stores facts per kube context in a single JSON file
+type ClusterMemory struct {
+	path     string
+	MaxFacts int // per context; the oldest facts are dropped first
+
+	mu       sync.Mutex
+	contexts map[string][]MemoryFact
+}
+
+// NewClusterMemory creates a store backed by path
+func NewClusterMemory(path string) *ClusterMemory {
+	return &ClusterMemory{
+		path:     path,
+		MaxFacts: 100,
+		contexts: make(map[string][]MemoryFact),
+	}
+}
+
+// Load reads the store from disk; a missing file is an empty store
+func (cm *ClusterMemory) Load() error {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	data, err := os.ReadFile(cm.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read cluster memory: %w", err)
+	}
+	contexts := make(map[string][]MemoryFact)
+	if err := json.Unmarshal(data, &contexts); err != nil {
+		return fmt.Errorf("failed to parse cluster memory %s: %w", cm.path, err)
+	}
+	cm.contexts = contexts
+	return nil
+}
+
+func (cm *ClusterMemory) save() error {
+	if err := os.MkdirAll(filepath.Dir(cm.path), 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	data, err := json.MarshalIndent(cm.contexts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cluster memory: %w", err)
+	}
+	if err := os.WriteFile(cm.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cluster memory: %w", err)
+	}
+	return nil
+}
+
+// Add saves a fact for a context. Secrets are redacted, text that looks like prompt
+// injection is refused, and an existing identical fact is returned with added=false.
+func (cm *ClusterMemory) Add(kubeContext, namespace, fact, source string) (saved MemoryFact, added bool, err error) {
+	fact = strings.Join(strings.Fields(fact), " ")
+	if fact == "" {
+		return MemoryFact{}, false, fmt.Errorf("memory is empty")
+	}
+	if len(fact) > maxMemoryFactLength {
+		return MemoryFact{}, false, fmt.Errorf("memory is too long (%d characters, max %d); keep it to one fact", len(fact), maxMemoryFactLength)
+	}
+	if findings := DetectInjection(fact); len(findings) > 0 {
+		return MemoryFact{}, false, fmt.Errorf("memory looks like a prompt injection (%s)", strings.Join(FindingRules(findings), ", "))
+	}
+	fact = RedactSensitive(fact).Sanitized
+	kubeContext = memoryContextKey(kubeContext)
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	facts := cm.contexts[kubeContext]
+	nextID := 1
+	for _, existing := range facts {
+		if strings.EqualFold(existing.Fact, fact) && existing.Namespace == namespace {
+			return existing, false, nil
+		}
+		if existing.ID >= nextID {
+			nextID = existing.ID + 1
+		}
+	}
+
+	saved = MemoryFact{ID: nextID, Fact: fact, Namespace: namespace, Source: source, CreatedAt: time.Now()}
+	facts = append(facts, saved)
+	if cm.MaxFacts > 0 && len(facts) > cm.MaxFacts {
+		facts = facts[len(facts)-cm.MaxFacts:]
+	}
+	cm.contexts[kubeContext] = facts
+	if err := cm.save(); err != nil {
+		return saved, true, err
+	}
+	return saved, true, nil
+}
+
+// Forget removes a fact by ID
+func (cm *ClusterMemory) Forget(kubeContext string, id int) (MemoryFact, error) {
+	kubeContext = memoryContextKey(kubeContext)
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	facts := cm.contexts[kubeContext]
+	for i, fact := range facts {
+		if fact.ID == id {
+			cm.contexts[kubeContext] = append(facts[:i:i], facts[i+1:]...)
+			return fact, cm.save()
+		}
+	}
+	return MemoryFact{}, fmt.Errorf("no memory #%d for context %s", id, kubeContext)
+}
+
+// List returns every fact saved for a context, oldest first
+func (cm *ClusterMemory) List(kubeContext string) []MemoryFact {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return append([]MemoryFact(nil), cm.contexts[memoryContextKey(kubeContext)]...)
+}
+
+// Relevant returns up to limit facts that share words with the query, preferring facts
+// about the active namespace. Facts scoped to another namespace need the query to name it.
+func (cm *ClusterMemory) Relevant(kubeContext, namespace, query string, limit int) []MemoryFact {
+	queryWords := memoryWords(query)
+	type scored struct {
+		fact  MemoryFact
+		score int
+	}
+	var hits []scored
+	for _, fact := range cm.List(kubeContext) {
+		score := 0
+		for word := range memoryWords(fact.Fact) {
+			if queryWords[word] {
+				score++
+			}
+		}
+		if fact.Namespace != "" {
+			switch {
+			case fact.Namespace == namespace:
+				score++
+			case !queryWords[memoryStem(strings.ToLower(fact.Namespace))]:
+				continue
+			}
+		}
+		if score > 0 {
+			hits = append(hits, scored{fact, score})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].fact.CreatedAt.After(hits[j].fact.CreatedAt)
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	facts := make([]MemoryFact, len(hits))
+	for i, hit := range hits {
+		facts[i] = hit.fact
+	}
+	return facts
+}
+
+// RenderMemories formats facts for the cluster section of a prompt
+func RenderMemories(kubeContext string, facts []MemoryFact) string {
+	if len(facts) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Remembered facts about %s (saved from earlier sessions):", memoryContextKey(kubeContext))
+	for _, fact := range facts {
+		sb.WriteString("\n- ")
+		if fact.Namespace != "" {
+			fmt.Fprintf(&sb, "[ns %s] ", fact.Namespace)
+		}
+		sb.WriteString(fact.Fact)
+	}
+	return sb.String()
+}
+
+var reRememberLine = regexp.MustCompile(`(?im)^\s*(?:[-*]\s*)?remember:\s*(.+?)\s*$`)
+
+// ParseMemoryProposals extracts the "Remember: <fact>" lines an agent reply proposes
+func ParseMemoryProposals(reply string) []string {
+	var facts []string
+	seen := make(map[string]bool)
+	for _, match := range reRememberLine.FindAllStringSubmatch(reply, -1) {
+		fact := strings.Trim(match[1], "`\"' ")
+		if fact != "" && !seen[strings.ToLower(fact)] {
+			seen[strings.ToLower(fact)] = true
+			facts = append(facts, fact)
+		}
+	}
+	return facts
+}
+
+func memoryContextKey(kubeContext string) string {
+	if kubeContext = strings.TrimSpace(kubeContext); kubeContext == "" {
+		return "default"
+	}
+	return kubeContext
+}
+
+var memoryStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "be": true, "by": true, "for": true, "from": true,
+	"how": true, "in": true, "is": true, "it": true, "its": true, "me": true, "my": true, "of": true,
+	"on": true, "or": true, "our": true, "show": true, "the": true, "this": true, "that": true,
+	"to": true, "use": true, "uses": true, "we": true, "what": true, "why": true, "with": true,
+}
+
+// memoryWords tokenizes text for fact matching, ignoring stop words and plural endings
+func memoryWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range explainWords(text) {
+		if len(word) < 2 || memoryStopWords[word] {
+			continue
+		}
+		words[memoryStem(word)] = true
+	}
+	return words
+}
+
+func memoryStem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "sses"):
+		return strings.TrimSuffix(word, "es")
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
@@ -0,0 +1,353 @@
+// playbook_files.go - Playbooks and root-cause patterns loaded from YAML directories
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BuiltinOrigin is the origin reported for playbooks and patterns compiled into kubemage
+const BuiltinOrigin = "built-in"
+
+var (
+	rePlaybookID          = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	rePlaybookPlaceholder = regexp.MustCompile(`\{[A-Za-z_]+\}`)
+	// Values substituted into a step must not be able to widen the read-only command
+	rePlaybookValue = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/=-]*$`)
+)
+
+// playbookFile is the layout of a playbook YAML file:
+//
+//	playbooks:
+//	  - id: kafka-lag
+//	    name: Kafka Consumer Lag
+//	    steps:
+//	      - name: Consumer pods
+//	        command: kubectl get pods -n {namespace} -l app=consumer
+//	patterns:
+//	  - id: kafka-rebalance
+//	    name: Consumer group rebalancing
+//	    indicators: ["rebalance in progress"]
+//	    conditions:
+//	      - field: status.containerStatuses[*].restartCount
+//	        operator: greater_than
+//	        value: 5
+//	    confidence: 0.8
+//	    severity: medium
+type playbookFile struct {
+	Playbooks []playbookEntry `yaml:"playbooks"`
+	Patterns  []patternEntry  `yaml:"patterns"`
+}
+
+type playbookEntry struct {
+	ID          string   `yaml:"id"`
+	Name        string   `yaml:"name"`
+	Category    string   `yaml:"category"`
+	Description string   `yaml:"description"`
+	Triggers    []string `yaml:"triggers"`
+	Steps       []struct {
+		Name        string `yaml:"name"`
+		Command     string `yaml:"command"`
+		Description string `yaml:"description"`
+		Expected    string `yaml:"expected"`
+		Timeout     int    `yaml:"timeout"` // seconds
+	} `yaml:"steps"`
+	Heuristics []string `yaml:"heuristics"`
+	NextMoves  []struct {
+		Action      string `yaml:"action"`
+		Command     string `yaml:"command"`
+		Risk        string `yaml:"risk"`
+		Category    string `yaml:"category"`
+		Description string `yaml:"description"`
+	} `yaml:"next_moves"`
+}
+
+type patternEntry struct {
+	ID         string   `yaml:"id"`
+	Name       string   `yaml:"name"`
+	Category   string   `yaml:"category"`
+	Indicators []string `yaml:"indicators"`
+	Conditions []struct {
+		Field    string      `yaml:"field"`
+		Operator string      `yaml:"operator"`
+		Value    interface{} `yaml:"value"`
+	} `yaml:"conditions"`
+	Solutions []struct {
+		Title         string  `yaml:"title"`
+		Description   string  `yaml:"description"`
+		Command       string  `yaml:"command"`
+		Risk          string  `yaml:"risk"`
+		Effectiveness float64 `yaml:"effectiveness"`
+	} `yaml:"solutions"`
+	Confidence float64 `yaml:"confidence"`
+	Severity   string  `yaml:"severity"`
+}
+
+// toPlaybook validates the entry; every diagnostic step must be a read-only command
+func (e playbookEntry) toPlaybook() (*Playbook, error) {
+	if !rePlaybookID.MatchString(e.ID) {
+		return nil, fmt.Errorf("id %q must be lowercase letters, digits and dashes", e.ID)
+	}
+	if strings.TrimSpace(e.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(e.Steps) == 0 {
+		return nil, fmt.Errorf("at least one step is required")
+	}
+	pb := &Playbook{
+		Name:        e.Name,
+		Category:    e.Category,
+		Description: e.Description,
+		Triggers:    e.Triggers,
+		Heuristics:  e.Heuristics,
+	}
+	for i, s := range e.Steps {
+		command := strings.TrimSpace(s.Command)
+		switch {
+		case command == "":
+			return nil, fmt.Errorf("step %d: command is required", i+1)
+		case !IsWhitelistedAction(command):
+			return nil, fmt.Errorf("step %d: %q is not a read-only command", i+1, command)
+		case s.Timeout < 0:
+			return nil, fmt.Errorf("step %d: timeout must not be negative", i+1)
+		}
+		name := s.Name
+		if name == "" {
+			name = fmt.Sprintf("Step %d", i+1)
+		}
+		pb.Steps = append(pb.Steps, PlaybookStep{Name: name, Command: command, Description: s.Description, Expected: s.Expected, Timeout: s.Timeout})
+	}
+	for i, m := range e.NextMoves {
+		if !validRisk(m.Risk) {
+			return nil, fmt.Errorf("next move %d: risk %q must be low, medium or high", i+1, m.Risk)
+		}
+		pb.NextMoves = append(pb.NextMoves, ActionableStep{Action: m.Action, Command: m.Command, Risk: m.Risk, Category: m.Category, Description: m.Description})
+	}
+	return pb, nil
+}
+
+// toPattern validates the entry
+func (e patternEntry) toPattern() (*RootCausePattern, error) {
+	if !rePlaybookID.MatchString(e.ID) {
+		return nil, fmt.Errorf("id %q must be lowercase letters, digits and dashes", e.ID)
+	}
+	if strings.TrimSpace(e.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if len(e.Indicators) == 0 && len(e.Conditions) == 0 {
+		return nil, fmt.Errorf("at least one indicator or condition is required")
+	}
+	if e.Confidence <= 0 || e.Confidence > 1 {
+		return nil, fmt.Errorf("confidence %.2f must be in (0, 1]", e.Confidence)
+	}
+	switch e.Severity {
+	case "low", "medium", "high", "critical":
+	default:
+		return nil, fmt.Errorf("severity %q must be low, medium, high or critical", e.Severity)
+	}
+	p := &RootCausePattern{Name: e.Name, Category: e.Category, Indicators: e.Indicators, Confidence: e.Confidence, Severity: e.Severity}
+	for i, c := range e.Conditions {
+		check := ConditionCheck{Field: c.Field, Operator: c.Operator, Value: c.Value}
+		if err := ValidateCondition(check); err != nil {
+			return nil, fmt.Errorf("condition %d: %w", i+1, err)
+		}
+		p.Conditions = append(p.Conditions, check)
+	}
+	for i, s := range e.Solutions {
+		if !validRisk(s.Risk) {
+			return nil, fmt.Errorf("solution %d: risk %q must be low, medium or high", i+1, s.Risk)
+		}
+		if s.Effectiveness < 0 || s.Effectiveness > 1 {
+			return nil, fmt.Errorf("solution %d: effectiveness %.2f must be in [0, 1]", i+1, s.Effectiveness)
+		}
+		p.Solutions = append(p.Solutions, Solution{Title: s.Title, Description: s.Description, Command: s.Command, Risk: s.Risk, Effectiveness: s.Effectiveness})
+	}
+	return p, nil
+}
+
+func validRisk(risk string) bool {
+	return risk == "low" || risk == "medium" || risk == "high"
+}
+
+// LoadPlaybookDirs replaces the library's contents with the built-ins overlaid by the
+// *.yaml and *.yml files in dirs. Later directories override earlier ones and both
+// override the built-ins. Missing directories are skipped; invalid files and entries
+// are skipped and returned as errors.
+func (pl *PlaybookLibrary) LoadPlaybookDirs(dirs []string) []error {
+	// Taken before reading so that an edit made during the load triggers another one
+	fingerprint := playbookFingerprint(dirs)
+	fresh := NewPlaybookLibrary()
+	origins := make(map[string]string)
+	for id := range fresh.playbooks {
+		origins["playbook:"+id] = BuiltinOrigin
+	}
+	for id := range fresh.patterns {
+		origins["pattern:"+id] = BuiltinOrigin
+	}
+
+	var errs []error
+	for _, path := range playbookFiles(dirs, &errs) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		var file playbookFile
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		for _, entry := range file.Playbooks {
+			pb, err := entry.toPlaybook()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: playbook %q: %w", path, entry.ID, err))
+				continue
+			}
+			fresh.playbooks[entry.ID] = pb
+			origins["playbook:"+entry.ID] = path
+		}
+		for _, entry := range file.Patterns {
+			p, err := entry.toPattern()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: pattern %q: %w", path, entry.ID, err))
+				continue
+			}
+			fresh.patterns[entry.ID] = p
+			origins["pattern:"+entry.ID] = path
+		}
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.playbooks = fresh.playbooks
+	pl.patterns = fresh.patterns
+	pl.origins = origins
+	pl.dirs = append([]string(nil), dirs...)
+	pl.fingerprint = fingerprint
+	pl.loadErrs = errs
+	return errs
+}
+
+// ReloadIfChanged reloads the playbook directories when a file was added, removed or
+// modified since the last load
+func (pl *PlaybookLibrary) ReloadIfChanged() (bool, []error) {
+	pl.mu.RLock()
+	dirs, fingerprint := pl.dirs, pl.fingerprint
+	pl.mu.RUnlock()
+	if len(dirs) == 0 || playbookFingerprint(dirs) == fingerprint {
+		return false, nil
+	}
+	return true, pl.LoadPlaybookDirs(dirs)
+}
+
+// LoadErrors returns the problems found by the last load
+func (pl *PlaybookLibrary) LoadErrors() []error {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	return pl.loadErrs
+}
+
+// PlaybookIDs returns the IDs of all playbooks, sorted
+func (pl *PlaybookLibrary) PlaybookIDs() []string {
+	playbooks, _ := pl.snapshot()
+	ids := make([]string, 0, len(playbooks))
+	for id := range playbooks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// PlaybookByID returns a playbook and where it came from: BuiltinOrigin or a file path
+func (pl *PlaybookLibrary) PlaybookByID(id string) (*Playbook, string, bool) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+	pb, ok := pl.playbooks[id]
+	if !ok {
+		return nil, "", false
+	}
+	origin := pl.origins["playbook:"+id]
+	if origin == "" {
+		origin = BuiltinOrigin
+	}
+	return pb, origin, true
+}
+
+// PlaybookPlan turns a playbook into a diagnostic plan, substituting {key} placeholders
+// with vars. Every placeholder must be given, and values must be plain resource names.
+func (pl *PlaybookLibrary) PlaybookPlan(id string, vars map[string]string) (DiagPlan, error) {
+	pb, _, ok := pl.PlaybookByID(id)
+	if !ok {
+		return DiagPlan{}, fmt.Errorf("unknown playbook %q", id)
+	}
+	for key, value := range vars {
+		if !rePlaybookValue.MatchString(value) {
+			return DiagPlan{}, fmt.Errorf("invalid value %q for %s", value, key)
+		}
+	}
+	plan := DiagPlanFromPlaybook(pb, vars)
+	var missing []string
+	for _, step := range plan.Steps {
+		for _, placeholder := range rePlaybookPlaceholder.FindAllString(step, -1) {
+			if key := strings.Trim(placeholder, "{}"); !containsString(missing, key) {
+				missing = append(missing, key)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return DiagPlan{}, fmt.Errorf("playbook %q needs %s", id, strings.Join(missing, ", "))
+	}
+	return plan, nil
+}
+
+// playbookFiles lists the YAML files of each directory in name order
+func playbookFiles(dirs []string, errs *[]error) []string {
+	var files []string
+	for _, dir := range dirs {
+		dir = expandHome(dir)
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) && errs != nil {
+				*errs = append(*errs, err)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files
+}
+
+// playbookFingerprint changes whenever a playbook file is added, removed or modified
+func playbookFingerprint(dirs []string) string {
+	var sb strings.Builder
+	for _, path := range playbookFiles(dirs, nil) {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&sb, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return sb.String()
+}
+
+// expandHome resolves a leading "~/" to the user's home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
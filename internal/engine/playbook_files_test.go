@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const teamPlaybooks = `playbooks:
+  - id: kafka-lag
+    name: Kafka Consumer Lag
+    category: messaging
+    triggers: ["consumer lag"]
+    steps:
+      - name: Consumer pods
+        command: kubectl get pods -n {namespace} -l app={pod}
+        timeout: 5
+      - command: kubectl logs {pod} -n {namespace} --tail=50
+    next_moves:
+      - action: Restart consumers
+        command: kubectl rollout restart deploy/{pod} -n {namespace}
+        risk: medium
+  - id: pod-not-ready
+    name: Team Pod Triage
+    steps:
+      - command: kubectl describe pod {pod} -n {namespace}
+  - id: wipe
+    name: Dangerous
+    steps:
+      - command: kubectl delete pod {pod} -n {namespace}
+patterns:
+  - id: kafka-rebalance
+    name: Consumer group rebalancing
+    category: messaging
+    indicators: ["rebalance in progress"]
+    confidence: 0.9
+    severity: medium
+  - id: vague
+    name: Vague
+    indicators: ["error"]
+    confidence: 3
+    severity: medium
+`
+
+func TestLoadPlaybookDirsMergesAndValidates(t *testing.T) {
+	userDir, repoDir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(userDir, "team.yaml"), []byte(teamPlaybooks), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	override := "playbooks:\n  - id: kafka-lag\n    name: Repo Kafka Lag\n    steps:\n      - command: kubectl get pods -n {namespace}\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "kafka.yml"), []byte(override), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "notes.txt"), []byte("not yaml: ["), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pl := NewPlaybookLibrary()
+	errs := pl.LoadPlaybookDirs([]string{userDir, repoDir, filepath.Join(repoDir, "missing")})
+	if len(errs) != 2 {
+		t.Fatalf("expected the delete step and the confidence to be rejected, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), `playbook "wipe"`) || !strings.Contains(errs[1].Error(), `pattern "vague"`) {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	if pb, origin, ok := pl.PlaybookByID("kafka-lag"); !ok || pb.Name != "Repo Kafka Lag" || origin != filepath.Join(repoDir, "kafka.yml") {
+		t.Errorf("repo playbook should override the user one: %+v %s", pb, origin)
+	}
+	if pb, _, _ := pl.PlaybookByID("pod-not-ready"); pb.Name != "Team Pod Triage" {
+		t.Errorf("file playbook should override the built-in, got %q", pb.Name)
+	}
+	if _, origin, ok := pl.PlaybookByID("crashloop-backoff"); ok && origin != BuiltinOrigin {
+		t.Errorf("built-in origin = %q", origin)
+	}
+	if _, _, ok := pl.PlaybookByID("wipe"); ok {
+		t.Error("a playbook with a mutating step must not load")
+	}
+	if ranked := pl.RankRootCauses([]string{"group rebalance in progress"}); len(ranked) == 0 || ranked[0].RootCause != "Consumer group rebalancing" {
+		t.Errorf("file pattern not detected: %+v", ranked)
+	}
+	if len(PlaybookSources(pl)) != len(pl.PlaybookIDs()) {
+		t.Error("every playbook should be indexable")
+	}
+}
+
+func TestPlaybookReloadIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	pl := NewPlaybookLibrary()
+	pl.LoadPlaybookDirs([]string{dir})
+	if changed, _ := pl.ReloadIfChanged(); changed {
+		t.Fatal("nothing changed yet")
+	}
+
+	path := filepath.Join(dir, "team.yaml")
+	if err := os.WriteFile(path, []byte(teamPlaybooks), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed, errs := pl.ReloadIfChanged()
+	if !changed || len(errs) != 2 {
+		t.Fatalf("new file should reload: %v %v", changed, errs)
+	}
+	if _, _, ok := pl.PlaybookByID("kafka-lag"); !ok {
+		t.Error("kafka-lag should be loaded")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := pl.ReloadIfChanged(); !changed {
+		t.Fatal("removed file should reload")
+	}
+	if _, _, ok := pl.PlaybookByID("kafka-lag"); ok {
+		t.Error("kafka-lag should be gone")
+	}
+	if pb, origin, ok := pl.PlaybookByID("pod-not-ready"); !ok || origin != BuiltinOrigin || pb.Name == "Team Pod Triage" {
+		t.Error("the built-in should be back")
+	}
+	if len(pl.LoadErrors()) != 0 {
+		t.Errorf("stale load errors: %v", pl.LoadErrors())
+	}
+}
+
+func TestPlaybookPlanSubstitutesPlaceholders(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "team.yaml"), []byte(teamPlaybooks), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pl := NewPlaybookLibrary()
+	pl.LoadPlaybookDirs([]string{dir})
+
+	plan, err := pl.PlaybookPlan("kafka-lag", map[string]string{"pod": "orders", "namespace": "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Steps[0] != "kubectl get pods -n shop -l app=orders" || plan.Timeouts[0] != 5*time.Second {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+
+	if _, err := pl.PlaybookPlan("kafka-lag", map[string]string{"namespace": "shop"}); err == nil || !strings.Contains(err.Error(), "pod") {
+		t.Errorf("expected a missing pod error, got %v", err)
+	}
+	if _, err := pl.PlaybookPlan("kafka-lag", map[string]string{"pod": "x; kubectl delete ns shop", "namespace": "shop"}); err == nil {
+		t.Error("values must not be able to add commands")
+	}
+	if _, err := pl.PlaybookPlan("nope", nil); err == nil {
+		t.Error("expected an unknown playbook error")
+	}
+}
@@ -0,0 +1,56 @@
+package promptbudget
+
+import "strings"
+
+// ChatMessage is one chat message, already redacted and fenced when untrusted
+type ChatMessage struct {
+	Role    string // label rendered before the content, e.g. "User"
+	Content string
+	User    bool // typed by the user rather than produced by the model or a command
+}
+
+// ChatInput collects a chat turn before budgeting
+type ChatInput struct {
+	System        string
+	Cluster       []string // the [CTX] summary and remembered facts, in that order
+	Knowledge     []string
+	History       []ChatMessage
+	HistoryLength int // most recent messages considered, 0 for all
+}
+
+// PackChat packs a chat turn. A trailing user message becomes the user turn, which is
+// never cut; the cluster context is its own section and the first to be truncated.
+func (b *PromptBudget) PackChat(in ChatInput) PackedPrompt {
+	history := in.History
+	if in.HistoryLength > 0 && len(history) > in.HistoryLength {
+		history = history[len(history)-in.HistoryLength:]
+	}
+
+	user := ""
+	if last := len(history) - 1; last >= 0 && history[last].User {
+		user = strings.TrimSpace(history[last].Content)
+		history = history[:last]
+	}
+
+	turns := make([]PromptTurn, 0, len(history))
+	for _, msg := range history {
+		if content := strings.TrimSpace(msg.Content); content != "" {
+			turns = append(turns, PromptTurn{Role: msg.Role, Content: content})
+		}
+	}
+
+	var cluster []string
+	for _, part := range in.Cluster {
+		if part = strings.TrimSpace(part); part != "" {
+			cluster = append(cluster, part)
+		}
+	}
+
+	return b.Pack(PromptInput{
+		System:    in.System,
+		Cluster:   strings.Join(cluster, "\n"),
+		Knowledge: in.Knowledge,
+		History:   turns,
+		User:      user,
+	})
+}
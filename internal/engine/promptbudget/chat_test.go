@@ -0,0 +1,56 @@
+package promptbudget
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPackChatTruncatesClusterContext(t *testing.T) {
+	budget := NewPromptBudget(400)
+	budget.ResponseReserve = 100
+	question := "Why does web-0 keep restarting after the last rollout? " + strings.Repeat("It ran fine yesterday. ", 12)
+
+	packed := budget.PackChat(ChatInput{
+		Cluster: []string{"context=kind-dev namespace=shop " + strings.Repeat("pods=12 crashloop=1 ", 150) + "END-OF-CLUSTER"},
+		History: []ChatMessage{
+			{Role: "User", Content: "show pods", User: true},
+			{Role: "Assistant", Content: "kubectl get pods"},
+			{Role: "User", Content: question, User: true},
+		},
+		HistoryLength: 10,
+	})
+
+	if !strings.HasSuffix(packed.Prompt, "User: "+strings.TrimSpace(question)+"\n\nAssistant:") {
+		t.Errorf("the user turn should be kept whole and last:\n%s", packed.Prompt)
+	}
+	if !strings.Contains(packed.Prompt, "[CTX] context=kind-dev namespace=shop") || strings.Contains(packed.Prompt, "END-OF-CLUSTER") {
+		t.Errorf("the cluster context should be cut from its end:\n%s", packed.Prompt)
+	}
+	if !strings.Contains(packed.DropReport(), "cluster context truncated") {
+		t.Errorf("the truncation should be reported, got %q", packed.DropReport())
+	}
+	if packed.Tokens[SectionUser] == 0 || packed.Tokens[SectionCluster] == 0 {
+		t.Errorf("user and cluster should be budgeted as their own sections: %v", packed.Tokens)
+	}
+}
+
+func TestPackChatKeepsRecentHistory(t *testing.T) {
+	packed := NewPromptBudget(0).PackChat(ChatInput{
+		Cluster: []string{"", "Remembered: shop runs on spot nodes"},
+		History: []ChatMessage{
+			{Role: "User", Content: "first", User: true},
+			{Role: "Assistant", Content: "Hi there"},
+			{Role: "Command Output", Content: "  "},
+			{Role: "Assistant", Content: "done"},
+		},
+		HistoryLength: 3,
+	})
+
+	expected := "[CTX] Remembered: shop runs on spot nodes\n\nAssistant: Hi there\n\nAssistant: done\n\nAssistant:"
+	if packed.Prompt != expected {
+		t.Errorf("expected prompt %q, got %q", expected, packed.Prompt)
+	}
+	if packed.Tokens[SectionUser] != 0 {
+		t.Error("a history ending in a model turn has no user turn")
+	}
+}
@@ -0,0 +1,418 @@
+// Package promptbudget packs prompts sent to Ollama into the model's context window,
+// trimming the lowest-value sections first.
+package promptbudget
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PromptSection identifies a budgeted part of a prompt
+type PromptSection string
+
+const (
+	SectionSystem    PromptSection = "system"
+	SectionCluster   PromptSection = "cluster"
+	SectionKnowledge PromptSection = "knowledge"
+	SectionHistory   PromptSection = "history"
+	SectionFile      PromptSection = "file"
+	SectionUser      PromptSection = "user"
+)
+
+const (
+	// defaultResponseReserve keeps room in num_ctx for the model's answer
+	defaultResponseReserve = 1024
+	// summaryWordsPerTurn bounds how much of each dropped turn survives in the summary
+	summaryWordsPerTurn = 12
+)
+
+// PromptTurn is a single conversational turn fed to the model
+type PromptTurn struct {
+	Role    string // label rendered before the content, e.g. "User"
+	Content string
+}
+
+// PromptFile is a file payload (manifest or values) attached to a prompt
+type PromptFile struct {
+	Path    string
+	Content string
+}
+
+// PromptInput collects everything a prompt may contain before budgeting
+type PromptInput struct {
+	System    string
+	Cluster   string
+	Knowledge []string
+	History   []PromptTurn
+	File      *PromptFile
+	User      string
+}
+
+// PromptBudget allocates num_ctx tokens across prompt sections
+type PromptBudget struct {
+	NumCtx          int
+	ResponseReserve int
+	Shares          map[PromptSection]float64 // fraction of the flexible budget per section
+}
+
+// PackedPrompt is the result of packing a PromptInput into a budget
+type PackedPrompt struct {
+	System  string
+	Prompt  string
+	Tokens  map[PromptSection]int
+	Dropped []string // human-readable notes about what was cut
+}
+
+// NewPromptBudget creates a budget for the given context length; 0 disables limits
+func NewPromptBudget(numCtx int) *PromptBudget {
+	reserve := defaultResponseReserve
+	if numCtx > 0 && reserve > numCtx/4 {
+		reserve = numCtx / 4
+	}
+	return &PromptBudget{
+		NumCtx:          numCtx,
+		ResponseReserve: reserve,
+		Shares: map[PromptSection]float64{
+			SectionCluster:   0.10,
+			SectionKnowledge: 0.20,
+			SectionFile:      0.40,
+			SectionHistory:   0.30,
+		},
+	}
+}
+
+// Unlimited reports whether the budget imposes no limit
+func (b *PromptBudget) Unlimited() bool {
+	return b == nil || b.NumCtx <= 0
+}
+
+// Available returns the tokens usable by the prompt after reserving the response
+func (b *PromptBudget) Available() int {
+	if b.Unlimited() {
+		return 0
+	}
+	avail := b.NumCtx - b.ResponseReserve
+	if avail < 0 {
+		return 0
+	}
+	return avail
+}
+
+// FileCap returns the maximum tokens a file payload may use on its own
+func (b *PromptBudget) FileCap() int {
+	if b.Unlimited() {
+		return 0
+	}
+	return int(float64(b.Available()) * b.Shares[SectionFile])
+}
+
+// EstimateTokens approximates token usage (~4 characters per token)
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return (len(text) + 3) / 4
+}
+
+// Pack fits the input into the budget, trimming the lowest-value content first.
+// The system prompt, user turn and the latest history turn are never dropped.
+func (b *PromptBudget) Pack(in PromptInput) PackedPrompt {
+	packed := PackedPrompt{
+		System: in.System,
+		Tokens: make(map[PromptSection]int),
+	}
+
+	cluster := strings.TrimSpace(in.Cluster)
+	knowledge := in.Knowledge
+	history := in.History
+	var file *PromptFile
+	if in.File != nil {
+		f := *in.File
+		file = &f
+	}
+
+	if !b.Unlimited() {
+		var latest []PromptTurn
+		if len(history) > 0 {
+			latest = history[len(history)-1:]
+			history = history[:len(history)-1]
+		}
+
+		mandatory := EstimateTokens(in.System) + EstimateTokens(in.User) + turnsTokens(latest)
+		remaining := b.Available() - mandatory
+		if remaining < 0 {
+			packed.Dropped = append(packed.Dropped, fmt.Sprintf("prompt exceeds num_ctx=%d before optional context (%d tokens required)", b.NumCtx, mandatory))
+			remaining = 0
+		}
+
+		needs := map[PromptSection]int{
+			SectionCluster:   EstimateTokens(cluster),
+			SectionKnowledge: snippetsTokens(knowledge),
+			SectionHistory:   turnsTokens(history),
+		}
+		if file != nil {
+			needs[SectionFile] = EstimateTokens(file.Content)
+		}
+		alloc := b.allocate(remaining, needs)
+
+		if needs[SectionCluster] > alloc[SectionCluster] {
+			cluster = truncateToTokens(cluster, alloc[SectionCluster])
+			packed.Dropped = append(packed.Dropped, fmt.Sprintf("cluster context truncated to %d tokens", alloc[SectionCluster]))
+		}
+		if needs[SectionKnowledge] > alloc[SectionKnowledge] {
+			kept := fitSnippets(knowledge, alloc[SectionKnowledge])
+			packed.Dropped = append(packed.Dropped, fmt.Sprintf("knowledge: dropped %d of %d snippets", len(knowledge)-len(kept), len(knowledge)))
+			knowledge = kept
+		}
+		if file != nil && needs[SectionFile] > alloc[SectionFile] {
+			content, notes := TruncateYAMLDocuments(file.Content, alloc[SectionFile])
+			file.Content = content
+			for _, n := range notes {
+				packed.Dropped = append(packed.Dropped, fmt.Sprintf("file %s: %s", file.Path, n))
+			}
+		}
+		if needs[SectionHistory] > alloc[SectionHistory] {
+			var note string
+			history, note = fitHistory(history, alloc[SectionHistory])
+			packed.Dropped = append(packed.Dropped, note)
+		}
+		history = append(history, latest...)
+	}
+
+	var sb strings.Builder
+	if cluster != "" {
+		section := fmt.Sprintf("[CTX] %s\n\n", cluster)
+		packed.Tokens[SectionCluster] = EstimateTokens(section)
+		sb.WriteString(section)
+	}
+	if len(knowledge) > 0 {
+		var kb strings.Builder
+		kb.WriteString("Relevant knowledge:\n")
+		for _, k := range knowledge {
+			kb.WriteString("- ")
+			kb.WriteString(strings.TrimSpace(k))
+			kb.WriteString("\n")
+		}
+		kb.WriteString("\n")
+		packed.Tokens[SectionKnowledge] = EstimateTokens(kb.String())
+		sb.WriteString(kb.String())
+	}
+	if file != nil && strings.TrimSpace(file.Content) != "" {
+		section := fmt.Sprintf("File %s:\n```yaml\n%s\n```\n\n", file.Path, file.Content)
+		packed.Tokens[SectionFile] = EstimateTokens(section)
+		sb.WriteString(section)
+	}
+	for _, turn := range history {
+		content := strings.TrimSpace(turn.Content)
+		if content == "" {
+			continue
+		}
+		line := turn.Role + ": " + content + "\n\n"
+		packed.Tokens[SectionHistory] += EstimateTokens(line)
+		sb.WriteString(line)
+	}
+	if in.User != "" {
+		line := "User: " + in.User + "\n\n"
+		packed.Tokens[SectionUser] = EstimateTokens(line)
+		sb.WriteString(line)
+	}
+	sb.WriteString("Assistant:")
+	packed.Tokens[SectionSystem] = EstimateTokens(in.System)
+
+	packed.Prompt = sb.String()
+	return packed
+}
+
+// DropReport renders the dropped notes for display, or "" when nothing was cut
+func (p PackedPrompt) DropReport() string {
+	if len(p.Dropped) == 0 {
+		return ""
+	}
+	return "✂️ Prompt budget: " + strings.Join(p.Dropped, "; ")
+}
+
+// TotalTokens returns the estimated size of the packed prompt
+func (p PackedPrompt) TotalTokens() int {
+	total := 0
+	for _, t := range p.Tokens {
+		total += t
+	}
+	return total
+}
+
+// allocate splits remaining tokens by share, then hands unused share to sections still short
+func (b *PromptBudget) allocate(remaining int, needs map[PromptSection]int) map[PromptSection]int {
+	alloc := make(map[PromptSection]int)
+	pool := remaining
+	order := []PromptSection{SectionCluster, SectionKnowledge, SectionFile, SectionHistory}
+
+	for _, section := range order {
+		share := int(float64(remaining) * b.Shares[section])
+		give := needs[section]
+		if give > share {
+			give = share
+		}
+		alloc[section] = give
+		pool -= give
+	}
+
+	// Second pass: recent history first, then file, knowledge and cluster
+	for _, section := range []PromptSection{SectionHistory, SectionFile, SectionKnowledge, SectionCluster} {
+		if pool <= 0 {
+			break
+		}
+		short := needs[section] - alloc[section]
+		if short <= 0 {
+			continue
+		}
+		if short > pool {
+			short = pool
+		}
+		alloc[section] += short
+		pool -= short
+	}
+	return alloc
+}
+
+// fitHistory keeps the most recent turns that fit and summarizes the rest
+func fitHistory(turns []PromptTurn, budget int) ([]PromptTurn, string) {
+	keepFrom := len(turns)
+	used := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		cost := EstimateTokens(turns[i].Role + ": " + turns[i].Content + "\n\n")
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		keepFrom = i
+	}
+
+	dropped := turns[:keepFrom]
+	kept := append([]PromptTurn(nil), turns[keepFrom:]...)
+	if len(dropped) == 0 {
+		return kept, ""
+	}
+
+	summary := summarizeTurns(dropped)
+	if summary != "" && used+EstimateTokens(summary) <= budget {
+		kept = append([]PromptTurn{{Role: "Summary", Content: summary}}, kept...)
+		return kept, fmt.Sprintf("history: summarized %d older turns", len(dropped))
+	}
+	return kept, fmt.Sprintf("history: dropped %d older turns", len(dropped))
+}
+
+// summarizeTurns condenses turns into a single line of short user/assistant gists
+func summarizeTurns(turns []PromptTurn) string {
+	var parts []string
+	for _, t := range turns {
+		words := strings.Fields(t.Content)
+		if len(words) == 0 {
+			continue
+		}
+		if len(words) > summaryWordsPerTurn {
+			words = append(words[:summaryWordsPerTurn], "…")
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", t.Role, strings.Join(words, " ")))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Earlier conversation (%d turns) — %s", len(turns), strings.Join(parts, " | "))
+}
+
+// TruncateYAMLDocuments keeps whole leading YAML documents that fit in maxTokens.
+// Keeping a prefix preserves line numbers, so diffs against the full file still apply.
+func TruncateYAMLDocuments(content string, maxTokens int) (string, []string) {
+	if maxTokens <= 0 || EstimateTokens(content) <= maxTokens {
+		return content, nil
+	}
+
+	docs := splitYAMLDocuments(content)
+	var kept []string
+	used := 0
+	for _, doc := range docs {
+		cost := EstimateTokens(doc)
+		if used+cost > maxTokens {
+			break
+		}
+		kept = append(kept, doc)
+		used += cost
+	}
+
+	if len(kept) == 0 {
+		// First document alone is too large: cut it on a line boundary
+		lines := strings.SplitAfter(docs[0], "\n")
+		var sb strings.Builder
+		for _, line := range lines {
+			if EstimateTokens(sb.String()+line) > maxTokens {
+				break
+			}
+			sb.WriteString(line)
+		}
+		note := fmt.Sprintf("first YAML document cut to %d tokens, %d later documents dropped", maxTokens, len(docs)-1)
+		return strings.TrimRight(sb.String(), "\n") + "\n# ...(truncated by prompt budget)...", []string{note}
+	}
+
+	note := fmt.Sprintf("kept %d of %d YAML documents", len(kept), len(docs))
+	return strings.TrimRight(strings.Join(kept, ""), "\n") + "\n# ...(later documents omitted by prompt budget)...", []string{note}
+}
+
+// splitYAMLDocuments splits on "---" separator lines, keeping the separators with each document
+func splitYAMLDocuments(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	var docs []string
+	var current strings.Builder
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "---" && strings.TrimSpace(current.String()) != "" {
+			docs = append(docs, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+	}
+	if current.Len() > 0 {
+		docs = append(docs, current.String())
+	}
+	return docs
+}
+
+func fitSnippets(snippets []string, budget int) []string {
+	var kept []string
+	used := 0
+	for _, s := range snippets {
+		cost := EstimateTokens("- " + s + "\n")
+		if used+cost > budget {
+			break
+		}
+		kept = append(kept, s)
+		used += cost
+	}
+	return kept
+}
+
+func truncateToTokens(text string, tokens int) string {
+	limit := tokens * 4
+	if limit <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "…"
+}
+
+func turnsTokens(turns []PromptTurn) int {
+	total := 0
+	for _, t := range turns {
+		total += EstimateTokens(t.Role + ": " + t.Content + "\n\n")
+	}
+	return total
+}
+
+func snippetsTokens(snippets []string) int {
+	total := 0
+	for _, s := range snippets {
+		total += EstimateTokens("- " + s + "\n")
+	}
+	return total
+}
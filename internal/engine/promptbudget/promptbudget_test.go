@@ -0,0 +1,91 @@
+package promptbudget
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptBudgetUnlimitedKeepsEverything(t *testing.T) {
+	budget := NewPromptBudget(0)
+	packed := budget.Pack(PromptInput{
+		History: []PromptTurn{
+			{Role: "User", Content: "Hello"},
+			{Role: "Assistant", Content: "Hi there"},
+		},
+	})
+
+	expected := "User: Hello\n\nAssistant: Hi there\n\nAssistant:"
+	if packed.Prompt != expected {
+		t.Errorf("expected prompt %q, got %q", expected, packed.Prompt)
+	}
+	if len(packed.Dropped) != 0 {
+		t.Errorf("expected nothing dropped, got %v", packed.Dropped)
+	}
+}
+
+func TestPromptBudgetDropsOldestHistory(t *testing.T) {
+	var history []PromptTurn
+	for i := 0; i < 40; i++ {
+		history = append(history, PromptTurn{Role: "User", Content: strings.Repeat("old words ", 30)})
+	}
+	history = append(history, PromptTurn{Role: "User", Content: "why is my pod crashing?"})
+
+	budget := NewPromptBudget(1024)
+	packed := budget.Pack(PromptInput{System: "You are helpful.", History: history})
+
+	if !strings.Contains(packed.Prompt, "why is my pod crashing?") {
+		t.Error("latest turn must always be kept")
+	}
+	if packed.DropReport() == "" {
+		t.Error("expected a drop report when history exceeds the budget")
+	}
+	if packed.TotalTokens() > budget.Available() {
+		t.Errorf("packed prompt uses %d tokens, budget is %d", packed.TotalTokens(), budget.Available())
+	}
+}
+
+func TestPromptBudgetTrimsKnowledgeBeforeLatestTurn(t *testing.T) {
+	knowledge := make([]string, 50)
+	for i := range knowledge {
+		knowledge[i] = strings.Repeat("snippet ", 40)
+	}
+
+	packed := NewPromptBudget(2048).Pack(PromptInput{
+		Knowledge: knowledge,
+		History:   []PromptTurn{{Role: "User", Content: "explain"}},
+	})
+
+	if !strings.Contains(packed.Prompt, "User: explain") {
+		t.Error("latest turn was dropped")
+	}
+	found := false
+	for _, d := range packed.Dropped {
+		if strings.HasPrefix(d, "knowledge:") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected knowledge to be trimmed, got %v", packed.Dropped)
+	}
+}
+
+func TestTruncateYAMLDocumentsKeepsLeadingDocuments(t *testing.T) {
+	first := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n"
+	second := "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\ndata:\n  big: " + strings.Repeat("x", 400) + "\n"
+	content := first + second
+
+	out, notes := TruncateYAMLDocuments(content, EstimateTokens(first)+5)
+	if !strings.HasPrefix(out, first) {
+		t.Errorf("expected first document to be kept verbatim, got %q", out)
+	}
+	if strings.Contains(out, "name: b") {
+		t.Error("expected second document to be dropped")
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], "kept 1 of 2") {
+		t.Errorf("unexpected notes: %v", notes)
+	}
+
+	if same, notes := TruncateYAMLDocuments(content, 10000); same != content || notes != nil {
+		t.Error("content under budget should be returned unchanged")
+	}
+}
@@ -0,0 +1,140 @@
+// proposals.go - Structured fix proposals from the agent's final answer, bound to their evidence
+package engine
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/siryoos/kubemage/internal/engine/validator"
+)
+
+// Kinds of fix proposals
+const (
+	ProposalCommand = "command" // a remediation command, previewed as a PreExecPlan
+	ProposalPatch   = "patch"   // a unified diff for a workspace file, previewed as a DiffSession
+)
+
+// FixProposal is a remediation the agent proposes after Final:. Proposals are never
+// executed directly; they go through the same preview and confirmation as any command.
+type FixProposal struct {
+	Kind     string
+	Command  string // ProposalCommand
+	FilePath string // ProposalPatch
+	Diff     string // ProposalPatch
+	Evidence []string
+	// Ungrounded lists evidence quotes that appear in no observation of the session
+	Ungrounded []string
+}
+
+var (
+	reProposalFix      = regexp.MustCompile(`(?i)^\s*(?:[-*]\s*)?fix:\s*(.+?)\s*$`)
+	reProposalPatch    = regexp.MustCompile(`(?i)^\s*(?:[-*]\s*)?patch:\s*(.+?)\s*$`)
+	reProposalEvidence = regexp.MustCompile(`(?i)^\s*(?:[-*]\s*)?evidence:\s*(.+?)\s*$`)
+)
+
+// ParseFixProposals extracts "Fix: <command>" and "Patch: <file>" proposals, each followed
+// by optional "Evidence: <quote>" lines; a patch is the ```diff block that follows it
+func ParseFixProposals(reply string) []FixProposal {
+	var proposals []FixProposal
+	var current *FixProposal
+	var diff strings.Builder
+	inDiff := false
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if current.Kind == ProposalPatch {
+			current.Diff = strings.TrimSpace(diff.String())
+			diff.Reset()
+		}
+		if current.Command != "" || (current.FilePath != "" && current.Diff != "") {
+			proposals = append(proposals, *current)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(reply, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if inDiff {
+			if strings.HasPrefix(trimmed, "```") {
+				inDiff = false
+				continue
+			}
+			diff.WriteString(line + "\n")
+			continue
+		}
+		if m := reProposalFix.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &FixProposal{Kind: ProposalCommand, Command: strings.Trim(m[1], "` ")}
+			continue
+		}
+		if m := reProposalPatch.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &FixProposal{Kind: ProposalPatch, FilePath: strings.Trim(m[1], "` ")}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := reProposalEvidence.FindStringSubmatch(line); m != nil {
+			current.Evidence = append(current.Evidence, strings.Trim(m[1], "`\"' "))
+			continue
+		}
+		if current.Kind == ProposalPatch && current.Diff == "" && diff.Len() == 0 && strings.HasPrefix(trimmed, "```") {
+			inDiff = true
+		}
+	}
+	flush()
+	return proposals
+}
+
+// GroundEvidence records which evidence quotes appear in none of the observations
+func (p *FixProposal) GroundEvidence(observations []string) {
+	normalized := make([]string, len(observations))
+	for i, obs := range observations {
+		normalized[i] = normalizeEvidence(obs)
+	}
+	p.Ungrounded = nil
+	for _, quote := range p.Evidence {
+		needle := normalizeEvidence(quote)
+		found := false
+		for _, obs := range normalized {
+			if needle != "" && strings.Contains(obs, needle) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			p.Ungrounded = append(p.Ungrounded, quote)
+		}
+	}
+}
+
+// Grounded reports whether the proposal cites evidence and all of it was observed
+func (p FixProposal) Grounded() bool {
+	return len(p.Evidence) > 0 && len(p.Ungrounded) == 0
+}
+
+// TagPlan adds the proposal's evidence to a plan's notes so the preview shows what the fix relies on
+func (p FixProposal) TagPlan(plan *validator.PreExecPlan) {
+	if len(p.Evidence) == 0 {
+		plan.Notes = append(plan.Notes, "⚠️  The agent cited no evidence for this fix")
+		return
+	}
+	for _, quote := range p.Evidence {
+		note := "🔎 Evidence: " + quote
+		if containsString(p.Ungrounded, quote) {
+			note += " (not found in any observation)"
+		}
+		plan.Notes = append(plan.Notes, note)
+	}
+}
+
+// Title is a one-line description for proposal lists
+func (p FixProposal) Title() string {
+	if p.Kind == ProposalPatch {
+		return "patch " + p.FilePath
+	}
+	return p.Command
+}
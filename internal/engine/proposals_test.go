@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/siryoos/kubemage/internal/engine/validator"
+)
+
+const proposalReply = `Final: The api pods are OOMKilled because the 256Mi limit is too low.
+
+Fix: ` + "`kubectl set resources deployment/api -n payments --limits=memory=512Mi`" + `
+Evidence: Reason: OOMKilled
+Evidence: "Exit Code: 137"
+
+Patch: deploy/api.yaml
+Evidence: memory limit 256Mi exceeded
+` + "```diff" + `
+--- a/deploy/api.yaml
++++ b/deploy/api.yaml
+@@ -20,1 +20,1 @@
+-            memory: 256Mi
++            memory: 512Mi
+` + "```" + `
+Fix: kubectl rollout restart deployment/api -n payments`
+
+func TestParseFixProposals(t *testing.T) {
+	proposals := ParseFixProposals(proposalReply)
+	if len(proposals) != 3 {
+		t.Fatalf("expected 3 proposals, got %+v", proposals)
+	}
+
+	resize := proposals[0]
+	if resize.Kind != ProposalCommand || resize.Command != "kubectl set resources deployment/api -n payments --limits=memory=512Mi" {
+		t.Errorf("unexpected command proposal: %+v", resize)
+	}
+	if len(resize.Evidence) != 2 || resize.Evidence[1] != "Exit Code: 137" {
+		t.Errorf("unexpected evidence: %q", resize.Evidence)
+	}
+
+	patch := proposals[1]
+	if patch.Kind != ProposalPatch || patch.FilePath != "deploy/api.yaml" || !strings.HasPrefix(patch.Diff, "--- a/deploy/api.yaml") || !strings.HasSuffix(patch.Diff, "+            memory: 512Mi") {
+		t.Errorf("unexpected patch proposal: %+v", patch)
+	}
+
+	if restart := proposals[2]; restart.Command != "kubectl rollout restart deployment/api -n payments" || len(restart.Evidence) != 0 {
+		t.Errorf("unexpected trailing proposal: %+v", restart)
+	}
+	if ParseFixProposals("Final: nothing to fix\nPatch: deploy/api.yaml\n") != nil {
+		t.Error("a patch without a diff block should be dropped")
+	}
+}
+
+func TestFixProposalEvidenceGrounding(t *testing.T) {
+	proposal := ParseFixProposals(proposalReply)[0]
+	proposal.GroundEvidence([]string{"    Last State:     Terminated\n      Reason:       OOMKilled\n      Exit Code:    137\n"})
+	if !proposal.Grounded() {
+		t.Errorf("both quotes appear in the observation: ungrounded %q", proposal.Ungrounded)
+	}
+
+	proposal.GroundEvidence([]string{"Reason: OOMKilled"})
+	if proposal.Grounded() || len(proposal.Ungrounded) != 1 {
+		t.Errorf("exit code was never observed: ungrounded %q", proposal.Ungrounded)
+	}
+
+	plan := validator.BuildPreExecPlan(proposal.Command)
+	proposal.TagPlan(&plan)
+	preview := plan.HumanPreview()
+	if !strings.Contains(preview, "🔎 Evidence: Reason: OOMKilled") || !strings.Contains(preview, "Exit Code: 137 (not found in any observation)") {
+		t.Errorf("plan preview should carry the evidence:\n%s", preview)
+	}
+}
+
+func TestReActSessionCollectsProposalsWithoutRunningThem(t *testing.T) {
+	rs := NewReActSession(5)
+	rs.Steps = append(rs.Steps, ReActStep{Action: "kubectl describe pod api-1 -n payments", Allowed: true, Observation: "Reason: OOMKilled\nExit Code: 137"})
+	if err := rs.ProcessModelResponse(proposalReply); err != nil {
+		t.Fatal(err)
+	}
+	if !rs.Completed || len(rs.Proposals) != 3 || len(rs.Steps) != 1 {
+		t.Fatalf("final answer should only record proposals: %+v", rs)
+	}
+	if !rs.Proposals[0].Grounded() || rs.Proposals[1].Grounded() {
+		t.Errorf("unexpected grounding: %+v", rs.Proposals)
+	}
+}
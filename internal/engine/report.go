@@ -0,0 +1,221 @@
+// report.go - Markdown and HTML incident reports of diagnostic runs and agent sessions
+package engine
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Report formats
+const (
+	ReportMarkdown = "markdown"
+	ReportHTML     = "html"
+)
+
+// maxReportRootCauses caps the root causes listed in a report
+const maxReportRootCauses = 3
+
+//go:embed report_templates/*.tmpl
+var reportTemplates embed.FS
+
+// IncidentReport is what /report exports about one diagnosis
+type IncidentReport struct {
+	Title       string
+	GeneratedAt time.Time
+	Cluster     string // kube context
+	Namespace   string
+	User        string
+	Context     string // one-line cluster summary
+	Plan        *DiagPlan
+	Steps       []ReportStep
+	RootCauses  []*RootCauseAnalysis
+	FinalAnswer string
+}
+
+// ReportStep is one command run during the diagnosis
+type ReportStep struct {
+	Source   string // "diagnostic" or "agent"
+	Name     string
+	Command  string
+	Output   string
+	Notes    []string
+	Started  time.Time // zero when unknown
+	Duration time.Duration
+}
+
+// ReportStepsFromDiag converts a diagnostic plan's results
+func ReportStepsFromDiag(results []DiagResult) []ReportStep {
+	steps := make([]ReportStep, 0, len(results))
+	for _, r := range results {
+		steps = append(steps, ReportStep{
+			Source:   "diagnostic",
+			Name:     r.Step,
+			Command:  r.Command,
+			Output:   r.Output,
+			Notes:    r.Notes,
+			Started:  r.Started,
+			Duration: r.Duration,
+		})
+	}
+	return steps
+}
+
+// DetectRootCauses ranks the library's patterns against every step's output and notes
+func (r *IncidentReport) DetectRootCauses(pl *PlaybookLibrary) {
+	if pl == nil {
+		return
+	}
+	var observations []string
+	for _, step := range r.Steps {
+		observations = append(observations, step.Output)
+		observations = append(observations, step.Notes...)
+	}
+	ranked := pl.RankRootCauses(observations)
+	if len(ranked) > maxReportRootCauses {
+		ranked = ranked[:maxReportRootCauses]
+	}
+	// the same indicator often matches both a step's output and its notes
+	for _, a := range ranked {
+		var unique []string
+		for _, ind := range a.Indicators {
+			if !containsString(unique, ind) {
+				unique = append(unique, ind)
+			}
+		}
+		a.Indicators = unique
+	}
+	r.RootCauses = ranked
+}
+
+// redacted returns a copy with secrets masked in everything taken from the cluster or the model
+func (r *IncidentReport) redacted() *IncidentReport {
+	out := *r
+	out.Title = RedactText(r.Title)
+	out.Context = RedactText(r.Context)
+	out.FinalAnswer = RedactText(r.FinalAnswer)
+	out.Steps = make([]ReportStep, len(r.Steps))
+	for i, step := range r.Steps {
+		step.Command = RedactText(step.Command)
+		step.Output = RedactText(step.Output)
+		notes := make([]string, len(step.Notes))
+		for j, note := range step.Notes {
+			notes[j] = RedactText(note)
+		}
+		step.Notes = notes
+		out.Steps[i] = step
+	}
+	return &out
+}
+
+// RenderReport renders the redacted report. A report.md.tmpl or report.html.tmpl file in
+// templateDir replaces the built-in template for its format.
+func RenderReport(r *IncidentReport, format, templateDir string) (string, error) {
+	name, err := reportTemplateName(format)
+	if err != nil {
+		return "", err
+	}
+	text, origin, err := reportTemplateSource(name, templateDir)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	data := r.redacted()
+	if format == ReportHTML {
+		tmpl, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(reportFuncs)).Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("report template %s: %w", origin, err)
+		}
+		err = tmpl.Execute(&buf, data)
+		if err != nil {
+			return "", fmt.Errorf("report template %s: %w", origin, err)
+		}
+		return buf.String(), nil
+	}
+	tmpl, err := template.New(name).Funcs(reportFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("report template %s: %w", origin, err)
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("report template %s: %w", origin, err)
+	}
+	return buf.String(), nil
+}
+
+// WriteReport renders the report into dir as report-<timestamp>.md or .html and returns the path
+func WriteReport(r *IncidentReport, format, dir, templateDir string) (string, error) {
+	content, err := RenderReport(r, format, templateDir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	ext := ".md"
+	if format == ReportHTML {
+		ext = ".html"
+	}
+	path := filepath.Join(dir, "report-"+r.GeneratedAt.Format("20060102-150405")+ext)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func reportTemplateName(format string) (string, error) {
+	switch format {
+	case ReportMarkdown:
+		return "report.md.tmpl", nil
+	case ReportHTML:
+		return "report.html.tmpl", nil
+	default:
+		return "", fmt.Errorf("unknown report format %q, use %s or %s", format, ReportMarkdown, ReportHTML)
+	}
+}
+
+// reportTemplateSource prefers the override in templateDir over the built-in template
+func reportTemplateSource(name, templateDir string) (string, string, error) {
+	if templateDir != "" {
+		path := filepath.Join(expandHome(templateDir), name)
+		if data, err := os.ReadFile(path); err == nil {
+			return string(data), path, nil
+		}
+	}
+	data, err := reportTemplates.ReadFile("report_templates/" + name)
+	if err != nil {
+		return "", "", err
+	}
+	return string(data), "built-in " + name, nil
+}
+
+var reportFuncs = template.FuncMap{
+	"timestamp": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04:05 MST")
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	},
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.0f%%", f*100)
+	},
+	"join": strings.Join,
+	"add":  func(a, b int) int { return a + b },
+	// fence wraps text in a Markdown code block longer than any backtick run inside it
+	"fence": func(text string) string {
+		fence := "```"
+		for strings.Contains(text, fence) {
+			fence += "`"
+		}
+		return fence + "\n" + strings.TrimRight(text, "\n") + "\n" + fence
+	},
+}
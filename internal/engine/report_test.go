@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleReport() *IncidentReport {
+	started := time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC)
+	r := &IncidentReport{
+		Title:       "Diagnosis of pod api-7d9f",
+		GeneratedAt: started.Add(time.Minute),
+		Cluster:     "prod-eu",
+		Namespace:   "payments",
+		Plan:        &DiagPlan{Title: "Pod diagnostics", Steps: []string{"kubectl describe pod api-7d9f -n payments"}},
+		Steps: ReportStepsFromDiag([]DiagResult{{
+			Step:     "describe",
+			Command:  "kubectl describe pod api-7d9f -n payments",
+			Output:   "Last State: Terminated\n  Reason: OOMKilled\n  Exit Code: 137\nDB_PASSWORD=hunter2\n```",
+			Notes:    []string{"container api was OOMKilled"},
+			Started:  started,
+			Duration: 1234 * time.Millisecond,
+		}}),
+		FinalAnswer: "Raise the memory limit <script>alert(1)</script>",
+	}
+	r.Steps = append(r.Steps, ReportStep{Source: "agent", Command: "kubectl top pod -n payments", Output: "api-7d9f 120m 510Mi"})
+	return r
+}
+
+func TestRenderReportMarkdown(t *testing.T) {
+	r := sampleReport()
+	r.DetectRootCauses(NewPlaybookLibrary())
+	if len(r.RootCauses) == 0 || r.RootCauses[0].PatternID != "oom-killed" {
+		t.Fatalf("the OOM kill should be detected from the step output, got %+v", r.RootCauses)
+	}
+
+	out, err := RenderReport(r, ReportMarkdown, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"# Diagnosis of pod api-7d9f",
+		"| Cluster | prod-eu |",
+		"1. `kubectl describe pod api-7d9f -n payments`",
+		"<summary><code>kubectl describe pod api-7d9f -n payments</code> (diagnostic, 2025-03-04 10:00:00 UTC, 1.234s)</summary>",
+		"````\nLast State",
+		"(agent, -)",
+		"Indicators: oomkilled, killed\n",
+		"Next steps:",
+		"## Conclusion",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("report should contain %q:\n%s", s, out)
+		}
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Error("secrets in command output must be redacted")
+	}
+}
+
+func TestRenderReportHTMLEscapesAndCollapses(t *testing.T) {
+	out, err := RenderReport(sampleReport(), ReportHTML, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "<details>") || !strings.Contains(out, "<style>") {
+		t.Error("HTML report should be self-contained with collapsible outputs")
+	}
+	if strings.Contains(out, "<script>") || strings.Contains(out, "hunter2") {
+		t.Errorf("HTML report must escape and redact:\n%s", out)
+	}
+}
+
+func TestRenderReportRedactsTitle(t *testing.T) {
+	r := sampleReport()
+	r.Title = "Diagnosis of DB_PASSWORD=hunter2"
+	for _, format := range []string{ReportMarkdown, ReportHTML} {
+		out, err := RenderReport(r, format, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out, "hunter2") {
+			t.Errorf("%s report must redact secrets in the title:\n%s", format, out)
+		}
+	}
+}
+
+func TestReportTemplateOverrideAndWrite(t *testing.T) {
+	tmplDir := t.TempDir()
+	custom := "INC: {{.Title}} on {{.Cluster}}\n{{range .Steps}}- {{.Command}}\n{{end}}"
+	if err := os.WriteFile(filepath.Join(tmplDir, "report.md.tmpl"), []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outDir := filepath.Join(t.TempDir(), "reports")
+	path, err := WriteReport(sampleReport(), ReportMarkdown, outDir, tmplDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "report-20250304-100100.md" {
+		t.Errorf("unexpected report name %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "INC: Diagnosis of pod api-7d9f on prod-eu\n- kubectl describe") {
+		t.Errorf("the override template should be used:\n%s", data)
+	}
+
+	if _, err := RenderReport(sampleReport(), "pdf", ""); err == nil {
+		t.Error("unknown formats should be rejected")
+	}
+}
@@ -0,0 +1,498 @@
+// retrieval.go - Local vector index over playbooks, workspace files and resolved sessions
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	maxChunkChars      = 1500       // workspace chunks are split to stay near this size
+	maxIndexedFileSize = 512 * 1024 // larger YAML files are skipped
+	embedBatchSize     = 16
+)
+
+// Embedder turns texts into vectors; llm.Embed satisfies it
+type Embedder func(model string, texts []string) ([][]float64, error)
+
+// RetrievalSource is a piece of text that can be indexed
+type RetrievalSource struct {
+	ID       string // stable key, e.g. "workspace:charts/web/values.yaml#2"
+	Kind     string // "playbook", "workspace", "session"
+	Citation string // shown to the user, e.g. "charts/web/values.yaml:40"
+	Text     string
+}
+
+// RetrievalDoc is an indexed source with its content hash and embedding
+type RetrievalDoc struct {
+	ID       string    `json:"id"`
+	Kind     string    `json:"kind"`
+	Citation string    `json:"citation"`
+	Hash     string    `json:"hash"`
+	Text     string    `json:"text"`
+	Vector   []float64 `json:"vector"`
+}
+
+// RetrievalHit is a search result with its cosine similarity
+type RetrievalHit struct {
+	Doc   RetrievalDoc
+	Score float64
+}
+
+// Snippet renders the hit for the prompt's knowledge section
+func (h RetrievalHit) Snippet() string {
+	return fmt.Sprintf("[%s] %s", h.Doc.Citation, strings.TrimSpace(h.Doc.Text))
+}
+
+// RefreshStats reports what a refresh changed
+type RefreshStats struct {
+	Total    int // documents in the index after the refresh
+	Embedded int // new or changed documents sent to the embedder
+	Removed  int // documents whose source disappeared
+	Skipped  int // sources left out because they look like prompt injection
+}
+
+// VectorIndex is a small on-disk embedding index searched by cosine similarity
+type VectorIndex struct {
+	mu    sync.RWMutex
+	path  string
+	model string
+	embed Embedder
+	docs  map[string]RetrievalDoc
+
+	TopK     int
+	MinScore float64
+}
+
+type vectorIndexFile struct {
+	Model string         `json:"model"`
+	Docs  []RetrievalDoc `json:"docs"`
+}
+
+// NewVectorIndex creates an index persisted at path using the given embedding model
+func NewVectorIndex(path, model string, embed Embedder) *VectorIndex {
+	return &VectorIndex{
+		path:     path,
+		model:    model,
+		embed:    embed,
+		docs:     make(map[string]RetrievalDoc),
+		TopK:     4,
+		MinScore: 0.3,
+	}
+}
+
+// Load restores the index from disk; an index built with another model is discarded
+func (vi *VectorIndex) Load() error {
+	data, err := os.ReadFile(vi.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	var file vectorIndexFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to unmarshal index: %w", err)
+	}
+
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+	vi.docs = make(map[string]RetrievalDoc)
+	if file.Model != vi.model {
+		return nil
+	}
+	for _, doc := range file.Docs {
+		vi.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+// Save writes the index to disk
+func (vi *VectorIndex) Save() error {
+	vi.mu.RLock()
+	file := vectorIndexFile{Model: vi.model, Docs: make([]RetrievalDoc, 0, len(vi.docs))}
+	for _, doc := range vi.docs {
+		file.Docs = append(file.Docs, doc)
+	}
+	vi.mu.RUnlock()
+	sort.Slice(file.Docs, func(i, j int) bool { return file.Docs[i].ID < file.Docs[j].ID })
+
+	if err := os.MkdirAll(filepath.Dir(vi.path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+	// Indexed text comes from the workspace and past sessions; keep it private to the user
+	if err := os.WriteFile(vi.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of indexed documents
+func (vi *VectorIndex) Len() int {
+	vi.mu.RLock()
+	defer vi.mu.RUnlock()
+	return len(vi.docs)
+}
+
+// Refresh brings the index in line with sources, embedding only new or changed text
+func (vi *VectorIndex) Refresh(sources []RetrievalSource) (RefreshStats, error) {
+	var stats RefreshStats
+
+	vi.mu.RLock()
+	existing := make(map[string]string, len(vi.docs))
+	for id, doc := range vi.docs {
+		existing[id] = doc.Hash
+	}
+	vi.mu.RUnlock()
+
+	seen := make(map[string]bool, len(sources))
+	var pending []RetrievalDoc
+	for _, src := range sources {
+		if strings.TrimSpace(src.Text) == "" || seen[src.ID] {
+			continue
+		}
+		if len(DetectInjection(src.Text)) > 0 {
+			stats.Skipped++
+			continue
+		}
+		seen[src.ID] = true
+		// Secrets in manifests and values files never reach the embedder or the index file
+		text := RedactText(src.Text)
+		hash := contentHash(text)
+		if existing[src.ID] == hash {
+			continue
+		}
+		pending = append(pending, RetrievalDoc{ID: src.ID, Kind: src.Kind, Citation: src.Citation, Hash: hash, Text: text})
+	}
+
+	// Embed outside the lock so searches keep working during a refresh
+	for start := 0; start < len(pending); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(pending))
+		texts := make([]string, 0, end-start)
+		for _, doc := range pending[start:end] {
+			texts = append(texts, doc.Text)
+		}
+		if err := vi.embedDocs(pending[start:end]); err != nil {
+			return stats, err
+		}
+	}
+
+	vi.mu.Lock()
+	defer vi.mu.Unlock()
+	for id := range vi.docs {
+		if !seen[id] {
+			delete(vi.docs, id)
+			stats.Removed++
+		}
+	}
+	for _, doc := range pending {
+		vi.docs[doc.ID] = doc
+	}
+	stats.Embedded = len(pending)
+	stats.Total = len(vi.docs)
+	return stats, nil
+}
+
+// embedDocs fills in the vectors of docs. Documents the embedder returned no vector for
+// are retried once; still missing vectors are an error rather than unsearchable entries.
+func (vi *VectorIndex) embedDocs(docs []RetrievalDoc) error {
+	missing := make([]int, len(docs))
+	for i := range docs {
+		missing[i] = i
+	}
+	for attempt := 0; attempt < 2 && len(missing) > 0; attempt++ {
+		texts := make([]string, 0, len(missing))
+		for _, i := range missing {
+			texts = append(texts, docs[i].Text)
+		}
+		vectors, err := vi.embed(vi.model, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed %d documents with %s: %w", len(texts), vi.model, err)
+		}
+		var retry []int
+		for j, i := range missing {
+			if j < len(vectors) && len(vectors[j]) > 0 {
+				docs[i].Vector = vectors[j]
+			} else {
+				retry = append(retry, i)
+			}
+		}
+		missing = retry
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s returned no vector for %d of %d documents", vi.model, len(missing), len(docs))
+	}
+	return nil
+}
+
+// Search returns the TopK documents most similar to the query
+func (vi *VectorIndex) Search(query string) ([]RetrievalHit, error) {
+	if strings.TrimSpace(query) == "" || vi.Len() == 0 {
+		return nil, nil
+	}
+	vectors, err := vi.embed(vi.model, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query with %s: %w", vi.model, err)
+	}
+	if len(vectors) == 0 {
+		return nil, nil
+	}
+
+	vi.mu.RLock()
+	hits := make([]RetrievalHit, 0, len(vi.docs))
+	for _, doc := range vi.docs {
+		if score := CosineSimilarity(vectors[0], doc.Vector); score >= vi.MinScore {
+			hits = append(hits, RetrievalHit{Doc: doc, Score: score})
+		}
+	}
+	vi.mu.RUnlock()
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Doc.ID < hits[j].Doc.ID
+	})
+	if vi.TopK > 0 && len(hits) > vi.TopK {
+		hits = hits[:vi.TopK]
+	}
+	return hits, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, 0 for mismatched vectors
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Citations lists the distinct citations of the hits in rank order
+func Citations(hits []RetrievalHit) []string {
+	var cites []string
+	seen := make(map[string]bool)
+	for _, hit := range hits {
+		if !seen[hit.Doc.Citation] {
+			seen[hit.Doc.Citation] = true
+			cites = append(cites, hit.Doc.Citation)
+		}
+	}
+	return cites
+}
+
+// KnowledgeRetriever keeps a VectorIndex in sync with playbooks, the workspace and resolved sessions
+type KnowledgeRetriever struct {
+	Index    *VectorIndex
+	library  *PlaybookLibrary
+	recorder *FlightRecorder
+	root     string
+}
+
+// NewKnowledgeRetriever creates a retriever over the library, the recorder's sessions and root
+func NewKnowledgeRetriever(index *VectorIndex, library *PlaybookLibrary, recorder *FlightRecorder, root string) *KnowledgeRetriever {
+	return &KnowledgeRetriever{Index: index, library: library, recorder: recorder, root: root}
+}
+
+// Refresh re-indexes changed sources and persists the index
+func (kr *KnowledgeRetriever) Refresh() (RefreshStats, error) {
+	var sources []RetrievalSource
+	if kr.library != nil {
+		sources = append(sources, PlaybookSources(kr.library)...)
+	}
+	if kr.root != "" {
+		sources = append(sources, WorkspaceSources(kr.root)...)
+	}
+	if kr.recorder != nil {
+		sources = append(sources, SessionSources(kr.recorder.ResolvedSessions())...)
+	}
+
+	stats, err := kr.Index.Refresh(sources)
+	if err != nil {
+		return stats, err
+	}
+	if stats.Embedded > 0 || stats.Removed > 0 {
+		if err := kr.Index.Save(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Retrieve returns the snippets most relevant to the query
+func (kr *KnowledgeRetriever) Retrieve(query string) ([]RetrievalHit, error) {
+	return kr.Index.Search(query)
+}
+
+// PlaybookSources renders each playbook as one indexable document
+func PlaybookSources(pl *PlaybookLibrary) []RetrievalSource {
+	playbooks, _ := pl.snapshot()
+	names := make([]string, 0, len(playbooks))
+	for name := range playbooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sources := make([]RetrievalSource, 0, len(names))
+	for _, name := range names {
+		p := playbooks[name]
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Playbook %s (%s): %s\n", p.Name, p.Category, p.Description)
+		if len(p.Triggers) > 0 {
+			fmt.Fprintf(&sb, "Triggers: %s\n", strings.Join(p.Triggers, ", "))
+		}
+		for _, step := range p.Steps {
+			fmt.Fprintf(&sb, "Step %s: %s\n", step.Name, step.Command)
+		}
+		for _, h := range p.Heuristics {
+			fmt.Fprintf(&sb, "Look for: %s\n", h)
+		}
+		for _, move := range p.NextMoves {
+			fmt.Fprintf(&sb, "Next: %s: %s\n", move.Action, move.Command)
+		}
+		sources = append(sources, RetrievalSource{
+			ID:       "playbook:" + name,
+			Kind:     "playbook",
+			Citation: "playbook:" + name,
+			Text:     sb.String(),
+		})
+	}
+	return sources
+}
+
+// WorkspaceSources chunks the YAML manifests and values files under root
+func WorkspaceSources(root string) []RetrievalSource {
+	var sources []RetrievalSource
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || name == "kubemage_data") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxIndexedFileSize {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		rel = filepath.ToSlash(rel)
+		for i, chunk := range chunkYAML(string(data)) {
+			sources = append(sources, RetrievalSource{
+				ID:       fmt.Sprintf("workspace:%s#%d", rel, i),
+				Kind:     "workspace",
+				Citation: fmt.Sprintf("%s:%d", rel, chunk.line),
+				Text:     rel + "\n" + chunk.text,
+			})
+		}
+		return nil
+	})
+	return sources
+}
+
+// SessionSources renders resolved sessions as question, commands and outcome
+func SessionSources(sessions []SessionRecord) []RetrievalSource {
+	sources := make([]RetrievalSource, 0, len(sessions))
+	for _, session := range sessions {
+		if session.Outcome != "success" {
+			continue
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Resolved issue: %s\n", session.UserInput)
+		for _, suggestion := range session.Suggestions {
+			if suggestion.Type == "command" && suggestion.Applied {
+				fmt.Fprintf(&sb, "Ran: %s\n", suggestion.Content)
+			}
+		}
+		for _, action := range session.UserActions {
+			if action.Action == "resolve" && action.Target != "" {
+				fmt.Fprintf(&sb, "Resolution: %s\n", action.Target)
+			}
+		}
+		sources = append(sources, RetrievalSource{
+			ID:       "session:" + session.ID,
+			Kind:     "session",
+			Citation: fmt.Sprintf("session %s", session.Timestamp.Format("2006-01-02 15:04")),
+			Text:     sb.String(),
+		})
+	}
+	return sources
+}
+
+type yamlChunk struct {
+	line int // 1-based line where the chunk starts
+	text string
+}
+
+// chunkYAML splits on document separators, then on line boundaries for long documents
+func chunkYAML(content string) []yamlChunk {
+	var chunks []yamlChunk
+	var current []string
+	start := 1
+	size := 0
+
+	flush := func(next int) {
+		text := strings.TrimSpace(strings.Join(current, "\n"))
+		if text != "" {
+			chunks = append(chunks, yamlChunk{line: start, text: text})
+		}
+		current = current[:0]
+		size = 0
+		start = next
+	}
+
+	for i, line := range strings.Split(content, "\n") {
+		lineNo := i + 1
+		if strings.TrimSpace(line) == "---" {
+			flush(lineNo + 1)
+			continue
+		}
+		if size+len(line) > maxChunkChars && len(current) > 0 {
+			flush(lineNo)
+		}
+		current = append(current, line)
+		size += len(line) + 1
+	}
+	flush(0)
+	return chunks
+}
+
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
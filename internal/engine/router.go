@@ -5,7 +5,10 @@ import (
 	"math"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/siryoos/kubemage/internal/prompts"
 )
 
 // AgentMode represents different operational modes
@@ -71,7 +74,7 @@ var intentPatterns = []IntentPattern{
 		Weight: 1.0,
 		Patterns: []string{
 			`(?i)(create|generate|new|scaffold|build|make)`,
-			`(?i)(deployment|service|ingress|configmap|secret).*(?i)(for|with)`,
+			`(?i)({kinds}).*(?i)(for|with)`,
 			`(?i)(helm.*chart|values.*file)`,
 			`(?i)(template|manifest|yaml)`,
 		},
@@ -187,11 +190,57 @@ func RouteIntent(input string, context *KubeContextSummary) IntentRouter {
 	}
 }
 
+// ClassifyIntent lets the model pick the mode and falls back to the intent patterns
+// when no model is set or the structured call fails
+func ClassifyIntent(input string, context *KubeContextSummary, model string) IntentRouter {
+	if model != "" {
+		if router, err := ClassifyIntentStructured(input, context, model); err == nil {
+			return *router
+		}
+	}
+	return RouteIntent(input, context)
+}
+
+// Resource kinds matched by the {kinds} placeholder in intent patterns
+const builtinKinds = "deployment|service|ingress|configmap|secret"
+
+var (
+	customKindsMu sync.RWMutex
+	customKinds   string
+)
+
+// SetCustomKinds lets intent patterns recognize CRD kinds installed in the cluster
+func SetCustomKinds(kinds []string) {
+	seen := make(map[string]bool, len(kinds))
+	var quoted []string
+	for _, kind := range kinds {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if kind == "" || seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		quoted = append(quoted, regexp.QuoteMeta(kind))
+	}
+	customKindsMu.Lock()
+	customKinds = strings.Join(quoted, "|")
+	customKindsMu.Unlock()
+}
+
+func kindAlternation() string {
+	customKindsMu.RLock()
+	defer customKindsMu.RUnlock()
+	if customKinds == "" {
+		return builtinKinds
+	}
+	return builtinKinds + "|" + customKinds
+}
+
 // calculatePatternScore scores input against intent patterns
 func calculatePatternScore(input string, pattern IntentPattern) float64 {
 	score := 0.0
 
 	for _, patternStr := range pattern.Patterns {
+		patternStr = strings.ReplaceAll(patternStr, "{kinds}", kindAlternation())
 		if matched, _ := regexp.MatchString(patternStr, input); matched {
 			score += pattern.Confidence
 		}
@@ -314,12 +363,31 @@ func generateReasoning(input string, mode AgentMode, confidence float64, alterna
 	return reasoning.String()
 }
 
-// getPromptPolicy returns mode-specific prompting policies
+var (
+	promptPoliciesOnce sync.Once
+	promptPolicies     map[AgentMode]*PromptPolicy
+)
+
+// getPromptPolicy returns mode-specific prompting policies. The policy templates are
+// rendered once, on first use after the config and its overrides are loaded.
 func getPromptPolicy(mode AgentMode) *PromptPolicy {
-	policies := map[AgentMode]*PromptPolicy{
+	promptPoliciesOnce.Do(loadPromptPolicies)
+
+	policy, exists := promptPolicies[mode]
+	if !exists {
+		policy = promptPolicies[""]
+	}
+	copied := *policy
+	copied.Mode = mode
+	return &copied
+}
+
+// loadPromptPolicies renders every policy template
+func loadPromptPolicies() {
+	promptPolicies = map[AgentMode]*PromptPolicy{
 		ModeDiagnose: {
 			Mode:            ModeDiagnose,
-			SystemPrompt:    "You are a Kubernetes diagnostic specialist. Use the ReAct protocol to systematically investigate issues. Always start with the most relevant describe/events/logs commands. Limit to 5 steps maximum. End with 'Final:' and actionable next steps.",
+			SystemPrompt:    prompts.Text(prompts.PolicyPrefix+string(ModeDiagnose), nil),
 			Temperature:     0.2,
 			MaxTokens:       1024,
 			ContextStrategy: "surgical", // Use minimal, targeted context
@@ -327,7 +395,7 @@ func getPromptPolicy(mode AgentMode) *PromptPolicy {
 		},
 		ModeGenerate: {
 			Mode:            ModeGenerate,
-			SystemPrompt:    "You are a Kubernetes resource generator. Create valid, production-ready YAML manifests. Always include resource limits, labels, and annotations. Propose kubectl apply --dry-run commands for validation.",
+			SystemPrompt:    prompts.Text(prompts.PolicyPrefix+string(ModeGenerate), nil),
 			Temperature:     0.3,
 			MaxTokens:       2048,
 			ContextStrategy: "schema-aware", // Include relevant schemas
@@ -335,7 +403,7 @@ func getPromptPolicy(mode AgentMode) *PromptPolicy {
 		},
 		ModeEdit: {
 			Mode:            ModeEdit,
-			SystemPrompt:    "You are a precision Kubernetes editor. Generate minimal, targeted unified diffs only. Preserve existing structure and comments. Always follow with validation commands.",
+			SystemPrompt:    prompts.Text(prompts.PolicyPrefix+string(ModeEdit), nil),
 			Temperature:     0.25,
 			MaxTokens:       1024,
 			ContextStrategy: "diff-focused", // Show current vs desired state
@@ -343,7 +411,7 @@ func getPromptPolicy(mode AgentMode) *PromptPolicy {
 		},
 		ModeExplain: {
 			Mode:            ModeExplain,
-			SystemPrompt:    "You are a Kubernetes educator. Provide concise, accurate explanations with practical examples. Reference current cluster state when relevant. Keep responses under 200 words.",
+			SystemPrompt:    prompts.Text(prompts.PolicyPrefix+string(ModeExplain), nil),
 			Temperature:     0.4,
 			MaxTokens:       512,
 			ContextStrategy: "educational", // Include relevant docs/examples
@@ -351,7 +419,7 @@ func getPromptPolicy(mode AgentMode) *PromptPolicy {
 		},
 		ModeCommand: {
 			Mode:            ModeCommand,
-			SystemPrompt:    "You are a kubectl/helm command synthesizer. Generate single, executable commands only. Prefer read-only operations. For mutations, always include --dry-run first.",
+			SystemPrompt:    prompts.Text(prompts.PolicyPrefix+string(ModeCommand), nil),
 			Temperature:     0.2,
 			MaxTokens:       256,
 			ContextStrategy: "command-focused", // Minimal context, high precision
@@ -359,14 +427,9 @@ func getPromptPolicy(mode AgentMode) *PromptPolicy {
 		},
 	}
 
-	if policy, exists := policies[mode]; exists {
-		return policy
-	}
-
-	// Default policy
-	return &PromptPolicy{
-		Mode:            mode,
-		SystemPrompt:    "You are a helpful Kubernetes assistant.",
+	// Default policy, for any other mode
+	promptPolicies[""] = &PromptPolicy{
+		SystemPrompt:    prompts.Text(prompts.PolicyPrefix+"default", nil),
 		Temperature:     0.3,
 		MaxTokens:       1024,
 		ContextStrategy: "balanced",
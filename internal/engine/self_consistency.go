@@ -0,0 +1,409 @@
+// self_consistency.go - Sample several command candidates and vote with dry-runs
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/engine/validator"
+	"github.com/siryoos/kubemage/internal/execx"
+	"github.com/siryoos/kubemage/internal/llm"
+)
+
+// CommandSampler produces one command candidate at the given temperature
+type CommandSampler func(ctx context.Context, prompt, model string, temperature float64) (string, error)
+
+// SelfConsistencyConfig controls candidate sampling; Samples <= 1 disables it
+type SelfConsistencyConfig struct {
+	Samples       int
+	Temperatures  []float64
+	TimeBudget    time.Duration // bounds sampling; late candidates are ignored
+	DryRunTimeout time.Duration // per-candidate validation timeout
+}
+
+// Enabled reports whether more than one candidate should be sampled
+func (c SelfConsistencyConfig) Enabled() bool {
+	return c.Samples > 1
+}
+
+// CommandCandidate is a cluster of equivalent sampled commands
+type CommandCandidate struct {
+	Command      string                `json:"command"`
+	Normalized   string                `json:"normalized"`
+	Votes        int                   `json:"votes"`
+	Temperatures []float64             `json:"temperatures"`
+	Plan         validator.PreExecPlan `json:"-"`
+	DryRun       string                `json:"dry_run"` // command used for validation, if any
+	DryRunOK     bool                  `json:"dry_run_ok"`
+	Valid        bool                  `json:"valid"`
+	Reason       string                `json:"reason"`
+}
+
+// ConsistencyResult is the outcome of a self-consistency run
+type ConsistencyResult struct {
+	Best         *CommandCandidate   `json:"best"`
+	Alternatives []*CommandCandidate `json:"alternatives"`
+	Sampled      int                 `json:"sampled"`
+	Elapsed      time.Duration       `json:"elapsed"`
+}
+
+var defaultConsistencyTemperatures = []float64{0.1, 0.4, 0.7, 0.9, 1.1}
+
+var resourceAliases = map[string]string{
+	"po": "pods", "pod": "pods",
+	"svc": "services", "service": "services",
+	"deploy": "deployments", "deployment": "deployments",
+	"ns": "namespaces", "namespace": "namespaces",
+	"no": "nodes", "node": "nodes",
+	"cm": "configmaps", "configmap": "configmaps", "secret": "secrets",
+	"ing": "ingresses", "ingress": "ingresses",
+	"sts": "statefulsets", "statefulset": "statefulsets",
+	"ds": "daemonsets", "daemonset": "daemonsets",
+	"rs": "replicasets", "replicaset": "replicasets",
+	"pvc": "persistentvolumeclaims", "persistentvolumeclaim": "persistentvolumeclaims",
+	"ev": "events", "event": "events",
+}
+
+var flagAliases = map[string]string{
+	"--namespace":      "-n",
+	"--output":         "-o",
+	"--selector":       "-l",
+	"--all-namespaces": "-A",
+	"--container":      "-c",
+	"--filename":       "-f",
+}
+
+// consistencyReadVerbs are the kubectl verbs a candidate may run as-is during validation
+var consistencyReadVerbs = map[string]bool{
+	"get": true, "describe": true, "logs": true, "top": true, "explain": true, "events": true,
+	"api-resources": true, "api-versions": true, "version": true, "cluster-info": true,
+}
+
+// consistencyDryRunVerbs are the kubectl verbs that honor --dry-run=client; any other
+// verb (exec, cp, port-forward, proxy, attach, plugins, ...) is never run during validation
+var consistencyDryRunVerbs = map[string]bool{
+	"apply": true, "create": true, "delete": true, "scale": true, "patch": true,
+	"set": true, "label": true, "annotate": true, "rollout": true,
+}
+
+// streamingFlags keep a read running until it is interrupted
+var streamingFlags = map[string]bool{"-f": true, "--follow": true, "-w": true, "--watch": true, "--watch-only": true}
+
+// RunSelfConsistency samples candidates concurrently within the time budget,
+// clusters equivalent commands and validates each cluster with BuildPreExecPlan
+// and, for the verbs that support it, a client dry-run.
+func RunSelfConsistency(ctx context.Context, prompt, model string, cfg SelfConsistencyConfig, sampler CommandSampler, runner execx.Runner) (*ConsistencyResult, error) {
+	if sampler == nil {
+		return nil, fmt.Errorf("no command sampler configured")
+	}
+	start := time.Now()
+	sampleCtx := ctx
+	if cfg.TimeBudget > 0 {
+		var cancel context.CancelFunc
+		sampleCtx, cancel = context.WithTimeout(ctx, cfg.TimeBudget)
+		defer cancel()
+	}
+
+	temps := cfg.Temperatures
+	if len(temps) == 0 {
+		temps = defaultConsistencyTemperatures
+	}
+	samples := cfg.Samples
+	if samples < 1 {
+		samples = 1
+	}
+
+	type sample struct {
+		command     string
+		temperature float64
+	}
+	results := make(chan sample, samples)
+	var wg sync.WaitGroup
+	for i := 0; i < samples; i++ {
+		temp := temps[i%len(temps)]
+		wg.Add(1)
+		go func(temp float64) {
+			defer wg.Done()
+			out, err := sampler(sampleCtx, prompt, model, temp)
+			if err != nil {
+				return
+			}
+			if cmd := cleanCandidate(out); cmd != "" {
+				results <- sample{command: cmd, temperature: temp}
+			}
+		}(temp)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	clusters := make(map[string]*CommandCandidate)
+	var order []string
+	sampled := 0
+collect:
+	for {
+		select {
+		case s, ok := <-results:
+			if !ok {
+				break collect
+			}
+			sampled++
+			key := NormalizeCommand(s.command)
+			c, exists := clusters[key]
+			if !exists {
+				c = &CommandCandidate{Command: s.command, Normalized: key}
+				clusters[key] = c
+				order = append(order, key)
+			}
+			c.Votes++
+			c.Temperatures = append(c.Temperatures, s.temperature)
+		case <-sampleCtx.Done():
+			break collect
+		}
+	}
+
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no command candidates produced within %s", cfg.TimeBudget)
+	}
+
+	dryRunTimeout := cfg.DryRunTimeout
+	if dryRunTimeout <= 0 {
+		dryRunTimeout = 8 * time.Second
+	}
+	candidates := make([]*CommandCandidate, 0, len(order))
+	for _, key := range order {
+		c := clusters[key]
+		validateCtx, cancel := context.WithTimeout(ctx, dryRunTimeout)
+		validateCandidate(validateCtx, c, runner)
+		cancel()
+		candidates = append(candidates, c)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Valid != b.Valid {
+			return a.Valid
+		}
+		if a.Votes != b.Votes {
+			return a.Votes > b.Votes
+		}
+		return dangerRank(a.Plan.DangerLevel) < dangerRank(b.Plan.DangerLevel)
+	})
+
+	return &ConsistencyResult{
+		Best:         candidates[0],
+		Alternatives: candidates[1:],
+		Sampled:      sampled,
+		Elapsed:      time.Since(start),
+	}, nil
+}
+
+// validateCandidate builds the pre-exec plan and, where that is safe, runs the candidate:
+// kubectl reads as-is, the kubectl verbs in consistencyDryRunVerbs with --dry-run=client
+// and helm only when its plan has a dry-run. Every other candidate is validated by its plan
+// only and never runs.
+func validateCandidate(ctx context.Context, c *CommandCandidate, runner execx.Runner) {
+	c.Plan = validator.BuildPreExecPlan(c.Command)
+
+	positional, flags := normalizeCommandParts(c.Command)
+	if len(positional) == 0 || (positional[0] != "kubectl" && positional[0] != "helm") {
+		c.Reason = "not a kubectl or helm command"
+		return
+	}
+
+	dryRun := ""
+	switch {
+	case positional[0] == "helm":
+		if strings.Contains(c.Plan.FirstRunCommand, "--dry-run") {
+			dryRun = c.Plan.FirstRunCommand
+		}
+	case len(positional) > 1 && consistencyReadVerbs[positional[1]]:
+		for _, flag := range flags {
+			if name, _, _ := strings.Cut(flag, " "); streamingFlags[name] {
+				c.Reason = name + " streams until interrupted"
+				return
+			}
+		}
+		dryRun = strings.Join(strings.Fields(c.Command), " ")
+	case len(positional) > 1 && consistencyDryRunVerbs[positional[1]]:
+		dryRun = withClientDryRun(c.Command)
+	}
+
+	if dryRun == "" || runner == nil {
+		c.Valid = true
+		c.Reason = "validated by plan only"
+		return
+	}
+
+	c.DryRun = dryRun
+	_, stderr, err := runner.RunCommand(ctx, dryRun)
+	if err != nil {
+		c.Reason = firstLine(strings.TrimSpace(stderr))
+		if c.Reason == "" {
+			c.Reason = err.Error()
+		}
+		return
+	}
+	c.DryRunOK = true
+	c.Valid = true
+	c.Reason = "dry-run passed"
+}
+
+// dryRunModes are the values a separate --dry-run argument may take
+var dryRunModes = map[string]bool{"none": true, "client": true, "server": true}
+
+// withClientDryRun replaces whatever dry-run the model asked for with a client one. The flag
+// goes before any "--", after which kubectl hands the arguments to the container command.
+func withClientDryRun(command string) string {
+	fields := strings.Fields(command)
+	out := make([]string, 0, len(fields)+1)
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if f == "--" {
+			out = append(out, "--dry-run=client")
+			return strings.Join(append(out, fields[i:]...), " ")
+		}
+		if strings.HasPrefix(f, "--dry-run") {
+			if f == "--dry-run" && i+1 < len(fields) && dryRunModes[fields[i+1]] {
+				i++
+			}
+			continue
+		}
+		out = append(out, f)
+	}
+	return strings.Join(append(out, "--dry-run=client"), " ")
+}
+
+// NormalizeCommand canonicalizes a command so equivalent candidates cluster together:
+// resource aliases are expanded, long flags shortened and flags sorted after positionals.
+func NormalizeCommand(command string) string {
+	positional, flags := normalizeCommandParts(command)
+	return strings.Join(append(positional, flags...), " ")
+}
+
+// normalizeCommandParts returns the alias-expanded positionals and the sorted, shortened flags of a command
+func normalizeCommandParts(command string) (positional, flags []string) {
+	fields := strings.Fields(cleanCandidate(command))
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if !strings.HasPrefix(f, "-") {
+			if len(positional) == 2 {
+				// kubectl <verb> <resource>: expand short resource names
+				parts := strings.Split(f, ",")
+				for j, part := range parts {
+					if alias, ok := resourceAliases[part]; ok {
+						parts[j] = alias
+					}
+				}
+				f = strings.Join(parts, ",")
+			}
+			positional = append(positional, f)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(f, "=")
+		if short, ok := flagAliases[name]; ok {
+			name = short
+		}
+		if !hasValue && i+1 < len(fields) && !strings.HasPrefix(fields[i+1], "-") && flagTakesValue(name) {
+			value = fields[i+1]
+			hasValue = true
+			i++
+		}
+		if hasValue {
+			flags = append(flags, name+" "+value)
+		} else {
+			flags = append(flags, name)
+		}
+	}
+	sort.Strings(flags)
+	return positional, flags
+}
+
+func flagTakesValue(name string) bool {
+	switch name {
+	case "-n", "-o", "-l", "-c", "-f", "-p", "-L", "--namespace", "--output", "--selector", "--container", "--filename",
+		"--tail", "--since", "--since-time", "--field-selector", "--context", "--cluster", "--user", "--kubeconfig",
+		"--replicas", "--image", "--sort-by", "--patch", "--type", "--timeout", "--grace-period", "--label-columns",
+		"--for", "--revision", "--to-revision", "--port", "--target-port", "--kube-context", "--values", "--set",
+		"--set-string", "--set-file", "--version", "--repo":
+		return true
+	}
+	return false
+}
+
+var shellFenceLanguages = map[string]bool{"bash": true, "sh": true, "shell": true, "console": true, "zsh": true}
+
+// cleanCandidate strips code fences and prompt markers from a raw model answer
+func cleanCandidate(raw string) string {
+	content := ParseGeneratedContent(raw)
+	// ParseGeneratedContent leaves the language tag of ```bash fences on the first line
+	if lang, rest, ok := strings.Cut(content, "\n"); ok && shellFenceLanguages[strings.TrimSpace(lang)] {
+		content = strings.TrimSpace(rest)
+	}
+	line := firstLine(content)
+	line = strings.TrimPrefix(line, "$ ")
+	return strings.Trim(strings.TrimSpace(line), "`")
+}
+
+func firstLine(s string) string {
+	if idx := strings.IndexByte(s, '\n'); idx >= 0 {
+		return strings.TrimSpace(s[:idx])
+	}
+	return s
+}
+
+func dangerRank(level string) int {
+	switch level {
+	case "low":
+		return 0
+	case "medium":
+		return 1
+	case "high":
+		return 2
+	case "critical":
+		return 3
+	}
+	return 1
+}
+
+// LLMCommandSampler samples commands from Ollama
+func LLMCommandSampler(ctx context.Context, prompt, model string, temperature float64) (string, error) {
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := llm.GenerateCommandWithTemperature(prompt, model, temperature)
+		done <- result{out, err}
+	}()
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// ConfigureSelfConsistency enables candidate voting in GenerateOptimizedCommand
+func (icg *IntelligentCommandGenerator) ConfigureSelfConsistency(cfg SelfConsistencyConfig, sampler CommandSampler, runner execx.Runner) {
+	icg.mu.Lock()
+	defer icg.mu.Unlock()
+	icg.consistency = cfg
+	icg.sampler = sampler
+	icg.runner = runner
+}
+
+// sampleConsistentCommand runs self-consistency when it is enabled; ctx ends sampling and validation
+func (icg *IntelligentCommandGenerator) sampleConsistentCommand(ctx context.Context, query, model string) (*ConsistencyResult, error) {
+	if !icg.consistency.Enabled() || icg.sampler == nil {
+		return nil, nil
+	}
+	return RunSelfConsistency(ctx, query, model, icg.consistency, icg.sampler, icg.runner)
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dryRunRunner fails any command containing one of the rejected substrings
+type dryRunRunner struct {
+	rejected []string
+	ran      []string
+}
+
+func (r *dryRunRunner) Run(ctx context.Context, name string, args ...string) (string, string, error) {
+	return r.RunCommand(ctx, name+" "+strings.Join(args, " "))
+}
+
+func (r *dryRunRunner) RunCommand(ctx context.Context, command string) (string, string, error) {
+	r.ran = append(r.ran, command)
+	for _, bad := range r.rejected {
+		if strings.Contains(command, bad) {
+			return "", "error: unknown flag: " + bad, errors.New("exit status 1")
+		}
+	}
+	return "ok", "", nil
+}
+
+func TestNormalizeCommandClustersEquivalents(t *testing.T) {
+	a := NormalizeCommand("kubectl get po --namespace=default -o wide")
+	b := NormalizeCommand("```bash\nkubectl get pods -o wide -n default\n```")
+	if a != b {
+		t.Errorf("expected equivalent commands to normalize the same:\n%q\n%q", a, b)
+	}
+	if NormalizeCommand("kubectl get pods -n default") == NormalizeCommand("kubectl get pods -n kube-system") {
+		t.Error("different namespaces must not cluster together")
+	}
+}
+
+func TestRunSelfConsistencyPicksValidMajority(t *testing.T) {
+	answers := map[float64]string{
+		0.1: "kubectl get pods -n default",
+		0.4: "kubectl get po --namespace default",
+		0.7: "kubectl get pods -n default --show-lables",
+	}
+	sampler := func(ctx context.Context, prompt, model string, temperature float64) (string, error) {
+		return answers[temperature], nil
+	}
+	runner := &dryRunRunner{rejected: []string{"--show-lables"}}
+
+	cfg := SelfConsistencyConfig{Samples: 3, Temperatures: []float64{0.1, 0.4, 0.7}, TimeBudget: time.Second}
+	result, err := RunSelfConsistency(context.Background(), "list pods", "test", cfg, sampler, runner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Best.Votes != 2 || !result.Best.Valid || !result.Best.DryRunOK {
+		t.Errorf("expected a valid 2-vote winner, got %+v", result.Best)
+	}
+	if len(result.Alternatives) != 1 || result.Alternatives[0].Valid {
+		t.Errorf("expected one invalid alternative, got %+v", result.Alternatives)
+	}
+	if result.Sampled != 3 {
+		t.Errorf("expected 3 samples, got %d", result.Sampled)
+	}
+}
+
+func TestRunSelfConsistencyPrefersValidOverVotes(t *testing.T) {
+	answers := map[float64]string{
+		0.1: "kubectl apply -f web.yaml --bogus",
+		0.4: "kubectl apply -f web.yaml --bogus",
+		0.7: "kubectl apply -f web.yaml",
+	}
+	sampler := func(ctx context.Context, prompt, model string, temperature float64) (string, error) {
+		return answers[temperature], nil
+	}
+	runner := &dryRunRunner{rejected: []string{"--bogus"}}
+
+	cfg := SelfConsistencyConfig{Samples: 3, Temperatures: []float64{0.1, 0.4, 0.7}}
+	result, err := RunSelfConsistency(context.Background(), "apply web", "test", cfg, sampler, runner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Best.Command != "kubectl apply -f web.yaml" {
+		t.Errorf("expected the dry-run-valid candidate to win, got %q", result.Best.Command)
+	}
+	for _, ran := range runner.ran {
+		if strings.HasPrefix(ran, "kubectl apply") && !strings.Contains(ran, "--dry-run=client") {
+			t.Errorf("mutating candidate was run without --dry-run: %q", ran)
+		}
+	}
+}
+
+func TestRunSelfConsistencyRespectsTimeBudget(t *testing.T) {
+	sampler := func(ctx context.Context, prompt, model string, temperature float64) (string, error) {
+		if temperature > 0.1 {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}
+		return "kubectl get pods", nil
+	}
+
+	cfg := SelfConsistencyConfig{Samples: 3, Temperatures: []float64{0.1, 0.5, 0.9}, TimeBudget: 50 * time.Millisecond}
+	result, err := RunSelfConsistency(context.Background(), "pods", "test", cfg, sampler, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Sampled != 1 || result.Best.Command != "kubectl get pods" {
+		t.Errorf("expected only the fast candidate, got %+v", result)
+	}
+}
+
+func TestValidateCandidateNeverRunsMutationsOrStreams(t *testing.T) {
+	cases := []struct {
+		command string
+		ran     string // empty when nothing may run
+		valid   bool
+	}{
+		{"kubectl get pods -n shop", "kubectl get pods -n shop", true},
+		{"kubectl logs -f web", "", false},
+		{"kubectl logs web --follow=true", "", false},
+		{"kubectl get pods -w", "", false},
+		{"kubectl rollout restart deploy/web", "kubectl rollout restart deploy/web --dry-run=client", true},
+		{"kubectl scale deploy/web --replicas=0", "kubectl scale deploy/web --replicas=0 --dry-run=client", true},
+		{"kubectl delete pod web-0 --dry-run=none", "kubectl delete pod web-0 --dry-run=client", true},
+		{"kubectl apply -f web.yaml", "kubectl apply -f web.yaml --dry-run=client", true},
+		{"kubectl delete pod web-0 --dry-run server", "kubectl delete pod web-0 --dry-run=client", true},
+		{"kubectl create job wipe --image=busybox -- rm -rf /data", "kubectl create job wipe --image=busybox --dry-run=client -- rm -rf /data", true},
+		{"kubectl exec web-0 -- rm -rf /x", "", true},
+		{"kubectl cp web-0:/etc/passwd ./passwd", "", true},
+		{"kubectl port-forward svc/web 8080:80", "", true},
+		{"kubectl proxy", "", true},
+		{"kubectl attach web-0", "", true},
+		{"kubectl wipe-cluster --all", "", true},
+	}
+	for _, tc := range cases {
+		runner := &dryRunRunner{}
+		c := &CommandCandidate{Command: tc.command}
+		validateCandidate(context.Background(), c, runner)
+
+		if tc.ran == "" && len(runner.ran) != 0 {
+			t.Errorf("%q should not run, ran %q", tc.command, runner.ran)
+		}
+		if tc.ran != "" && (len(runner.ran) != 1 || runner.ran[0] != tc.ran) {
+			t.Errorf("%q should run as %q, ran %q", tc.command, tc.ran, runner.ran)
+		}
+		if c.Valid != tc.valid {
+			t.Errorf("%q: valid = %v, want %v (%s)", tc.command, c.Valid, tc.valid, c.Reason)
+		}
+	}
+}
+
+func TestRunSelfConsistencyStopsWithTheCaller(t *testing.T) {
+	sampler := func(ctx context.Context, prompt, model string, temperature float64) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := SelfConsistencyConfig{Samples: 3, TimeBudget: time.Minute}
+	if _, err := RunSelfConsistency(ctx, "pods", "test", cfg, sampler, nil); err == nil {
+		t.Error("a cancelled caller should end sampling without candidates")
+	}
+}
@@ -0,0 +1,178 @@
+// structured_output.go - Schema-constrained generation, intent and root-cause calls
+package engine
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/siryoos/kubemage/internal/llm"
+)
+
+const structuredSystemPrompt = "You are KubeMage, a Kubernetes and Helm expert. Respond only with JSON that matches the provided schema. Do not add markdown or commentary."
+
+var (
+	// HelmChartSchema describes {files:[{path,content}]}
+	HelmChartSchema = llm.ObjectSchema(map[string]*llm.Schema{
+		"files": llm.ArraySchema(llm.ObjectSchema(map[string]*llm.Schema{
+			"path":    llm.StringSchema(),
+			"content": llm.StringSchema(),
+		}), 1),
+	})
+
+	// ManifestSchema describes a single generated manifest
+	ManifestSchema = llm.ObjectSchema(map[string]*llm.Schema{
+		"manifest":    llm.StringSchema(),
+		"explanation": llm.StringSchema(),
+	})
+
+	// CommandSchema describes {command, explanation, risk}; llm.GenerateCommand uses it too
+	CommandSchema = llm.CommandSchema
+
+	// IntentSchema describes an intent classification
+	IntentSchema = llm.ObjectSchema(map[string]*llm.Schema{
+		"mode":       llm.StringSchema(string(ModeDiagnose), string(ModeGenerate), string(ModeEdit), string(ModeExplain), string(ModeCommand)),
+		"confidence": llm.NumberSchema(),
+		"reasoning":  llm.StringSchema(),
+	})
+
+	// RootCauseSchema describes the subset of RootCauseAnalysis the model fills in
+	RootCauseSchema = llm.ObjectSchema(map[string]*llm.Schema{
+		"root_cause": llm.StringSchema(),
+		"confidence": llm.NumberSchema(),
+		"category":   llm.StringSchema(),
+		"severity":   llm.StringSchema("low", "medium", "high", "critical"),
+		"indicators": llm.ArraySchema(llm.StringSchema(), 0),
+		"next_steps": llm.ArraySchema(llm.ObjectSchema(map[string]*llm.Schema{
+			"action":      llm.StringSchema(),
+			"command":     llm.StringSchema(),
+			"risk":        llm.StringSchema("low", "medium", "high"),
+			"category":    llm.StringSchema(),
+			"description": llm.StringSchema(),
+		}), 0),
+	})
+)
+
+// StructuredFile is a single generated file
+type StructuredFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// StructuredHelmChart is the decoded HelmChartSchema response
+type StructuredHelmChart struct {
+	Files []StructuredFile `json:"files"`
+}
+
+// StructuredManifest is the decoded ManifestSchema response
+type StructuredManifest struct {
+	Manifest    string         `json:"manifest"`
+	Explanation string         `json:"explanation"`
+	Usage       llm.TokenUsage `json:"-"`
+}
+
+// StructuredCommand is the decoded CommandSchema response
+type StructuredCommand = llm.StructuredCommand
+
+type structuredIntent struct {
+	Mode       AgentMode `json:"mode"`
+	Confidence float64   `json:"confidence"`
+	Reasoning  string    `json:"reasoning"`
+}
+
+// GenerateHelmChartStructured generates chart files keyed by relative path
+func GenerateHelmChartStructured(prompt, model string) (map[string]string, error) {
+	var chart StructuredHelmChart
+	fullPrompt := prompt + "\n\nReturn every chart file as an entry in \"files\" with its path relative to the chart root (e.g. Chart.yaml, templates/deployment.yaml) and its full content."
+	if err := llm.GenerateStructured(fullPrompt, structuredSystemPrompt, model, HelmChartSchema, &chart); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(chart.Files))
+	for _, f := range chart.Files {
+		clean, err := sanitizeChartPath(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		files[clean] = strings.TrimSpace(f.Content) + "\n"
+	}
+	return files, nil
+}
+
+// GenerateManifestStructured generates a single manifest
+func GenerateManifestStructured(prompt, model string) (*StructuredManifest, error) {
+	var manifest StructuredManifest
+	fullPrompt := prompt + "\n\nPut the complete YAML manifest in \"manifest\" and a one-sentence summary in \"explanation\"."
+	usage, err := llm.GenerateStructuredUsage(fullPrompt, structuredSystemPrompt, model, ManifestSchema, &manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifest.Usage = usage
+	if strings.TrimSpace(manifest.Manifest) == "" {
+		return nil, fmt.Errorf("model returned an empty manifest")
+	}
+	return &manifest, nil
+}
+
+// GenerateCommandStructured synthesizes a single command with its risk level
+func GenerateCommandStructured(prompt, model string) (*StructuredCommand, error) {
+	return llm.GenerateCommandStructured(prompt, model, nil)
+}
+
+// ClassifyIntentStructured asks the model to pick an AgentMode for the input
+func ClassifyIntentStructured(input string, context *KubeContextSummary, model string) (*IntentRouter, error) {
+	var sb strings.Builder
+	if context != nil && context.RenderedOneLiner != "" {
+		sb.WriteString(fmt.Sprintf("[CTX] %s\n\n", context.RenderedOneLiner))
+	}
+	sb.WriteString("Classify the user's request into one mode: diagnose (troubleshoot a problem), generate (create new YAML/charts), edit (change existing files), explain (describe a concept or resource), command (run a specific kubectl/helm command).\n\n")
+	sb.WriteString("Request: ")
+	sb.WriteString(input)
+
+	var intent structuredIntent
+	if err := llm.GenerateStructured(sb.String(), structuredSystemPrompt, model, IntentSchema, &intent); err != nil {
+		return nil, err
+	}
+
+	confidence := intent.Confidence
+	if confidence > 1 {
+		confidence = confidence / 100 // some models answer in percent
+	}
+	return &IntentRouter{
+		Mode:       intent.Mode,
+		Confidence: confidence,
+		Reasoning:  intent.Reasoning,
+		Policy:     getPromptPolicy(intent.Mode),
+	}, nil
+}
+
+// AnalyzeRootCauseStructured asks the model for a root-cause analysis of observations
+func AnalyzeRootCauseStructured(observations []string, model string) (*RootCauseAnalysis, error) {
+	var sb strings.Builder
+	sb.WriteString("Determine the most likely root cause for these Kubernetes observations. Suggest read-only investigation commands first.\n\nObservations:\n")
+	var list strings.Builder
+	for _, obs := range observations {
+		list.WriteString("- ")
+		list.WriteString(obs)
+		list.WriteString("\n")
+	}
+	sb.WriteString(FenceUntrusted("observations", list.String()).Text)
+
+	var analysis RootCauseAnalysis
+	if err := llm.GenerateStructured(sb.String(), structuredSystemPrompt, model, RootCauseSchema, &analysis); err != nil {
+		return nil, err
+	}
+	if analysis.Confidence > 1 {
+		analysis.Confidence = analysis.Confidence / 100
+	}
+	return &analysis, nil
+}
+
+// sanitizeChartPath keeps generated files inside the chart directory
+func sanitizeChartPath(p string) (string, error) {
+	clean := path.Clean(strings.TrimSpace(p))
+	if clean == "." || clean == "" || path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("generated file path %q escapes the chart directory", p)
+	}
+	return clean, nil
+}
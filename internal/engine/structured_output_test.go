@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeOllama answers every generate call with response, or fails when response is empty
+func fakeOllama(t *testing.T, response string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if response == "" {
+			http.Error(w, "model not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"response": response, "done": true})
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("OLLAMA_HOST", srv.URL)
+}
+
+func TestClassifyIntentUsesTheModel(t *testing.T) {
+	fakeOllama(t, `{"mode":"explain","confidence":92,"reasoning":"asks what a resource is"}`)
+
+	router := ClassifyIntent("list pods that are failing", nil, "llama3")
+	if router.Mode != ModeExplain || router.Confidence != 0.92 {
+		t.Errorf("the model's answer should win, got %s at %.2f", router.Mode, router.Confidence)
+	}
+	if router.Policy == nil || router.Policy.Mode != ModeExplain {
+		t.Errorf("the policy should follow the model's mode: %+v", router.Policy)
+	}
+}
+
+func TestClassifyIntentFallsBackToPatterns(t *testing.T) {
+	fakeOllama(t, "")
+
+	input := "Why is my pod in CrashLoopBackOff?"
+	want := RouteIntent(input, nil)
+	if got := ClassifyIntent(input, nil, "llama3"); got.Mode != want.Mode || got.Confidence != want.Confidence {
+		t.Errorf("a failed call should fall back to the patterns: got %s, want %s", got.Mode, want.Mode)
+	}
+	if got := ClassifyIntent(input, nil, ""); got.Mode != want.Mode {
+		t.Errorf("without a model the patterns should answer: got %s, want %s", got.Mode, want.Mode)
+	}
+}
+
+func TestAnalyzeRootCause(t *testing.T) {
+	library := NewPlaybookLibrary()
+	observations := []string{"pod web-0 is in CrashLoopBackOff", "Back-off restarting failed container"}
+
+	fakeOllama(t, `{"root_cause":"The readiness probe points at the wrong port","confidence":0.8,"category":"config","severity":"high","indicators":["CrashLoopBackOff"],"next_steps":[]}`)
+	analysis := library.AnalyzeRootCause(observations, "llama3")
+	if !strings.Contains(analysis.RootCause, "wrong port") || analysis.Severity != "high" {
+		t.Errorf("the model's analysis should win: %+v", analysis)
+	}
+
+	fakeOllama(t, `{"root_cause":" ","confidence":0,"category":"","severity":"low","indicators":[],"next_steps":[]}`)
+	want := library.DetectRootCause(observations)
+	if got := library.AnalyzeRootCause(observations, "llama3"); got.RootCause != want.RootCause {
+		t.Errorf("an empty answer should fall back to the playbooks: got %q, want %q", got.RootCause, want.RootCause)
+	}
+}
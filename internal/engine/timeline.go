@@ -0,0 +1,469 @@
+// timeline.go - Incident timeline: events, rollouts, restarts and Helm releases in one ordered view
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+const (
+	timelineBurstGap    = 2 * time.Minute // entries closer than this belong to the same burst
+	timelineBurstMin    = 4               // entries a burst needs to be highlighted
+	maxTimelineEntries  = 150
+	maxTimelineReleases = 5
+	timelineHelmHistory = "10"
+)
+
+// Timeline entry sources
+const (
+	TimelineSourceEvent   = "event"
+	TimelineSourceRollout = "rollout"
+	TimelineSourceRestart = "restart"
+	TimelineSourceScale   = "scale"
+	TimelineSourceHelm    = "helm"
+)
+
+// TimelineEntry is one dated change or warning
+type TimelineEntry struct {
+	Time    time.Time
+	Source  string // TimelineSourceEvent, TimelineSourceRollout, TimelineSourceRestart, TimelineSourceScale or TimelineSourceHelm
+	Object  string // "Pod/web-7d9f8b6c5-x2k4j", "release/web"
+	Reason  string
+	Message string
+	Warning bool
+}
+
+// TimelineBurst is a run of entries close together in time
+type TimelineBurst struct {
+	Start, End time.Time
+	First      int // index of the first entry
+	Count      int
+	Warnings   int
+}
+
+// Timeline merges what happened in a namespace, or to one workload in it, oldest first
+type Timeline struct {
+	Namespace string
+	Workload  string // empty for the whole namespace
+	Window    time.Duration
+	Entries   []TimelineEntry
+	Bursts    []TimelineBurst
+	Errors    []string // sources that could not be read, e.g. "helm: executable file not found"
+}
+
+// TimelineBuilder collects timelines with fixed read-only kubectl and helm queries
+type TimelineBuilder struct {
+	runner  execx.Runner
+	Timeout time.Duration // per query
+	Window  time.Duration // how far back entries are kept
+	now     func() time.Time
+}
+
+// NewTimelineBuilder creates a builder; a zero timeout or window uses 8s and 24h
+func NewTimelineBuilder(runner execx.Runner, timeout, window time.Duration) *TimelineBuilder {
+	if timeout <= 0 {
+		timeout = 8 * time.Second
+	}
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return &TimelineBuilder{runner: runner, Timeout: timeout, Window: window, now: time.Now}
+}
+
+// Build reads every source concurrently and merges them. A source that fails is noted in
+// Errors; the timeline is still built from the others.
+func (b *TimelineBuilder) Build(ctx context.Context, namespace, workload string) *Timeline {
+	tl := &Timeline{Namespace: namespace, Workload: workload, Window: b.Window}
+	sources := []struct {
+		name    string
+		collect func(context.Context, string, string) ([]TimelineEntry, error)
+	}{
+		{"events", b.events},
+		{"replicasets", b.replicaSets},
+		{"pods", b.restarts},
+		{"helm", b.helmReleases},
+	}
+
+	results := make([][]TimelineEntry, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, collect func(context.Context, string, string) ([]TimelineEntry, error)) {
+			defer wg.Done()
+			results[i], errs[i] = collect(ctx, namespace, workload)
+		}(i, source.collect)
+	}
+	wg.Wait()
+
+	cutoff := b.now().Add(-b.Window)
+	for i, source := range sources {
+		if errs[i] != nil {
+			tl.Errors = append(tl.Errors, fmt.Sprintf("%s: %v", source.name, errs[i]))
+		}
+		for _, entry := range results[i] {
+			if !entry.Time.IsZero() && entry.Time.After(cutoff) {
+				tl.Entries = append(tl.Entries, entry)
+			}
+		}
+	}
+	sort.SliceStable(tl.Entries, func(i, j int) bool { return tl.Entries[i].Time.Before(tl.Entries[j].Time) })
+	if len(tl.Entries) > maxTimelineEntries {
+		tl.Entries = tl.Entries[len(tl.Entries)-maxTimelineEntries:]
+	}
+	tl.Bursts = findBursts(tl.Entries)
+	return tl
+}
+
+// query runs one read-only command and decodes its JSON output
+func (b *TimelineBuilder) query(ctx context.Context, out interface{}, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.Timeout)
+	defer cancel()
+	stdout, stderr, err := b.runner.Run(ctx, name, args...)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr); msg != "" {
+			return fmt.Errorf("%s", firstLine(msg))
+		}
+		return err
+	}
+	return json.Unmarshal([]byte(stdout), out)
+}
+
+func (b *TimelineBuilder) events(ctx context.Context, namespace, workload string) ([]TimelineEntry, error) {
+	var list struct {
+		Items []struct {
+			Type           string    `json:"type"`
+			Reason         string    `json:"reason"`
+			Message        string    `json:"message"`
+			Count          int       `json:"count"`
+			FirstTimestamp time.Time `json:"firstTimestamp"`
+			LastTimestamp  time.Time `json:"lastTimestamp"`
+			EventTime      time.Time `json:"eventTime"`
+			Metadata       struct {
+				CreationTimestamp time.Time `json:"creationTimestamp"`
+			} `json:"metadata"`
+			InvolvedObject struct {
+				Kind string `json:"kind"`
+				Name string `json:"name"`
+			} `json:"involvedObject"`
+		} `json:"items"`
+	}
+	if err := b.query(ctx, &list, "kubectl", "get", "events", "-n", namespace, "-o", "json"); err != nil {
+		return nil, err
+	}
+
+	var entries []TimelineEntry
+	for _, ev := range list.Items {
+		if !matchesWorkload(ev.InvolvedObject.Name, workload) {
+			continue
+		}
+		at := firstTime(ev.LastTimestamp, ev.EventTime, ev.FirstTimestamp, ev.Metadata.CreationTimestamp)
+		source := TimelineSourceEvent
+		switch {
+		case ev.InvolvedObject.Kind == "HorizontalPodAutoscaler":
+			source = TimelineSourceScale
+		case ev.Reason == "ScalingReplicaSet":
+			source = TimelineSourceRollout
+		}
+		message := ev.Message
+		if ev.Count > 1 {
+			message = fmt.Sprintf("%s (x%d)", message, ev.Count)
+		}
+		entries = append(entries, TimelineEntry{
+			Time:    at,
+			Source:  source,
+			Object:  ev.InvolvedObject.Kind + "/" + ev.InvolvedObject.Name,
+			Reason:  ev.Reason,
+			Message: message,
+			Warning: ev.Type == "Warning",
+		})
+	}
+	return entries, nil
+}
+
+// replicaSets dates each rollout by its ReplicaSet's creation, with the images it runs
+func (b *TimelineBuilder) replicaSets(ctx context.Context, namespace, workload string) ([]TimelineEntry, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name              string            `json:"name"`
+				CreationTimestamp time.Time         `json:"creationTimestamp"`
+				Annotations       map[string]string `json:"annotations"`
+			} `json:"metadata"`
+			Spec struct {
+				Template struct {
+					Spec struct {
+						Containers []struct {
+							Image string `json:"image"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := b.query(ctx, &list, "kubectl", "get", "replicasets", "-n", namespace, "-o", "json"); err != nil {
+		return nil, err
+	}
+
+	var entries []TimelineEntry
+	for _, rs := range list.Items {
+		if !matchesWorkload(rs.Metadata.Name, workload) {
+			continue
+		}
+		var images []string
+		for _, c := range rs.Spec.Template.Spec.Containers {
+			images = append(images, c.Image)
+		}
+		message := "ReplicaSet created"
+		if revision := rs.Metadata.Annotations["deployment.kubernetes.io/revision"]; revision != "" {
+			message += ", revision " + revision
+		}
+		if len(images) > 0 {
+			message += ", image " + strings.Join(images, ", ")
+		}
+		entries = append(entries, TimelineEntry{
+			Time:    rs.Metadata.CreationTimestamp,
+			Source:  TimelineSourceRollout,
+			Object:  "ReplicaSet/" + rs.Metadata.Name,
+			Reason:  "Created",
+			Message: message,
+		})
+	}
+	return entries, nil
+}
+
+// restarts dates each container's last termination
+func (b *TimelineBuilder) restarts(ctx context.Context, namespace, workload string) ([]TimelineEntry, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				ContainerStatuses []struct {
+					Name         string `json:"name"`
+					RestartCount int    `json:"restartCount"`
+					LastState    struct {
+						Terminated *struct {
+							Reason     string    `json:"reason"`
+							ExitCode   int       `json:"exitCode"`
+							FinishedAt time.Time `json:"finishedAt"`
+						} `json:"terminated"`
+					} `json:"lastState"`
+				} `json:"containerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := b.query(ctx, &list, "kubectl", "get", "pods", "-n", namespace, "-o", "json"); err != nil {
+		return nil, err
+	}
+
+	var entries []TimelineEntry
+	for _, pod := range list.Items {
+		if !matchesWorkload(pod.Metadata.Name, workload) {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			term := cs.LastState.Terminated
+			if term == nil {
+				continue
+			}
+			entries = append(entries, TimelineEntry{
+				Time:    term.FinishedAt,
+				Source:  TimelineSourceRestart,
+				Object:  "Pod/" + pod.Metadata.Name,
+				Reason:  term.Reason,
+				Message: fmt.Sprintf("container %s exited with code %d, %d restarts", cs.Name, term.ExitCode, cs.RestartCount),
+				Warning: term.Reason != "Completed",
+			})
+		}
+	}
+	return entries, nil
+}
+
+// helmReleases lists recent revisions of the namespace's releases, or of the workload's
+func (b *TimelineBuilder) helmReleases(ctx context.Context, namespace, workload string) ([]TimelineEntry, error) {
+	var releases []struct {
+		Name string `json:"name"`
+	}
+	if err := b.query(ctx, &releases, "helm", "list", "-n", namespace, "-o", "json"); err != nil {
+		return nil, err
+	}
+
+	var entries []TimelineEntry
+	read := 0
+	for _, release := range releases {
+		if workload != "" && !matchesWorkload(workload, release.Name) && !matchesWorkload(release.Name, workload) {
+			continue
+		}
+		if read == maxTimelineReleases {
+			break
+		}
+		read++
+		var history []struct {
+			Revision    int       `json:"revision"`
+			Updated     time.Time `json:"updated"`
+			Status      string    `json:"status"`
+			Chart       string    `json:"chart"`
+			AppVersion  string    `json:"app_version"`
+			Description string    `json:"description"`
+		}
+		if err := b.query(ctx, &history, "helm", "history", release.Name, "-n", namespace, "--max", timelineHelmHistory, "-o", "json"); err != nil {
+			return entries, err
+		}
+		for _, rev := range history {
+			message := fmt.Sprintf("revision %d %s: %s", rev.Revision, rev.Status, rev.Description)
+			if rev.Chart != "" {
+				message += fmt.Sprintf(" (chart %s, app %s)", rev.Chart, rev.AppVersion)
+			}
+			entries = append(entries, TimelineEntry{
+				Time:    rev.Updated,
+				Source:  TimelineSourceHelm,
+				Object:  "release/" + release.Name,
+				Reason:  rev.Status,
+				Message: message,
+				Warning: rev.Status == "failed",
+			})
+		}
+	}
+	return entries, nil
+}
+
+// findBursts groups entries with less than timelineBurstGap between neighbours
+func findBursts(entries []TimelineEntry) []TimelineBurst {
+	var bursts []TimelineBurst
+	start := 0
+	for i := 1; i <= len(entries); i++ {
+		if i < len(entries) && entries[i].Time.Sub(entries[i-1].Time) <= timelineBurstGap {
+			continue
+		}
+		if count := i - start; count >= timelineBurstMin {
+			burst := TimelineBurst{Start: entries[start].Time, End: entries[i-1].Time, First: start, Count: count}
+			for _, entry := range entries[start:i] {
+				if entry.Warning {
+					burst.Warnings++
+				}
+			}
+			bursts = append(bursts, burst)
+		}
+		start = i
+	}
+	return bursts
+}
+
+// Scope describes what the timeline covers
+func (tl *Timeline) Scope() string {
+	if tl.Workload == "" {
+		return "namespace " + tl.Namespace
+	}
+	return fmt.Sprintf("%s in namespace %s", tl.Workload, tl.Namespace)
+}
+
+// Render shows the timeline for the preview pane, with bursts of activity highlighted
+func (tl *Timeline) Render() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🕒 %s, last %s: %d entries", tl.Scope(), shortDuration(tl.Window), len(tl.Entries))
+	if len(tl.Bursts) > 0 {
+		fmt.Fprintf(&sb, ", %d bursts", len(tl.Bursts))
+	}
+	sb.WriteString("\n")
+	if len(tl.Entries) == 0 {
+		sb.WriteString("\nNothing happened in this window.\n")
+	}
+
+	layout := timelineTimeLayout(tl.Entries)
+	burstAt := make(map[int]TimelineBurst)
+	for _, burst := range tl.Bursts {
+		burstAt[burst.First] = burst
+	}
+	for i, entry := range tl.Entries {
+		if burst, ok := burstAt[i]; ok {
+			fmt.Fprintf(&sb, "\n🔥 %s–%s: %d changes, %d warnings\n", burst.Start.Local().Format(layout), burst.End.Local().Format(layout), burst.Count, burst.Warnings)
+		}
+		fmt.Fprintf(&sb, "%s %s %-7s %s %s: %s\n", entry.Time.Local().Format(layout), timelineMarker(entry), entry.Source, entry.Object, entry.Reason, entry.Message)
+	}
+	for _, e := range tl.Errors {
+		fmt.Fprintf(&sb, "\n⚠️ Not included: %s", e)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// PromptText renders the timeline for the model in UTC with bursts listed first
+func (tl *Timeline) PromptText() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Timeline of %s, oldest first (UTC):\n", tl.Scope())
+	for _, burst := range tl.Bursts {
+		fmt.Fprintf(&sb, "Burst %s to %s: %d changes, %d warnings\n", burst.Start.UTC().Format(time.RFC3339), burst.End.UTC().Format(time.RFC3339), burst.Count, burst.Warnings)
+	}
+	for _, entry := range tl.Entries {
+		level := ""
+		if entry.Warning {
+			level = " WARNING"
+		}
+		fmt.Fprintf(&sb, "%s [%s%s] %s %s: %s\n", entry.Time.UTC().Format(time.RFC3339), entry.Source, level, entry.Object, entry.Reason, entry.Message)
+	}
+	for _, e := range tl.Errors {
+		fmt.Fprintf(&sb, "Unavailable source: %s\n", e)
+	}
+	return sb.String()
+}
+
+func timelineMarker(entry TimelineEntry) string {
+	if entry.Warning {
+		return "⚠️"
+	}
+	switch entry.Source {
+	case TimelineSourceRollout:
+		return "🚀"
+	case TimelineSourceRestart:
+		return "🔁"
+	case TimelineSourceScale:
+		return "📈"
+	case TimelineSourceHelm:
+		return "⎈"
+	default:
+		return "•"
+	}
+}
+
+// timelineTimeLayout adds the date only when the entries span more than one day
+func timelineTimeLayout(entries []TimelineEntry) string {
+	if len(entries) > 1 && entries[0].Time.Local().YearDay() != entries[len(entries)-1].Time.Local().YearDay() {
+		return "01-02 15:04:05"
+	}
+	return "15:04:05"
+}
+
+// shortDuration drops zero minutes and seconds, e.g. 24h instead of 24h0m0s
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// matchesWorkload reports whether name is the workload or one of its generated children,
+// e.g. web-7d9f8b6c5 and web-7d9f8b6c5-x2k4j for deployment web
+func matchesWorkload(name, workload string) bool {
+	return workload == "" || name == workload || strings.HasPrefix(name, workload+"-")
+}
+
+func firstTime(times ...time.Time) time.Time {
+	for _, t := range times {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
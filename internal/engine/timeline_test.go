@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/execx"
+)
+
+const (
+	timelineEventsJSON = `{"items": [
+  {"type": "Normal", "reason": "ScalingReplicaSet", "message": "Scaled up replica set web-66b8 to 1",
+   "lastTimestamp": "2025-03-04T10:00:05Z", "involvedObject": {"kind": "Deployment", "name": "web"}},
+  {"type": "Warning", "reason": "BackOff", "message": "Back-off restarting failed container", "count": 6,
+   "lastTimestamp": "2025-03-04T10:03:00Z", "involvedObject": {"kind": "Pod", "name": "web-66b8-x2k4j"}},
+  {"type": "Normal", "reason": "SuccessfulRescale", "message": "New size: 4; reason: cpu resource utilization above target",
+   "eventTime": "2025-03-04T10:01:30.000000Z", "involvedObject": {"kind": "HorizontalPodAutoscaler", "name": "web"}},
+  {"type": "Warning", "reason": "Unhealthy", "message": "Readiness probe failed",
+   "lastTimestamp": "2025-03-04T09:10:00Z", "involvedObject": {"kind": "Pod", "name": "worker-5c9d-abcde"}}
+]}`
+	timelineReplicaSetsJSON = `{"items": [
+  {"metadata": {"name": "web-66b8", "creationTimestamp": "2025-03-04T10:00:00Z",
+                "annotations": {"deployment.kubernetes.io/revision": "7"}},
+   "spec": {"template": {"spec": {"containers": [{"image": "shop/web:1.8.0"}]}}}},
+  {"metadata": {"name": "web-5f7a", "creationTimestamp": "2025-02-01T08:00:00Z"}}
+]}`
+	timelinePodsJSON = `{"items": [
+  {"metadata": {"name": "web-66b8-x2k4j"},
+   "status": {"containerStatuses": [{"name": "web", "restartCount": 6,
+     "lastState": {"terminated": {"reason": "Error", "exitCode": 1, "finishedAt": "2025-03-04T10:02:40Z"}}}]}}
+]}`
+	timelineHelmListJSON    = `[{"name": "web", "namespace": "shop", "revision": "12"}, {"name": "redis", "namespace": "shop", "revision": "3"}]`
+	timelineHelmHistoryJSON = `[
+  {"revision": 11, "updated": "2025-03-02T16:00:00Z", "status": "superseded", "chart": "web-1.7.0", "app_version": "1.7.0", "description": "Upgrade complete"},
+  {"revision": 12, "updated": "2025-03-04T09:59:50Z", "status": "deployed", "chart": "web-1.8.0", "app_version": "1.8.0", "description": "Upgrade complete"}
+]`
+)
+
+func timelineRunner() *execx.MockRunner {
+	r := execx.NewMockRunner()
+	r.Expect("kubectl", []string{"get", "events", "-n", "shop", "-o", "json"}, timelineEventsJSON, "", nil)
+	r.Expect("kubectl", []string{"get", "replicasets", "-n", "shop", "-o", "json"}, timelineReplicaSetsJSON, "", nil)
+	r.Expect("kubectl", []string{"get", "pods", "-n", "shop", "-o", "json"}, timelinePodsJSON, "", nil)
+	r.Expect("helm", []string{"list", "-n", "shop", "-o", "json"}, timelineHelmListJSON, "", nil)
+	r.Expect("helm", []string{"history", "web", "-n", "shop", "--max", "10", "-o", "json"}, timelineHelmHistoryJSON, "", nil)
+	return r
+}
+
+func TestTimelineMergesSourcesForWorkload(t *testing.T) {
+	b := NewTimelineBuilder(timelineRunner(), time.Second, 24*time.Hour)
+	b.now = func() time.Time { return time.Date(2025, 3, 4, 10, 30, 0, 0, time.UTC) }
+	tl := b.Build(context.Background(), "shop", "web")
+
+	if len(tl.Errors) != 0 {
+		t.Fatalf("unexpected source errors: %v", tl.Errors)
+	}
+	var got []string
+	for _, e := range tl.Entries {
+		got = append(got, e.Source+" "+e.Object)
+	}
+	want := []string{
+		"helm release/web",                  // 09:59:50
+		"rollout ReplicaSet/web-66b8",       // 10:00:00
+		"rollout Deployment/web",            // 10:00:05
+		"scale HorizontalPodAutoscaler/web", // 10:01:30
+		"restart Pod/web-66b8-x2k4j",        // 10:02:40
+		"event Pod/web-66b8-x2k4j",          // 10:03:00
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("entries out of order or unfiltered:\n%s", strings.Join(got, "\n"))
+	}
+	if len(tl.Bursts) != 1 || tl.Bursts[0].Count != 6 || tl.Bursts[0].Warnings != 2 {
+		t.Errorf("expected one burst of 6 entries with 2 warnings, got %+v", tl.Bursts)
+	}
+
+	view := tl.Render()
+	for _, s := range []string{"🔥", "revision 7, image shop/web:1.8.0", "Back-off restarting failed container (x6)"} {
+		if !strings.Contains(view, s) {
+			t.Errorf("render should contain %q:\n%s", s, view)
+		}
+	}
+	prompt := tl.PromptText()
+	if !strings.Contains(prompt, "2025-03-04T10:02:40Z [restart WARNING] Pod/web-66b8-x2k4j Error") {
+		t.Errorf("prompt should list entries in UTC:\n%s", prompt)
+	}
+}
+
+func TestTimelineKeepsWorkingWhenASourceFails(t *testing.T) {
+	r := execx.NewMockRunner()
+	r.Expect("kubectl", []string{"get", "events", "-n", "shop", "-o", "json"}, timelineEventsJSON, "", nil)
+	r.Expect("kubectl", []string{"get", "replicasets", "-n", "shop", "-o", "json"}, `{"items": []}`, "", nil)
+	r.Expect("kubectl", []string{"get", "pods", "-n", "shop", "-o", "json"}, `{"items": []}`, "", nil)
+	r.Expect("helm", nil, "", "", errors.New(`exec: "helm": executable file not found in $PATH`))
+
+	b := NewTimelineBuilder(r, time.Second, time.Hour)
+	b.now = func() time.Time { return time.Date(2025, 3, 4, 10, 30, 0, 0, time.UTC) }
+	tl := b.Build(context.Background(), "shop", "")
+
+	if len(tl.Errors) != 1 || !strings.HasPrefix(tl.Errors[0], "helm: ") {
+		t.Errorf("the helm failure should be noted, got %v", tl.Errors)
+	}
+	if len(tl.Entries) != 3 {
+		t.Errorf("namespace timeline should keep the events inside the window, got %d", len(tl.Entries))
+	}
+	if len(tl.Bursts) != 0 {
+		t.Errorf("three entries are not a burst: %+v", tl.Bursts)
+	}
+	if !strings.Contains(tl.Render(), "Not included: helm") {
+		t.Error("render should mention the missing source")
+	}
+}
@@ -0,0 +1,94 @@
+// injection.go - Detect prompt-injection attempts in cluster output commands are derived from
+package validator
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// InjectionFinding is one instruction-like pattern found in cluster output
+type InjectionFinding struct {
+	Rule    string
+	Excerpt string
+}
+
+// UntrustedDelimiter matches the delimiters that fence untrusted cluster output in prompts
+var UntrustedDelimiter = regexp.MustCompile(`(?i)<<<\s*(END\s+)?UNTRUSTED[^>\n]*(>>>)?`)
+
+type injectionRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var injectionRules = []injectionRule{
+	{"ignore-instructions", regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override|bypass)\b[^\n]{0,40}\b(previous|prior|above|earlier|all|any|system|your|the)\b[^\n]{0,30}\b(instructions?|prompts?|rules|directions|guidelines|guardrails)\b`)},
+	{"role-override", regexp.MustCompile(`(?i)\byou are now\b|\bnew (system )?instructions?\s*:|\bdeveloper mode\b|\bjailbreak|\bact as (an? )?(admin|root|unrestricted|cluster-admin)\b`)},
+	{"prompt-markers", regexp.MustCompile(`(?im)<\|im_(start|end)\|>|\[/?INST\]|<</?SYS>>|^\s*#{2,}\s*(system|instructions?)\b|^\s*(system|assistant)\s*:`)},
+	{"agent-directive", regexp.MustCompile(`(?im)^\s*(Action|Final)\s*:`)},
+	{"command-directive", regexp.MustCompile(`(?i)\b(run|execute|exec|invoke|type|issue)\b[^\n]{0,30}\b(kubectl|helm)\s+(delete|apply|create|patch|scale|drain|cordon|edit|replace|exec|uninstall|rollout)\b`)},
+	{"remote-script", regexp.MustCompile(`(?i)\b(curl|wget)\b[^\n|]*\|\s*(ba|z)?sh\b`)},
+	{"fence-escape", UntrustedDelimiter},
+}
+
+// DetectInjection scans untrusted text for instruction-like content
+func DetectInjection(text string) []InjectionFinding {
+	var findings []InjectionFinding
+	for _, rule := range injectionRules {
+		loc := rule.pattern.FindStringIndex(text)
+		if loc == nil {
+			continue
+		}
+		findings = append(findings, InjectionFinding{Rule: rule.name, Excerpt: excerptAround(text, loc[0], loc[1])})
+	}
+	return findings
+}
+
+// FindingRules returns the distinct rule names of the findings
+func FindingRules(findings []InjectionFinding) []string {
+	seen := make(map[string]bool)
+	var rules []string
+	for _, f := range findings {
+		if !seen[f.Rule] {
+			seen[f.Rule] = true
+			rules = append(rules, f.Rule)
+		}
+	}
+	return rules
+}
+
+// BuildPreExecPlanTainted builds the plan for a command derived from context: the cluster
+// output, chat turns or step results the command was suggested from. Instruction-like text
+// anywhere in the context makes the plan untrusted, so the command needs typing "yes".
+func BuildPreExecPlanTainted(cmd string, context ...string) PreExecPlan {
+	var findings []InjectionFinding
+	for _, text := range context {
+		findings = append(findings, DetectInjection(text)...)
+	}
+	if len(findings) > 0 {
+		return BuildUntrustedPreExecPlan(cmd, FindingRules(findings))
+	}
+	return BuildPreExecPlan(cmd)
+}
+
+func excerptAround(text string, start, end int) string {
+	const pad = 20
+	from, to := start-pad, end+pad
+	if from < 0 {
+		from = 0
+	}
+	if to > len(text) {
+		to = len(text)
+	}
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+	excerpt := strings.Join(strings.Fields(text[from:to]), " ")
+	if runes := []rune(excerpt); len(runes) > 120 {
+		excerpt = string(runes[:120]) + "…"
+	}
+	return excerpt
+}
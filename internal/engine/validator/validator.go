@@ -40,6 +40,7 @@ type PreExecPlan struct {
 	DangerLevel          string         // "low", "medium", "high", "critical"
 	Notes                []string
 	SafetyChecks         []string // Additional safety validation messages
+	Untrusted            bool     // derived from a turn whose cluster output looked like prompt injection
 }
 
 // BuildPreExecPlan decides the safe preview flow for a command.
@@ -203,6 +204,29 @@ func BuildPreExecPlan(cmd string) PreExecPlan {
 	return plan
 }
 
+// BuildUntrustedPreExecPlan builds the usual plan for a command that was derived from
+// a turn containing suspected prompt injection, and always requires typing "yes".
+func BuildUntrustedPreExecPlan(cmd string, reasons []string) PreExecPlan {
+	plan := BuildPreExecPlan(cmd)
+	plan.Untrusted = true
+	plan.RequireSecondConfirm = true
+	plan.RequireTypedConfirm = true
+	if plan.FirstRunCommand == plan.Original {
+		// Nothing runs before the typed confirmation unless it is a dry-run
+		plan.FirstRunCommand = ""
+	}
+	if plan.DangerLevel == "low" || plan.DangerLevel == "medium" {
+		plan.DangerLevel = "high"
+	}
+	note := "🛡️ Suggested after cluster output that looked like prompt injection"
+	if len(reasons) > 0 {
+		note += ": " + strings.Join(reasons, ", ")
+	}
+	plan.Notes = append(plan.Notes, note)
+	plan.SafetyChecks = append(plan.SafetyChecks, "⛔ Verify this command is what YOU asked for, not what the cluster data asked for")
+	return plan
+}
+
 // HumanPreview renders a short explanation for the UI preview panel.
 func (p PreExecPlan) HumanPreview() string {
 	var b strings.Builder
@@ -215,7 +239,7 @@ func (p PreExecPlan) HumanPreview() string {
 	}
 	if p.FirstRunCommand != "" && p.FirstRunCommand != p.Original {
 		fmt.Fprintf(&b, "• First run (safe): %s\n", p.FirstRunCommand)
-	} else {
+	} else if p.FirstRunCommand != "" {
 		fmt.Fprintf(&b, "• First run: %s\n", p.FirstRunCommand)
 	}
 	if p.RequireSecondConfirm {
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"sync"
 )
 
 // Runner defines the interface for command execution
@@ -14,6 +16,12 @@ type Runner interface {
 	RunCommand(ctx context.Context, command string) (stdout string, stderr string, err error)
 }
 
+// Streamer runs long-lived commands such as kubectl logs -f whose output is read as it
+// is written. Closing the reader stops the command.
+type Streamer interface {
+	Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error)
+}
+
 // OSRunner implements Runner using os/exec
 type OSRunner struct{}
 
@@ -64,7 +72,9 @@ func (r *OSRunner) RunCommand(ctx context.Context, command string) (string, stri
 	return stdout.String(), stderr.String(), err
 }
 
-// MockRunner implements Runner for testing
+// MockRunner implements Runner for testing. Each call consumes the first unused command
+// with the same name and, when Args is set, the same arguments, so concurrent callers
+// get their own output.
 type MockRunner struct {
 	Commands []struct {
 		Name   string
@@ -74,6 +84,9 @@ type MockRunner struct {
 		Err    error
 	}
 	CallCount int
+
+	mu   sync.Mutex
+	used map[int]bool
 }
 
 // NewMockRunner creates a new mock runner for testing
@@ -91,14 +104,34 @@ func NewMockRunner() *MockRunner {
 
 // Run executes a mocked command
 func (m *MockRunner) Run(ctx context.Context, name string, args ...string) (string, string, error) {
-	if m.CallCount >= len(m.Commands) {
-		return "", "", fmt.Errorf("unexpected command: %s %v", name, args)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.used == nil {
+		m.used = make(map[int]bool)
 	}
-	
-	cmd := m.Commands[m.CallCount]
 	m.CallCount++
 	
-	return cmd.Stdout, cmd.Stderr, cmd.Err
+	for i, cmd := range m.Commands {
+		if m.used[i] || cmd.Name != name || (cmd.Args != nil && strings.Join(cmd.Args, " ") != strings.Join(args, " ")) {
+			continue
+		}
+		m.used[i] = true
+		return cmd.Stdout, cmd.Stderr, cmd.Err
+	}
+	return "", "", fmt.Errorf("unexpected command: %s %v", name, args)
+}
+
+// Expect adds a command the mock answers with the given output
+func (m *MockRunner) Expect(name string, args []string, stdout, stderr string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Commands = append(m.Commands, struct {
+		Name   string
+		Args   []string
+		Stdout string
+		Stderr string
+		Err    error
+	}{name, args, stdout, stderr, err})
 }
 
 // RunCommand executes a mocked command string
@@ -109,4 +142,43 @@ func (m *MockRunner) RunCommand(ctx context.Context, command string) (string, st
 	}
 	
 	return m.Run(ctx, parts[0], parts[1:]...)
-}
\ No newline at end of file
+}
+
+// Stream starts a command and returns its combined stdout and stderr. The command runs
+// until it exits, ctx is done or the reader is closed; its exit error is returned by Read.
+func (r *OSRunner) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		pw.CloseWithError(cmd.Wait())
+		cancel()
+	}()
+	return &streamReader{PipeReader: pr, cancel: cancel}, nil
+}
+
+// streamReader kills the command when the reader is closed
+type streamReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (s *streamReader) Close() error {
+	s.cancel()
+	return s.PipeReader.Close()
+}
+
+// Stream answers like Run and returns the stdout of the matching command, or its error
+func (m *MockRunner) Stream(ctx context.Context, name string, args ...string) (io.ReadCloser, error) {
+	stdout, _, err := m.Run(ctx, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(strings.NewReader(stdout)), nil
+}
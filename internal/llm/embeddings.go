@@ -0,0 +1,51 @@
+// embeddings.go - Ollama text embeddings (/api/embed)
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type embedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embedResponse struct {
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
+// Embed returns one embedding vector per input text, in input order
+func Embed(model string, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	base := ollamaBaseURL()
+	payload, err := json.Marshal(embedRequest{Model: model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	resp, err := httpClient.Post(base+"/api/embed", "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Ollama at %s: %w", base, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Ollama at %s returned status %d: %s", base, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var out embedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to decode embeddings: %w", err)
+	}
+	if len(out.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings from %s, got %d", len(texts), model, len(out.Embeddings))
+	}
+	return out.Embeddings, nil
+}
@@ -0,0 +1,215 @@
+// models.go - Ollama model inspection (/api/show) and download (/api/pull)
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// autoNumCtxCeiling caps how far num_ctx is raised toward a model's native context length;
+// larger windows cost memory for the KV cache, so only an explicit num_ctx goes beyond it
+const autoNumCtxCeiling = 32768
+
+// ModelInfo summarizes the details reported by /api/show
+type ModelInfo struct {
+	Name          string
+	Family        string
+	ParameterSize string
+	Quantization  string
+	ContextLength int // native context window, 0 when unknown
+}
+
+// PullProgress is a single progress update from /api/pull
+type PullProgress struct {
+	Status    string
+	Digest    string
+	Total     int64
+	Completed int64
+	Done      bool
+	Err       error
+}
+
+// Percent returns download completion for the current layer (0-100)
+func (p PullProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) / float64(p.Total) * 100
+}
+
+type showResponse struct {
+	Details struct {
+		Family            string `json:"family"`
+		ParameterSize     string `json:"parameter_size"`
+		QuantizationLevel string `json:"quantization_level"`
+	} `json:"details"`
+	ModelInfo map[string]interface{} `json:"model_info"`
+}
+
+// Native context lengths reported by ShowModel, by model name
+var (
+	contextLengthsMu sync.RWMutex
+	contextLengths   = map[string]int{}
+)
+
+type pullResponse struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest"`
+	Total     int64  `json:"total"`
+	Completed int64  `json:"completed"`
+	Error     string `json:"error"`
+}
+
+// ShowModel fetches parameter size, quantization and native context length
+func ShowModel(name string) (*ModelInfo, error) {
+	base := ollamaBaseURL()
+	payload, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling JSON: %w", err)
+	}
+
+	resp, err := httpClient.Post(base+"/api/show", "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Ollama at %s: %w", base, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Ollama at %s returned status %d: %s", base, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var show showResponse
+	if err := json.NewDecoder(resp.Body).Decode(&show); err != nil {
+		return nil, fmt.Errorf("failed to decode model info: %w", err)
+	}
+	info := parseShowResponse(name, show)
+	if info.ContextLength > 0 {
+		contextLengthsMu.Lock()
+		contextLengths[name] = info.ContextLength
+		contextLengthsMu.Unlock()
+	}
+	return info, nil
+}
+
+func parseShowResponse(name string, show showResponse) *ModelInfo {
+	info := &ModelInfo{
+		Name:          name,
+		Family:        show.Details.Family,
+		ParameterSize: show.Details.ParameterSize,
+		Quantization:  show.Details.QuantizationLevel,
+	}
+	// model_info keys are architecture-prefixed, e.g. "llama.context_length"
+	for key, value := range show.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if n, ok := value.(float64); ok && int(n) > info.ContextLength {
+			info.ContextLength = int(n)
+		}
+	}
+	return info
+}
+
+// PullModel downloads a model, streaming progress updates until ch is closed.
+// The final update has Done set, or Err when the pull failed or ctx was cancelled.
+func PullModel(ctx context.Context, name string, ch chan<- PullProgress) {
+	defer close(ch)
+
+	base := ollamaBaseURL()
+	payload, err := json.Marshal(map[string]interface{}{"model": name, "stream": true})
+	if err != nil {
+		ch <- PullProgress{Err: fmt.Errorf("error marshaling JSON: %w", err)}
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base+"/api/pull", bytes.NewBuffer(payload))
+	if err != nil {
+		ch <- PullProgress{Err: fmt.Errorf("error creating request: %w", err)}
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := streamingClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			ch <- PullProgress{Err: fmt.Errorf("pull %s cancelled", name)}
+			return
+		}
+		ch <- PullProgress{Err: fmt.Errorf("failed to reach Ollama at %s: %w", base, err)}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		ch <- PullProgress{Err: fmt.Errorf("Ollama at %s returned status %d: %s", base, resp.StatusCode, strings.TrimSpace(string(body)))}
+		return
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var update pullResponse
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			continue
+		}
+		if update.Error != "" {
+			ch <- PullProgress{Err: fmt.Errorf("pull %s failed: %s", name, update.Error)}
+			return
+		}
+		progress := PullProgress{
+			Status:    update.Status,
+			Digest:    update.Digest,
+			Total:     update.Total,
+			Completed: update.Completed,
+			Done:      update.Status == "success",
+		}
+		ch <- progress
+		if progress.Done {
+			return
+		}
+	}
+
+	if ctx.Err() != nil {
+		ch <- PullProgress{Err: fmt.Errorf("pull %s cancelled", name)}
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		ch <- PullProgress{Err: fmt.Errorf("pull %s interrupted: %w", name, err)}
+		return
+	}
+	ch <- PullProgress{Err: fmt.Errorf("pull %s ended without success status", name)}
+}
+
+// TuneNumCtx fits the configured context length to the model's native limit: a larger
+// value is lowered to it, and with raise a smaller one is raised toward it, up to
+// autoNumCtxCeiling. It returns the value to use and whether it differs from configured.
+func TuneNumCtx(configured int, info *ModelInfo, raise bool) (int, bool) {
+	if info == nil || info.ContextLength <= 0 {
+		return configured, false
+	}
+	native := info.ContextLength
+	switch {
+	case configured > native:
+		return native, true
+	case raise && configured < autoNumCtxCeiling && configured < native:
+		return min(native, autoNumCtxCeiling), true
+	}
+	return configured, false
+}
+
+// ModelNumCtx returns the num_ctx to send for model: configured, tuned to the model's
+// native context length once ShowModel has reported it
+func ModelNumCtx(configured int, model string, raise bool) int {
+	contextLengthsMu.RLock()
+	native := contextLengths[model]
+	contextLengthsMu.RUnlock()
+	tuned, _ := TuneNumCtx(configured, &ModelInfo{ContextLength: native}, raise)
+	return tuned
+}
@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestShowModel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/show" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"details":{"family":"llama","parameter_size":"8.0B","quantization_level":"Q4_K_M"},"model_info":{"general.architecture":"llama","llama.context_length":131072}}`)
+	}))
+	defer srv.Close()
+	t.Setenv("OLLAMA_HOST", srv.URL)
+
+	info, err := ShowModel("llama3.1:8b")
+	if err != nil {
+		t.Fatalf("ShowModel returned error: %v", err)
+	}
+	if info.ParameterSize != "8.0B" || info.Quantization != "Q4_K_M" || info.Family != "llama" {
+		t.Errorf("unexpected details: %+v", info)
+	}
+	if info.ContextLength != 131072 {
+		t.Errorf("expected context length 131072, got %d", info.ContextLength)
+	}
+}
+
+func TestPullModelStreamsProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"downloading","digest":"sha256:abc","total":100,"completed":50}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer srv.Close()
+	t.Setenv("OLLAMA_HOST", srv.URL)
+
+	ch := make(chan PullProgress)
+	go PullModel(context.Background(), "tiny", ch)
+
+	var updates []PullProgress
+	for p := range ch {
+		updates = append(updates, p)
+	}
+	if len(updates) != 3 {
+		t.Fatalf("expected 3 updates, got %d: %+v", len(updates), updates)
+	}
+	if updates[1].Percent() != 50 {
+		t.Errorf("expected 50%%, got %.1f", updates[1].Percent())
+	}
+	if !updates[2].Done {
+		t.Error("final update should be marked done")
+	}
+}
+
+func TestPullModelReportsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+	}))
+	defer srv.Close()
+	t.Setenv("OLLAMA_HOST", srv.URL)
+
+	ch := make(chan PullProgress)
+	go PullModel(context.Background(), "missing", ch)
+
+	var last PullProgress
+	for p := range ch {
+		last = p
+	}
+	if last.Err == nil {
+		t.Fatal("expected pull error")
+	}
+}
+
+func TestPullModelStopsWhenCancelled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"downloading","digest":"sha256:abc","total":100,"completed":1}`)
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	t.Setenv("OLLAMA_HOST", srv.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan PullProgress)
+	go PullModel(ctx, "big", ch)
+
+	if first := <-ch; first.Err != nil || first.Completed != 1 {
+		t.Fatalf("expected a progress update first, got %+v", first)
+	}
+	cancel()
+	var last PullProgress
+	for p := range ch {
+		last = p
+	}
+	if last.Err == nil || !strings.Contains(last.Err.Error(), "cancelled") {
+		t.Errorf("expected the pull to report cancellation, got %+v", last)
+	}
+}
+
+func TestTuneNumCtx(t *testing.T) {
+	cases := []struct {
+		name       string
+		configured int
+		info       *ModelInfo
+		raise      bool
+		want       int
+		changed    bool
+	}{
+		{"clamps to native", 12288, &ModelInfo{ContextLength: 8192}, true, 8192, true},
+		{"raises to native", 4096, &ModelInfo{ContextLength: 8192}, true, 8192, true},
+		{"raises up to the ceiling", 12288, &ModelInfo{ContextLength: 131072}, true, autoNumCtxCeiling, true},
+		{"keeps a value above the ceiling", 65536, &ModelInfo{ContextLength: 131072}, true, 65536, false},
+		{"fixed keeps smaller value", 4096, &ModelInfo{ContextLength: 8192}, false, 4096, false},
+		{"fixed still clamps", 12288, &ModelInfo{ContextLength: 8192}, false, 8192, true},
+		{"unknown native", 12288, &ModelInfo{}, true, 12288, false},
+		{"nil info", 12288, nil, true, 12288, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, changed := TuneNumCtx(tc.configured, tc.info, tc.raise)
+			if got != tc.want || changed != tc.changed {
+				t.Errorf("TuneNumCtx(%d) = %d, %v; want %d, %v", tc.configured, got, changed, tc.want, tc.changed)
+			}
+		})
+	}
+}
+
+func TestModelNumCtxFollowsEachModel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"details":{"family":"phi"},"model_info":{"phi.context_length":2048}}`)
+	}))
+	defer srv.Close()
+	t.Setenv("OLLAMA_HOST", srv.URL)
+
+	if _, err := ShowModel("phi:small"); err != nil {
+		t.Fatalf("ShowModel returned error: %v", err)
+	}
+	if got := ModelNumCtx(12288, "phi:small", true); got != 2048 {
+		t.Errorf("the small model should get its native window, got %d", got)
+	}
+	if got := ModelNumCtx(12288, "llama3.1:8b-large", true); got != 12288 {
+		t.Errorf("other models should keep the configured num_ctx, got %d", got)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]]}`)
+	}))
+	defer srv.Close()
+	t.Setenv("OLLAMA_HOST", srv.URL)
+
+	vectors, err := Embed("nomic-embed-text", []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed returned error: %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 0.3 {
+		t.Errorf("unexpected vectors: %v", vectors)
+	}
+
+	if _, err := Embed("nomic-embed-text", []string{"only one"}); err == nil {
+		t.Error("expected an error when the embedding count does not match")
+	}
+}
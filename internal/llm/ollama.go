@@ -13,6 +13,7 @@ import (
 	"time"
 	
 	"github.com/siryoos/kubemage/internal/config"
+	"github.com/siryoos/kubemage/internal/prompts"
 )
 
 // OllamaRequest represents the request payload for the Ollama API.
@@ -22,6 +23,7 @@ type OllamaRequest struct {
 	Prompt  string                 `json:"prompt"`
 	System  string                 `json:"system"`
 	Stream  bool                   `json:"stream"`
+	Format  json.RawMessage        `json:"format,omitempty"` // JSON schema for structured output
 	Options map[string]interface{} `json:"options,omitempty"`
 }
 
@@ -29,8 +31,10 @@ type OllamaRequest struct {
 // In streaming mode, each response is a separate JSON object.
 // The `Done` field is true when the stream is complete.
 type OllamaResponse struct {
-	Response string `json:"response"`
-	Done     bool   `json:"done"`
+	Response        string `json:"response"`
+	Done            bool   `json:"done"`
+	PromptEvalCount int    `json:"prompt_eval_count,omitempty"` // prompt tokens, on the final response
+	EvalCount       int    `json:"eval_count,omitempty"`        // generated tokens, on the final response
 }
 
 type tagList struct {
@@ -40,13 +44,19 @@ type tagList struct {
 }
 
 const (
-	defaultModelName          = "llama3.1:8b"
-	defaultOllamaEndpoint     = "http://localhost:11434"
-	commandOnlySystemPrompt   = "You are KubeMage, an AI assistant that translates natural language into precise kubectl or helm commands. Always respond with a single command string that can be run as-is. Do not include explanations, markdown, backticks, or additional text. Favor read-only or --dry-run variations when the user intent is ambiguous."
-	chatAssistantSystemPrompt = "You are KubeMage, an AI assistant helping with Kubernetes and Helm. Translate user intent into safe kubectl/helm guidance. Answer with short explanations tailored to the cluster context, then conclude with a fenced ```bash code block containing exactly one command that fulfills the request (prefer read-only or --dry-run first when risky). Warn the user about destructive actions and never assume consent."
-	agentSystemPrompt         = "You are an agent that can use tools to answer questions. You can use the following tools:\n- `kubectl get ...`\n- `kubectl describe ...`\n- `kubectl logs ...`\n- `kubectl events ...`\n\nTo use a tool, you must respond with an `Action:` block, for example:\n```\nAction: kubectl get pods\n```\n\nI will then execute the tool and provide you with an `Observation:` block containing the output.\n\nWhen you have enough information to answer the user's question, you must respond with a `Final:` block containing your final answer."
+	defaultModelName      = "llama3.1:8b"
+	defaultOllamaEndpoint = "http://localhost:11434"
 )
 
+// CommandOnlySystemPrompt renders the system prompt for one-shot command generation
+func CommandOnlySystemPrompt() string { return prompts.Text(prompts.CommandOnly, nil) }
+
+// ChatAssistantSystemPrompt renders the system prompt for interactive chat
+func ChatAssistantSystemPrompt() string { return prompts.Text(prompts.ChatAssistant, nil) }
+
+// AgentSystemPrompt renders the system prompt for the ReAct agent
+func AgentSystemPrompt() string { return prompts.Text(prompts.Agent, nil) }
+
 var (
 	httpClient      = &http.Client{Timeout: 10 * time.Second}
 	streamingClient = &http.Client{Timeout: 0} // No timeout for streaming
@@ -77,23 +87,20 @@ func GenerateCommand(prompt, model string) (string, error) {
 	prompt = RedactText(prompt)
 	*/
 
-	res, err := postOllama(prompt, commandOnlySystemPrompt, modelName, false)
+	cmd, err := GenerateCommandStructured(prompt, modelName, nil)
 	if err != nil {
 		return "", err
 	}
-	defer res.Body.Close()
+	return cmd.Command, nil
+}
 
-	body, err := io.ReadAll(res.Body)
+// GenerateCommandWithTemperature samples a single command at the given temperature.
+func GenerateCommandWithTemperature(prompt, model string, temperature float64) (string, error) {
+	cmd, err := GenerateCommandStructured(prompt, model, map[string]interface{}{"temperature": temperature})
 	if err != nil {
-		return "", fmt.Errorf("error reading response body: %w", err)
-	}
-
-	var ollamaResponse OllamaResponse
-	if err := json.Unmarshal(body, &ollamaResponse); err != nil {
-		return "", fmt.Errorf("error unmarshaling response: %w", err)
+		return "", err
 	}
-
-	return strings.TrimSpace(ollamaResponse.Response), nil
+	return cmd.Command, nil
 }
 
 // GenerateChatStream sends a prompt to the Ollama API and streams the response.
@@ -149,17 +156,33 @@ func GenerateChatStream(prompt string, ch chan<- string, model string, systemPro
 }
 
 func postOllama(prompt, systemPrompt, model string, stream bool) (*http.Response, error) {
-	requestPayload := OllamaRequest{
+	return sendOllama(OllamaRequest{
 		Model:  model,
 		Prompt: prompt,
 		System: systemPrompt,
-		Stream: stream,
+	}, stream)
+}
+
+// sendOllama posts a generate request, applying num_ctx/keep_alive from config
+func sendOllama(requestPayload OllamaRequest, stream bool) (*http.Response, error) {
+	client := httpClient
+	if stream {
+		client = streamingClient
 	}
+	return sendOllamaWith(client, requestPayload, stream)
+}
+
+// sendOllamaWith is sendOllama with a caller-chosen HTTP client
+func sendOllamaWith(client *http.Client, requestPayload OllamaRequest, stream bool) (*http.Response, error) {
+	requestPayload.Stream = stream
 
 	if cfg := config.ActiveConfig(); cfg != nil {
 		options := make(map[string]interface{})
-		if cfg.NumCtx > 0 {
-			options["num_ctx"] = cfg.NumCtx
+		for k, v := range requestPayload.Options {
+			options[k] = v
+		}
+		if numCtx := ModelNumCtx(cfg.NumCtx, requestPayload.Model, !cfg.NumCtxFixed); numCtx > 0 {
+			options["num_ctx"] = numCtx
 		}
 		if ka := strings.TrimSpace(cfg.KeepAlive); ka != "" {
 			options["keep_alive"] = ka
@@ -176,10 +199,6 @@ func postOllama(prompt, systemPrompt, model string, stream bool) (*http.Response
 
 	endpoint := ollamaEndpoint()
 
-	client := httpClient
-	if stream {
-		client = streamingClient
-	}
 	res, err := client.Post(endpoint, "application/json", bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return nil, fmt.Errorf("error making request to Ollama API: %w", err)
@@ -27,7 +27,7 @@ func NewOllamaClient(opts Options) *OllamaClient {
 
 // Complete generates a completion for the given prompt
 func (c *OllamaClient) Complete(ctx context.Context, prompt string) (string, error) {
-	return c.CompleteWithSystem(ctx, chatAssistantSystemPrompt, prompt)
+	return c.CompleteWithSystem(ctx, ChatAssistantSystemPrompt(), prompt)
 }
 
 // CompleteWithSystem generates a completion with a system prompt
@@ -39,7 +39,7 @@ func (c *OllamaClient) CompleteWithSystem(ctx context.Context, system, prompt st
 
 // Stream generates a streaming completion
 func (c *OllamaClient) Stream(ctx context.Context, prompt string, handler StreamHandler) error {
-	return c.StreamWithSystem(ctx, chatAssistantSystemPrompt, prompt, handler)
+	return c.StreamWithSystem(ctx, ChatAssistantSystemPrompt(), prompt, handler)
 }
 
 // StreamWithSystem generates a streaming completion with a system prompt
@@ -177,17 +177,17 @@ func TestSystemPrompts(t *testing.T) {
 	}{
 		{
 			name:     "command only system prompt",
-			prompt:   commandOnlySystemPrompt,
+			prompt:   CommandOnlySystemPrompt(),
 			expected: "You are KubeMage",
 		},
 		{
 			name:     "chat assistant system prompt",
-			prompt:   chatAssistantSystemPrompt,
+			prompt:   ChatAssistantSystemPrompt(),
 			expected: "You are KubeMage",
 		},
 		{
 			name:     "agent system prompt",
-			prompt:   agentSystemPrompt,
+			prompt:   AgentSystemPrompt(),
 			expected: "You are an agent",
 		},
 	}
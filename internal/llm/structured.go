@@ -0,0 +1,274 @@
+// structured.go - Schema-constrained JSON output via Ollama's "format" field
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/config"
+)
+
+// defaultResponseTimeout bounds a structured call when performance.response_timeout is unset
+const defaultResponseTimeout = 120 * time.Second
+
+// CommandSchema describes {command, explanation, risk}
+var CommandSchema = ObjectSchema(map[string]*Schema{
+	"command":     StringSchema(),
+	"explanation": StringSchema(),
+	"risk":        StringSchema("low", "medium", "high"),
+})
+
+// StructuredCommand is the decoded CommandSchema response
+type StructuredCommand struct {
+	Command     string     `json:"command"`
+	Explanation string     `json:"explanation"`
+	Risk        string     `json:"risk"`
+	Usage       TokenUsage `json:"-"`
+}
+
+// TokenUsage is what Ollama reported in prompt_eval_count and eval_count, summed over retries
+type TokenUsage struct {
+	Prompt     int
+	Completion int
+}
+
+// Total returns prompt plus completion tokens, 0 when Ollama reported neither
+func (u TokenUsage) Total() int {
+	return u.Prompt + u.Completion
+}
+
+// Schema is the subset of JSON Schema understood by Ollama structured outputs
+// and by the local validator.
+type Schema struct {
+	Type       string             `json:"type"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	MinItems   int                `json:"minItems,omitempty"`
+}
+
+// ObjectSchema builds an object schema where every listed property is required
+func ObjectSchema(props map[string]*Schema) *Schema {
+	required := make([]string, 0, len(props))
+	for name := range props {
+		required = append(required, name)
+	}
+	sort.Strings(required)
+	return &Schema{Type: "object", Properties: props, Required: required}
+}
+
+// StringSchema, NumberSchema and ArraySchema are shorthands for leaf schemas
+func StringSchema(enum ...string) *Schema { return &Schema{Type: "string", Enum: enum} }
+func NumberSchema() *Schema               { return &Schema{Type: "number"} }
+func ArraySchema(items *Schema, minItems int) *Schema {
+	return &Schema{Type: "array", Items: items, MinItems: minItems}
+}
+
+// Validate checks a raw JSON document against the schema
+func (s *Schema) Validate(raw []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	return s.validateValue(doc, "$")
+}
+
+func (s *Schema) validateValue(v interface{}, path string) error {
+	if s == nil {
+		return nil
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object", path)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		for name, prop := range s.Properties {
+			if val, ok := obj[name]; ok {
+				if err := prop.validateValue(val, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array", path)
+		}
+		if len(arr) < s.MinItems {
+			return fmt.Errorf("%s: expected at least %d items, got %d", path, s.MinItems, len(arr))
+		}
+		for i, item := range arr {
+			if err := s.Items.validateValue(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string", path)
+		}
+		if len(s.Enum) > 0 {
+			for _, allowed := range s.Enum {
+				if str == allowed {
+					return nil
+				}
+			}
+			return fmt.Errorf("%s: %q is not one of [%s]", path, str, strings.Join(s.Enum, ", "))
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: expected number", path)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", path)
+		}
+	}
+	return nil
+}
+
+// structuredCall performs one model round-trip and returns the raw response text
+type structuredCall func(prompt string) (string, error)
+
+// GenerateStructured asks the model for JSON matching schema and decodes it into out.
+// A response failing validation is retried once with the validation error fed back.
+func GenerateStructured(prompt, systemPrompt, model string, schema *Schema, out interface{}) error {
+	_, err := generateStructured(prompt, systemPrompt, model, schema, nil, out)
+	return err
+}
+
+// GenerateStructuredUsage is GenerateStructured that also returns the tokens Ollama counted
+func GenerateStructuredUsage(prompt, systemPrompt, model string, schema *Schema, out interface{}) (TokenUsage, error) {
+	return generateStructured(prompt, systemPrompt, model, schema, nil, out)
+}
+
+// GenerateCommandStructured asks for one command with its explanation and risk.
+// options are passed to Ollama as-is, e.g. a sampling temperature.
+func GenerateCommandStructured(prompt, model string, options map[string]interface{}) (*StructuredCommand, error) {
+	var cmd StructuredCommand
+	fullPrompt := prompt + "\n\nReturn one kubectl or helm command in \"command\", a short \"explanation\" and its \"risk\" (low for read-only, high for destructive)."
+	usage, err := generateStructured(fullPrompt, CommandOnlySystemPrompt(), model, CommandSchema, options, &cmd)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Usage = usage
+	cmd.Command = strings.TrimSpace(cmd.Command)
+	if cmd.Command == "" {
+		return nil, fmt.Errorf("model returned an empty command")
+	}
+	return &cmd, nil
+}
+
+// responseTimeout is how long a structured call may take, from performance.response_timeout
+func responseTimeout() time.Duration {
+	if cfg := config.ActiveConfig(); cfg != nil && cfg.Performance.ResponseTimeout > 0 {
+		return time.Duration(cfg.Performance.ResponseTimeout) * time.Second
+	}
+	return defaultResponseTimeout
+}
+
+func generateStructured(prompt, systemPrompt, model string, schema *Schema, options map[string]interface{}, out interface{}) (TokenUsage, error) {
+	modelName := model
+	if modelName == "" {
+		modelName = defaultModelName
+	}
+
+	format, err := json.Marshal(schema)
+	if err != nil {
+		return TokenUsage{}, fmt.Errorf("error marshaling schema: %w", err)
+	}
+
+	var usage TokenUsage
+	call := func(p string) (string, error) {
+		// Charts and manifests take far longer than the shared client allows
+		client := &http.Client{Timeout: responseTimeout()}
+		res, err := sendOllamaWith(client, OllamaRequest{
+			Model:   modelName,
+			Prompt:  p,
+			System:  systemPrompt,
+			Format:  format,
+			Options: options,
+		}, false)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return "", fmt.Errorf("error reading response body: %w", err)
+		}
+		var ollamaResponse OllamaResponse
+		if err := json.Unmarshal(body, &ollamaResponse); err != nil {
+			return "", fmt.Errorf("error unmarshaling response: %w", err)
+		}
+		usage.Prompt += ollamaResponse.PromptEvalCount
+		usage.Completion += ollamaResponse.EvalCount
+		return ollamaResponse.Response, nil
+	}
+
+	err = generateStructuredWith(call, prompt, schema, out)
+	return usage, err
+}
+
+func generateStructuredWith(call structuredCall, prompt string, schema *Schema, out interface{}) error {
+	response, err := call(prompt)
+	if err != nil {
+		return err
+	}
+
+	raw := []byte(extractJSON(response))
+	validationErr := schema.Validate(raw)
+	if validationErr != nil {
+		retryPrompt := fmt.Sprintf("%s\n\nYour previous response was rejected: %v\nPrevious response:\n%s\n\nRespond again with JSON that matches the schema exactly.",
+			prompt, validationErr, truncateForRetry(response))
+		response, err = call(retryPrompt)
+		if err != nil {
+			return err
+		}
+		raw = []byte(extractJSON(response))
+		if err := schema.Validate(raw); err != nil {
+			return fmt.Errorf("structured output failed validation after retry: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("error decoding structured output: %w", err)
+	}
+	return nil
+}
+
+// extractJSON trims chatter or code fences some models wrap around JSON
+func extractJSON(response string) string {
+	trimmed := strings.TrimSpace(response)
+	start := strings.IndexAny(trimmed, "{[")
+	if start == -1 {
+		return trimmed
+	}
+	end := strings.LastIndexAny(trimmed, "}]")
+	if end < start {
+		return trimmed[start:]
+	}
+	return trimmed[start : end+1]
+}
+
+func truncateForRetry(response string) string {
+	const limit = 2000
+	if len(response) <= limit {
+		return response
+	}
+	return response[:limit] + "..."
+}
@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/siryoos/kubemage/internal/config"
+)
+
+var testCommandSchema = ObjectSchema(map[string]*Schema{
+	"command":     StringSchema(),
+	"explanation": StringSchema(),
+	"risk":        StringSchema("low", "medium", "high"),
+})
+
+type testCommand struct {
+	Command     string `json:"command"`
+	Explanation string `json:"explanation"`
+	Risk        string `json:"risk"`
+}
+
+func TestSchemaValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{"valid", `{"command":"kubectl get pods","explanation":"list","risk":"low"}`, ""},
+		{"missing field", `{"command":"kubectl get pods","risk":"low"}`, `missing required field "explanation"`},
+		{"bad enum", `{"command":"x","explanation":"y","risk":"extreme"}`, "is not one of"},
+		{"wrong type", `{"command":1,"explanation":"y","risk":"low"}`, "expected string"},
+		{"not json", `kubectl get pods`, "not valid JSON"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := testCommandSchema.Validate([]byte(tc.raw))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGenerateStructuredRetriesOnceWithFeedback(t *testing.T) {
+	var prompts []string
+	responses := []string{
+		`{"command":"kubectl get pods"}`,
+		"```json\n{\"command\":\"kubectl get pods\",\"explanation\":\"list pods\",\"risk\":\"low\"}\n```",
+	}
+	call := func(p string) (string, error) {
+		prompts = append(prompts, p)
+		return responses[len(prompts)-1], nil
+	}
+
+	var out testCommand
+	if err := generateStructuredWith(call, "list pods", testCommandSchema, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[1], `missing required field "explanation"`) {
+		t.Errorf("retry prompt should include the validation error, got %q", prompts[1])
+	}
+	if out.Command != "kubectl get pods" || out.Risk != "low" {
+		t.Errorf("unexpected decoded output: %+v", out)
+	}
+}
+
+func TestGenerateStructuredFailsAfterSecondInvalidResponse(t *testing.T) {
+	calls := 0
+	call := func(p string) (string, error) {
+		calls++
+		return `{"command":"x"}`, nil
+	}
+
+	var out testCommand
+	err := generateStructuredWith(call, "p", testCommandSchema, &out)
+	if err == nil || !strings.Contains(err.Error(), "after retry") {
+		t.Fatalf("expected validation failure after retry, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected exactly one retry, got %d calls", calls)
+	}
+}
+
+func TestGenerateStructuredPropagatesTransportError(t *testing.T) {
+	call := func(p string) (string, error) { return "", errors.New("connection refused") }
+
+	var out testCommand
+	if err := generateStructuredWith(call, "p", testCommandSchema, &out); err == nil {
+		t.Fatal("expected transport error")
+	}
+}
+
+func TestGenerateCommandUsesCommandSchema(t *testing.T) {
+	var request OllamaRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		json.NewEncoder(w).Encode(OllamaResponse{Response: `{"command":" kubectl get pods -n shop ","explanation":"list pods","risk":"low"}`})
+	}))
+	defer srv.Close()
+	t.Setenv("OLLAMA_HOST", srv.URL)
+
+	command, err := GenerateCommandWithTemperature("list pods in shop", "llama3", 0.7)
+	if err != nil {
+		t.Fatalf("GenerateCommandWithTemperature returned error: %v", err)
+	}
+	if command != "kubectl get pods -n shop" {
+		t.Errorf("expected the command field, got %q", command)
+	}
+	if !strings.Contains(string(request.Format), `"risk"`) {
+		t.Errorf("the request should carry the command schema, got %s", request.Format)
+	}
+	if request.Options["temperature"] != 0.7 {
+		t.Errorf("the temperature should be passed through, got %v", request.Options)
+	}
+}
+
+func TestGenerateCommandStructuredReportsUsage(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		response := `{"command":"kubectl get pods"}`
+		if calls > 1 {
+			response = `{"command":"kubectl get pods","explanation":"list pods","risk":"low"}`
+		}
+		json.NewEncoder(w).Encode(OllamaResponse{Response: response, Done: true, PromptEvalCount: 40, EvalCount: 12})
+	}))
+	defer srv.Close()
+	t.Setenv("OLLAMA_HOST", srv.URL)
+
+	cmd, err := GenerateCommandStructured("list pods", "llama3", nil)
+	if err != nil {
+		t.Fatalf("GenerateCommandStructured returned error: %v", err)
+	}
+	if calls != 2 || cmd.Usage != (TokenUsage{Prompt: 80, Completion: 24}) || cmd.Usage.Total() != 104 {
+		t.Errorf("usage should be summed over the retry, got %+v after %d calls", cmd.Usage, calls)
+	}
+}
+
+func TestResponseTimeoutFollowsConfig(t *testing.T) {
+	previous := config.ActiveConfig()
+	defer config.SetActiveConfig(previous)
+
+	config.SetActiveConfig(nil)
+	if got := responseTimeout(); got != defaultResponseTimeout {
+		t.Errorf("without config the default should apply, got %s", got)
+	}
+	config.SetActiveConfig(&config.AppConfig{Performance: config.PerformanceSettings{ResponseTimeout: 300}})
+	if got := responseTimeout(); got != 5*time.Minute {
+		t.Errorf("performance.response_timeout should apply, got %s", got)
+	}
+}
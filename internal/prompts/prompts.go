@@ -0,0 +1,176 @@
+// Package prompts renders the LLM prompt templates shipped with KubeMage.
+// Templates are embedded; a file with the same name in the configured
+// override directory (prompt.template_dir) replaces the built-in one. A relative
+// override directory is ignored unless prompt.allow_relative_dir is set, so a
+// checked-out repository cannot swap the prompts of whoever runs KubeMage in it.
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/siryoos/kubemage/internal/config"
+)
+
+// Template names
+const (
+	CommandOnly          = "command_only"
+	ChatAssistant        = "chat_assistant"
+	Agent                = "agent"
+	DiffEdit             = "diff_edit"
+	DeploymentGeneration = "deployment_generation"
+	PolicyPrefix         = "policy_"
+)
+
+const templateExt = ".tmpl"
+
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+var (
+	clusterMu sync.RWMutex
+	cluster   clusterState
+)
+
+type clusterState struct {
+	context   string
+	namespace string
+	summary   string
+}
+
+// Vars holds template variables. Context, Namespace, Cluster and Conventions
+// are filled in automatically when not set by the caller.
+type Vars map[string]interface{}
+
+// SetClusterContext records the current kube context for use in templates
+func SetClusterContext(context, namespace, summary string) {
+	clusterMu.Lock()
+	defer clusterMu.Unlock()
+	cluster = clusterState{context: context, namespace: namespace, summary: summary}
+}
+
+// Names lists every available template, built-in and override
+func Names() []string {
+	seen := make(map[string]bool)
+	entries, _ := embedded.ReadDir("templates")
+	for _, e := range entries {
+		seen[strings.TrimSuffix(e.Name(), templateExt)] = true
+	}
+	if dir := overrideDir(); dir != "" {
+		if files, err := os.ReadDir(dir); err == nil {
+			for _, f := range files {
+				if !f.IsDir() && strings.HasSuffix(f.Name(), templateExt) {
+					seen[strings.TrimSuffix(f.Name(), templateExt)] = true
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Source returns the raw template text and where it was loaded from
+func Source(name string) (string, string, error) {
+	if dir := overrideDir(); dir != "" {
+		path := filepath.Join(dir, name+templateExt)
+		if data, err := os.ReadFile(path); err == nil {
+			return string(data), path, nil
+		}
+	}
+	data, err := embedded.ReadFile("templates/" + name + templateExt)
+	if err != nil {
+		return "", "", fmt.Errorf("unknown prompt template %q", name)
+	}
+	return string(data), "built-in", nil
+}
+
+// Render executes the named template, preferring the user override
+func Render(name string, vars Vars) (string, error) {
+	text, origin, err := Source(name)
+	if err != nil {
+		return "", err
+	}
+	out, err := execute(name, text, vars)
+	if err != nil {
+		return "", fmt.Errorf("prompt template %s (%s): %w", name, origin, err)
+	}
+	return out, nil
+}
+
+// Text renders the named template, falling back to the built-in version when
+// an override fails to parse or execute.
+func Text(name string, vars Vars) string {
+	if out, err := Render(name, vars); err == nil {
+		return out
+	}
+	data, err := embedded.ReadFile("templates/" + name + templateExt)
+	if err != nil {
+		return ""
+	}
+	out, _ := execute(name, string(data), vars)
+	return out
+}
+
+func execute(name, text string, vars Vars) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	data := withDefaults(vars)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	// Drop the newline that ends the template file
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func withDefaults(vars Vars) Vars {
+	data := make(Vars, len(vars)+4)
+	clusterMu.RLock()
+	data["Context"] = cluster.context
+	data["Namespace"] = cluster.namespace
+	data["Cluster"] = cluster.summary
+	clusterMu.RUnlock()
+	data["Conventions"] = ""
+	if cfg := config.ActiveConfig(); cfg != nil {
+		data["Conventions"] = strings.TrimSpace(cfg.Prompt.Conventions)
+	}
+
+	for k, v := range vars {
+		data[k] = v
+	}
+	return data
+}
+
+func overrideDir() string {
+	cfg := config.ActiveConfig()
+	if cfg == nil {
+		return ""
+	}
+	dir := strings.TrimSpace(cfg.Prompt.TemplateDir)
+	if strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, dir[2:])
+	}
+	if dir != "" && !filepath.IsAbs(dir) && !cfg.Prompt.AllowRelativeDir {
+		return ""
+	}
+	return dir
+}
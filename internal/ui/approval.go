@@ -0,0 +1,90 @@
+// approval.go - Step-approval mode for the ReAct agent: approve, edit, skip or abort each action
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// showAgentCard pauses the agent loop until the user decides what to do with the action
+func (m *model) showAgentCard(action string) {
+	m.agentState = "approving"
+	m.agentCard = action
+	m.command = action
+	m.refreshPreviewPane()
+	// The action starts out in the input box so it can be edited in place
+	m.textarea.SetValue(action)
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf(
+		"🃏 The agent wants to run:\n    $ %s\nEnter or Ctrl+E: approve (edit it in the input box first to change it) • Ctrl+K: skip • Esc: abort • Ctrl+G: approve and switch to auto mode",
+		action)})
+}
+
+// approveAgentAction runs the card's action, or the user's edit of it if that is still read-only
+func (m *model) approveAgentAction() tea.Cmd {
+	proposed := m.agentCard
+	action := strings.TrimSpace(m.textarea.Value())
+	if action == "" {
+		action = proposed
+	}
+	// The model's action already passed the agent whitelist; an edit must pass it again
+	if action != proposed && !engine.IsWhitelistedAction(action) {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⛔ `%s` is not a read-only command the agent may run. Edit it again, press Ctrl+K to skip it or Esc to abort.", action)})
+		return nil
+	}
+	if action != proposed {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("✏️ Running your edit instead of `%s`.", proposed)})
+	}
+	m.noteAccepted(proposed, action)
+
+	m.agentCard = ""
+	m.agentState = "acting"
+	m.command = action
+	m.textarea.Reset()
+	m.messages = append(m.messages, message{sender: execSender, content: "$ " + action})
+	m.beginCommandExecution(action)
+	return execCmd(action, m.program)
+}
+
+// skipAgentAction declines the card's action and lets the agent choose another one
+func (m *model) skipAgentAction() tea.Cmd {
+	action := m.agentCard
+	m.agentCard = ""
+	m.command = ""
+	m.textarea.Reset()
+	m.refreshPreviewPane()
+
+	m.messages = append(m.messages, message{sender: user, content: fmt.Sprintf("I skipped `%s`. Choose a different action or give your Final: answer.", action)})
+	history := append([]message(nil), m.messages...)
+	m.messages = append(m.messages, message{sender: assist, content: waitingMessage})
+	m.agentState = "thinking"
+	return generateStreamCmd(m, history, m.ollamaModel)
+}
+
+// abortAgentSession ends the agent session without running the card's action
+func (m *model) abortAgentSession() {
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⏹️ Agent session aborted; `%s` was not run.", m.agentCard)})
+	m.agentCard = ""
+	m.agentMode = false
+	m.agentState = ""
+	m.command = ""
+	m.textarea.Reset()
+	m.refreshPreviewPane()
+}
+
+// toggleAgentApproval switches between step approval and auto mode; switching to auto
+// approves the action on the card, if any
+func (m *model) toggleAgentApproval() tea.Cmd {
+	m.agentApproval = !m.agentApproval
+	if m.agentApproval {
+		m.messages = append(m.messages, message{sender: systemSender, content: "🃏 Step approval on: each agent action waits for you."})
+		return nil
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: "▶️ Auto mode: read-only agent actions run without asking."})
+	if m.agentCard != "" {
+		return m.approveAgentAction()
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// command_guard.go - Run generated commands through the hallucination guard before preview
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// commandGuardMsg carries the guard report for a proposed command
+type commandGuardMsg struct {
+	report engine.GuardReport
+}
+
+func guardCommandCmd(guard *engine.CommandGuard, command string) tea.Cmd {
+	return func() tea.Msg {
+		return commandGuardMsg{report: guard.Check(context.Background(), command)}
+	}
+}
+
+// proposeCommand routes a parsed command through the guard; it reaches the
+// preview pane once the check finishes
+func (m *model) proposeCommand(command string) tea.Cmd {
+	m.guardCorrection = ""
+	m.explainView = ""
+	m.timelineView = ""
+	m.noteSuggestion(command)
+	if m.guard == nil || command == "" {
+		m.command = command
+		m.refreshPreviewPane()
+		return nil
+	}
+	m.command = ""
+	m.guardPending = command
+	m.refreshPreviewPane()
+	return guardCommandCmd(m.guard, command)
+}
+
+// handleCommandGuard shows the command and any nearest-match corrections
+func (m *model) handleCommandGuard(msg commandGuardMsg) {
+	if msg.report.Command != m.guardPending {
+		return // superseded by a newer suggestion
+	}
+	m.guardPending = ""
+	m.command = msg.report.Command
+	m.refreshPreviewPane()
+	if msg.report.OK() {
+		return
+	}
+
+	m.guard.Record(msg.report)
+	m.guardCorrection = msg.report.Corrected
+	content := msg.report.Summary()
+	if m.guardCorrection != "" {
+		content += "\nType /fix to use the corrected command, or press Ctrl+E to run it as suggested."
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: content})
+}
+
+// applyGuardCorrection replaces the pending command with the guard's correction
+func (m *model) applyGuardCorrection() string {
+	if m.guardCorrection == "" {
+		return "Nothing to fix: the last suggested command had no corrections."
+	}
+	m.noteCorrection(m.command, m.guardCorrection)
+	m.command = m.guardCorrection
+	m.guardCorrection = ""
+	m.currentPlan = nil
+	m.refreshPreviewPane()
+	return fmt.Sprintf("✏️ Command updated: `%s`", m.command)
+}
@@ -0,0 +1,83 @@
+// command_voting.go - /cmd: generate one command by voting on sampled candidates
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// commandVoteTimeout bounds sampling, the dry-runs and the single-shot fallback together
+const commandVoteTimeout = 3 * time.Minute
+
+// commandVoteMsg carries the command generated for a /cmd request
+type commandVoteMsg struct {
+	generated *engine.GeneratedCommand
+	err       error
+}
+
+func commandVoteCmd(generator *engine.IntelligentCommandGenerator, request string, summary *engine.KubeContextSummary) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), commandVoteTimeout)
+		defer cancel()
+		generated, err := generator.GenerateOptimizedCommand(ctx, request, summary)
+		return commandVoteMsg{generated: generated, err: err}
+	}
+}
+
+// handleCmdCommand implements /cmd <request>
+func (m *model) handleCmdCommand(input string) tea.Cmd {
+	request := strings.TrimSpace(strings.TrimPrefix(input, "/cmd"))
+	if request == "" {
+		m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /cmd <request>, e.g. /cmd scale the web deployment to 3 replicas"})
+		return nil
+	}
+	if m.commandGenerator == nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: "ℹ️ Command generation with voting is unavailable."})
+		return nil
+	}
+	if m.commandVoting {
+		m.messages = append(m.messages, message{sender: systemSender, content: "⏳ A /cmd request is already running."})
+		return nil
+	}
+
+	summary := m.currentContext
+	if summary == nil {
+		summary = &engine.KubeContextSummary{Namespace: m.namespace}
+	}
+	m.commandVoting = true
+	m.messages = append(m.messages, message{sender: systemSender, content: "🗳️ Sampling command candidates and checking them with dry-runs..."})
+	return commandVoteCmd(m.commandGenerator, request, summary)
+}
+
+// handleCommandVote shows the winning command and its alternatives, then proposes the
+// winner through the guard like any other suggestion
+func (m *model) handleCommandVote(msg commandVoteMsg) tea.Cmd {
+	m.commandVoting = false
+	if msg.err != nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("❌ Command generation failed: %v", msg.err)})
+		return nil
+	}
+
+	generated := msg.generated
+	var b strings.Builder
+	fmt.Fprintf(&b, "`%s`", generated.Command)
+	if votes, ok := generated.Metadata["votes"].(string); ok {
+		fmt.Fprintf(&b, " (%s votes)", votes)
+	}
+	if generated.Explanation != "" {
+		fmt.Fprintf(&b, "\n%s", generated.Explanation)
+	}
+	if len(generated.AlternativeCommands) > 0 {
+		b.WriteString("\nAlternatives:")
+		for _, alt := range generated.AlternativeCommands {
+			fmt.Fprintf(&b, "\n- `%s`", alt)
+		}
+	}
+	m.messages = append(m.messages, message{sender: assist, content: b.String()})
+	return m.proposeCommand(generated.Command)
+}
@@ -0,0 +1,147 @@
+// crd.go - CRD discovery for prompts and /ctx, and /gen-crd custom resource generation
+package ui
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+const (
+	crdContextLimit = 2
+	crdScryLines    = 15
+	crdTimeout      = 15 * time.Second
+)
+
+// discoverCRDsCmd warms the CRD catalog so intent routing knows installed kinds
+func discoverCRDsCmd(catalog *engine.CRDCatalog) tea.Cmd {
+	if catalog == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), crdTimeout)
+		defer cancel()
+		// A cluster without CRD access simply gets no custom kinds
+		_, _ = catalog.List(ctx)
+		return nil
+	}
+}
+
+// crdDocs scries CRDs mentioned in the query: their schema summary plus current objects
+func (m *model) crdDocs(query string) []engine.RetrievalHit {
+	if m.crds == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), crdTimeout)
+	defer cancel()
+
+	var hits []engine.RetrievalHit
+	for _, crd := range m.crds.Relevant(ctx, query, crdContextLimit) {
+		text := crd.Summary()
+		if objects, err := m.crds.Scry(ctx, crd, m.namespace, crdScryLines); err == nil {
+			text += "\n" + engine.FenceUntrusted("kubectl get "+crd.Plural, objects).Text
+		}
+		hits = append(hits, engine.RetrievalHit{
+			Doc:   engine.RetrievalDoc{ID: "crd:" + crd.Name, Kind: "crd", Citation: "crd " + crd.Name, Text: text},
+			Score: 1,
+		})
+	}
+	return hits
+}
+
+// crdContextLine summarizes installed CRDs for /ctx
+func (m *model) crdContextLine() string {
+	if m.crds == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), crdTimeout)
+	defer cancel()
+	crds, err := m.crds.List(ctx)
+	if err != nil && len(crds) == 0 {
+		return fmt.Sprintf("crds: unavailable (%v)", err)
+	}
+
+	groups := make(map[string][]string)
+	var order []string
+	for _, crd := range crds {
+		if _, ok := groups[crd.Group]; !ok {
+			order = append(order, crd.Group)
+		}
+		groups[crd.Group] = append(groups[crd.Group], crd.Kind)
+	}
+	parts := make([]string, 0, len(order))
+	for _, group := range order {
+		parts = append(parts, fmt.Sprintf("%s: %s", group, strings.Join(groups[group], ", ")))
+	}
+	if len(parts) == 0 {
+		return "crds: none"
+	}
+	return fmt.Sprintf("crds=%d\n  %s", len(crds), strings.Join(parts, "\n  "))
+}
+
+// parseGenCRDCommand parses /gen-crd <kind> <name> [description]
+func parseGenCRDCommand(input string) (kind, name, description string, err error) {
+	fields := strings.Fields(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/gen-crd")))
+	if len(fields) < 2 {
+		return "", "", "", fmt.Errorf("usage: /gen-crd <kind> <name> [description]")
+	}
+	return fields[0], fields[1], strings.Join(fields[2:], " "), nil
+}
+
+// customResourceTarget is the manifest path for a generated custom resource
+func customResourceTarget(name, kind string) string {
+	return filepath.Join("out", fmt.Sprintf("%s-%s.yaml", name, strings.ToLower(kind)))
+}
+
+// customResourceGenerationCmd generates a custom resource and repairs it once against the CRD schema
+func customResourceGenerationCmd(catalog *engine.CRDCatalog, kind, name, namespace, description, modelName string) tea.Cmd {
+	return func() tea.Msg {
+		if catalog == nil {
+			return generationResultMsg{err: fmt.Errorf("CRD discovery is not available")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), crdTimeout)
+		crd, err := catalog.Lookup(ctx, kind)
+		cancel()
+		if err != nil {
+			return generationResultMsg{err: err}
+		}
+
+		prompt := engine.CustomResourcePrompt(*crd, name, namespace, description)
+		manifest, err := engine.GenerateManifestStructured(prompt, modelName)
+		if err != nil {
+			return generationResultMsg{err: err}
+		}
+		issues := engine.ValidateCustomResource(manifest.Manifest, *crd)
+		if len(issues) > 0 {
+			repair := prompt + "\n\nThe previous attempt violated the CRD schema:\n- " + strings.Join(issues, "\n- ") +
+				"\n\nPrevious attempt:\n" + manifest.Manifest + "\n\nReturn a corrected manifest."
+			if repaired, err := engine.GenerateManifestStructured(repair, modelName); err == nil {
+				if remaining := engine.ValidateCustomResource(repaired.Manifest, *crd); len(remaining) < len(issues) {
+					manifest, issues = repaired, remaining
+				}
+			}
+		}
+		return generationResultMsg{
+			manifest:     manifest.Manifest,
+			explanation:  manifest.Explanation,
+			crd:          crd,
+			schemaIssues: issues,
+		}
+	}
+}
+
+// renderSchemaCheck reports the OpenAPI schema check of a generated custom resource
+func renderSchemaCheck(crd *engine.CRDInfo, issues []string) string {
+	if crd == nil {
+		return ""
+	}
+	if len(issues) == 0 {
+		return fmt.Sprintf("🧩 Schema check (%s %s): ✅ passed", crd.APIVersion(), crd.Kind)
+	}
+	return fmt.Sprintf("🧩 Schema check (%s %s): ⚠️ %d issue(s)\n- %s", crd.APIVersion(), crd.Kind, len(issues), strings.Join(issues, "\n- "))
+}
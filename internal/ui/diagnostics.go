@@ -0,0 +1,149 @@
+// diagnostics.go - /diag-<kind>: run a diagnostic plan concurrently and stream each step's output
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// maxLogTemplates caps the log templates sent to the model per logs step
+const maxLogTemplates = 25
+
+// diagStepMsg reports one finished step of a diagnostic plan
+type diagStepMsg struct {
+	run    int
+	result engine.DiagResult
+}
+
+// diagDoneMsg carries every result of a finished diagnostic plan, in plan order
+type diagDoneMsg struct {
+	run       int
+	plan      engine.DiagPlan
+	results   []engine.DiagResult
+	cancelled bool
+}
+
+// handleDiagCommand implements /diag-<kind> [name], e.g. /diag-pvc data-db-0 or /diag-dns
+func (m *model) handleDiagCommand(input string) tea.Cmd {
+	fields := strings.Fields(input)
+	kind := strings.TrimPrefix(fields[0], "/diag-")
+	name := ""
+	if len(fields) > 1 {
+		name = fields[1]
+	}
+	ns, _ := GetCurrentNamespace()
+	plan, err := engine.BuildDiagPlan(kind, name, ns)
+	if err != nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ %v. Usage: /diag-<kind> <name>, where kind is one of %s", err, strings.Join(engine.DiagPlanKinds(), ", "))})
+		return nil
+	}
+	return m.startDiagnostics(plan)
+}
+
+// startDiagnostics runs a diagnostic plan; steps are shown as they finish
+func (m *model) startDiagnostics(plan engine.DiagPlan) tea.Cmd {
+	if m.diagRunner == nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: "ℹ️ Diagnostics are unavailable."})
+		return nil
+	}
+	if m.diagCancel != nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: "⏳ Diagnostics are already running. Press Esc to cancel them."})
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.diagRun++
+	m.diagCancel = cancel
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("🔍 Running diagnostic plan: %s (%d checks, up to %d at once)...", plan.Title, len(plan.Steps), m.diagRunner.Parallelism)})
+
+	run, runner, program := m.diagRun, m.diagRunner, m.program
+	return func() tea.Msg {
+		results := runner.Run(ctx, plan, func(_ int, r engine.DiagResult) {
+			if program != nil {
+				program.Send(diagStepMsg{run: run, result: r})
+			}
+		})
+		return diagDoneMsg{run: run, plan: plan, results: results, cancelled: ctx.Err() != nil}
+	}
+}
+
+// handleDiagStep shows a finished step's output and heuristic notes
+func (m *model) handleDiagStep(msg diagStepMsg) {
+	if msg.run != m.diagRun {
+		return
+	}
+	r := msg.result
+	m.messages = append(m.messages, message{sender: execSender, content: "$ " + r.Command})
+	if r.Output != "" {
+		m.messages = append(m.messages, message{sender: systemSender, content: r.Output, untrusted: true})
+		m.flagInjection(r.Command, r.Output)
+	}
+	for _, note := range r.Notes {
+		m.messages = append(m.messages, message{sender: systemSender, content: "💡 " + note})
+	}
+}
+
+// handleDiagDone asks the model to analyse the results of a completed plan
+func (m *model) handleDiagDone(msg diagDoneMsg) tea.Cmd {
+	if msg.run != m.diagRun {
+		return nil
+	}
+	m.diagCancel = nil
+	if msg.cancelled {
+		m.messages = append(m.messages, message{sender: systemSender, content: "⏹️ Diagnostics cancelled."})
+		return nil
+	}
+	m.lastDiag = &diagnosisRecord{plan: msg.plan, results: msg.results}
+
+	var diagnosticSummary strings.Builder
+	for _, r := range msg.results {
+		diagnosticSummary.WriteString(fmt.Sprintf("Command: %s\n", r.Command))
+		if r.Logs != nil {
+			// Log templates with counts say more than the last 2000 characters of raw lines
+			diagnosticSummary.WriteString(fmt.Sprintf("Log templates (<*> marks variable parts):\n%s\n", engine.FenceUntrusted(r.Command, r.Logs.Summary(maxLogTemplates)).Text))
+		} else if r.Output != "" {
+			// Truncate output for LLM to avoid token limits
+			output := r.Output
+			if len(output) > 2000 {
+				output = output[:2000] + "\n...(truncated)..."
+			}
+			diagnosticSummary.WriteString(fmt.Sprintf("Output:\n%s\n", engine.FenceUntrusted(r.Command, output).Text))
+		}
+		if len(r.Notes) > 0 {
+			diagnosticSummary.WriteString(fmt.Sprintf("Heuristic Notes: %s\n", strings.Join(r.Notes, "; ")))
+		}
+		diagnosticSummary.WriteString("\n")
+	}
+
+	analysisPrompt := fmt.Sprintf(`Based on the diagnostic outputs above for "%s", please provide a concise analysis with:
+
+1. **Root Cause**: What is likely causing the issue?
+2. **Next Steps**: What specific actions should be taken to resolve this?
+
+Focus: %s
+
+Diagnostic Data:
+%s
+
+Please be specific and actionable in your recommendations.`, msg.plan.Title, msg.plan.Summary, diagnosticSummary.String())
+
+	m.messages = append(m.messages, message{sender: user, content: analysisPrompt})
+	history := append([]message(nil), m.messages...)
+	m.messages = append(m.messages, message{sender: assist, content: waitingMessage})
+	m.resetLiveTokens()
+	return generateStreamCmd(m, history, m.generationModel)
+}
+
+// cancelDiagnostics stops a running diagnostic plan; steps not yet started are skipped
+func (m *model) cancelDiagnostics() bool {
+	if m.diagCancel == nil {
+		return false
+	}
+	m.diagCancel()
+	m.diagCancel = nil
+	return true
+}
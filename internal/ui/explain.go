@@ -0,0 +1,108 @@
+// explain.go - /explain browsing and API field docs for explain and generation prompts
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+const (
+	explainFieldLimit = 12
+	explainTimeout    = 10 * time.Second
+)
+
+// explainMsg carries documentation for an /explain path
+type explainMsg struct {
+	path string
+	doc  string
+	err  error
+}
+
+func explainCmd(cache *engine.ExplainCache, path string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+		defer cancel()
+		doc, err := cache.Explain(ctx, path)
+		return explainMsg{path: path, doc: doc, err: err}
+	}
+}
+
+// handleExplainCommand implements /explain <kind.field.path>
+func (m *model) handleExplainCommand(input string) tea.Cmd {
+	path := strings.TrimSpace(strings.TrimPrefix(input, "/explain"))
+	switch {
+	case path == "":
+		m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /explain <kind.field.path>, e.g. /explain deployment.spec.strategy"})
+		return nil
+	case m.explain == nil:
+		m.messages = append(m.messages, message{sender: systemSender, content: "⚠️ API documentation cache is not available."})
+		return nil
+	}
+	return explainCmd(m.explain, path)
+}
+
+// handleExplain shows the documentation in the preview pane
+func (m *model) handleExplain(msg explainMsg) {
+	if msg.err != nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ /explain %s: %v", msg.path, msg.err)})
+		return
+	}
+	m.explainView = msg.doc
+	m.timelineView = ""
+	m.refreshPreviewPane()
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("📖 %s shown in the preview pane.", msg.path)})
+}
+
+// explainDocs grounds explain and generate turns in the cluster's own API fields
+func (m *model) explainDocs(query string) []engine.RetrievalHit {
+	if m.explain == nil {
+		return nil
+	}
+	switch engine.RouteIntent(query, nil).Mode {
+	case engine.ModeExplain, engine.ModeGenerate:
+	default:
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+	return m.explain.FieldDocs(ctx, query, explainFieldLimit)
+}
+
+// generationKinds are the resources each generator always emits; their fields ground the
+// prompt when the request names no resource the cluster serves
+var generationKinds = map[GenerationType][]string{
+	GenerationTypeDeployment: {"deployments"},
+	GenerationTypeHelmChart:  {"deployments", "services"},
+}
+
+// withFieldDocs prefixes a generation prompt with the spec fields of the resources the
+// request mentions, or of the generator's own kinds when it mentions none
+func withFieldDocs(cache *engine.ExplainCache, genType GenerationType, request, prompt string) string {
+	if cache == nil {
+		return prompt
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+
+	hits := cache.FieldDocs(ctx, request, explainFieldLimit)
+	if len(hits) == 0 {
+		for _, resource := range generationKinds[genType] {
+			if hit, err := cache.ResourceDocs(ctx, resource, explainFieldLimit); err == nil {
+				hits = append(hits, hit)
+			}
+		}
+	}
+	if len(hits) == 0 {
+		return prompt
+	}
+	docs := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		docs = append(docs, "- "+hit.Snippet())
+	}
+	return "API fields served by the target cluster:\n" + strings.Join(docs, "\n") + "\n\n" + prompt
+}
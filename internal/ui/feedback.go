@@ -0,0 +1,260 @@
+// feedback.go - Capture ratings and implicit accept/reject signals for the flight recorder
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// defaultSuggestionConfidence seeds calculateQuality for commands parsed from chat replies,
+// which carry no confidence of their own
+const defaultSuggestionConfidence = 0.6
+
+// feedbackSavedMsg reports the outcome of writing the flight recorder to disk
+type feedbackSavedMsg struct {
+	err error
+}
+
+// turnFeedback collects what happened to the suggestions of one user prompt
+type turnFeedback struct {
+	input        string
+	started      time.Time
+	suggestions  []engine.SuggestionRecord
+	actions      []engine.UserActionRecord
+	errors       []string
+	satisfaction int
+	lastFailed   string            // most recent command that failed; the next run counts as a rerun
+	corrections  map[string]string // guard corrections applied via /fix, keyed by the corrected command
+}
+
+// startFeedbackTurn records the previous turn, rejecting suggestions that were never run,
+// and starts tracking a new prompt. The returned command saves the recorder.
+func (m *model) startFeedbackTurn(input string) tea.Cmd {
+	m.rejectPendingSuggestions()
+	save := m.flushFeedback()
+	m.feedback = turnFeedback{input: RedactText(input), started: time.Now()}
+	return save
+}
+
+// noteSuggestion tracks a command proposed to the user
+func (m *model) noteSuggestion(command string) {
+	if command == "" || m.feedback.input == "" {
+		return
+	}
+	command = RedactText(command)
+	for _, s := range m.feedback.suggestions {
+		if s.Content == command && !s.Accepted {
+			return
+		}
+	}
+	m.feedback.suggestions = append(m.feedback.suggestions, engine.SuggestionRecord{
+		Type:       "command",
+		Content:    command,
+		Confidence: defaultSuggestionConfidence,
+		Timestamp:  time.Now(),
+	})
+}
+
+// noteCorrection remembers that /fix replaced a suggestion, so running it counts as an edit
+func (m *model) noteCorrection(from, to string) {
+	if m.feedback.corrections == nil {
+		m.feedback.corrections = make(map[string]string)
+	}
+	m.feedback.corrections[RedactText(to)] = RedactText(from)
+}
+
+// noteAccepted marks the proposed command as run; ran differs from proposed when the user edited it
+func (m *model) noteAccepted(proposed, ran string) {
+	proposed, ran = RedactText(proposed), RedactText(ran)
+	if original, ok := m.feedback.corrections[proposed]; ok {
+		proposed = original
+	}
+	now := time.Now()
+	if m.feedback.lastFailed != "" {
+		m.feedback.actions = append(m.feedback.actions, engine.UserActionRecord{
+			Action: engine.UserActionRerun, Target: m.feedback.lastFailed, ModifiedTo: ran, Timestamp: now,
+		})
+		m.feedback.lastFailed = ""
+	}
+	for i := len(m.feedback.suggestions) - 1; i >= 0; i-- {
+		s := &m.feedback.suggestions[i]
+		if s.Content != proposed || s.Accepted {
+			continue
+		}
+		s.Accepted = true
+		action := engine.UserActionRecord{Action: engine.UserActionAccept, Target: proposed, Timestamp: now}
+		if ran != proposed {
+			action.Action, action.ModifiedTo = engine.UserActionModify, ran
+		}
+		m.feedback.actions = append(m.feedback.actions, action)
+		return
+	}
+}
+
+// editedCommand returns the textarea contents when they are an edit of the proposed command,
+// i.e. a command line starting with the same binary
+func (m *model) editedCommand() string {
+	edited := strings.TrimSpace(m.textarea.Value())
+	proposed := strings.Fields(m.command)
+	fields := strings.Fields(edited)
+	if len(proposed) == 0 || len(fields) == 0 || fields[0] != proposed[0] || edited == m.command {
+		return ""
+	}
+	return edited
+}
+
+// rejectPendingSuggestions records every proposed command that was dropped without running
+func (m *model) rejectPendingSuggestions() {
+	for _, s := range m.feedback.suggestions {
+		if !s.Accepted && !m.feedbackHasAction(engine.UserActionReject, s.Content) {
+			m.feedback.actions = append(m.feedback.actions, engine.UserActionRecord{
+				Action: engine.UserActionReject, Target: s.Content, Timestamp: time.Now(),
+			})
+		}
+	}
+}
+
+func (m *model) feedbackHasAction(action, target string) bool {
+	for _, a := range m.feedback.actions {
+		if a.Action == action && a.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// noteExecution marks accepted suggestions applied, or remembers the failure for rerun detection
+func (m *model) noteExecution(command string, err error) {
+	command = RedactText(command)
+	if err != nil {
+		m.feedback.lastFailed = command
+		m.feedback.errors = append(m.feedback.errors, RedactText(fmt.Sprintf("%s: %v", command, err)))
+		return
+	}
+	session := engine.SessionRecord{UserActions: m.feedback.actions}
+	for i, s := range m.feedback.suggestions {
+		if s.Accepted && session.AcceptedContent(s) == command {
+			m.feedback.suggestions[i].Applied = true
+		}
+	}
+}
+
+// feedbackSession builds the SessionRecord for the current turn
+func (m *model) feedbackSession() engine.SessionRecord {
+	f := m.feedback
+	session := engine.SessionRecord{
+		UserInput:        f.input,
+		Context:          m.currentContext,
+		Suggestions:      append([]engine.SuggestionRecord(nil), f.suggestions...),
+		UserActions:      append([]engine.UserActionRecord(nil), f.actions...),
+		UserSatisfaction: f.satisfaction,
+		Duration:         time.Since(f.started),
+		ErrorMessages:    append([]string(nil), f.errors...),
+	}
+
+	applied, rejected := false, false
+	for _, s := range f.suggestions {
+		applied = applied || s.Applied
+	}
+	for _, a := range f.actions {
+		rejected = rejected || a.Action == engine.UserActionReject
+	}
+	switch {
+	case f.satisfaction > 0 && f.satisfaction <= 2, f.lastFailed != "":
+		session.Outcome = "failure"
+	case applied:
+		session.Outcome = "success"
+	case rejected:
+		session.Outcome = "failure"
+	default:
+		session.Outcome = "partial"
+	}
+	return session
+}
+
+// flushFeedback records the current turn with the flight recorder and the predictive engine,
+// once per turn. The returned command writes the recorder to disk off the update loop.
+func (m *model) flushFeedback() tea.Cmd {
+	if m.feedback.input == "" || (len(m.feedback.suggestions) == 0 && m.feedback.satisfaction == 0) {
+		return nil
+	}
+	session := m.feedbackSession()
+	m.feedback = turnFeedback{input: m.feedback.input, started: time.Now()}
+
+	if PredictiveIntelligence != nil {
+		PredictiveIntelligence.LearnFromFeedback(session)
+	}
+	if m.recorder == nil {
+		return nil
+	}
+	if err := m.recorder.RecordSession(session); err != nil {
+		return func() tea.Msg { return feedbackSavedMsg{err: err} }
+	}
+	recorder := m.recorder
+	return func() tea.Msg {
+		return feedbackSavedMsg{err: recorder.Save()}
+	}
+}
+
+// handleFeedbackSaved reports a failed save; successful saves stay quiet
+func (m *model) handleFeedbackSaved(msg feedbackSavedMsg) {
+	if msg.err != nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ Failed to save feedback: %v", msg.err)})
+	}
+}
+
+// parseRateCommand parses "/rate 1-5 [comment]"
+func parseRateCommand(input string) (int, string, error) {
+	fields := strings.Fields(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/rate")))
+	if len(fields) == 0 {
+		return 0, "", fmt.Errorf("usage: /rate 1-5 [comment]")
+	}
+	score, err := strconv.Atoi(fields[0])
+	if err != nil || score < 1 || score > 5 {
+		return 0, "", fmt.Errorf("rating must be a number from 1 to 5, got %q", fields[0])
+	}
+	return score, strings.Join(fields[1:], " "), nil
+}
+
+// ratingKey maps Alt+1..Alt+5 to a rating of the last reply
+func ratingKey(key tea.KeyMsg) (int, bool) {
+	if !key.Alt || key.Type != tea.KeyRunes || len(key.Runes) != 1 {
+		return 0, false
+	}
+	if r := key.Runes[0]; r >= '1' && r <= '5' {
+		return int(r - '0'), true
+	}
+	return 0, false
+}
+
+// rateLastReply rates the latest assistant reply. The rating stays on the open turn, so
+// commands run afterwards are still credited and rating again replaces it; the turn is
+// recorded once, when the next prompt starts or kubemage exits.
+func (m *model) rateLastReply(score int, comment string) string {
+	if m.feedback.input == "" {
+		return "ℹ️ Nothing to rate yet: ask something first."
+	}
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].sender == assist {
+			m.messages[i].rating = score
+			break
+		}
+	}
+	rerated := m.feedback.satisfaction != 0
+	m.feedback.satisfaction = score
+	if comment != "" {
+		m.feedback.actions = append(m.feedback.actions, engine.UserActionRecord{
+			Action: engine.UserActionRate, Target: RedactText(comment), Timestamp: time.Now(),
+		})
+	}
+
+	if rerated {
+		return fmt.Sprintf("⭐ Rating changed to %d/5.", score)
+	}
+	return fmt.Sprintf("⭐ Rated %d/5. It is saved with this prompt's suggested commands for learning and dataset export.", score)
+}
@@ -0,0 +1,110 @@
+// investigation.go - Plan-then-act agent mode: /investigate and /agent plan
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// investigationWaveMsg reports a finished wave of parallel checks
+type investigationWaveMsg struct {
+	inv     *engine.Investigation
+	summary string
+}
+
+// investigationDoneMsg carries the finding of a finished investigation
+type investigationDoneMsg struct {
+	inv     *engine.Investigation
+	finding *engine.InvestigationFinding
+}
+
+// startInvestigation plans an investigation of the question, shows the plan and runs it
+func (m *model) startInvestigation(question string) tea.Cmd {
+	if m.investigator == nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: "ℹ️ Plan-then-act investigations are unavailable."})
+		return nil
+	}
+	if m.investigation != nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: "⏳ An investigation is already running. Press Esc to cancel it."})
+		return nil
+	}
+
+	contextSummary := ""
+	if summary, err := engine.BuildContextSummary(); err == nil {
+		contextSummary = summary.RenderedOneLiner
+	}
+	target := engine.ParseInvestigationTarget(question, m.namespace)
+	inv := m.investigator.Plan(question, target, contextSummary)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.investigation, m.investigationCancel = inv, cancel
+	m.messages = append(m.messages, message{sender: systemSender, content: inv.RenderPlan()})
+
+	program := m.program
+	return func() tea.Msg {
+		finding := inv.Run(ctx, func(wave []engine.InvestigationStep) {
+			if program != nil {
+				program.Send(investigationWaveMsg{inv: inv, summary: inv.RenderWave(wave)})
+			}
+		})
+		return investigationDoneMsg{inv: inv, finding: finding}
+	}
+}
+
+// handleInvestigationWave shows each wave's checks and the hypotheses left standing
+func (m *model) handleInvestigationWave(msg investigationWaveMsg) {
+	if msg.inv != m.investigation {
+		return
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: msg.summary})
+}
+
+// handleInvestigationDone shows the finding and stages its fix for review
+func (m *model) handleInvestigationDone(msg investigationDoneMsg) tea.Cmd {
+	if msg.inv != m.investigation {
+		return nil
+	}
+	m.investigation, m.investigationCancel = nil, nil
+	m.lastInvestigation = msg.inv
+
+	var untrusted []string
+	for _, step := range msg.inv.Steps {
+		if len(engine.DetectInjection(step.Output)) > 0 {
+			untrusted = append(untrusted, step.Command)
+		}
+	}
+	content := msg.finding.Render()
+	if len(untrusted) > 0 {
+		content += fmt.Sprintf("\n🛡️ Instruction-like text was found in the output of %s; it was treated as data.", strings.Join(untrusted, ", "))
+	}
+	m.messages = append(m.messages, message{sender: assist, content: content})
+	if msg.finding.Fix == nil {
+		return nil
+	}
+
+	cmd := m.proposeCommand(msg.finding.FixCommand)
+	m.currentPlan = msg.finding.Fix
+	m.refreshPreviewPane()
+	hint := "Press Ctrl+E to review the proposed fix, or edit it first."
+	if strings.Contains(msg.finding.FixCommand, "<") {
+		// Placeholders must be filled in, so the fix starts out as an edit
+		m.textarea.SetValue(msg.finding.FixCommand)
+		hint = "The fix is in the input box: fill in the placeholders, then press Ctrl+E to review it."
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: hint})
+	return cmd
+}
+
+// cancelInvestigation stops a running investigation; its finding still reports what was learned
+func (m *model) cancelInvestigation() bool {
+	if m.investigationCancel == nil {
+		return false
+	}
+	m.investigationCancel()
+	m.investigationCancel = nil
+	return true
+}
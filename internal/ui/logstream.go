@@ -0,0 +1,224 @@
+// logstream.go - /logs: follow every pod of a workload or selector in the output pane
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+	components "github.com/siryoos/kubemage/internal/ui/ui"
+)
+
+const logStreamTickInterval = 300 * time.Millisecond
+
+const logStreamUsage = "Usage: /logs <deploy/name|sts/name|svc/name|pod query> [-l selector] [-c container] [-n namespace], " +
+	"then /logs pause|resume|follow|errors|warnings|all|search <term>|stop"
+
+// logStreamStartMsg carries a started stream, or why it could not start; start tells
+// stale starts apart
+type logStreamStartMsg struct {
+	start  int
+	stream *engine.LogStream
+	err    error
+}
+
+// logStreamTickMsg asks for the lines that arrived since the last tick
+type logStreamTickMsg struct {
+	stream *engine.LogStream
+}
+
+func logStreamTick(stream *engine.LogStream) tea.Cmd {
+	return tea.Tick(logStreamTickInterval, func(time.Time) tea.Msg {
+		return logStreamTickMsg{stream: stream}
+	})
+}
+
+// handleLogsCommand implements /logs <target> and the controls of a running stream
+func (m *model) handleLogsCommand(input string) tea.Cmd {
+	fields := strings.Fields(strings.TrimSpace(strings.TrimPrefix(input, "/logs")))
+	if len(fields) == 0 {
+		m.messages = append(m.messages, message{sender: systemSender, content: logStreamUsage})
+		return nil
+	}
+	switch fields[0] {
+	case "stop", "pause", "resume", "follow", "errors", "warnings", "all", "search":
+		m.controlLogStream(fields[0], strings.Join(fields[1:], " "))
+		return nil
+	}
+	if m.logStreamer == nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: "ℹ️ Log streaming is unavailable."})
+		return nil
+	}
+
+	target := engine.LogStreamTarget{Namespace: m.namespace}
+	for i := 0; i < len(fields); i++ {
+		flag := fields[i]
+		if strings.HasPrefix(flag, "-") {
+			if i+1 >= len(fields) {
+				m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ %s needs a value. %s", flag, logStreamUsage)})
+				return nil
+			}
+			i++
+		}
+		switch flag {
+		case "-n", "--namespace":
+			target.Namespace = fields[i]
+		case "-l", "--selector":
+			target.Selector = fields[i]
+		case "-c", "--container":
+			target.Container = fields[i]
+		default:
+			if strings.HasPrefix(flag, "-") {
+				m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ Unknown option %s. %s", flag, logStreamUsage)})
+				return nil
+			}
+			// deploy/web names a workload; anything else is a pod name pattern, like stern
+			if strings.Contains(flag, "/") {
+				target.Workload = flag
+			} else {
+				target.PodQuery = flag
+			}
+		}
+	}
+	if target.Namespace == "" {
+		target.Namespace = "default"
+	}
+
+	m.stopLogStream()
+	m.logStart++
+	m.logStarting = true
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("📜 Following %s in the output pane. Press Esc or run /logs stop to end it.", target)})
+	start, streamer := m.logStart, m.logStreamer
+	return func() tea.Msg {
+		stream, err := streamer.Start(context.Background(), target)
+		return logStreamStartMsg{start: start, stream: stream, err: err}
+	}
+}
+
+// handleLogStreamStart shows a started stream in the output pane
+func (m *model) handleLogStreamStart(msg logStreamStartMsg) tea.Cmd {
+	if msg.start != m.logStart || !m.logStarting {
+		// /logs stop, Esc or a newer /logs came first
+		if msg.stream != nil {
+			msg.stream.Stop()
+		}
+		return nil
+	}
+	m.logStarting = false
+	if msg.err != nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ Could not follow the logs: %v", msg.err)})
+		return nil
+	}
+	m.logStream = msg.stream
+	m.logSeq = 0
+	m.logViewer = components.NewLogViewer(components.CurrentTheme())
+	m.logViewer.SetMaxLines(m.logStreamer.MaxLines)
+	m.logViewer.ToggleExpanded()
+	m.logViewer.SetFollow(true)
+	if msg.stream.Selector != "" {
+		m.messages = append(m.messages, message{sender: systemSender, content: "📜 Pods are selected by " + msg.stream.Selector + "; new ones join the stream as they start."})
+	}
+	m.refreshOutputPane()
+	return logStreamTick(msg.stream)
+}
+
+// handleLogStreamTick moves new lines into the log viewer. Ticks of a stopped or
+// replaced stream end the loop.
+func (m *model) handleLogStreamTick(msg logStreamTickMsg) tea.Cmd {
+	if msg.stream != m.logStream || m.logViewer == nil {
+		return nil
+	}
+	lines, next, missed := m.logStream.Since(m.logSeq)
+	m.logSeq = next
+	if missed > 0 {
+		m.logViewer.AppendFrom("", fmt.Sprintf("(%d lines dropped: the stream is faster than the pane)", missed))
+	}
+	for _, line := range lines {
+		origin := line.Pod
+		if line.Container != "" {
+			origin += "/" + line.Container
+		}
+		if line.Event {
+			origin = ""
+		}
+		m.logViewer.AppendFrom(origin, line.Text)
+	}
+	if len(lines) > 0 || missed > 0 {
+		m.refreshOutputPane()
+	}
+	return logStreamTick(msg.stream)
+}
+
+// controlLogStream applies /logs pause|resume|follow|errors|warnings|all|search|stop
+func (m *model) controlLogStream(action, arg string) {
+	if m.logViewer == nil && !(action == "stop" && m.logStarting) {
+		m.messages = append(m.messages, message{sender: systemSender, content: "ℹ️ No logs are being followed. " + logStreamUsage})
+		return
+	}
+	reply := ""
+	switch action {
+	case "stop":
+		m.stopLogStream()
+		reply = "⏹️ Stopped following the logs; they stay in the output pane until the next command."
+	case "pause":
+		m.logViewer.Pause()
+		reply = "⏸️ Paused; new lines are kept until /logs resume."
+	case "resume":
+		m.logViewer.Resume()
+		reply = "▶️ Resumed."
+	case "follow":
+		following := !m.logViewer.IsFollowing()
+		m.logViewer.SetFollow(following)
+		reply = "📜 Following the newest lines."
+		if !following {
+			reply = "📜 No longer following the newest lines."
+		}
+	case "errors":
+		m.logViewer.SetLevelFilter(components.LogLevelError)
+		reply = "🔎 Showing errors only."
+	case "warnings":
+		m.logViewer.SetLevelFilter(components.LogLevelWarn)
+		reply = "🔎 Showing warnings only."
+	case "all":
+		m.logViewer.SetLevelFilter(components.LogLevelAll)
+		m.logViewer.SetSearchTerm("")
+		reply = "🔎 Showing every line."
+	case "search":
+		m.logViewer.SetSearchTerm(arg)
+		reply = fmt.Sprintf("🔎 Showing lines containing %q.", arg)
+		if arg == "" {
+			reply = "🔎 Search cleared."
+		}
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: reply})
+	m.refreshOutputPane()
+}
+
+// stopLogStream ends the running stream, or drops the one still starting; the viewer
+// keeps its lines until the next command
+func (m *model) stopLogStream() bool {
+	stopped := m.logStarting || m.logStream != nil
+	m.logStarting = false
+	if m.logStream != nil {
+		m.logStream.Stop()
+		m.logStream = nil
+	}
+	return stopped
+}
+
+// renderLogStream renders the followed logs for the output pane
+func (m *model) renderLogStream() string {
+	m.logViewer.SetSize(m.outputViewport.Width, m.outputViewport.Height-1) // minus the title line
+	var sb strings.Builder
+	if m.logStream != nil {
+		pods := m.logStream.Pods()
+		sb.WriteString(fmt.Sprintf("📜 %s • %d pods\n", m.logStream.Target, len(pods)))
+	} else {
+		sb.WriteString("📜 Stopped • /logs <target> follows again\n")
+	}
+	sb.WriteString(m.logViewer.RenderBody())
+	return sb.String()
+}
@@ -0,0 +1,180 @@
+// memory.go - /memory and agent-proposed facts remembered per kube context
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+const memoryUsage = "Usage: /memory list | /memory add [-n <namespace>] <fact> | /memory forget <id> | /memory keep [n] | /memory drop [n]"
+
+// maxPromptMemories caps how many remembered facts go into one prompt
+const maxPromptMemories = 5
+
+// relevantMemories renders the remembered facts that match the latest user turn
+func (m *model) relevantMemories(history []message) string {
+	if m.memory == nil {
+		return ""
+	}
+	query := ""
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].sender == user {
+			query = history[i].content
+			break
+		}
+	}
+	if strings.TrimSpace(query) == "" {
+		return ""
+	}
+	return engine.RenderMemories(m.ctxName, m.memory.Relevant(m.ctxName, m.namespace, query, maxPromptMemories))
+}
+
+// proposeMemories queues "Remember:" lines from an agent reply for the user to approve
+func (m *model) proposeMemories(reply string) {
+	if m.memory == nil {
+		return
+	}
+	known := make(map[string]bool)
+	for _, fact := range m.memory.List(m.ctxName) {
+		known[strings.ToLower(fact.Fact)] = true
+	}
+	for _, fact := range m.pendingMemories {
+		known[strings.ToLower(fact)] = true
+	}
+
+	var proposed []string
+	for _, fact := range engine.ParseMemoryProposals(reply) {
+		if !known[strings.ToLower(fact)] {
+			proposed = append(proposed, fact)
+		}
+	}
+	if len(proposed) == 0 {
+		return
+	}
+	m.pendingMemories = append(m.pendingMemories, proposed...)
+	m.messages = append(m.messages, message{sender: systemSender, content: "🧠 The agent wants to remember:\n" + m.renderPendingMemories() + "\nType /memory keep [n] to save, or /memory drop [n] to discard."})
+}
+
+func (m *model) renderPendingMemories() string {
+	lines := make([]string, len(m.pendingMemories))
+	for i, fact := range m.pendingMemories {
+		lines[i] = fmt.Sprintf("  %d. %s", i+1, fact)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleMemoryCommand implements /memory list|add|forget|keep|drop
+func (m *model) handleMemoryCommand(input string) string {
+	if m.memory == nil {
+		return "ℹ️ Cluster memory is unavailable."
+	}
+	fields := strings.Fields(input)
+	if len(fields) < 2 {
+		fields = append(fields, "list")
+	}
+
+	switch fields[1] {
+	case "list":
+		facts := m.memory.List(m.ctxName)
+		var sb strings.Builder
+		if len(facts) == 0 {
+			fmt.Fprintf(&sb, "🧠 Nothing remembered for %s yet. Add facts with /memory add <fact>.", m.memoryContextName())
+		} else {
+			fmt.Fprintf(&sb, "🧠 Remembered for %s:", m.memoryContextName())
+			for _, fact := range facts {
+				scope := ""
+				if fact.Namespace != "" {
+					scope = fmt.Sprintf(" [ns %s]", fact.Namespace)
+				}
+				fmt.Fprintf(&sb, "\n  #%d%s %s (%s, %s)", fact.ID, scope, fact.Fact, fact.Source, fact.CreatedAt.Format("2006-01-02"))
+			}
+		}
+		if len(m.pendingMemories) > 0 {
+			sb.WriteString("\nAwaiting approval:\n" + m.renderPendingMemories())
+		}
+		return sb.String()
+
+	case "add":
+		namespace := ""
+		rest := fields[2:]
+		if len(rest) >= 2 && (rest[0] == "-n" || rest[0] == "--namespace") {
+			namespace, rest = rest[1], rest[2:]
+		}
+		if len(rest) == 0 {
+			return memoryUsage
+		}
+		return m.saveMemory(strings.Join(rest, " "), namespace, engine.MemorySourceUser)
+
+	case "forget":
+		if len(fields) < 3 {
+			return memoryUsage
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fields[2], "#"))
+		if err != nil {
+			return fmt.Sprintf("⚠️ Memory id must be a number, got %q", fields[2])
+		}
+		fact, err := m.memory.Forget(m.ctxName, id)
+		if err != nil {
+			return fmt.Sprintf("⚠️ %v", err)
+		}
+		return fmt.Sprintf("🧠 Forgot #%d: %s", fact.ID, fact.Fact)
+
+	case "keep", "drop":
+		picked, err := m.pickPendingMemories(fields[2:])
+		if err != nil {
+			return fmt.Sprintf("⚠️ %v", err)
+		}
+		var results []string
+		for _, fact := range picked {
+			if fields[1] == "keep" {
+				results = append(results, m.saveMemory(fact, "", engine.MemorySourceAgent))
+			} else {
+				results = append(results, "🗑️ Discarded: "+fact)
+			}
+		}
+		return strings.Join(results, "\n")
+	}
+	return memoryUsage
+}
+
+// pickPendingMemories removes and returns the n-th pending proposal, or all of them
+func (m *model) pickPendingMemories(args []string) ([]string, error) {
+	if len(m.pendingMemories) == 0 {
+		return nil, fmt.Errorf("no memories are awaiting approval")
+	}
+	if len(args) == 0 {
+		picked := m.pendingMemories
+		m.pendingMemories = nil
+		return picked, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(m.pendingMemories) {
+		return nil, fmt.Errorf("pick a proposal between 1 and %d", len(m.pendingMemories))
+	}
+	picked := m.pendingMemories[n-1]
+	m.pendingMemories = append(m.pendingMemories[:n-1:n-1], m.pendingMemories[n:]...)
+	return []string{picked}, nil
+}
+
+func (m *model) saveMemory(fact, namespace, source string) string {
+	saved, added, err := m.memory.Add(m.ctxName, namespace, fact, source)
+	switch {
+	case err != nil && saved.ID == 0:
+		return fmt.Sprintf("⚠️ Not remembered: %v", err)
+	case err != nil:
+		return fmt.Sprintf("⚠️ Remembered #%d for this session but failed to save it: %v", saved.ID, err)
+	case !added:
+		return fmt.Sprintf("🧠 Already remembered as #%d.", saved.ID)
+	}
+	return fmt.Sprintf("🧠 Remembered #%d for %s: %s", saved.ID, m.memoryContextName(), saved.Fact)
+}
+
+func (m *model) memoryContextName() string {
+	if m.ctxName == "" {
+		return "the default context"
+	}
+	return "context " + m.ctxName
+}
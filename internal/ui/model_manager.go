@@ -0,0 +1,216 @@
+// model_manager.go - /model pull and /model info handling with NumCtx auto-tune
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/llm"
+)
+
+const pullBarWidth = 24
+
+// modelPullMsg carries a progress update for an in-flight /model pull
+type modelPullMsg struct {
+	name     string
+	progress llm.PullProgress
+}
+
+// modelInfoMsg carries /api/show details; autoTune marks background lookups
+type modelInfoMsg struct {
+	name     string
+	info     *llm.ModelInfo
+	err      error
+	autoTune bool
+}
+
+func pullModelCmd(ctx context.Context, m *model, name string) tea.Cmd {
+	return func() tea.Msg {
+		ch := make(chan llm.PullProgress)
+		go llm.PullModel(ctx, name, ch)
+
+		first, ok := <-ch
+		if !ok {
+			return modelPullMsg{name: name, progress: llm.PullProgress{Err: fmt.Errorf("pull %s ended unexpectedly", name)}}
+		}
+
+		go func() {
+			for progress := range ch {
+				m.program.Send(modelPullMsg{name: name, progress: progress})
+			}
+		}()
+
+		return modelPullMsg{name: name, progress: first}
+	}
+}
+
+// generationModelInfoCmd looks up the generation model too when it differs from the chat model
+func generationModelInfoCmd(m *model) tea.Cmd {
+	if m.generationModel == "" || m.generationModel == m.ollamaModel {
+		return nil
+	}
+	return modelInfoCmd(m.generationModel, true)
+}
+
+func modelInfoCmd(name string, autoTune bool) tea.Cmd {
+	return func() tea.Msg {
+		info, err := llm.ShowModel(name)
+		return modelInfoMsg{name: name, info: info, err: err, autoTune: autoTune}
+	}
+}
+
+// startModelPull begins a pull, or reports why it cannot start
+func (m *model) startModelPull(name string) tea.Cmd {
+	if name == "" {
+		name = m.pendingPullOffer
+	}
+	if name == "" {
+		m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /model pull <name>"})
+		return nil
+	}
+	if m.activePull != "" {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ Already pulling %s.", m.activePull)})
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.activePull = name
+	m.pullCancel = cancel
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⬇️ Pulling %s... (Esc cancels)", name)})
+	m.pullMessageIdx = len(m.messages) - 1
+	return pullModelCmd(ctx, m, name)
+}
+
+// cancelModelPull stops the running pull; its progress message reports the cancellation
+func (m *model) cancelModelPull() bool {
+	if m.pullCancel == nil {
+		return false
+	}
+	m.pullCancel()
+	m.pullCancel = nil
+	return true
+}
+
+// handleModelPull updates the progress message in place
+func (m *model) handleModelPull(msg modelPullMsg) {
+	if m.pullMessageIdx < 0 || m.pullMessageIdx >= len(m.messages) {
+		m.messages = append(m.messages, message{sender: systemSender})
+		m.pullMessageIdx = len(m.messages) - 1
+	}
+
+	progress := msg.progress
+	switch {
+	case progress.Err != nil:
+		m.messages[m.pullMessageIdx].content = fmt.Sprintf("⚠️ %v", progress.Err)
+		m.finishModelPull()
+	case progress.Done:
+		m.messages[m.pullMessageIdx].content = fmt.Sprintf("✅ Pulled %s.", msg.name)
+		m.finishModelPull()
+		if msg.name == m.pendingPullOffer {
+			m.pendingPullOffer = ""
+			m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("💡 Switch to it with /model set chat %s", msg.name)})
+		}
+	default:
+		m.messages[m.pullMessageIdx].content = renderPullProgress(msg.name, progress)
+	}
+}
+
+// finishModelPull clears the pull state once the final update arrived
+func (m *model) finishModelPull() {
+	if m.pullCancel != nil {
+		m.pullCancel()
+		m.pullCancel = nil
+	}
+	m.activePull = ""
+}
+
+// handleModelInfo shows /model info output or applies the NumCtx auto-tune
+func (m *model) handleModelInfo(msg modelInfoMsg) {
+	if msg.err != nil {
+		if !msg.autoTune {
+			m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ Unable to inspect %s: %v", msg.name, msg.err)})
+		}
+		return
+	}
+
+	if !msg.autoTune {
+		m.messages = append(m.messages, message{sender: assist, content: renderModelInfo(msg.info)})
+	}
+
+	if m.config == nil || (msg.name != m.ollamaModel && msg.name != m.generationModel) {
+		return
+	}
+	// The configured num_ctx stays as is; requests to this model are tuned to its window
+	if tuned, changed := llm.TuneNumCtx(m.config.NumCtx, msg.info, !m.config.NumCtxFixed); changed {
+		direction := "lowered"
+		if tuned > m.config.NumCtx {
+			direction = "raised"
+		}
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("🔧 num_ctx %s from %d to %d for %s (native context length %d).", direction, m.config.NumCtx, tuned, msg.name, msg.info.ContextLength)})
+	}
+}
+
+// offerPullOnFallback suggests pulling the preferred model after ResolveModel fell back
+func (m *model) offerPullOnFallback(preferred, resolved string) {
+	preferred = strings.TrimSpace(preferred)
+	if preferred == "" || preferred == resolved {
+		return
+	}
+	m.pendingPullOffer = preferred
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("💡 Run /model pull to download %s instead of using %s.", preferred, resolved)})
+}
+
+func renderModelInfo(info *llm.ModelInfo) string {
+	ctx := "unknown"
+	if info.ContextLength > 0 {
+		ctx = fmt.Sprintf("%d tokens", info.ContextLength)
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🧠 Model %s\n", info.Name))
+	if info.Family != "" {
+		sb.WriteString(fmt.Sprintf("Family: %s\n", info.Family))
+	}
+	sb.WriteString(fmt.Sprintf("Parameters: %s\n", valueOrUnknown(info.ParameterSize)))
+	sb.WriteString(fmt.Sprintf("Quantization: %s\n", valueOrUnknown(info.Quantization)))
+	sb.WriteString(fmt.Sprintf("Native context: %s", ctx))
+	return sb.String()
+}
+
+func renderPullProgress(name string, p llm.PullProgress) string {
+	status := p.Status
+	if status == "" {
+		status = "pulling"
+	}
+	if p.Total <= 0 {
+		return fmt.Sprintf("⬇️ %s: %s", name, status)
+	}
+
+	filled := int(p.Percent() / 100 * pullBarWidth)
+	if filled > pullBarWidth {
+		filled = pullBarWidth
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", pullBarWidth-filled)
+	return fmt.Sprintf("⬇️ %s: %s\n[%s] %5.1f%% (%s / %s)", name, status, bar, p.Percent(), formatByteSize(p.Completed), formatByteSize(p.Total))
+}
+
+func formatByteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func valueOrUnknown(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+// playbooks.go - /playbook list|show|run and hot reload of the playbook directories
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+const playbookReloadInterval = 5 * time.Second
+
+// playbookReloadMsg reports a check of the playbook directories
+type playbookReloadMsg struct {
+	changed bool
+	errs    []error
+}
+
+func schedulePlaybookReload(library *engine.PlaybookLibrary) tea.Cmd {
+	if library == nil {
+		return nil
+	}
+	return tea.Tick(playbookReloadInterval, func(time.Time) tea.Msg {
+		changed, errs := library.ReloadIfChanged()
+		return playbookReloadMsg{changed: changed, errs: errs}
+	})
+}
+
+// handlePlaybookReload announces reloaded playbooks and re-indexes them for retrieval
+func (m *model) handlePlaybookReload(msg playbookReloadMsg) tea.Cmd {
+	next := schedulePlaybookReload(m.knowledge)
+	if !msg.changed {
+		return next
+	}
+	content := fmt.Sprintf("📘 Playbooks reloaded: %d available.", len(m.knowledge.PlaybookIDs()))
+	if len(msg.errs) > 0 {
+		content += "\n" + formatPlaybookErrors(msg.errs)
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: content})
+	return tea.Batch(next, refreshKnowledgeCmd(m.retriever))
+}
+
+// handlePlaybookCommand implements /playbook list, /playbook show <name> and
+// /playbook run <name> [key=value ...]
+func (m *model) handlePlaybookCommand(input string) (string, tea.Cmd) {
+	if m.knowledge == nil {
+		return "ℹ️ Playbooks are unavailable.", nil
+	}
+	fields := strings.Fields(input)
+	sub := "list"
+	if len(fields) > 1 {
+		sub = fields[1]
+	}
+	switch sub {
+	case "list":
+		return m.listPlaybooks(), nil
+	case "show":
+		if len(fields) < 3 {
+			return "Usage: /playbook show <name>", nil
+		}
+		return m.showPlaybook(fields[2]), nil
+	case "run":
+		if len(fields) < 3 {
+			return "Usage: /playbook run <name> pod=<pod> namespace=<namespace>", nil
+		}
+		vars := make(map[string]string)
+		for _, arg := range fields[3:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok || key == "" {
+				return fmt.Sprintf("⚠️ %q is not a key=value argument.", arg), nil
+			}
+			vars[key] = value
+		}
+		if vars["namespace"] == "" {
+			if ns, err := GetCurrentNamespace(); err == nil && ns != "" {
+				vars["namespace"] = ns
+			}
+		}
+		plan, err := m.knowledge.PlaybookPlan(fields[2], vars)
+		if err != nil {
+			return fmt.Sprintf("⚠️ %v. Pass values as key=value, e.g. /playbook run %s pod=web-0 namespace=shop", err, fields[2]), nil
+		}
+		return "", m.startDiagnostics(plan)
+	default:
+		return "Usage: /playbook list | show <name> | run <name> pod=<pod> namespace=<namespace>", nil
+	}
+}
+
+func (m *model) listPlaybooks() string {
+	var sb strings.Builder
+	sb.WriteString("📘 Playbooks:")
+	for _, id := range m.knowledge.PlaybookIDs() {
+		pb, origin, ok := m.knowledge.PlaybookByID(id)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n  %s — %s (%d steps, %s)", id, pb.Name, len(pb.Steps), origin)
+	}
+	if errs := m.knowledge.LoadErrors(); len(errs) > 0 {
+		sb.WriteString("\n" + formatPlaybookErrors(errs))
+	}
+	sb.WriteString("\nShow one with /playbook show <name>; run it with /playbook run <name> pod=<pod>.")
+	return sb.String()
+}
+
+func (m *model) showPlaybook(id string) string {
+	pb, origin, ok := m.knowledge.PlaybookByID(id)
+	if !ok {
+		return fmt.Sprintf("⚠️ Unknown playbook %q. See /playbook list.", id)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📘 %s (%s, %s)", pb.Name, id, origin)
+	if pb.Description != "" {
+		fmt.Fprintf(&sb, "\n%s", pb.Description)
+	}
+	if len(pb.Triggers) > 0 {
+		fmt.Fprintf(&sb, "\nTriggers: %s", strings.Join(pb.Triggers, ", "))
+	}
+	sb.WriteString("\nSteps:")
+	for i, step := range pb.Steps {
+		fmt.Fprintf(&sb, "\n  %d. %s: %s", i+1, step.Name, step.Command)
+		if step.Timeout > 0 {
+			fmt.Fprintf(&sb, " (%ds)", step.Timeout)
+		}
+	}
+	for _, h := range pb.Heuristics {
+		fmt.Fprintf(&sb, "\n  💡 %s", h)
+	}
+	for _, move := range pb.NextMoves {
+		fmt.Fprintf(&sb, "\n  → %s [%s risk]: %s", move.Action, move.Risk, move.Command)
+	}
+	return sb.String()
+}
+
+func formatPlaybookErrors(errs []error) string {
+	lines := make([]string, 0, len(errs))
+	for _, err := range errs {
+		lines = append(lines, "⚠️ Skipped: "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
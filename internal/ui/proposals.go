@@ -0,0 +1,132 @@
+// proposals.go - Load the agent's Fix:/Patch: proposals into the preview pane, never running them
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// loadAgentProposals parses the fixes proposed with a final answer, checks their evidence
+// against the session's observations and stages the first one for review
+func (m *model) loadAgentProposals(reply string) tea.Cmd {
+	proposals := engine.ParseFixProposals(reply)
+	if len(proposals) == 0 {
+		m.pendingProposals = nil
+		return nil
+	}
+	observations := m.agentObservations()
+	for i := range proposals {
+		proposals[i].GroundEvidence(observations)
+	}
+	m.pendingProposals = proposals
+
+	var sb strings.Builder
+	sb.WriteString("🧰 The agent proposed these fixes. Nothing runs until you confirm it:")
+	for i, p := range proposals {
+		fmt.Fprintf(&sb, "\n  %d. %s", i+1, p.Title())
+		switch {
+		case len(p.Evidence) == 0:
+			sb.WriteString("\n     ⚠️ no evidence cited")
+		case len(p.Ungrounded) > 0:
+			fmt.Fprintf(&sb, "\n     ⚠️ evidence not found in any observation: %s", strings.Join(p.Ungrounded, "; "))
+		default:
+			fmt.Fprintf(&sb, "\n     🔎 %s", strings.Join(p.Evidence, "; "))
+		}
+	}
+	if len(proposals) > 1 {
+		sb.WriteString("\nType /proposal <n> to review another one.")
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: sb.String()})
+	return m.loadProposal(0)
+}
+
+// agentObservations returns the cluster output fed back to the agent in this conversation
+func (m *model) agentObservations() []string {
+	var observations []string
+	for _, msg := range m.messages {
+		if msg.sender == user && strings.HasPrefix(msg.content, "Observation: ") {
+			observations = append(observations, strings.TrimPrefix(msg.content, "Observation: "))
+		}
+	}
+	return observations
+}
+
+// loadProposal stages the i-th proposal: commands as a PreExecPlan, patches as a DiffSession
+func (m *model) loadProposal(i int) tea.Cmd {
+	p := m.pendingProposals[i]
+	if p.Kind == engine.ProposalPatch {
+		if err := m.loadPatchProposal(p); err != nil {
+			m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ Cannot preview the patch for %s: %v", p.FilePath, err)})
+		}
+		return nil
+	}
+
+	cmd := m.proposeCommand(p.Command)
+	plan := m.buildPlan(p.Command)
+	p.TagPlan(&plan)
+	m.currentPlan = &plan
+	m.refreshPreviewPane()
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("Fix %d staged in the preview pane. Press Ctrl+E to start its safety checks, edit it first, or press Ctrl+K to discard it.", i+1)})
+	return cmd
+}
+
+// loadPatchProposal previews the agent's diff the same way /edit-yaml previews a generated one
+func (m *model) loadPatchProposal(p engine.FixProposal) error {
+	if m.pendingDiff != nil {
+		return fmt.Errorf("another diff is pending; /cancel it first")
+	}
+	if err := ensureWorkspaceInitialized(); err != nil {
+		return err
+	}
+	path := WorkspaceIdx.NormalizePath(p.FilePath)
+	content, err := GetFileContent(WorkspaceIdx.AbsPath(path))
+	if err != nil {
+		return err
+	}
+
+	mode := DiffModeManifest
+	if strings.Contains(filepath.Base(path), "values") {
+		mode = DiffModeValues
+	}
+	session := NewDiffSession(mode, path, "agent proposal", content, RedactSensitive(content))
+	session.AppendResponse(p.Diff)
+	m.pendingDiff = session
+
+	// handleDiffCompletion replaces the last message with the rendered diff
+	m.messages = append(m.messages, message{sender: systemSender, content: "✏️ Loading the agent's patch for " + path})
+	m.handleDiffCompletion()
+	if m.pendingDiff != nil {
+		evidence := "⚠️ The agent cited no evidence for this patch."
+		if len(p.Evidence) > 0 {
+			evidence = "🔎 Evidence: " + strings.Join(p.Evidence, "; ")
+		}
+		m.messages = append(m.messages, message{sender: systemSender, content: evidence})
+	}
+	return nil
+}
+
+// handleProposalCommand implements /proposal [n]
+func (m *model) handleProposalCommand(input string) (string, tea.Cmd) {
+	if len(m.pendingProposals) == 0 {
+		return "ℹ️ The agent has not proposed any fixes.", nil
+	}
+	fields := strings.Fields(input)
+	if len(fields) < 2 {
+		var sb strings.Builder
+		sb.WriteString("🧰 Proposed fixes:")
+		for i, p := range m.pendingProposals {
+			fmt.Fprintf(&sb, "\n  %d. %s", i+1, p.Title())
+		}
+		return sb.String(), nil
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil || n < 1 || n > len(m.pendingProposals) {
+		return fmt.Sprintf("⚠️ Pick a proposal between 1 and %d", len(m.pendingProposals)), nil
+	}
+	return "", m.loadProposal(n - 1)
+}
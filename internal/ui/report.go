@@ -0,0 +1,122 @@
+// report.go - /report: export the last diagnosis and agent session as a Markdown or HTML incident report
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// diagnosisRecord keeps the last finished diagnostic plan for /report
+type diagnosisRecord struct {
+	plan    engine.DiagPlan
+	results []engine.DiagResult
+}
+
+// reportMsg carries the path of a written report
+type reportMsg struct {
+	path string
+	err  error
+}
+
+// handleReportCommand implements /report [md|html]
+func (m *model) handleReportCommand(input string) tea.Cmd {
+	format := engine.ReportMarkdown
+	dir, templateDir := "kubemage_data/reports", ""
+	if m.config != nil {
+		format, dir, templateDir = m.config.Report.Format, m.config.Report.Dir, m.config.Report.TemplateDir
+	}
+	switch arg := strings.TrimSpace(strings.TrimPrefix(input, "/report")); arg {
+	case "":
+	case "md", "markdown":
+		format = engine.ReportMarkdown
+	case "html":
+		format = engine.ReportHTML
+	default:
+		m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /report [md|html]"})
+		return nil
+	}
+
+	report := m.buildReport()
+	if len(report.Steps) == 0 && report.FinalAnswer == "" {
+		m.messages = append(m.messages, message{sender: systemSender, content: "ℹ️ Nothing to report yet. Run /diag-<kind> <name> or an agent session first."})
+		return nil
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("📄 Writing the %s report of %d commands...", format, len(report.Steps))})
+	return func() tea.Msg {
+		if summary, err := engine.BuildContextSummary(); err == nil {
+			report.Context = summary.RenderedOneLiner
+		}
+		path, err := engine.WriteReport(report, format, dir, templateDir)
+		return reportMsg{path: path, err: err}
+	}
+}
+
+// buildReport collects the last diagnostic plan, the commands run since and the final answer
+func (m *model) buildReport() *engine.IncidentReport {
+	report := &engine.IncidentReport{
+		Title:       "Incident report",
+		GeneratedAt: time.Now(),
+		Cluster:     m.ctxName,
+		Namespace:   m.namespace,
+		User:        m.rbacUser,
+	}
+	seen := map[string]bool{}
+	if d := m.lastDiag; d != nil {
+		plan := d.plan
+		report.Title = "Incident report: " + plan.Title
+		report.Plan = &plan
+		report.Steps = engine.ReportStepsFromDiag(d.results)
+		for _, r := range d.results {
+			seen[r.Command] = true
+		}
+	}
+	if inv := m.lastInvestigation; inv != nil {
+		for _, step := range inv.Steps {
+			if seen[step.Command] {
+				continue
+			}
+			seen[step.Command] = true
+			output := step.Output
+			if step.Error != "" {
+				output = strings.TrimSpace(output + "\n" + step.Error)
+			}
+			report.Steps = append(report.Steps, engine.ReportStep{Source: "agent", Command: step.Command, Output: output, Duration: step.Duration})
+		}
+	}
+	// Agent actions and executed commands are "$ <command>" messages with their output appended
+	for _, msg := range m.messages {
+		if msg.sender != execSender || !strings.HasPrefix(msg.content, "$ ") {
+			continue
+		}
+		command, output, _ := strings.Cut(strings.TrimPrefix(msg.content, "$ "), "\n")
+		if command = strings.TrimSpace(command); command == "" || seen[command] {
+			continue
+		}
+		seen[command] = true
+		report.Steps = append(report.Steps, engine.ReportStep{Source: "agent", Command: command, Output: output})
+	}
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if msg := m.messages[i]; msg.sender == assist && msg.content != waitingMessage && strings.TrimSpace(msg.content) != "" {
+			report.FinalAnswer = msg.content
+			break
+		}
+	}
+	if report.Plan == nil && m.lastInvestigation != nil {
+		report.Title = "Incident report: " + m.lastInvestigation.Question
+	}
+	report.DetectRootCauses(m.knowledge)
+	return report
+}
+
+// handleReport tells where the report was written
+func (m *model) handleReport(msg reportMsg) {
+	if msg.err != nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ Could not write the report: %v", msg.err)})
+		return
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: "📄 Report written to " + msg.path})
+}
@@ -0,0 +1,121 @@
+// retrieval.go - Knowledge snippets for chat and agent prompts, and resolved-session capture
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+// knowledgeIndexMsg reports the outcome of an index refresh
+type knowledgeIndexMsg struct {
+	stats engine.RefreshStats
+	err   error
+}
+
+// retrievalMsg lists the sources cited in the prompt being sent
+type retrievalMsg string
+
+func refreshKnowledgeCmd(retriever *engine.KnowledgeRetriever) tea.Cmd {
+	if retriever == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		stats, err := retriever.Refresh()
+		return knowledgeIndexMsg{stats: stats, err: err}
+	}
+}
+
+// handleKnowledgeIndex reports first-time indexing and failures; quiet refreshes stay quiet
+func (m *model) handleKnowledgeIndex(msg knowledgeIndexMsg) {
+	var content string
+	switch {
+	case msg.err != nil:
+		if m.knowledgeWarned {
+			return
+		}
+		m.knowledgeWarned = true
+		content = fmt.Sprintf("📚 Knowledge retrieval unavailable: %v\nPull the model with /model pull %s, or set retrieval.disabled in config.yaml.", msg.err, m.config.Retrieval.EmbeddingModel)
+	case msg.stats.Embedded > 0:
+		content = fmt.Sprintf("📚 Knowledge index: %d snippets (%d updated).", msg.stats.Total, msg.stats.Embedded)
+	default:
+		return
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: content})
+}
+
+// retrieveKnowledge returns prompt snippets for the latest user turn; runs inside tea.Cmds
+func (m *model) retrieveKnowledge(history []message) []string {
+	query := ""
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].sender == user {
+			query = RedactText(history[i].content)
+			break
+		}
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+
+	var hits []engine.RetrievalHit
+	hits = append(hits, m.explainDocs(query)...)
+	hits = append(hits, m.crdDocs(query)...)
+	if m.retriever != nil {
+		if found, err := m.retriever.Retrieve(query); err == nil {
+			hits = append(hits, found...)
+		}
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+
+	snippets := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		snippets = append(snippets, RedactText(hit.Snippet()))
+	}
+	if m.program != nil {
+		m.program.Send(retrievalMsg("📚 Sources: " + strings.Join(engine.Citations(hits), ", ")))
+	}
+	return snippets
+}
+
+// recordResolution stores the recent exchange as a resolved session for future retrieval
+func (m *model) recordResolution(note string) error {
+	if m.recorder == nil {
+		return nil
+	}
+	start := 0
+	if len(m.messages) > m.config.HistoryLength {
+		start = len(m.messages) - m.config.HistoryLength
+	}
+
+	session := engine.SessionRecord{Outcome: "success"}
+	for _, msg := range m.messages[start:] {
+		switch {
+		case msg.sender == user && !strings.HasPrefix(msg.content, "/"):
+			if session.UserInput == "" {
+				session.UserInput = RedactText(msg.content)
+			}
+		case msg.sender == execSender && strings.HasPrefix(msg.content, "$ "):
+			session.Suggestions = append(session.Suggestions, engine.SuggestionRecord{
+				Type:     "command",
+				Content:  RedactText(strings.TrimPrefix(msg.content, "$ ")),
+				Accepted: true,
+				Applied:  true,
+			})
+		}
+	}
+	if session.UserInput == "" {
+		return nil
+	}
+	if note != "" {
+		session.UserActions = append(session.UserActions, engine.UserActionRecord{Action: "resolve", Target: RedactText(note)})
+	}
+
+	if err := m.recorder.RecordSession(session); err != nil {
+		return err
+	}
+	return m.recorder.Save()
+}
@@ -0,0 +1,112 @@
+// timeline.go - /timeline: incident timeline in the preview pane and "what changed" summaries
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/siryoos/kubemage/internal/engine"
+)
+
+const timelineTimeout = 30 * time.Second
+
+// timelineMsg carries a freshly built timeline
+type timelineMsg struct {
+	timeline *engine.Timeline
+}
+
+func timelineCmd(builder *engine.TimelineBuilder, namespace, workload string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), timelineTimeout)
+		defer cancel()
+		return timelineMsg{timeline: builder.Build(ctx, namespace, workload)}
+	}
+}
+
+// handleTimelineCommand implements /timeline [workload] [-n namespace], /timeline summarize
+// and /timeline close
+func (m *model) handleTimelineCommand(input string) tea.Cmd {
+	fields := strings.Fields(strings.TrimSpace(strings.TrimPrefix(input, "/timeline")))
+	if len(fields) == 1 {
+		switch fields[0] {
+		case "summarize":
+			return m.summarizeTimeline()
+		case "close":
+			m.timelineView = ""
+			m.refreshPreviewPane()
+			m.messages = append(m.messages, message{sender: systemSender, content: "🕒 Timeline closed."})
+			return nil
+		}
+	}
+	if m.timelineBuilder == nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: "ℹ️ Incident timelines are unavailable."})
+		return nil
+	}
+
+	namespace, workload := m.namespace, ""
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "-n", "--namespace":
+			if i+1 < len(fields) {
+				namespace = fields[i+1]
+				i++
+			}
+		default:
+			// deploy/web and web name the same workload
+			workload = fields[i][strings.LastIndex(fields[i], "/")+1:]
+		}
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	target := "namespace " + namespace
+	if workload != "" {
+		target = fmt.Sprintf("%s in namespace %s", workload, namespace)
+	}
+	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("🕒 Building the timeline of %s from events, ReplicaSets, container restarts and Helm history...", target)})
+	return timelineCmd(m.timelineBuilder, namespace, workload)
+}
+
+// handleTimeline shows the timeline in the preview pane
+func (m *model) handleTimeline(msg timelineMsg) {
+	tl := msg.timeline
+	m.timeline = tl
+	m.timelineView = tl.Render()
+	m.explainView = ""
+	m.refreshPreviewPane()
+
+	content := fmt.Sprintf("🕒 Timeline of %s shown in the preview pane: %d entries", tl.Scope(), len(tl.Entries))
+	if len(tl.Bursts) > 0 {
+		last := tl.Bursts[len(tl.Bursts)-1]
+		content += fmt.Sprintf(", %d bursts of activity (latest: %d changes from %s)", len(tl.Bursts), last.Count, last.Start.Local().Format("15:04:05"))
+	}
+	content += ". Ask what changed before it broke with /timeline summarize."
+	m.messages = append(m.messages, message{sender: systemSender, content: content})
+}
+
+// summarizeTimeline asks the model what changed before things broke
+func (m *model) summarizeTimeline() tea.Cmd {
+	if m.timeline == nil {
+		m.messages = append(m.messages, message{sender: systemSender, content: "ℹ️ Build a timeline first with /timeline [workload]."})
+		return nil
+	}
+	tl := m.timeline
+	prompt := fmt.Sprintf(`Here is the incident timeline of %s. Warnings and bursts of activity mark where things went wrong.
+
+%s
+
+Answer briefly:
+1. **When it broke**: the first warning or failure that starts the incident
+2. **What changed before**: rollouts, image or chart upgrades, scaling and config changes shortly before it, most suspicious first
+3. **Likely cause and next check**: the change most likely responsible and one read-only command to confirm it`,
+		tl.Scope(), engine.FenceUntrusted("timeline "+tl.Scope(), tl.PromptText()).Text)
+
+	m.messages = append(m.messages, message{sender: user, content: prompt})
+	history := append([]message(nil), m.messages...)
+	m.messages = append(m.messages, message{sender: assist, content: waitingMessage})
+	m.resetLiveTokens()
+	return generateStreamCmd(m, history, m.ollamaModel)
+}
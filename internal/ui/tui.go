@@ -1,9 +1,11 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,10 +16,13 @@ import (
 	
 	"github.com/siryoos/kubemage/internal/config"
 	"github.com/siryoos/kubemage/internal/engine"
+	"github.com/siryoos/kubemage/internal/engine/promptbudget"
 	"github.com/siryoos/kubemage/internal/engine/validator"
 	"github.com/siryoos/kubemage/internal/execx"
 	"github.com/siryoos/kubemage/internal/llm"
 	"github.com/siryoos/kubemage/internal/metrics"
+	"github.com/siryoos/kubemage/internal/prompts"
+	components "github.com/siryoos/kubemage/internal/ui/ui"
 )
 
 type styles struct {
@@ -80,6 +85,7 @@ type rightPaneMode int
 const (
 	rightPaneText rightPaneMode = iota
 	rightPaneDiff
+	rightPaneTimeline
 )
 
 func (l layoutMode) next() layoutMode {
@@ -170,18 +176,46 @@ type commandHint struct {
 var commandPalette = []commandHint{
 	{"/help", "Toggle comprehensive help with new features"},
 	{"/model list", "List available Ollama models"},
+	{"/model pull <name>", "Download a model with progress"},
+	{"/model info [name]", "Show parameters, quantization and context length"},
 	{"/model set chat <name>", "Switch chat assistant model"},
 	{"/model set generation <name>", "Switch generation/diff model"},
 	{"/edit-yaml <path> <instruction>", "Generate a diff for a manifest"},
 	{"/edit-values <path> <instruction>", "Generate a diff for Helm values"},
 	{"/gen-deploy <name> --image <img>", "Draft a deployment manifest"},
 	{"/gen-helm <chart> [flags]", "Generate a Helm chart skeleton"},
+	{"/gen-crd <kind> <name> [description]", "Draft a custom resource from its CRD schema"},
 	{"/diag-pod <name>", "Run intelligent pod diagnostics"},
-	{"/agent", "Toggle ReAct agent mode"},
+	{"/diag-node <name>", "Diagnose a NotReady node or node pressure"},
+	{"/diag-pvc <name>", "Diagnose a Pending PVC or volume attach failures"},
+	{"/diag-job <name>", "Diagnose a failed Job (/diag-cronjob for CronJobs)"},
+	{"/diag-hpa <name>", "Diagnose an HPA that does not scale"},
+	{"/diag-dns", "Diagnose CoreDNS resolution problems"},
+	{"/diag-ingress <name>", "Diagnose Ingress 404/502 responses"},
+	{"/diag-sts <name>", "Diagnose a stuck StatefulSet rollout"},
+	{"/timeline [workload]", "Show an incident timeline of events, rollouts, restarts and Helm releases"},
+	{"/timeline summarize", "Ask what changed before the incident"},
+	{"/report [md|html]", "Export the last diagnosis and agent session as an incident report"},
+	{"/logs <deploy/name|pod query> [-l selector]", "Follow the logs of every matching pod in the output pane"},
+	{"/logs pause|resume|errors|search <term>|stop", "Control the followed logs"},
+	{"/playbook list", "List built-in and team playbooks"},
+	{"/playbook show <name>", "Show a playbook's steps and heuristics"},
+	{"/playbook run <name> pod=<pod>", "Run a playbook's read-only steps"},
+	{"/agent [plan|react]", "Toggle agent mode (plan: plan-then-act investigation)"},
+	{"/agent [step|auto]", "Approve each agent action before it runs, or run them automatically"},
+	{"/cmd <request>", "Generate one command, voting on sampled candidates checked with dry-runs"},
+	{"/investigate <question>", "Investigate with ranked hypotheses and parallel checks"},
+	{"/proposal [n]", "List the agent's proposed fixes or stage the n-th"},
 	{"/ctx", "Show current cluster context"},
+	{"/fix", "Use the guard's corrected command"},
+	{"/prompt list", "List prompt templates"},
+	{"/prompt show <name>", "Show a prompt template and its source"},
+	{"/explain <kind.field.path>", "Browse the cluster's API field docs"},
 	{"/ns set <namespace>", "Switch active namespace"},
 	{"/metrics", "Show comprehensive session metrics"},
 	{"/resolve [note]", "Mark the current task as resolved"},
+	{"/memory list|add|forget", "Manage facts remembered for this cluster"},
+	{"/rate 1-5 [comment]", "Rate the last reply (or press Alt+1..Alt+5)"},
 }
 
 type contextSummaryMsg struct {
@@ -201,13 +235,17 @@ type asyncIntelligenceResultMsg struct {
 
 func generateStreamCmd(m *model, history []message, modelName string) tea.Cmd {
 	return func() tea.Msg {
-		prompt := m.buildChatPrompt(history)
-		ch := make(chan string)
-		systemPrompt := chatAssistantSystemPrompt
+		// The cluster summary is packed as its own section rather than into the system prompt
+		systemPrompt := prompts.Text(prompts.ChatAssistant, prompts.Vars{"Cluster": ""})
 		if m.agentMode {
-			systemPrompt = agentSystemPrompt
+			systemPrompt = llm.AgentSystemPrompt()
+		}
+		packed := m.packChatPrompt(history, systemPrompt, m.retrieveKnowledge(history), modelName)
+		if report := packed.DropReport(); report != "" && m.program != nil {
+			m.program.Send(promptBudgetMsg(report))
 		}
-		go GenerateChatStream(prompt, ch, modelName, systemPrompt)
+		ch := make(chan string)
+		go GenerateChatStream(packed.Prompt, ch, modelName, systemPrompt)
 
 		initialResponse, ok := <-ch
 		if !ok {
@@ -225,9 +263,15 @@ func generateStreamCmd(m *model, history []message, modelName string) tea.Cmd {
 	}
 }
 
+// promptBudgetMsg reports context that was cut to fit num_ctx
+type promptBudgetMsg string
+
 type message struct {
-	sender  string
-	content string
+	sender    string
+	content   string
+	untrusted bool                      // cluster output; fenced before it reaches the model
+	injection []engine.InjectionFinding // suspected prompt injection flagged on this message
+	rating    int                       // 1-5 from /rate or Alt+1..Alt+5
 }
 
 type model struct {
@@ -247,7 +291,7 @@ type model struct {
 	styles                styles
 	showHelp              bool
 	agentMode             bool
-	agentState            string // "", "thinking", "acting"
+	agentState            string // "", "thinking", "approving", "acting"
 	awaitingSecondConfirm *validator.PreExecPlan
 	awaitingTypedConfirm  *validator.PreExecPlan
 	currentPlan           *validator.PreExecPlan
@@ -264,6 +308,7 @@ type model struct {
 	rightTopMode          rightPaneMode
 	ctxName               string
 	namespace             string
+	clusterSummary        string // [CTX] one-liner packed into chat prompts
 	rbacUser              string
 	liveTokens            int
 	lastFooterUpdate      time.Time
@@ -289,6 +334,75 @@ type model struct {
 	pendingIntelligenceWork map[string]bool
 	asyncIntelligenceEnabled bool
 
+	// Ollama model management
+	activePull       string             // model currently being pulled
+	pullCancel       context.CancelFunc // stops the running pull
+	pullMessageIdx   int                // message updated with pull progress
+	pendingPullOffer string             // preferred model that ResolveModel fell back from
+
+	// Hallucination guard for generated commands
+	guard           *engine.CommandGuard
+	guardPending    string       // command being checked before it reaches the preview pane
+	guardCorrection string       // nearest-match correction offered via /fix
+	feedback        turnFeedback // ratings and accept/reject signals for the current prompt
+
+	// Facts remembered per kube context; agent proposals wait for /memory keep
+	memory          *engine.ClusterMemory
+	pendingMemories []string
+
+	// Plan-then-act investigations; agentPlanMode routes agent prompts to them
+	investigator        *engine.Investigator
+	investigation       *engine.Investigation // running investigation, nil when idle
+	investigationCancel context.CancelFunc
+	lastInvestigation   *engine.Investigation // last finished investigation, exported by /report
+	agentPlanMode       bool
+
+	// Diagnostic plans run through the engine's runner; diagRun tells stale results apart
+	diagRunner *engine.DiagRunner
+	diagCancel context.CancelFunc
+	diagRun    int
+	lastDiag   *diagnosisRecord // last finished plan, exported by /report
+
+	// Built-in and YAML playbooks, reloaded when their files change
+	knowledge *engine.PlaybookLibrary
+
+	// Incident timeline from /timeline; timelineView is shown in the preview pane
+	timelineBuilder *engine.TimelineBuilder
+	timeline        *engine.Timeline
+	timelineView    string
+
+	// Pod logs followed with /logs; logViewer takes over the output pane while it is set.
+	// logStart numbers each /logs so a start that arrives after /logs stop is dropped.
+	logStreamer *engine.LogStreamer
+	logStream   *engine.LogStream
+	logSeq      int
+	logViewer   *components.LogViewer
+	logStart    int
+	logStarting bool
+
+	// Step approval: agentCard is the action waiting to be approved, edited, skipped or aborted
+	agentApproval bool
+	agentCard     string
+
+	// /cmd samples command candidates and votes on them with dry-runs
+	commandGenerator *engine.IntelligentCommandGenerator
+	commandVoting    bool
+
+	// Fixes the agent proposed with its final answer; /proposal <n> stages another one
+	pendingProposals []engine.FixProposal
+
+	// Retrieval over playbooks, workspace files and resolved sessions
+	retriever       *engine.KnowledgeRetriever
+	recorder        *engine.FlightRecorder
+	knowledgeWarned bool
+
+	// API field documentation for /explain and prompts
+	explain     *engine.ExplainCache
+	explainView string // /explain output shown in the preview pane
+
+	// CustomResourceDefinitions installed in the cluster
+	crds *engine.CRDCatalog
+
 	// Streaming intelligence
 	streamingManager *engine.StreamingIntelligenceManager
 	intelligenceSubscriber *StreamSubscriber
@@ -347,6 +461,7 @@ func InitialModel(defaultModel string, cfg *config.Config, dumpMetrics bool) *mo
 		stderrContent:       make(map[string]string),
 		previewCheckResults: make(map[string]previewCheckDoneMsg),
 		config:              cfg,
+		agentApproval:       cfg != nil && cfg.Agent.Approval == "step",
 		metrics:             metrics.NewSessionMetrics(),
 		dumpMetrics:         dumpMetrics,
 		metricsFlushed:      false,
@@ -423,6 +538,7 @@ func InitialModel(defaultModel string, cfg *config.Config, dumpMetrics bool) *mo
 			m.showHelp = true
 		}
 	}
+	m.offerPullOnFallback(modelName, selectedModel)
 
 	m.chatViewport.SetContent(m.renderMessages())
 	m.refreshPreviewPane()
@@ -499,8 +615,8 @@ func (m *model) updateLayout() {
 
 		// Dynamic height allocation based on content importance
 		previewRatio := 0.5
-		if m.rightTopMode == rightPaneDiff {
-			previewRatio = 0.6 // More space for diffs
+		if m.rightTopMode == rightPaneDiff || m.rightTopMode == rightPaneTimeline {
+			previewRatio = 0.6 // More space for diffs and timelines
 		} else if m.activeCommand != "" {
 			previewRatio = 0.4 // More space for output when command running
 		}
@@ -644,7 +760,7 @@ func (m *model) DumpMetrics() {
 }
 
 func (m *model) Init() tea.Cmd {
-	return tea.Batch(textarea.Blink, requestContextSummary(), scheduleClockTick())
+	return tea.Batch(textarea.Blink, requestContextSummary(), scheduleClockTick(), modelInfoCmd(m.ollamaModel, true), generationModelInfoCmd(m), refreshKnowledgeCmd(m.retriever), discoverCRDsCmd(m.crds), schedulePlaybookReload(m.knowledge))
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -658,6 +774,16 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		tickCmd    tea.Cmd
 	)
 
+	// Rating keys must not reach the textarea
+	if key, ok := msg.(tea.KeyMsg); ok {
+		if score, ok := ratingKey(key); ok {
+			m.messages = append(m.messages, message{sender: systemSender, content: m.rateLastReply(score, "")})
+			m.chatViewport.SetContent(m.renderMessages())
+			m.chatViewport.GotoBottom()
+			return m, nil
+		}
+	}
+
 	m.textarea, tacmd = m.textarea.Update(msg)
 	m.chatViewport, chatcmd = m.chatViewport.Update(msg)
 	m.previewViewport, prevcmd = m.previewViewport.Update(msg)
@@ -673,6 +799,8 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.summary != nil && msg.err == nil {
 			m.ctxName = msg.summary.Context
 			m.namespace = msg.summary.Namespace
+			m.clusterSummary = msg.summary.RenderedOneLiner
+			prompts.SetClusterContext(msg.summary.Context, msg.summary.Namespace, msg.summary.RenderedOneLiner)
 		}
 		contextCmd = scheduleContextRefresh()
 	case clockTickMsg:
@@ -725,13 +853,61 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Force refresh intelligence
 			m.refreshIntelligence()
 			return m, nil
+		case tea.KeyCtrlG:
+			if m.agentMode && !m.agentPlanMode {
+				cmd = m.toggleAgentApproval()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
+			}
 		case tea.KeyCtrlC, tea.KeyEsc:
+			if msg.Type == tea.KeyEsc && m.agentCard != "" {
+				m.abortAgentSession()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, nil
+			}
+			if msg.Type == tea.KeyEsc && m.cancelInvestigation() {
+				m.messages = append(m.messages, message{sender: systemSender, content: "⏹️ Cancelling the investigation..."})
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, nil
+			}
+			if msg.Type == tea.KeyEsc && m.cancelDiagnostics() {
+				m.messages = append(m.messages, message{sender: systemSender, content: "⏹️ Cancelling diagnostics..."})
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, nil
+			}
+			if msg.Type == tea.KeyEsc && m.cancelModelPull() {
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, nil
+			}
+			if msg.Type == tea.KeyEsc && m.stopLogStream() {
+				m.messages = append(m.messages, message{sender: systemSender, content: "⏹️ Stopped following the logs."})
+				m.refreshOutputPane()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, nil
+			}
+			m.cancelInvestigation()
+			m.cancelDiagnostics()
+			m.cancelModelPull()
+			m.stopLogStream()
+			save := m.flushFeedback()
 			m.DumpMetrics()
-			return m, tea.Quit
+			return m, tea.Sequence(save, tea.Quit)
 		case tea.KeyCtrlH:
 			m.showHelp = !m.showHelp
 			return m, nil
 		case tea.KeyEnter:
+			if m.agentCard != "" && !strings.HasPrefix(strings.TrimSpace(m.textarea.Value()), "/") {
+				cmd = m.approveAgentAction()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
+			}
 			userInput := m.textarea.Value()
 			if strings.TrimSpace(userInput) != "" {
 				m.metrics.RecordTurn()
@@ -749,10 +925,40 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 			if strings.HasPrefix(userInput, "/agent") {
-				m.agentMode = !m.agentMode
-				if m.agentMode {
+				style := strings.TrimSpace(strings.TrimPrefix(userInput, "/agent"))
+				if style == "step" || style == "auto" {
+					if m.agentApproval != (style == "step") {
+						cmd = m.toggleAgentApproval()
+					} else if style == "step" {
+						m.messages = append(m.messages, message{sender: systemSender, content: "🃏 Step approval is already on."})
+					} else {
+						m.messages = append(m.messages, message{sender: systemSender, content: "▶️ Auto mode is already on."})
+					}
+					m.textarea.Reset()
+					m.chatViewport.SetContent(m.renderMessages())
+					m.chatViewport.GotoBottom()
+					return m, cmd
+				}
+				if style == "" {
+					m.agentMode = !m.agentMode
+					m.agentPlanMode = m.config != nil && m.config.Agent.Mode == "plan"
+				} else {
+					m.agentMode = true
+					m.agentPlanMode = style == "plan"
+				}
+				if !m.agentMode {
+					m.agentCard = ""
+				}
+				if m.agentMode && m.agentPlanMode {
+					m.agentState = ""
+					m.messages = append(m.messages, message{sender: systemSender, content: "Agent mode activated (plan-then-act): each question starts an investigation."})
+				} else if m.agentMode {
 					m.agentState = "thinking"
-					m.messages = append(m.messages, message{sender: systemSender, content: "Agent mode activated."})
+					content := "Agent mode activated."
+					if m.agentApproval {
+						content += " Each action waits for your approval; Ctrl+G switches to auto mode."
+					}
+					m.messages = append(m.messages, message{sender: systemSender, content: content})
 				} else {
 					m.agentState = ""
 					m.messages = append(m.messages, message{sender: systemSender, content: "Agent mode deactivated."})
@@ -844,6 +1050,16 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 			}
+			if strings.HasPrefix(userInput, "/gen-crd") {
+				cmdRun, err := m.startGenerationCommand(engine.GenerationTypeCustomResource, userInput)
+				if err != nil {
+					return m, nil
+				}
+				if cmdRun != nil {
+					return m, tea.Batch(tacmd, chatcmd, prevcmd, outcmd, cmdRun)
+				}
+				return m, nil
+			}
 			if strings.TrimSpace(userInput) == "/cancel" {
 				cancelled := false
 				if m.pendingDiff != nil {
@@ -860,79 +1076,79 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.textarea.Reset()
 				return m, nil
 			}
-			if strings.HasPrefix(userInput, "/diag-pod ") {
-				parts := strings.Fields(userInput)
-				if len(parts) >= 2 {
-					ns, _ := GetCurrentNamespace()
-					pod := parts[1]
-
-					m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("🔍 Running diagnostic plan for pod '%s' in namespace '%s'...", pod, ns)})
-					m.chatViewport.SetContent(m.renderMessages())
-					m.chatViewport.GotoBottom()
-
-					results, err := DiagnosePodNotReady(pod, ns)
-					if err != nil {
-						m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("Error running diagnostics: %v", err)})
-						m.textarea.Reset()
-						m.chatViewport.SetContent(m.renderMessages())
-						m.chatViewport.GotoBottom()
-						return m, nil
-					}
-
-					// Display diagnostic outputs and heuristic notes
-					var diagnosticSummary strings.Builder
-					diagnosticSummary.WriteString("🧪 Diagnostic Results:\n\n")
-
-					for _, r := range results {
-						m.messages = append(m.messages, message{sender: execSender, content: "$ " + r.Command})
-						m.messages = append(m.messages, message{sender: systemSender, content: r.Output})
-						for _, note := range r.Notes {
-							m.messages = append(m.messages, message{sender: systemSender, content: "💡 " + note})
-						}
-
-						// Build summary for LLM analysis
-						diagnosticSummary.WriteString(fmt.Sprintf("Command: %s\n", r.Command))
-						if r.Output != "" {
-							// Truncate output for LLM to avoid token limits
-							output := r.Output
-							if len(output) > 2000 {
-								output = output[:2000] + "\n...(truncated)..."
-							}
-							diagnosticSummary.WriteString(fmt.Sprintf("Output: %s\n", output))
-						}
-						if len(r.Notes) > 0 {
-							diagnosticSummary.WriteString(fmt.Sprintf("Heuristic Notes: %s\n", strings.Join(r.Notes, "; ")))
-						}
-						diagnosticSummary.WriteString("\n")
-					}
-
-					// Generate LLM synthesis prompt
-					analysisPrompt := fmt.Sprintf(`Based on the diagnostic outputs above for pod '%s' in namespace '%s', please provide a concise analysis with:
-
-1. **Root Cause**: What is likely causing the pod issues?
-2. **Next Steps**: What specific actions should be taken to resolve this?
-
-Diagnostic Data:
-%s
-
-Please be specific and actionable in your recommendations.`, pod, ns, diagnosticSummary.String())
-
-					m.messages = append(m.messages, message{sender: user, content: analysisPrompt})
-					history := append([]message(nil), m.messages...)
-					m.messages = append(m.messages, message{sender: assist, content: waitingMessage})
-					m.chatViewport.SetContent(m.renderMessages())
-					m.textarea.Reset()
-					m.chatViewport.GotoBottom()
-					m.resetLiveTokens()
-					cmd = generateStreamCmd(m, history, m.generationModel)
-					return m, tea.Batch(tacmd, chatcmd, prevcmd, outcmd, cmd)
+			if strings.HasPrefix(userInput, "/proposal") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				reply, proposalCmd := m.handleProposalCommand(userInput)
+				if reply != "" {
+					m.messages = append(m.messages, message{sender: systemSender, content: reply})
+				}
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, proposalCmd
+			}
+			if strings.HasPrefix(userInput, "/cmd") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				cmd = m.handleCmdCommand(userInput)
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
+			}
+			if strings.HasPrefix(userInput, "/investigate") {
+				question := strings.TrimSpace(strings.TrimPrefix(userInput, "/investigate"))
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				if question == "" {
+					m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /investigate <question>, e.g. /investigate why is pod api-7d9f8b6c5-x2k4j crashing"})
 				} else {
-					m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /diag-pod <pod-name>"})
-					m.textarea.Reset()
-					m.chatViewport.SetContent(m.renderMessages())
-					m.chatViewport.GotoBottom()
-					return m, nil
+					cmd = m.startInvestigation(question)
 				}
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
+			}
+			if strings.HasPrefix(userInput, "/report") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				cmd = m.handleReportCommand(userInput)
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
+			}
+			if strings.HasPrefix(userInput, "/logs") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				cmd = m.handleLogsCommand(userInput)
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
+			}
+			if strings.HasPrefix(userInput, "/timeline") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				cmd = m.handleTimelineCommand(userInput)
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
+			}
+			if strings.HasPrefix(userInput, "/playbook") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				reply, playbookCmd := m.handlePlaybookCommand(userInput)
+				if reply != "" {
+					m.messages = append(m.messages, message{sender: systemSender, content: reply})
+				}
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, playbookCmd
+			}
+			if strings.HasPrefix(userInput, "/diag-") {
+				cmd = m.handleDiagCommand(userInput)
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
 			}
 			if strings.HasPrefix(userInput, "/model") {
 				fields := strings.Fields(userInput)
@@ -950,6 +1166,18 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 						current := fmt.Sprintf("Chat: %s\nGeneration: %s", m.ollamaModel, m.generationModel)
 						m.messages = append(m.messages, message{sender: assist, content: "Available models:\n" + strings.Join(models, "\n") + "\n\n" + current})
 					}
+				case "pull":
+					name := ""
+					if len(fields) > 2 {
+						name = fields[2]
+					}
+					cmd = m.startModelPull(name)
+				case "info":
+					name := m.ollamaModel
+					if len(fields) > 2 {
+						name = fields[2]
+					}
+					cmd = modelInfoCmd(name, false)
 				case "set":
 					if len(fields) < 3 {
 						m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /model set [chat|generation] <name>"})
@@ -976,6 +1204,7 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 							m.config.Models.Generation = modelName
 						}
 						m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("Generation model set to %s", modelName)})
+						cmd = modelInfoCmd(modelName, true)
 					} else {
 						resolved, status, err := llm.ResolveModel(modelName, true)
 						if err != nil {
@@ -989,13 +1218,15 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 								m.messages = append(m.messages, message{sender: systemSender, content: status})
 							}
 							m.messages = append(m.messages, message{sender: assist, content: fmt.Sprintf("Chat model set to %s", resolved)})
+							m.offerPullOnFallback(modelName, resolved)
+							cmd = modelInfoCmd(resolved, true)
 						}
 					}
 				default:
 					// Legacy shorthand: /model <name>
 					modelName := strings.TrimSpace(strings.TrimPrefix(userInput, "/model"))
 					if modelName == "" {
-						m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /model list | /model set [chat|generation] <name>"})
+						m.messages = append(m.messages, message{sender: systemSender, content: "Usage: /model list | /model pull <name> | /model info [name] | /model set [chat|generation] <name>"})
 						break
 					}
 					if err := UpdateModelInConfig("chat", modelName); err != nil {
@@ -1014,9 +1245,43 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 							m.messages = append(m.messages, message{sender: systemSender, content: status})
 						}
 						m.messages = append(m.messages, message{sender: assist, content: fmt.Sprintf("Chat model set to %s", resolved)})
+						m.offerPullOnFallback(modelName, resolved)
+						cmd = modelInfoCmd(resolved, true)
 					}
 				}
 
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, cmd
+			}
+			if userInput == "/fix" {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				m.messages = append(m.messages, message{sender: systemSender, content: m.applyGuardCorrection()})
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, nil
+			}
+			if strings.HasPrefix(userInput, "/explain") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				lookup := m.handleExplainCommand(userInput)
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, lookup
+			}
+			if strings.HasPrefix(userInput, "/memory") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				m.messages = append(m.messages, message{sender: systemSender, content: m.handleMemoryCommand(userInput)})
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, nil
+			}
+			if strings.HasPrefix(userInput, "/prompt") {
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				m.messages = append(m.messages, message{sender: systemSender, content: m.handlePromptCommand(userInput)})
 				m.textarea.Reset()
 				m.chatViewport.SetContent(m.renderMessages())
 				m.chatViewport.GotoBottom()
@@ -1027,7 +1292,11 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 				if err != nil {
 					m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("Error getting context: %v", err)})
 				} else {
-					m.messages = append(m.messages, message{sender: assist, content: fmt.Sprintf("🔍 Current Context:\n%s", summary.RenderedOneLiner)})
+					content := fmt.Sprintf("🔍 Current Context:\n%s", summary.RenderedOneLiner)
+					if crds := m.crdContextLine(); crds != "" {
+						content += "\n" + crds
+					}
+					m.messages = append(m.messages, message{sender: assist, content: content})
 				}
 				m.textarea.Reset()
 				m.chatViewport.SetContent(m.renderMessages())
@@ -1071,10 +1340,27 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 				if note != "" {
 					msg = fmt.Sprintf("%s Note: %s", msg, note)
 				}
+				if err := m.recordResolution(note); err != nil {
+					msg = fmt.Sprintf("%s\n⚠️ Failed to save the session for retrieval: %v", msg, err)
+				}
 				m.messages = append(m.messages, message{sender: systemSender, content: msg})
 				m.textarea.Reset()
 				m.chatViewport.SetContent(m.renderMessages())
 				m.chatViewport.GotoBottom()
+				return m, refreshKnowledgeCmd(m.retriever)
+			}
+			if strings.HasPrefix(userInput, "/rate") {
+				reply := ""
+				if score, comment, err := parseRateCommand(userInput); err != nil {
+					reply = "⚠️ " + err.Error()
+				} else {
+					reply = m.rateLastReply(score, comment)
+				}
+				m.messages = append(m.messages, message{sender: user, content: userInput})
+				m.messages = append(m.messages, message{sender: systemSender, content: reply})
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
 				return m, nil
 			}
 			trimmed := strings.TrimSpace(userInput)
@@ -1087,7 +1373,8 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 
 			// Update intelligence with user input
 			if m.intelligentUI != nil && m.currentContext != nil {
-				err := m.intelligentUI.UpdateIntelligence(trimmed, m.currentContext)
+				// Intent and root cause of a submitted request are classified by the chat model
+				err := m.intelligentUI.UpdateIntelligence(trimmed, m.currentContext, m.ollamaModel)
 				if err == nil {
 					m.riskLevel = m.intelligentUI.GetCurrentRiskLevel()
 					m.lastIntelligenceUpdate = time.Now()
@@ -1110,18 +1397,32 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 				}
 			}
 
+			saveFeedback := m.startFeedbackTurn(trimmed)
 			m.command = ""
 			m.currentPlan = nil
 			m.refreshPreviewPane()
 			m.messages = append(m.messages, message{sender: user, content: trimmed})
+			if m.agentMode && m.agentPlanMode {
+				cmd = m.startInvestigation(trimmed)
+				m.textarea.Reset()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				return m, tea.Batch(cmd, saveFeedback)
+			}
 			history := append([]message(nil), m.messages...)
 			m.messages = append(m.messages, message{sender: assist, content: waitingMessage})
 			m.chatViewport.SetContent(m.renderMessages())
 			m.textarea.Reset()
 			m.chatViewport.GotoBottom()
 			m.resetLiveTokens()
-			cmd = generateStreamCmd(m, history, m.ollamaModel)
+			cmd = tea.Batch(generateStreamCmd(m, history, m.ollamaModel), saveFeedback)
 		case tea.KeyCtrlE:
+			if m.agentCard != "" {
+				cmd = m.approveAgentAction()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				break
+			}
 			if m.pendingDiff != nil && m.pendingDiff.Phase() == DiffPhasePreview {
 				if err := m.applyPendingDiff(); err != nil {
 					m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ Failed to apply diff: %v", err)})
@@ -1151,6 +1452,11 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 				break
 			}
 
+			// A command typed over the proposal replaces it and needs a fresh plan
+			if edited := m.editedCommand(); edited != "" && m.awaitingSecondConfirm != nil {
+				m.awaitingSecondConfirm = nil
+			}
+
 			if m.awaitingSecondConfirm != nil {
 				// Execute original for real
 				realCmd := m.awaitingSecondConfirm.Original
@@ -1164,7 +1470,15 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 			}
 
 			if m.command != "" {
-				plan := BuildPreExecPlan(m.command)
+				if edited := m.editedCommand(); edited != "" {
+					m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("✏️ Running your edit instead of `%s`.", m.command)})
+					m.noteAccepted(m.command, edited)
+					m.command = edited
+					m.textarea.Reset()
+				} else {
+					m.noteAccepted(m.command, m.command)
+				}
+				plan := m.buildPlan(m.command)
 				m.currentPlan = &plan
 				m.refreshPreviewPane()
 				if plan.RequireTypedConfirm {
@@ -1214,6 +1528,13 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 				}
 			}
 		case tea.KeyCtrlK:
+			if m.agentCard != "" {
+				cmd = m.skipAgentAction()
+				m.chatViewport.SetContent(m.renderMessages())
+				m.chatViewport.GotoBottom()
+				break
+			}
+			m.rejectPendingSuggestions()
 			m.command = ""
 			m.currentPlan = nil
 			m.refreshPreviewPane()
@@ -1239,6 +1560,127 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 		m.chatViewport.SetContent(m.renderMessages())
 		m.chatViewport.GotoBottom()
 
+	case generationResultMsg:
+		session := m.pendingGeneration
+		if session == nil || session.Phase() != GenerationPhaseAwaiting {
+			break
+		}
+		m.liveTokens = 0
+		last := len(m.messages) - 1
+		if msg.err != nil {
+			m.messages[last] = message{sender: systemSender, content: fmt.Sprintf("⚠️ Generation failed: %v", msg.err)}
+			m.pendingGeneration = nil
+			m.chatViewport.SetContent(m.renderMessages())
+			m.chatViewport.GotoBottom()
+			break
+		}
+		session.Manifest = msg.manifest
+		session.Files = msg.files
+		if msg.crd != nil {
+			session.CRD = msg.crd
+			session.SchemaIssues = msg.schemaIssues
+			session.TargetPath = customResourceTarget(session.Name, msg.crd.Kind)
+		}
+		if msg.manifest != "" {
+			m.messages[last].content = fmt.Sprintf("```yaml\n%s\n```\n%s", strings.TrimSpace(msg.manifest), msg.explanation)
+		} else {
+			paths := make([]string, 0, len(msg.files))
+			for p := range msg.files {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			m.messages[last].content = "Generated files:\n- " + strings.Join(paths, "\n- ")
+		}
+		m.handleGenerationCompletion()
+
+	case modelPullMsg:
+		m.handleModelPull(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case feedbackSavedMsg:
+		m.handleFeedbackSaved(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case commandVoteMsg:
+		cmd = m.handleCommandVote(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case commandGuardMsg:
+		m.handleCommandGuard(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case explainMsg:
+		m.handleExplain(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case modelInfoMsg:
+		m.handleModelInfo(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case promptBudgetMsg:
+		m.insertNote(string(msg))
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case retrievalMsg:
+		m.insertNote(string(msg))
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case knowledgeIndexMsg:
+		m.handleKnowledgeIndex(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case investigationWaveMsg:
+		m.handleInvestigationWave(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case investigationDoneMsg:
+		cmd = m.handleInvestigationDone(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case diagStepMsg:
+		m.handleDiagStep(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case diagDoneMsg:
+		cmd = m.handleDiagDone(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case timelineMsg:
+		m.handleTimeline(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case reportMsg:
+		m.handleReport(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case logStreamStartMsg:
+		cmd = m.handleLogStreamStart(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
+	case logStreamTickMsg:
+		cmd = m.handleLogStreamTick(msg)
+
+	case playbookReloadMsg:
+		cmd = m.handlePlaybookReload(msg)
+		m.chatViewport.SetContent(m.renderMessages())
+		m.chatViewport.GotoBottom()
+
 	case ollamaStreamDoneMsg:
 		last := len(m.messages) - 1
 		m.liveTokens = 0
@@ -1256,8 +1698,17 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 		assistantReply := m.messages[last].content
 
 		if m.agentMode && m.agentState == "thinking" {
+			m.proposeMemories(assistantReply)
 			if action := parseAction(assistantReply); action != "" {
-				if isActionWhitelisted(action) {
+				if findings := m.recentInjection(); isActionWhitelisted(action) && len(findings) > 0 {
+					// Never auto-run an action chosen after suspicious cluster output
+					m.agentState = "acting"
+					m.command = action
+					m.refreshPreviewPane()
+					m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("🛡️ Agent paused before `%s` (%s). Press Ctrl+E to review it.", action, strings.Join(engine.FindingRules(findings), ", "))})
+				} else if isActionWhitelisted(action) && m.agentApproval {
+					m.showAgentCard(action)
+				} else if isActionWhitelisted(action) {
 					m.agentState = "acting"
 					m.messages = append(m.messages, message{sender: execSender, content: "$ " + action})
 					m.beginCommandExecution(action)
@@ -1271,6 +1722,7 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 				m.messages[last].content = finalAnswer
 				m.agentState = ""
 				m.agentMode = false
+				cmd = m.loadAgentProposals(assistantReply)
 			}
 		} else if !m.agentMode {
 			if strings.HasPrefix(assistantReply, "Error") {
@@ -1279,12 +1731,12 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 				m.refreshPreviewPane()
 				break
 			}
-			m.command = parseCommandFromResponse(assistantReply)
-			m.refreshPreviewPane()
-			if m.command != "" {
+			proposed := parseCommandFromResponse(assistantReply)
+			cmd = m.proposeCommand(proposed)
+			if proposed != "" {
 				m.metrics.RecordSuggestion()
 			}
-			if m.command == "" {
+			if proposed == "" {
 				if strings.TrimSpace(assistantReply) == "" {
 					m.messages[last].content = "I didn’t receive any text from the model."
 				}
@@ -1309,12 +1761,15 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 
 	case stderrMsg:
 		m.stderrContent[msg.cmd] += msg.out + "\n"
-		m.messages = append(m.messages, message{sender: systemSender, content: "stderr: " + msg.out})
+		m.messages = append(m.messages, message{sender: systemSender, content: "stderr: " + msg.out, untrusted: true})
 		m.chatViewport.SetContent(m.renderMessages())
 		m.chatViewport.GotoBottom()
 		m.refreshOutputPane()
 
 	case execDoneMsg:
+		m.flagInjection(msg.cmd, m.stdoutContent[msg.cmd]+"\n"+m.stderrContent[msg.cmd])
+		m.noteExecution(msg.cmd, msg.err)
+
 		// Learn from command execution for predictive intelligence
 		if PredictiveIntelligence != nil && m.currentContext != nil {
 			userInput := ""
@@ -1348,7 +1803,7 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 				}
 			}
 
-			m.messages = append(m.messages, message{sender: user, content: "Observation: " + observation})
+			m.messages = append(m.messages, message{sender: user, content: "Observation: " + observation, untrusted: true})
 			history := append([]message(nil), m.messages...)
 			m.messages = append(m.messages, message{sender: assist, content: waitingMessage})
 			m.chatViewport.SetContent(m.renderMessages())
@@ -1363,7 +1818,7 @@ Please be specific and actionable in your recommendations.`, pod, ns, diagnostic
 
 			if stderr, ok := m.stderrContent[msg.cmd]; ok && stderr != "" {
 				// There was stderr, trigger self-correction
-				correctionPrompt := fmt.Sprintf("The command `%s` failed with the error:\n```\n%s\n```\nCan you fix it?", msg.cmd, stderr)
+				correctionPrompt := fmt.Sprintf("The command `%s` failed with the error:\n%s\nCan you fix it?", msg.cmd, engine.FenceUntrusted(msg.cmd, stderr).Text)
 				m.metrics.RecordCorrection()
 
 				m.messages = append(m.messages, message{sender: user, content: correctionPrompt})
@@ -1503,6 +1958,12 @@ func (m *model) previewTitle() string {
 	if m.rightTopMode == rightPaneDiff {
 		return "Diff Preview"
 	}
+	if m.rightTopMode == rightPaneTimeline {
+		return "Incident Timeline"
+	}
+	if m.explainView != "" {
+		return "API Reference"
+	}
 	if m.currentPlan != nil {
 		return "Plan Preview"
 	}
@@ -1586,6 +2047,15 @@ func (m *model) refreshPreviewPane() {
 		}
 	}
 
+	if len(sections) == 0 && m.timelineView != "" {
+		mode = rightPaneTimeline
+		sections = append(sections, m.timelineView)
+	}
+
+	if len(sections) == 0 && m.explainView != "" {
+		sections = append(sections, m.explainView)
+	}
+
 	if len(sections) == 0 && m.currentPlan != nil {
 		sections = append(sections, m.currentPlan.GetSafetyReport())
 		sections = append(sections, m.currentPlan.HumanPreview())
@@ -1612,6 +2082,10 @@ func (m *model) refreshPreviewPane() {
 }
 
 func (m *model) refreshOutputPane() {
+	if m.logViewer != nil {
+		m.outputViewport.SetContent(m.renderLogStream())
+		return
+	}
 	var sb strings.Builder
 	if m.activeCommand != "" {
 		sb.WriteString(fmt.Sprintf("$ %s\n", m.activeCommand))
@@ -1653,6 +2127,10 @@ func (m *model) beginCommandExecution(command string) {
 	m.activeCommand = command
 	m.stdoutContent[command] = ""
 	m.stderrContent[command] = ""
+	// Stopped logs give the pane back; logs still followed keep it until /logs stop
+	if m.logStream == nil {
+		m.logViewer = nil
+	}
 	m.refreshOutputPane()
 }
 
@@ -1729,6 +2207,9 @@ func (m *model) renderMessages() string {
 		if len(content) > m.config.Truncation.Message {
 			content = content[:m.config.Truncation.Message] + "\n(...truncated...)"
 		}
+		if msg.rating > 0 {
+			content += fmt.Sprintf(" [rated %d/5]", msg.rating)
+		}
 		history += senderStyle.Render(senderText) + ": " + content + "\n"
 	}
 	return history
@@ -1795,6 +2276,7 @@ func (m *model) renderHelpBlock() string {
 		m.styles.hintKeyStyle.Render("🎮 Enhanced Controls:"),
 		"Enter: Ask question • Ctrl+E: Execute command • Ctrl+K: Clear preview",
 		"Ctrl+H: Toggle help • F2: Change layout • Ctrl+C: Exit",
+		"Ctrl+G: Switch the agent between step approval and auto mode",
 		"",
 		m.styles.hintKeyStyle.Render("🤖 AI Intelligence Hotkeys:"),
 		"F1-F3: Execute AI suggestions • F4: Toggle suggestions • F5: Toggle insights",
@@ -1802,7 +2284,7 @@ func (m *model) renderHelpBlock() string {
 		"",
 		m.styles.hintKeyStyle.Render("💬 Slash Commands:"),
 		"/model set chat <name> • /edit-yaml <file> <instruction> • /metrics",
-		"/resolve [note] • /agent • /diag-pod <name> • /ctx • /ns set <namespace>",
+		"/resolve [note] • /agent [plan] • /investigate <question> • /diag-<pod|svc|deploy|node|pvc|job|cronjob|hpa|dns|ingress|sts> <name> • /playbook list|show|run • /timeline [workload] • /report [md|html] • /logs <target>|stop • /ctx • /ns set <namespace>",
 		"",
 		m.styles.hintKeyStyle.Render("🎨 Features:"),
 		"• Real-time cluster health monitoring with risk indicators",
@@ -2153,7 +2635,16 @@ func (m *model) startDiffCommand(mode DiffMode, input string) (tea.Cmd, error) {
 
 	m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("✏️ Generating diff for %s", normalizedPath)})
 
-	prompt := BuildDiffEditPrompt(mode, normalizedPath, redaction.Sanitized, safeInstruction)
+	promptContent := redaction.Sanitized
+	if fileCap := m.promptBudget(m.generationModel).FileCap(); fileCap > 0 {
+		var notes []string
+		promptContent, notes = promptbudget.TruncateYAMLDocuments(promptContent, fileCap)
+		for _, note := range notes {
+			m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("✂️ Prompt budget: %s: %s", normalizedPath, note)})
+		}
+	}
+
+	prompt := BuildDiffEditPrompt(mode, normalizedPath, promptContent, safeInstruction)
 	m.pendingDiff = NewDiffSession(mode, normalizedPath, safeInstruction, currentContent, redaction)
 	m.refreshPreviewPane()
 
@@ -2314,6 +2805,7 @@ func (m *model) startGenerationCommand(genType GenerationType, input string) (te
 		prompt  string
 		session *GenerationSession
 		summary string
+		run     tea.Cmd
 	)
 
 	switch genType {
@@ -2345,6 +2837,18 @@ func (m *model) startGenerationCommand(genType GenerationType, input string) (te
 		session = NewGenerationSession(GenerationTypeHelmChart, opts.Name, "", targetDir)
 		session.HelmOptions = &opts
 		summary = fmt.Sprintf("🧬 Generating helm chart %s", opts.Name)
+	case engine.GenerationTypeCustomResource:
+		kind, name, description, err := parseGenCRDCommand(input)
+		if err != nil {
+			m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("⚠️ %v", err)})
+			m.chatViewport.SetContent(m.renderMessages())
+			m.chatViewport.GotoBottom()
+			m.textarea.Reset()
+			return nil, err
+		}
+		session = NewGenerationSession(engine.GenerationTypeCustomResource, name, customResourceTarget(name, kind), "")
+		summary = fmt.Sprintf("🧬 Generating %s %s from its CRD schema", kind, name)
+		run = customResourceGenerationCmd(m.crds, kind, name, m.namespace, description, m.generationModel)
 	default:
 		return nil, fmt.Errorf("unsupported generation type")
 	}
@@ -2352,8 +2856,6 @@ func (m *model) startGenerationCommand(genType GenerationType, input string) (te
 	m.messages = append(m.messages, message{sender: systemSender, content: summary})
 
 	m.pendingGeneration = session
-	history := append([]message(nil), m.messages...)
-	history = append(history, message{sender: user, content: prompt})
 
 	m.messages = append(m.messages, message{sender: assist, content: waitingMessage})
 	m.chatViewport.SetContent(m.renderMessages())
@@ -2361,7 +2863,37 @@ func (m *model) startGenerationCommand(genType GenerationType, input string) (te
 	m.textarea.Reset()
 
 	m.resetLiveTokens()
-	return generateStreamCmd(m, history, m.generationModel), nil
+	if run == nil {
+		run = structuredGenerationCmd(session.Type, trimmed, prompt, m.generationModel, m.explain)
+	}
+	return run, nil
+}
+
+// generationResultMsg carries schema-validated generation output
+type generationResultMsg struct {
+	manifest     string
+	explanation  string
+	files        map[string]string
+	crd          *engine.CRDInfo // set for custom resources
+	schemaIssues []string        // CRD schema violations left after repair
+	err          error
+}
+
+func structuredGenerationCmd(genType GenerationType, request, prompt, modelName string, explain *engine.ExplainCache) tea.Cmd {
+	return func() tea.Msg {
+		prompt = withFieldDocs(explain, genType, request, prompt)
+		switch genType {
+		case GenerationTypeHelmChart:
+			files, err := engine.GenerateHelmChartStructured(prompt, modelName)
+			return generationResultMsg{files: files, err: err}
+		default:
+			manifest, err := engine.GenerateManifestStructured(prompt, modelName)
+			if err != nil {
+				return generationResultMsg{err: err}
+			}
+			return generationResultMsg{manifest: manifest.Manifest, explanation: manifest.Explanation}
+		}
+	}
 }
 
 func (m *model) handleGenerationCompletion() {
@@ -2374,8 +2906,11 @@ func (m *model) handleGenerationCompletion() {
 	raw := session.RawResponse()
 
 	switch session.Type {
-	case GenerationTypeDeployment:
-		content := ParseGeneratedContent(raw)
+	case GenerationTypeDeployment, engine.GenerationTypeCustomResource:
+		content := session.Manifest
+		if content == "" {
+			content = ParseGeneratedContent(raw)
+		}
 		if strings.TrimSpace(content) == "" {
 			m.messages[len(m.messages)-1] = message{sender: systemSender, content: "⚠️ Manifest generation returned empty content."}
 			m.pendingGeneration = nil
@@ -2411,12 +2946,20 @@ func (m *model) handleGenerationCompletion() {
 		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("💾 Saved manifest to %s", session.TargetPath)})
 		m.messages = append(m.messages, message{sender: systemSender, content: RenderValidationResults(results)})
 		m.command = fmt.Sprintf("kubectl apply --dry-run=client -f %s", session.TargetPath)
+		if session.Type == engine.GenerationTypeCustomResource {
+			m.messages = append(m.messages, message{sender: systemSender, content: renderSchemaCheck(session.CRD, session.SchemaIssues)})
+			// Only the API server knows the CRD, so let it validate the object
+			m.command = fmt.Sprintf("kubectl apply --dry-run=server -f %s", session.TargetPath)
+		}
 		m.refreshPreviewPane()
 		m.messages = append(m.messages, message{sender: systemSender, content: fmt.Sprintf("✅ Dry-run command staged: %s", m.command)})
 		m.metrics.RecordSuggestion()
 
 	case GenerationTypeHelmChart:
-		files := ParseHelmChartFiles(raw)
+		files := session.Files
+		if len(files) == 0 {
+			files = ParseHelmChartFiles(raw)
+		}
 		if len(files) == 0 {
 			m.messages[len(m.messages)-1] = message{sender: systemSender, content: "⚠️ Helm generation returned no files."}
 			m.pendingGeneration = nil
@@ -2478,24 +3021,29 @@ func (m *model) cancelGenerationSession() bool {
 	return true
 }
 
+// insertNote adds a system note, keeping the streaming assistant message last so chunks still append to it
+func (m *model) insertNote(content string) {
+	note := message{sender: systemSender, content: content}
+	if last := len(m.messages) - 1; last >= 0 && m.messages[last].sender == assist {
+		m.messages = append(m.messages[:last], note, m.messages[last])
+	} else {
+		m.messages = append(m.messages, note)
+	}
+}
+
 func (m *model) buildChatPrompt(history []message) string {
 	if len(history) == 0 {
 		return ""
 	}
+	return m.packChatPrompt(history, "", nil, m.ollamaModel).Prompt
+}
 
-	start := 0
-	if len(history) > m.config.HistoryLength {
-		start = len(history) - m.config.HistoryLength
-	}
-	trimmed := history[start:]
-
-	var sb strings.Builder
-	for _, msg := range trimmed {
+// packChatPrompt fits the cluster context, retrieved knowledge and recent history into the
+// num_ctx budget of modelName. A trailing user message is the user turn, which is never cut.
+func (m *model) packChatPrompt(history []message, systemPrompt string, knowledge []string, modelName string) promptbudget.PackedPrompt {
+	msgs := make([]promptbudget.ChatMessage, 0, len(history))
+	for _, msg := range history {
 		content := strings.TrimSpace(RedactText(msg.content))
-		if content == "" {
-			continue
-		}
-
 		label := "User"
 		switch msg.sender {
 		case assist:
@@ -2503,15 +3051,54 @@ func (m *model) buildChatPrompt(history []message) string {
 		case execSender:
 			label = "Command Output"
 		}
+		if content != "" && (msg.untrusted || msg.sender == execSender) {
+			content = engine.FenceUntrusted(label, content).Text
+		}
+		msgs = append(msgs, promptbudget.ChatMessage{Role: label, Content: content, User: msg.sender == user && !msg.untrusted})
+	}
+
+	return m.promptBudget(modelName).PackChat(promptbudget.ChatInput{
+		System:        systemPrompt,
+		Cluster:       []string{m.clusterSummary, m.relevantMemories(history)},
+		Knowledge:     knowledge,
+		History:       msgs,
+		HistoryLength: m.config.HistoryLength,
+	})
+}
 
-		sb.WriteString(label)
-		sb.WriteString(": ")
-		sb.WriteString(content)
-		sb.WriteString("\n\n")
+// promptBudget sizes prompts for the model they are sent to
+func (m *model) promptBudget(modelName string) *promptbudget.PromptBudget {
+	numCtx := llm.ModelNumCtx(m.config.NumCtx, modelName, !m.config.NumCtxFixed)
+	budget := promptbudget.NewPromptBudget(numCtx)
+	if m.config.Prompt.ResponseReserve > 0 && m.config.Prompt.ResponseReserve < numCtx {
+		budget.ResponseReserve = m.config.Prompt.ResponseReserve
+	}
+	return budget
+}
+
+// handlePromptCommand implements /prompt list and /prompt show <name>
+func (m *model) handlePromptCommand(input string) string {
+	fields := strings.Fields(input)
+	usage := "Usage: /prompt list | /prompt show <name>"
+	if len(fields) < 2 {
+		return usage
 	}
 
-	sb.WriteString("Assistant:")
-	return sb.String()
+	switch fields[1] {
+	case "list":
+		return "📝 Prompt templates:\n- " + strings.Join(prompts.Names(), "\n- ")
+	case "show":
+		if len(fields) < 3 {
+			return usage
+		}
+		text, origin, err := prompts.Source(fields[2])
+		if err != nil {
+			return fmt.Sprintf("⚠️ %v. Try /prompt list.", err)
+		}
+		return fmt.Sprintf("📝 Prompt %s (%s):\n%s", fields[2], origin, strings.TrimRight(text, "\n"))
+	default:
+		return usage
+	}
 }
 
 // Enhanced intelligence methods for TUI
@@ -2569,7 +3156,7 @@ func (m *model) refreshIntelligence() {
 			m.lastIntelligenceUpdate = time.Now()
 		} else {
 			// Fallback to regular intelligence update
-			err := m.intelligentUI.UpdateIntelligence(input, m.currentContext)
+			err := m.intelligentUI.UpdateIntelligence(input, m.currentContext, "")
 			if err == nil {
 				m.riskLevel = m.intelligentUI.GetCurrentRiskLevel()
 				m.lastIntelligenceUpdate = time.Now()
@@ -2940,7 +3527,7 @@ func (m *model) refreshIntelligenceAsync() {
 	if input == "" {
 		return
 	}
-
+	// Partial input is analyzed with the patterns only; the model is called on submit
 	// Use async processing if enabled
 	if m.asyncIntelligenceEnabled && Intelligence.processor != nil {
 		callback := func(result engine.IntelligenceResult) {
@@ -2957,7 +3544,7 @@ func (m *model) refreshIntelligenceAsync() {
 		}
 	} else {
 		// Fallback to sync processing
-		err := m.intelligentUI.UpdateIntelligence(input, m.currentContext)
+		err := m.intelligentUI.UpdateIntelligence(input, m.currentContext, "")
 		if err == nil {
 			m.riskLevel = m.intelligentUI.GetCurrentRiskLevel()
 			m.lastIntelligenceUpdate = time.Now()
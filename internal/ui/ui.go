@@ -41,6 +41,18 @@ func New(opts Options) *UI {
 func (ui *UI) Run(ctx context.Context) error {
 	// Create the tea program with the model
 	m := InitialModel(ui.config.GetModel(), ui.config, false)
+	m.guard = ui.engine.GetCommandGuard()
+	m.retriever = ui.engine.GetRetriever()
+	m.recorder = ui.engine.GetRecorder()
+	m.explain = ui.engine.GetExplainCache()
+	m.crds = ui.engine.GetCRDCatalog()
+	m.memory = ui.engine.GetClusterMemory()
+	m.investigator = ui.engine.GetInvestigator()
+	m.commandGenerator = ui.engine.GetCommandGenerator()
+	m.diagRunner = ui.engine.GetDiagRunner()
+	m.knowledge = ui.engine.GetKnowledge()
+	m.timelineBuilder = ui.engine.GetTimelineBuilder()
+	m.logStreamer = ui.engine.GetLogStreamer()
 	ui.program = tea.NewProgram(m, tea.WithAltScreen())
 	
 	// Run the program
@@ -2,14 +2,22 @@ package ui
 
 import (
 	"fmt"
+	"hash/fnv"
 	"regexp"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/siryoos/kubemage/internal/engine/logmine"
 )
 
+// maxGroupLines caps the lines listed under an expanded template group
+const maxGroupLines = 10
+
+// originColors tell the pods and containers of a multi-pod stream apart, like stern
+var originColors = []lipgloss.Color{"39", "170", "214", "77", "141", "45", "208", "118", "205", "111", "220", "43"}
+
 // LogLevel represents different log levels
 type LogLevel int
 
@@ -27,6 +35,7 @@ type LogLine struct {
 	Level       LogLevel
 	Content     string
 	Source      string
+	Origin      string // "pod/container" for streamed logs
 	Highlighted bool
 }
 
@@ -54,6 +63,21 @@ type LogViewer struct {
 	focused        bool
 	truncatedCount int
 	totalLines     int
+
+	// Template view: lines clustered into templates, each group collapsible
+	grouped    bool
+	previous   string            // previous container's log; its templates are not "new"
+	analysis   *logmine.Analysis // computed on demand, reset when the content changes
+	groupIndex int               // selected group
+	openGroups map[int]bool      // expanded template IDs
+
+	// Streaming: lines beyond maxLines are dropped, follow keeps the newest line in view
+	// and pause holds new lines back until resumed
+	maxLines int
+	follow   bool
+	paused   bool
+	pending  []LogLine
+	dropped  int
 }
 
 // Log level patterns for parsing
@@ -99,16 +123,93 @@ func (lv *LogViewer) SetContent(content string) {
 
 // AppendContent appends new content to the log
 func (lv *LogViewer) AppendContent(content string) {
+	lv.AppendFrom("", content)
+}
+
+// AppendFrom appends lines of one origin, e.g. "web-7d9f8b6c5-x2k4j/app", shown in the
+// origin's color. While paused the lines are held back until Resume.
+func (lv *LogViewer) AppendFrom(origin, content string) {
 	newLines := lv.parseLines(content)
-	lv.lines = append(lv.lines, newLines...)
+	for i := range newLines {
+		newLines[i].Origin = origin
+	}
+	if lv.paused {
+		lv.pending = lv.capLines(append(lv.pending, newLines...))
+		return
+	}
+	lv.lines = lv.capLines(append(lv.lines, newLines...))
+	lv.totalLines = len(lv.lines)
+	lv.analysis = nil
+	lv.applyFilters()
+	if lv.follow {
+		lv.ScrollToBottom()
+	}
+}
+
+// capLines drops the oldest lines beyond maxLines
+func (lv *LogViewer) capLines(lines []LogLine) []LogLine {
+	if over := len(lines) - lv.maxLines; lv.maxLines > 0 && over > 0 {
+		lv.dropped += over
+		return lines[over:]
+	}
+	return lines
+}
+
+// SetMaxLines caps the lines kept in memory; zero keeps every line
+func (lv *LogViewer) SetMaxLines(n int) {
+	lv.maxLines = n
+	lv.lines = lv.capLines(lv.lines)
+	lv.totalLines = len(lv.lines)
+	lv.analysis = nil
+	lv.applyFilters()
+}
+
+// SetFollow keeps the newest line in view as lines arrive
+func (lv *LogViewer) SetFollow(follow bool) {
+	lv.follow = follow
+	if follow {
+		lv.ScrollToBottom()
+	}
+}
+
+// Pause holds new lines back; the view stays still until Resume
+func (lv *LogViewer) Pause() {
+	lv.paused = true
+}
+
+// Resume shows the lines that arrived while paused
+func (lv *LogViewer) Resume() {
+	if !lv.paused {
+		return
+	}
+	lv.paused = false
+	pending := lv.pending
+	lv.pending = nil
+	lv.lines = lv.capLines(append(lv.lines, pending...))
 	lv.totalLines = len(lv.lines)
+	lv.analysis = nil
 	lv.applyFilters()
+	if lv.follow {
+		lv.ScrollToBottom()
+	}
+}
+
+// SetPreviousContent sets the previous container's log, e.g. from kubectl logs --previous,
+// so the template view can mark templates that are new since the restart
+func (lv *LogViewer) SetPreviousContent(content string) {
+	lv.previous = content
+	lv.analysis = nil
 }
 
 // parseContent parses log content into structured lines
 func (lv *LogViewer) parseContent(content string) {
 	lv.lines = lv.parseLines(content)
 	lv.totalLines = len(lv.lines)
+	lv.analysis = nil
+	lv.openGroups = nil
+	lv.groupIndex = 0
+	lv.pending = nil
+	lv.dropped = 0
 }
 
 // parseLines parses text content into LogLines
@@ -238,6 +339,58 @@ func (lv *LogViewer) ToggleExpanded() {
 	lv.applyFilters()
 }
 
+// ToggleGrouped switches between the line view and the template view
+func (lv *LogViewer) ToggleGrouped() {
+	lv.grouped = !lv.grouped
+	lv.groupIndex = 0
+	lv.viewOffset = 0
+}
+
+// ToggleGroup expands or collapses the selected template group
+func (lv *LogViewer) ToggleGroup() {
+	groups := lv.visibleGroups()
+	if lv.groupIndex >= len(groups) {
+		return
+	}
+	if lv.openGroups == nil {
+		lv.openGroups = map[int]bool{}
+	}
+	id := groups[lv.groupIndex].ID
+	lv.openGroups[id] = !lv.openGroups[id]
+}
+
+// Analysis returns the template view of the current lines, mining them if needed
+func (lv *LogViewer) Analysis() *logmine.Analysis {
+	if lv.analysis == nil {
+		contents := make([]string, len(lv.lines))
+		for i, line := range lv.lines {
+			contents[i] = line.Content
+		}
+		// parseLines drops blank lines, so template line numbers index lv.lines
+		lv.analysis = logmine.Analyze(strings.Join(contents, "\n"), lv.previous)
+	}
+	return lv.analysis
+}
+
+// visibleGroups returns the templates that pass the level filter
+func (lv *LogViewer) visibleGroups() []*logmine.Template {
+	var groups []*logmine.Template
+	for _, t := range lv.Analysis().Templates {
+		switch lv.filter.Level {
+		case LogLevelError:
+			if !t.Error {
+				continue
+			}
+		case LogLevelWarn:
+			if !t.Warning {
+				continue
+			}
+		}
+		groups = append(groups, t)
+	}
+	return groups
+}
+
 // applyFilters applies current filters to generate filtered view
 func (lv *LogViewer) applyFilters() {
 	lv.filteredLines = []LogLine{}
@@ -275,11 +428,11 @@ func (lv *LogViewer) passesFilter(line LogLine) bool {
 		return false
 	}
 
-	// Search term filter
+	// Search term filter; streamed lines also match their pod and container
 	if lv.filter.SearchTerm != "" {
-		if !strings.Contains(
-			strings.ToLower(line.Content),
-			strings.ToLower(lv.filter.SearchTerm)) {
+		term := strings.ToLower(lv.filter.SearchTerm)
+		if !strings.Contains(strings.ToLower(line.Content), term) &&
+			!strings.Contains(strings.ToLower(line.Origin), term) {
 			return false
 		}
 	}
@@ -306,7 +459,7 @@ func (lv *LogViewer) ScrollUp() {
 
 // ScrollDown scrolls the view down
 func (lv *LogViewer) ScrollDown() {
-	maxOffset := len(lv.filteredLines) - lv.viewHeight
+	maxOffset := len(lv.filteredLines) - lv.bodyHeight()
 	if maxOffset < 0 {
 		maxOffset = 0
 	}
@@ -317,13 +470,21 @@ func (lv *LogViewer) ScrollDown() {
 
 // ScrollToBottom scrolls to the bottom
 func (lv *LogViewer) ScrollToBottom() {
-	maxOffset := len(lv.filteredLines) - lv.viewHeight
+	maxOffset := len(lv.filteredLines) - lv.bodyHeight()
 	if maxOffset < 0 {
 		maxOffset = 0
 	}
 	lv.viewOffset = maxOffset
 }
 
+// bodyHeight is the number of lines shown, reserving space for the header and footer
+func (lv *LogViewer) bodyHeight() int {
+	if lv.viewHeight <= 3 {
+		return 1
+	}
+	return lv.viewHeight - 3
+}
+
 // SetSize updates the view dimensions
 func (lv *LogViewer) SetSize(width, height int) {
 	lv.viewWidth = width
@@ -354,6 +515,19 @@ func (lv *LogViewer) UpdateSearchQuery(query string) {
 
 // Render renders the log view
 func (lv *LogViewer) Render() string {
+	content := lv.RenderBody()
+
+	// Apply styling
+	style := lv.theme.PaneStyle()
+	if lv.focused {
+		style = lv.theme.PaneFocusedStyle()
+	}
+
+	return style.Width(lv.viewWidth).Height(lv.viewHeight).Render(content)
+}
+
+// RenderBody renders the log view without its pane, for embedding in another pane
+func (lv *LogViewer) RenderBody() string {
 	var sections []string
 
 	// Header with filter info
@@ -368,9 +542,12 @@ func (lv *LogViewer) Render() string {
 		sections = append(sections, truncationNotice)
 	}
 
-	// Log lines
-	logContent := lv.renderLogLines()
-	sections = append(sections, logContent)
+	// Log lines, or template groups
+	if lv.grouped {
+		sections = append(sections, lv.renderTemplateGroups())
+	} else {
+		sections = append(sections, lv.renderLogLines())
+	}
 
 	// Search bar (if active)
 	if lv.searchActive {
@@ -384,15 +561,7 @@ func (lv *LogViewer) Render() string {
 		sections = append(sections, footer)
 	}
 
-	content := strings.Join(sections, "\n")
-
-	// Apply styling
-	style := lv.theme.PaneStyle()
-	if lv.focused {
-		style = lv.theme.PaneFocusedStyle()
-	}
-
-	return style.Width(lv.viewWidth).Height(lv.viewHeight).Render(content)
+	return strings.Join(sections, "\n")
 }
 
 // renderHeader renders the log viewer header
@@ -402,7 +571,11 @@ func (lv *LogViewer) renderHeader() string {
 	var parts []string
 
 	// Total lines info
-	parts = append(parts, fmt.Sprintf("Lines: %d", len(lv.filteredLines)))
+	if lv.grouped {
+		parts = append(parts, "Templates: "+lv.Analysis().Headline())
+	} else {
+		parts = append(parts, fmt.Sprintf("Lines: %d", len(lv.filteredLines)))
+	}
 
 	// Filter info
 	if lv.filter.Level != LogLevelAll {
@@ -415,6 +588,16 @@ func (lv *LogViewer) renderHeader() string {
 		parts = append(parts, fmt.Sprintf("Search: \"%s\"", lv.filter.SearchTerm))
 	}
 
+	// Streaming state
+	if lv.paused {
+		parts = append(parts, fmt.Sprintf("Paused (%d new)", len(lv.pending)))
+	} else if lv.follow {
+		parts = append(parts, "Following")
+	}
+	if lv.dropped > 0 {
+		parts = append(parts, fmt.Sprintf("%d oldest dropped", lv.dropped))
+	}
+
 	return headerStyle.Render(strings.Join(parts, " • "))
 }
 
@@ -438,7 +621,7 @@ func (lv *LogViewer) renderLogLines() string {
 
 	// Calculate visible range
 	start := lv.viewOffset
-	end := start + lv.viewHeight - 3 // Reserve space for header/footer
+	end := start + lv.bodyHeight()
 	if end > len(lv.filteredLines) {
 		end = len(lv.filteredLines)
 	}
@@ -453,6 +636,67 @@ func (lv *LogViewer) renderLogLines() string {
 	return strings.Join(lines, "\n")
 }
 
+// renderTemplateGroups renders one row per template; expanded groups list their lines
+func (lv *LogViewer) renderTemplateGroups() string {
+	groups := lv.visibleGroups()
+	if len(groups) == 0 {
+		emptyStyle := lv.theme.HighlightStyle("comment").Italic(true)
+		return emptyStyle.Render("No log templates match the current filter")
+	}
+
+	var rows []string
+	selectedRow := 0
+	for i, t := range groups {
+		marker := "▸"
+		if lv.openGroups[t.ID] {
+			marker = "▾"
+		}
+		var tags []string
+		level := LogLevelInfo
+		if t.Error {
+			tags, level = append(tags, "ERR"), LogLevelError
+		} else if t.Warning {
+			tags, level = append(tags, "WRN"), LogLevelWarn
+		}
+		if t.New {
+			tags = append(tags, "NEW")
+		}
+		row := fmt.Sprintf("%s x%-5d %s", marker, t.Count, strings.Join(append(tags, t.String()), " "))
+		style := lv.getLevelStyle(level)
+		if i == lv.groupIndex {
+			selectedRow = len(rows)
+			style = style.Reverse(true)
+		}
+		rows = append(rows, style.Render(row))
+
+		if !lv.openGroups[t.ID] {
+			continue
+		}
+		lineStyle := lv.theme.HighlightStyle("comment")
+		numbers := lv.Analysis().LinesOf(t.ID)
+		for j, n := range numbers {
+			if j == maxGroupLines {
+				rows = append(rows, lineStyle.Render(fmt.Sprintf("    … %d more", len(numbers)-maxGroupLines)))
+				break
+			}
+			rows = append(rows, lineStyle.Render(fmt.Sprintf("    %5d │ ", n))+lv.lines[n-1].Content)
+		}
+	}
+
+	// Keep the selected group in view
+	height := lv.bodyHeight()
+	if selectedRow < lv.viewOffset {
+		lv.viewOffset = selectedRow
+	} else if selectedRow >= lv.viewOffset+height {
+		lv.viewOffset = selectedRow - height + 1
+	}
+	end := lv.viewOffset + height
+	if end > len(rows) {
+		end = len(rows)
+	}
+	return strings.Join(rows[lv.viewOffset:end], "\n")
+}
+
 // renderLogLine renders a single log line
 func (lv *LogViewer) renderLogLine(line LogLine) string {
 	var parts []string
@@ -469,6 +713,11 @@ func (lv *LogViewer) renderLogLine(line LogLine) string {
 	levelIndicator := lv.getLevelIndicator(line.Level)
 	parts = append(parts, levelStyle.Render(levelIndicator))
 
+	// Origin, one color per pod/container
+	if line.Origin != "" {
+		parts = append(parts, lipgloss.NewStyle().Foreground(originColor(line.Origin)).Render(line.Origin))
+	}
+
 	// Content
 	content := line.Content
 	contentStyle := lv.theme.HighlightStyle("string")
@@ -520,10 +769,20 @@ func (lv *LogViewer) renderSearchBar() string {
 // renderFooter renders keyboard shortcuts
 func (lv *LogViewer) renderFooter() string {
 	footerStyle := lv.theme.HighlightStyle("comment")
-	shortcuts := "e: errors • w: warnings • /: search • ]: expand • ↑↓: scroll"
+	shortcuts := "e: errors • w: warnings • /: search • ]: expand • t: templates • f: follow • p: pause • ↑↓: scroll"
+	if lv.grouped {
+		shortcuts = "e: errors • w: warnings • enter: open/close group • t: lines • ↑↓: select"
+	}
 	return footerStyle.Render(shortcuts)
 }
 
+// originColor picks a stable color for a pod/container
+func originColor(origin string) lipgloss.Color {
+	h := fnv.New32a()
+	h.Write([]byte(origin))
+	return originColors[h.Sum32()%uint32(len(originColors))]
+}
+
 // Helper methods for styling
 func (lv *LogViewer) getLevelStyle(level LogLevel) lipgloss.Style {
 	switch level {
@@ -592,20 +851,37 @@ func (lv *LogViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return lv.handleSearchInput(msg)
 		}
 
+		if lv.grouped {
+			return lv.handleGroupInput(msg)
+		}
+
 		switch msg.String() {
 		case "e":
 			lv.ToggleErrorFilter()
 		case "w":
 			lv.ToggleWarnFilter()
+		case "t":
+			lv.ToggleGrouped()
+		case "f":
+			lv.SetFollow(!lv.follow)
+		case "p":
+			if lv.paused {
+				lv.Resume()
+			} else {
+				lv.Pause()
+			}
 		case "/":
 			lv.StartSearch()
 		case "]":
 			lv.ToggleExpanded()
 		case "up", "k":
+			// Scrolling back stops following, like less +F
+			lv.follow = false
 			lv.ScrollUp()
 		case "down", "j":
 			lv.ScrollDown()
 		case "g":
+			lv.follow = false
 			lv.viewOffset = 0
 		case "G":
 			lv.ScrollToBottom()
@@ -615,6 +891,35 @@ func (lv *LogViewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return lv, nil
 }
 
+// handleGroupInput handles keys in the template view
+func (lv *LogViewer) handleGroupInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "e":
+		lv.ToggleErrorFilter()
+		lv.groupIndex = 0
+	case "w":
+		lv.ToggleWarnFilter()
+		lv.groupIndex = 0
+	case "t":
+		lv.ToggleGrouped()
+	case "enter", " ":
+		lv.ToggleGroup()
+	case "up", "k":
+		if lv.groupIndex > 0 {
+			lv.groupIndex--
+		}
+	case "down", "j":
+		if lv.groupIndex < len(lv.visibleGroups())-1 {
+			lv.groupIndex++
+		}
+	case "g":
+		lv.groupIndex = 0
+	case "G":
+		lv.groupIndex = len(lv.visibleGroups()) - 1
+	}
+	return lv, nil
+}
+
 // handleSearchInput handles input during search mode
 func (lv *LogViewer) handleSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.Type {
@@ -646,6 +951,21 @@ func (lv *LogViewer) GetTotalLineCount() int {
 	return lv.totalLines
 }
 
+// IsGrouped returns whether the template view is shown
+func (lv *LogViewer) IsGrouped() bool {
+	return lv.grouped
+}
+
+// IsFollowing returns whether the newest line is kept in view
+func (lv *LogViewer) IsFollowing() bool {
+	return lv.follow
+}
+
+// IsPaused returns whether new lines are held back
+func (lv *LogViewer) IsPaused() bool {
+	return lv.paused
+}
+
 // IsExpanded returns whether the view is expanded
 func (lv *LogViewer) IsExpanded() bool {
 	return lv.expanded
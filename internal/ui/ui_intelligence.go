@@ -41,10 +41,11 @@ func NewIntelligentUI() *IntelligentUI {
 	}
 }
 
-// UpdateIntelligence refreshes UI intelligence based on current context
-func (ui *IntelligentUI) UpdateIntelligence(input string, context *KubeContextSummary) error {
+// UpdateIntelligence refreshes UI intelligence based on current context. model is empty
+// while the user types, so only submitted input waits on a model call.
+func (ui *IntelligentUI) UpdateIntelligence(input string, context *KubeContextSummary, model string) error {
 	// Analyze current situation
-	session, err := Intelligence.AnalyzeIntelligently(input, context)
+	session, err := Intelligence.AnalyzeSubmitted(input, context, model)
 	if err != nil {
 		return err
 	}
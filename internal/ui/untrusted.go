@@ -0,0 +1,56 @@
+// untrusted.go - Flag prompt injection in cluster output and taint commands derived from it
+package ui
+
+import (
+	"github.com/siryoos/kubemage/internal/engine"
+	"github.com/siryoos/kubemage/internal/engine/validator"
+)
+
+// flagInjection scans cluster output and posts a warning when it looks like prompt
+// injection. The warning carries the findings so later commands stay tainted
+// while it is inside the history window sent to the model.
+func (m *model) flagInjection(source, text string) bool {
+	findings := engine.DetectInjection(text)
+	if len(findings) == 0 {
+		return false
+	}
+	m.messages = append(m.messages, message{
+		sender:    systemSender,
+		content:   engine.InjectionWarning(source, findings),
+		untrusted: true,
+		injection: findings,
+	})
+	return true
+}
+
+// recentInjection returns the findings inside the history window the model sees
+func (m *model) recentInjection() []engine.InjectionFinding {
+	start := 0
+	if m.config != nil && len(m.messages) > m.config.HistoryLength {
+		start = len(m.messages) - m.config.HistoryLength
+	}
+	var findings []engine.InjectionFinding
+	for _, msg := range m.messages[start:] {
+		findings = append(findings, msg.injection...)
+	}
+	return findings
+}
+
+// buildPlan builds the pre-exec plan from the untrusted messages the model saw, so
+// suspected injection there forces typed confirmation
+func (m *model) buildPlan(command string) validator.PreExecPlan {
+	start := 0
+	if m.config != nil && len(m.messages) > m.config.HistoryLength {
+		start = len(m.messages) - m.config.HistoryLength
+	}
+	var context []string
+	for _, msg := range m.messages[start:] {
+		if msg.untrusted {
+			context = append(context, msg.content)
+		}
+		for _, f := range msg.injection {
+			context = append(context, f.Excerpt)
+		}
+	}
+	return validator.BuildPreExecPlanTainted(command, context...)
+}